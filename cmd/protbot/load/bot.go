@@ -0,0 +1,307 @@
+package load
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"erupe-ce/cmd/protbot/protocol"
+)
+
+// Action is one step of a bot's behaviour script.
+type Action string
+
+// Actions a bot can perform between think times.
+const (
+	ActionMove  Action = "move"  // Enumerate lobby stages and move to one of them
+	ActionChat  Action = "chat"  // Send a stage chat message
+	ActionQuest Action = "quest" // Enumerate quests, enter a quest stage and come back
+	ActionSave  Action = "save"  // Write the loaded save blob back unchanged
+	ActionMail  Action = "mail"  // List the character's mail
+)
+
+// DefaultWeights is the relative frequency of each action.
+var DefaultWeights = map[Action]int{
+	ActionMove:  4,
+	ActionChat:  3,
+	ActionQuest: 2,
+	ActionSave:  1,
+	ActionMail:  2,
+}
+
+// errDisconnected marks a failure that ends the bot's session.
+var errDisconnected = errors.New("disconnected")
+
+// script is a bot's randomised behaviour: its own action weights, drawn
+// around the configured ones so that bots differ from each other.
+type script struct {
+	actions []Action
+	weights []int
+	total   int
+}
+
+// newScript jitters each weight by ±50% using rng. Actions with a zero
+// weight are never picked.
+func newScript(rng *rand.Rand, weights map[Action]int) *script {
+	sc := &script{}
+	for _, a := range []Action{ActionMove, ActionChat, ActionQuest, ActionSave, ActionMail} {
+		w := weights[a]
+		if w <= 0 {
+			continue
+		}
+		w = w*50 + rng.Intn(w*100+1) // 0.5w to 1.5w, scaled by 100
+		sc.actions = append(sc.actions, a)
+		sc.weights = append(sc.weights, w)
+		sc.total += w
+	}
+	return sc
+}
+
+// next picks an action by weight.
+func (sc *script) next(rng *rand.Rand) Action {
+	n := rng.Intn(sc.total)
+	for i, w := range sc.weights {
+		if n < w {
+			return sc.actions[i]
+		}
+		n -= w
+	}
+	return sc.actions[len(sc.actions)-1]
+}
+
+// bot is one simulated player.
+type bot struct {
+	id       int
+	cfg      Config
+	stats    *Stats
+	rng      *rand.Rand
+	script   *script
+	ch       *protocol.ChannelConn
+	charID   uint32
+	saveData []byte
+	stages   []string
+}
+
+// run logs in, performs random actions until ctx is done, then logs out.
+func (b *bot) run(ctx context.Context) {
+	if err := b.connect(); err != nil {
+		return
+	}
+	b.stats.Connected()
+	defer func() { _ = b.ch.Close() }()
+
+	if err := b.setup(); err != nil {
+		b.stats.ConnFail("setup")
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = b.ch.SendPacket(protocol.BuildLogoutPacket())
+			return
+		case <-time.After(b.think()):
+		}
+		if err := b.do(b.script.next(b.rng)); errors.Is(err, errDisconnected) {
+			b.stats.ConnFail("dropped")
+			return
+		}
+	}
+}
+
+// think returns a random pause before the next action.
+func (b *bot) think() time.Duration {
+	if b.cfg.Think <= 0 {
+		return 0
+	}
+	return time.Duration(b.rng.Int63n(int64(b.cfg.Think)))
+}
+
+// connect performs the sign → entrance → channel login flow, recording which
+// step failed.
+func (b *bot) connect() error {
+	sign, err := protocol.DoSign(b.cfg.SignAddr, b.cfg.username(b.id), b.cfg.Pass)
+	if err != nil {
+		b.stats.ConnFail("sign")
+		return err
+	}
+	if len(sign.CharIDs) == 0 {
+		b.stats.ConnFail("sign")
+		return fmt.Errorf("no characters on account")
+	}
+	servers, err := protocol.DoEntrance(sign.EntranceAddr)
+	if err != nil || len(servers) == 0 {
+		b.stats.ConnFail("entrance")
+		return fmt.Errorf("entrance: %v", err)
+	}
+	server := servers[b.id%len(servers)]
+	ch, err := protocol.ConnectChannel(fmt.Sprintf("%s:%d", server.IP, server.Port))
+	if err != nil {
+		b.stats.ConnFail("channel")
+		return err
+	}
+	// Server pushes (other players, chat) are expected under load.
+	ch.OnUnhandled(func(uint16, []byte) {})
+	b.ch = ch
+	b.charID = sign.CharIDs[0]
+
+	ack := ch.NextAckHandle()
+	resp, err := b.request(ack, protocol.BuildLoginPacket(ack, b.charID, sign.TokenID, sign.TokenString))
+	if err != nil || resp.ErrorCode != 0 {
+		b.stats.ConnFail("login")
+		_ = ch.Close()
+		return fmt.Errorf("login: %v", err)
+	}
+	return nil
+}
+
+// setup mirrors scenario.SetupSession and scenario.EnterLobby without the
+// progress output.
+func (b *bot) setup() error {
+	ack := b.ch.NextAckHandle()
+	if _, err := b.request(ack, protocol.BuildIssueLogkeyPacket(ack)); err != nil {
+		return err
+	}
+	ack = b.ch.NextAckHandle()
+	if _, err := b.request(ack, protocol.BuildRightsReloadPacket(ack)); err != nil {
+		return err
+	}
+	ack = b.ch.NextAckHandle()
+	resp, err := b.request(ack, protocol.BuildLoaddataPacket(ack))
+	if err != nil {
+		return err
+	}
+	b.saveData = resp.Data
+
+	if err := b.enumerateStages(); err != nil {
+		return err
+	}
+	stageID := "sl1Ns200p0a0u0"
+	if len(b.stages) > 0 {
+		stageID = b.stages[0]
+	}
+	ack = b.ch.NextAckHandle()
+	_, err = b.request(ack, protocol.BuildEnterStagePacket(ack, stageID))
+	return err
+}
+
+// do performs one action. Only errors that end the session are returned;
+// timeouts and failed ACKs are just counted.
+func (b *bot) do(action Action) error {
+	switch action {
+	case ActionMove:
+		if err := b.enumerateStages(); err != nil {
+			return err
+		}
+		if len(b.stages) == 0 {
+			return nil
+		}
+		ack := b.ch.NextAckHandle()
+		_, err := b.request(ack, protocol.BuildMoveStagePacket(ack, b.stages[b.rng.Intn(len(b.stages))]))
+		return err
+
+	case ActionChat:
+		msg := fmt.Sprintf("load test %d", b.rng.Intn(100000))
+		pkt := protocol.BuildCastBinaryPacket(0x03, 1, protocol.BuildChatPayload(1, msg, b.cfg.username(b.id)))
+		if err := b.ch.SendPacket(pkt); err != nil {
+			b.stats.Fail(protocol.MSG_SYS_CAST_BINARY)
+			return errDisconnected
+		}
+		b.stats.Sent(protocol.MSG_SYS_CAST_BINARY)
+		return nil
+
+	case ActionQuest:
+		ack := b.ch.NextAckHandle()
+		if _, err := b.request(ack, protocol.BuildEnumerateQuestPacket(ack, 0, 0, 0)); err != nil {
+			return err
+		}
+		stageID := fmt.Sprintf("sl1Qs%dp0a0u%d", 200+b.rng.Intn(100), b.charID)
+		ack = b.ch.NextAckHandle()
+		if _, err := b.request(ack, protocol.BuildCreateStagePacket(ack, 1, 4, stageID)); err != nil {
+			return err
+		}
+		ack = b.ch.NextAckHandle()
+		if _, err := b.request(ack, protocol.BuildMoveStagePacket(ack, stageID)); err != nil {
+			return err
+		}
+		ack = b.ch.NextAckHandle()
+		_, err := b.request(ack, protocol.BuildBackStagePacket(ack))
+		return err
+
+	case ActionSave:
+		if len(b.saveData) == 0 {
+			return nil
+		}
+		ack := b.ch.NextAckHandle()
+		_, err := b.request(ack, protocol.BuildSavedataPacket(ack, 0, b.saveData))
+		return err
+
+	case ActionMail:
+		ack := b.ch.NextAckHandle()
+		_, err := b.request(ack, protocol.BuildListMailPacket(ack))
+		return err
+	}
+	return nil
+}
+
+// enumerateStages refreshes the list of lobby stages the bot can move to.
+func (b *bot) enumerateStages() error {
+	ack := b.ch.NextAckHandle()
+	resp, err := b.request(ack, protocol.BuildEnumerateStagePacket(ack, "sl1Ns"))
+	if err != nil || resp.ErrorCode != 0 {
+		return err
+	}
+	b.stages = parseStageIDs(resp.Data)
+	return nil
+}
+
+// request sends pkt and waits for the ACK to ack, recording its latency
+// under the packet's opcode. A nil response means the ACK timed out; the
+// returned error is errDisconnected only if the packet could not be sent.
+func (b *bot) request(ack uint32, pkt []byte) (*protocol.AckResponse, error) {
+	opcode := binary.BigEndian.Uint16(pkt)
+	start := time.Now()
+	if err := b.ch.SendPacket(pkt); err != nil {
+		b.stats.Fail(opcode)
+		return nil, errDisconnected
+	}
+	resp, err := b.ch.WaitForAck(ack, b.cfg.AckTimeout)
+	if err != nil {
+		b.stats.Fail(opcode)
+		return nil, err
+	}
+	b.stats.Ack(opcode, time.Since(start), resp.ErrorCode)
+	return resp, nil
+}
+
+// parseStageIDs extracts the stage IDs of a MSG_SYS_ENUMERATE_STAGE ACK.
+// Layout matches scenario.parseEnumerateStageResponse.
+func parseStageIDs(data []byte) []string {
+	if len(data) < 2 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint16(data))
+	off := 2
+	var ids []string
+	for i := 0; i < count; i++ {
+		off += 9 // Reserved, clients, displayed, max players, flags
+		if off >= len(data) {
+			break
+		}
+		n := int(data[off])
+		off++
+		if off+n > len(data) {
+			break
+		}
+		id := data[off : off+n]
+		if len(id) > 0 && id[len(id)-1] == 0 {
+			id = id[:len(id)-1]
+		}
+		ids = append(ids, string(id))
+		off += n
+	}
+	return ids
+}
@@ -0,0 +1,111 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config controls a load run.
+type Config struct {
+	SignAddr string
+	// User is the account name. A %d verb is replaced by the bot number
+	// (starting at 1) so each bot logs in to its own account.
+	User       string
+	Pass       string
+	Bots       int
+	Duration   time.Duration // How long each bot stays online
+	Ramp       time.Duration // Delay between starting consecutive bots
+	Think      time.Duration // Upper bound of the random pause between actions
+	AckTimeout time.Duration
+	Seed       int64
+	Weights    map[Action]int
+}
+
+// username returns the account name of bot id.
+func (c Config) username(id int) string {
+	if strings.Contains(c.User, "%d") {
+		return fmt.Sprintf(c.User, id+1)
+	}
+	return c.User
+}
+
+// ParseWeights parses a comma-separated list of action=weight pairs,
+// e.g. "move=4,chat=2,save=0". Unlisted actions keep their default weight.
+func ParseWeights(list string) (map[Action]int, error) {
+	weights := make(map[Action]int, len(DefaultWeights))
+	for a, w := range DefaultWeights {
+		weights[a] = w
+	}
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid weight %q (want action=weight)", pair)
+		}
+		a := Action(strings.TrimSpace(name))
+		if _, known := DefaultWeights[a]; !known {
+			return nil, fmt.Errorf("unknown action %q", name)
+		}
+		var w int
+		if _, err := fmt.Sscan(strings.TrimSpace(value), &w); err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight for %s: %q", a, value)
+		}
+		weights[a] = w
+	}
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("all action weights are zero")
+	}
+	return weights, nil
+}
+
+// Run starts cfg.Bots bots, lets them act for cfg.Duration or until ctx is
+// cancelled, and returns the collected statistics once all have logged out.
+func Run(ctx context.Context, cfg Config) *Stats {
+	if cfg.Weights == nil {
+		cfg.Weights = DefaultWeights
+	}
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = 10 * time.Second
+	}
+	stats := NewStats()
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Bots; i++ {
+		if i > 0 && cfg.Ramp > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(cfg.Ramp):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		rng := rand.New(rand.NewSource(cfg.Seed + int64(i)))
+		b := &bot{
+			id:     i,
+			cfg:    cfg,
+			stats:  stats,
+			rng:    rng,
+			script: newScript(rng, cfg.Weights),
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			botCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+			defer cancel()
+			b.run(botCtx)
+		}()
+	}
+	wg.Wait()
+	return stats
+}
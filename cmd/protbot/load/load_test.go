@@ -0,0 +1,136 @@
+package load
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"erupe-ce/cmd/protbot/protocol"
+)
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	tests := []struct {
+		p    int
+		want time.Duration
+	}{
+		{50, 50 * time.Millisecond},
+		{95, 95 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("p%d = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(sorted[:1], 99); got != time.Millisecond {
+		t.Errorf("single sample p99 = %v", got)
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("empty p50 = %v", got)
+	}
+}
+
+func TestStatsReport(t *testing.T) {
+	s := NewStats()
+	s.Ack(protocol.MSG_MHF_LIST_MAIL, 2*time.Millisecond, 0)
+	s.Ack(protocol.MSG_MHF_LIST_MAIL, 4*time.Millisecond, 1)
+	s.Fail(protocol.MSG_MHF_LIST_MAIL)
+	s.Sent(protocol.MSG_SYS_CAST_BINARY)
+	s.Connected()
+	s.ConnFail("sign")
+	s.ConnFail("sign")
+	s.ConnFail("dropped")
+
+	reports := s.Report()
+	if len(reports) != 2 || reports[0].Opcode != protocol.MSG_SYS_CAST_BINARY {
+		t.Fatalf("reports = %+v", reports)
+	}
+	mail := reports[1]
+	if mail.Sent != 3 || mail.Acked != 2 || mail.Errors != 2 || mail.P50 != 2*time.Millisecond || mail.Max != 4*time.Millisecond {
+		t.Errorf("mail report = %+v", mail)
+	}
+
+	var buf bytes.Buffer
+	s.WriteReport(&buf)
+	out := buf.String()
+	for _, want := range []string{"MSG_MHF_LIST_MAIL", "MSG_SYS_CAST_BINARY", "connected: 1", "connection failures: 3", "sign: 2", "dropped: 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	w, err := ParseWeights("move=1, save=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w[ActionMove] != 1 || w[ActionSave] != 0 || w[ActionChat] != DefaultWeights[ActionChat] {
+		t.Errorf("weights = %v", w)
+	}
+	for _, bad := range []string{"move", "fly=1", "move=-1", "move=x", "move=0,chat=0,quest=0,save=0,mail=0"} {
+		if _, err := ParseWeights(bad); err == nil {
+			t.Errorf("ParseWeights(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestScriptSkipsZeroWeights(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sc := newScript(rng, map[Action]int{ActionMail: 1, ActionChat: 0})
+	for i := 0; i < 50; i++ {
+		if a := sc.next(rng); a != ActionMail {
+			t.Fatalf("picked %q", a)
+		}
+	}
+}
+
+func TestScriptsDifferPerBot(t *testing.T) {
+	a := newScript(rand.New(rand.NewSource(1)), DefaultWeights)
+	b := newScript(rand.New(rand.NewSource(2)), DefaultWeights)
+	if len(a.weights) != len(DefaultWeights) {
+		t.Fatalf("got %d actions", len(a.weights))
+	}
+	same := true
+	for i := range a.weights {
+		if a.weights[i] != b.weights[i] {
+			same = false
+		}
+	}
+	if same {
+		t.Error("different seeds produced identical scripts")
+	}
+}
+
+func TestUsername(t *testing.T) {
+	if got := (Config{User: "bot%d"}).username(0); got != "bot1" {
+		t.Errorf("username = %q, want bot1", got)
+	}
+	if got := (Config{User: "shared"}).username(5); got != "shared" {
+		t.Errorf("username = %q, want shared", got)
+	}
+}
+
+func TestParseStageIDs(t *testing.T) {
+	data := []byte{0x00, 0x02}
+	for _, id := range []string{"sl1Ns200p0a0u0", "sl1Ns211p0a0u0"} {
+		data = append(data, make([]byte, 9)...)
+		data = append(data, byte(len(id)+1))
+		data = append(data, id...)
+		data = append(data, 0)
+	}
+	ids := parseStageIDs(data)
+	if len(ids) != 2 || ids[0] != "sl1Ns200p0a0u0" || ids[1] != "sl1Ns211p0a0u0" {
+		t.Errorf("ids = %q", ids)
+	}
+	if ids := parseStageIDs(data[:15]); len(ids) != 0 {
+		t.Errorf("truncated ids = %q", ids)
+	}
+}
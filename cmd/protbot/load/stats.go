@@ -0,0 +1,181 @@
+// Package load runs many protbot sessions at once and measures how the
+// server holds up.
+package load
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"erupe-ce/cmd/protbot/protocol"
+)
+
+// Stats collects per-opcode ACK latencies and failures across bots.
+// It is safe for concurrent use.
+type Stats struct {
+	mu        sync.Mutex
+	ops       map[uint16]*opStats
+	connFails map[string]int
+	connected int
+}
+
+type opStats struct {
+	latencies []time.Duration
+	sent      int
+	errors    int
+}
+
+// NewStats creates an empty Stats.
+func NewStats() *Stats {
+	return &Stats{
+		ops:       make(map[uint16]*opStats),
+		connFails: make(map[string]int),
+	}
+}
+
+func (s *Stats) op(opcode uint16) *opStats {
+	o, ok := s.ops[opcode]
+	if !ok {
+		o = &opStats{}
+		s.ops[opcode] = o
+	}
+	return o
+}
+
+// Ack records an ACK received d after its request was sent. A non-zero
+// errorCode also counts as an error.
+func (s *Stats) Ack(opcode uint16, d time.Duration, errorCode uint8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.op(opcode)
+	o.sent++
+	o.latencies = append(o.latencies, d)
+	if errorCode != 0 {
+		o.errors++
+	}
+}
+
+// Fail records a request that could not be sent or whose ACK never came.
+func (s *Stats) Fail(opcode uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.op(opcode)
+	o.sent++
+	o.errors++
+}
+
+// Sent records a packet the server does not acknowledge, such as chat.
+func (s *Stats) Sent(opcode uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.op(opcode).sent++
+}
+
+// Connected records a bot that finished logging in.
+func (s *Stats) Connected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected++
+}
+
+// ConnFail records a bot that failed to connect or lost its connection.
+// stage names the step that failed, e.g. "sign" or "channel".
+func (s *Stats) ConnFail(stage string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connFails[stage]++
+}
+
+// OpReport summarises one opcode. Latencies are zero for opcodes that were
+// never acknowledged.
+type OpReport struct {
+	Opcode uint16
+	Name   string
+	Sent   int
+	Acked  int
+	Errors int
+	P50    time.Duration
+	P95    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+// Report returns a summary per opcode, sorted by opcode.
+func (s *Stats) Report() []OpReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reports := make([]OpReport, 0, len(s.ops))
+	for opcode, o := range s.ops {
+		sorted := append([]time.Duration(nil), o.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		r := OpReport{
+			Opcode: opcode,
+			Name:   protocol.OpcodeName(opcode),
+			Sent:   o.sent,
+			Acked:  len(sorted),
+			Errors: o.errors,
+			P50:    percentile(sorted, 50),
+			P95:    percentile(sorted, 95),
+			P99:    percentile(sorted, 99),
+		}
+		if len(sorted) > 0 {
+			r.Max = sorted[len(sorted)-1]
+		}
+		reports = append(reports, r)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Opcode < reports[j].Opcode })
+	return reports
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// WriteReport prints the per-opcode table followed by connection counts.
+func (s *Stats) WriteReport(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(tw, "opcode\tsent\tacked\terrors\tp50\tp95\tp99\tmax\t")
+	for _, r := range s.Report() {
+		if r.Acked == 0 {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t-\t-\t-\t-\t\n", r.Name, r.Sent, r.Acked, r.Errors)
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t\n", r.Name, r.Sent, r.Acked, r.Errors,
+			round(r.P50), round(r.P95), round(r.P99), round(r.Max))
+	}
+	_ = tw.Flush()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = fmt.Fprintf(w, "\nconnected: %d\n", s.connected)
+	stages := make([]string, 0, len(s.connFails))
+	total := 0
+	for stage, n := range s.connFails {
+		stages = append(stages, stage)
+		total += n
+	}
+	sort.Strings(stages)
+	_, _ = fmt.Fprintf(w, "connection failures: %d\n", total)
+	for _, stage := range stages {
+		_, _ = fmt.Fprintf(w, "  %s: %d\n", stage, s.connFails[stage])
+	}
+}
+
+// round trims a latency to a readable precision.
+func round(d time.Duration) time.Duration {
+	if d >= time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
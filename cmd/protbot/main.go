@@ -7,26 +7,68 @@
 //	protbot --sign-addr 127.0.0.1:53312 --user test --pass test --action session
 //	protbot --sign-addr 127.0.0.1:53312 --user test --pass test --action chat --message "Hello"
 //	protbot --sign-addr 127.0.0.1:53312 --user test --pass test --action quests
+//	protbot --sign-addr 127.0.0.1:53312 --user bot%d --pass test --action load --bots 50 --duration 5m
+//	protbot --sign-addr 127.0.0.1:53312 --action script --scenario guild.yaml --var user=test --var pass=test
+//
+// The load action logs in --bots bots (one account each when --user contains
+// %d) that move between stages, chat, enter quests, save and check mail in a
+// random order, then prints ACK latency percentiles per opcode.
+//
+// The script action runs a YAML or JSON scenario file (see package script)
+// and exits non-zero when a step fails or an expectation does not hold.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"erupe-ce/cmd/protbot/load"
 	"erupe-ce/cmd/protbot/scenario"
+	"erupe-ce/cmd/protbot/script"
 )
 
+// varFlags collects repeated --var name=value flags.
+type varFlags map[string]string
+
+func (v varFlags) String() string { return "" }
+
+func (v varFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("want name=value")
+	}
+	v[name] = value
+	return nil
+}
+
 func main() {
 	signAddr := flag.String("sign-addr", "127.0.0.1:53312", "Sign server address (host:port)")
 	user := flag.String("user", "", "Username")
 	pass := flag.String("pass", "", "Password")
-	action := flag.String("action", "login", "Action to perform: login, lobby, session, chat, quests")
+	action := flag.String("action", "login", "Action to perform: login, lobby, session, chat, quests, load, script")
 	message := flag.String("message", "", "Chat message to send (used with --action chat)")
+	bots := flag.Int("bots", 10, "Number of bots (used with --action load)")
+	duration := flag.Duration("duration", time.Minute, "How long each bot stays online (used with --action load)")
+	ramp := flag.Duration("ramp", 100*time.Millisecond, "Delay between bot logins (used with --action load)")
+	think := flag.Duration("think", 2*time.Second, "Maximum pause between bot actions (used with --action load)")
+	ackTimeout := flag.Duration("ack-timeout", 10*time.Second, "ACK timeout per request (used with --action load)")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "Random seed for bot behaviour (used with --action load)")
+	weights := flag.String("weights", "", "Action weights, e.g. move=4,chat=3,quest=2,save=1,mail=2 (used with --action load)")
+	scenarioFile := flag.String("scenario", "", "Scenario file to run (used with --action script)")
+	vars := varFlags{}
+	flag.Var(vars, "var", "Scenario variable as name=value, repeatable (used with --action script)")
 	flag.Parse()
 
+	if *action == "script" {
+		os.Exit(runScript(*signAddr, *scenarioFile, *user, *pass, vars))
+	}
+
 	if *user == "" || *pass == "" {
 		fmt.Fprintln(os.Stderr, "error: --user and --pass are required")
 		flag.Usage()
@@ -139,12 +181,62 @@ func main() {
 		fmt.Printf("[quests] Received %d bytes of quest data\n", len(data))
 		_ = scenario.Logout(result.Channel)
 
+	case "load":
+		w, err := load.ParseWeights(*weights)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		fmt.Printf("[load] Starting %d bot(s) for %s (seed %d). Press Ctrl+C to stop early.\n", *bots, *duration, *seed)
+		stats := load.Run(ctx, load.Config{
+			SignAddr:   *signAddr,
+			User:       *user,
+			Pass:       *pass,
+			Bots:       *bots,
+			Duration:   *duration,
+			Ramp:       *ramp,
+			Think:      *think,
+			AckTimeout: *ackTimeout,
+			Seed:       *seed,
+			Weights:    w,
+		})
+		stop()
+		fmt.Println()
+		stats.WriteReport(os.Stdout)
+
 	default:
-		fmt.Fprintf(os.Stderr, "unknown action: %s (supported: login, lobby, session, chat, quests)\n", *action)
+		fmt.Fprintf(os.Stderr, "unknown action: %s (supported: login, lobby, session, chat, quests, load, script)\n", *action)
 		os.Exit(1)
 	}
 }
 
+// runScript runs a scenario file and returns the process exit code. --user
+// and --pass, when given, set the user and pass variables.
+func runScript(signAddr, path, user, pass string, vars varFlags) int {
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "error: --scenario is required")
+		return 1
+	}
+	f, err := script.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, ok := vars["user"]; !ok && user != "" {
+		vars["user"] = user
+	}
+	if _, ok := vars["pass"]; !ok && pass != "" {
+		vars["pass"] = pass
+	}
+	r := &script.Runner{SignAddr: signAddr, Vars: vars}
+	if err := r.Run(f); err != nil {
+		fmt.Fprintf(os.Stderr, "scenario failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
 // waitForSignal blocks until SIGINT or SIGTERM is received.
 func waitForSignal() {
 	sig := make(chan os.Signal, 1)
@@ -17,8 +17,12 @@ type PacketHandler func(opcode uint16, data []byte)
 type ChannelConn struct {
 	conn       *conn.MHFConn
 	ackCounter uint32
-	waiters    sync.Map // map[uint32]chan *AckResponse
-	handlers   sync.Map // map[uint16]PacketHandler
+	ackMu      sync.Mutex
+	waiters    map[uint32]chan *AckResponse
+	early      map[uint32]*AckResponse // ACKs that arrived before WaitForAck
+	expired    map[uint32]struct{}     // Handles whose WaitForAck timed out
+	handlers   sync.Map                // map[uint16]PacketHandler
+	unhandled  atomic.Value            // PacketHandler
 	closed     atomic.Bool
 }
 
@@ -28,6 +32,12 @@ func (ch *ChannelConn) OnPacket(opcode uint16, handler PacketHandler) {
 	ch.handlers.Store(opcode, handler)
 }
 
+// OnUnhandled registers a handler for server-pushed packets that have no
+// opcode handler, replacing the default of printing them.
+func (ch *ChannelConn) OnUnhandled(handler PacketHandler) {
+	ch.unhandled.Store(handler)
+}
+
 // AckResponse holds the parsed ACK data from the server.
 type AckResponse struct {
 	AckHandle        uint32
@@ -45,7 +55,10 @@ func ConnectChannel(addr string) (*ChannelConn, error) {
 	}
 
 	ch := &ChannelConn{
-		conn: c,
+		conn:    c,
+		waiters: make(map[uint32]chan *AckResponse),
+		early:   make(map[uint32]*AckResponse),
+		expired: make(map[uint32]struct{}),
 	}
 
 	go ch.recvLoop()
@@ -64,15 +77,31 @@ func (ch *ChannelConn) SendPacket(data []byte) error {
 }
 
 // WaitForAck waits for an ACK response matching the given handle.
+// An ACK that arrived between sending the request and calling WaitForAck is
+// returned immediately.
 func (ch *ChannelConn) WaitForAck(handle uint32, timeout time.Duration) (*AckResponse, error) {
+	ch.ackMu.Lock()
+	if resp, ok := ch.early[handle]; ok {
+		delete(ch.early, handle)
+		ch.ackMu.Unlock()
+		return resp, nil
+	}
 	waitCh := make(chan *AckResponse, 1)
-	ch.waiters.Store(handle, waitCh)
-	defer ch.waiters.Delete(handle)
+	ch.waiters[handle] = waitCh
+	ch.ackMu.Unlock()
 
 	select {
 	case resp := <-waitCh:
 		return resp, nil
 	case <-time.After(timeout):
+		ch.ackMu.Lock()
+		defer ch.ackMu.Unlock()
+		if _, ok := ch.waiters[handle]; !ok {
+			// Delivered while the timeout fired.
+			return <-waitCh, nil
+		}
+		delete(ch.waiters, handle)
+		ch.expired[handle] = struct{}{}
 		return nil, fmt.Errorf("ACK timeout for handle %d", handle)
 	}
 }
@@ -115,6 +144,8 @@ func (ch *ChannelConn) recvLoop() {
 		default:
 			if val, ok := ch.handlers.Load(opcode); ok {
 				val.(PacketHandler)(opcode, pkt[2:])
+			} else if h, ok := ch.unhandled.Load().(PacketHandler); ok {
+				h(opcode, pkt[2:])
 			} else {
 				fmt.Printf("[channel] recv opcode 0x%04X (%d bytes)\n", opcode, len(pkt))
 			}
@@ -165,13 +196,21 @@ func (ch *ChannelConn) handleAck(data []byte) {
 		Data:             ackData,
 	}
 
-	if val, ok := ch.waiters.Load(ackHandle); ok {
-		waitCh := val.(chan *AckResponse)
-		select {
-		case waitCh <- resp:
-		default:
-		}
-	} else {
+	ch.ackMu.Lock()
+	waitCh, waiting := ch.waiters[ackHandle]
+	_, late := ch.expired[ackHandle]
+	switch {
+	case waiting:
+		delete(ch.waiters, ackHandle)
+		waitCh <- resp // Buffered, never blocks
+	case late:
+		delete(ch.expired, ackHandle)
+	default:
+		ch.early[ackHandle] = resp
+	}
+	ch.ackMu.Unlock()
+
+	if late {
 		fmt.Printf("[channel] unexpected ACK handle %d (error=%d, buffer=%v, %d bytes)\n",
 			ackHandle, errorCode, isBuffer, len(ackData))
 	}
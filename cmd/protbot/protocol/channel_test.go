@@ -0,0 +1,70 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func newTestChannelConn() *ChannelConn {
+	return &ChannelConn{
+		waiters: make(map[uint32]chan *AckResponse),
+		early:   make(map[uint32]*AckResponse),
+		expired: make(map[uint32]struct{}),
+	}
+}
+
+// simpleAck builds the body of a simple MSG_SYS_ACK (without the opcode).
+func simpleAck(handle uint32, errorCode uint8) []byte {
+	data := make([]byte, 12)
+	binary.BigEndian.PutUint32(data, handle)
+	data[5] = errorCode
+	return data
+}
+
+func TestWaitForAckReturnsEarlyAck(t *testing.T) {
+	ch := newTestChannelConn()
+	ch.handleAck(simpleAck(5, 1))
+
+	resp, err := ch.WaitForAck(5, time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForAck: %v", err)
+	}
+	if resp.AckHandle != 5 || resp.ErrorCode != 1 {
+		t.Errorf("resp = %+v", resp)
+	}
+	if len(ch.early) != 0 {
+		t.Errorf("early ACK not consumed")
+	}
+}
+
+func TestWaitForAckTimeoutDropsLateAck(t *testing.T) {
+	ch := newTestChannelConn()
+	if _, err := ch.WaitForAck(6, time.Millisecond); err == nil {
+		t.Fatal("expected timeout")
+	}
+	ch.handleAck(simpleAck(6, 0))
+	if len(ch.early) != 0 || len(ch.expired) != 0 {
+		t.Errorf("late ACK kept: early=%d expired=%d", len(ch.early), len(ch.expired))
+	}
+}
+
+func TestWaitForAckDelivered(t *testing.T) {
+	ch := newTestChannelConn()
+	go func() {
+		for {
+			ch.ackMu.Lock()
+			_, ok := ch.waiters[7]
+			ch.ackMu.Unlock()
+			if ok {
+				ch.handleAck(simpleAck(7, 0))
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	resp, err := ch.WaitForAck(7, 5*time.Second)
+	if err != nil || resp.AckHandle != 7 {
+		t.Fatalf("resp = %+v, err = %v", resp, err)
+	}
+}
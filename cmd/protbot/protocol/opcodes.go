@@ -1,23 +1,82 @@
 // Package protocol implements MHF network protocol message building and parsing.
 package protocol
 
+import "fmt"
+
 // Packet opcodes (subset from Erupe's network/packetid.go iota).
 const (
-	MSG_SYS_ACK              uint16 = 0x0012
-	MSG_SYS_LOGIN            uint16 = 0x0014
-	MSG_SYS_LOGOUT           uint16 = 0x0015
-	MSG_SYS_PING             uint16 = 0x0017
-	MSG_SYS_CAST_BINARY      uint16 = 0x0018
-	MSG_SYS_TIME             uint16 = 0x001A
-	MSG_SYS_CASTED_BINARY    uint16 = 0x001B
-	MSG_SYS_ISSUE_LOGKEY     uint16 = 0x001D
-	MSG_SYS_ENTER_STAGE      uint16 = 0x0022
-	MSG_SYS_ENUMERATE_STAGE  uint16 = 0x002F
-	MSG_SYS_INSERT_USER      uint16 = 0x0050
-	MSG_SYS_DELETE_USER      uint16 = 0x0051
-	MSG_SYS_UPDATE_RIGHT     uint16 = 0x0058
-	MSG_SYS_RIGHTS_RELOAD    uint16 = 0x005D
-	MSG_MHF_LOADDATA         uint16 = 0x0061
-	MSG_MHF_ENUMERATE_QUEST  uint16 = 0x009F
-	MSG_MHF_GET_WEEKLY_SCHED uint16 = 0x00E1
+	MSG_SYS_ACK                  uint16 = 0x0012
+	MSG_SYS_LOGIN                uint16 = 0x0014
+	MSG_SYS_LOGOUT               uint16 = 0x0015
+	MSG_SYS_PING                 uint16 = 0x0017
+	MSG_SYS_CAST_BINARY          uint16 = 0x0018
+	MSG_SYS_TIME                 uint16 = 0x001A
+	MSG_SYS_CASTED_BINARY        uint16 = 0x001B
+	MSG_SYS_ISSUE_LOGKEY         uint16 = 0x001D
+	MSG_SYS_CREATE_STAGE         uint16 = 0x0020
+	MSG_SYS_ENTER_STAGE          uint16 = 0x0022
+	MSG_SYS_BACK_STAGE           uint16 = 0x0023
+	MSG_SYS_MOVE_STAGE           uint16 = 0x0024
+	MSG_SYS_ENUMERATE_STAGE      uint16 = 0x002F
+	MSG_SYS_INSERT_USER          uint16 = 0x0050
+	MSG_SYS_DELETE_USER          uint16 = 0x0051
+	MSG_SYS_UPDATE_RIGHT         uint16 = 0x0058
+	MSG_SYS_RIGHTS_RELOAD        uint16 = 0x005D
+	MSG_MHF_SAVEDATA             uint16 = 0x0060
+	MSG_MHF_LOADDATA             uint16 = 0x0061
+	MSG_MHF_SEND_MAIL            uint16 = 0x0068
+	MSG_MHF_LIST_MAIL            uint16 = 0x006A
+	MSG_MHF_CREATE_GUILD         uint16 = 0x0090
+	MSG_MHF_OPERATE_GUILD        uint16 = 0x0091
+	MSG_MHF_OPERATE_GUILD_MEMBER uint16 = 0x0092
+	MSG_MHF_ENUMERATE_QUEST      uint16 = 0x009F
+	MSG_MHF_ENUMERATE_HOUSE      uint16 = 0x00A8
+	MSG_MHF_LOAD_HOUSE           uint16 = 0x00AA
+	MSG_MHF_OPERATE_WAREHOUSE    uint16 = 0x00AB
+	MSG_MHF_ENUMERATE_WAREHOUSE  uint16 = 0x00AC
+	MSG_MHF_UPDATE_WAREHOUSE     uint16 = 0x00AD
+	MSG_MHF_GET_WEEKLY_SCHED     uint16 = 0x00E1
 )
+
+// opcodeNames maps the opcodes above to their Erupe names.
+var opcodeNames = map[uint16]string{
+	MSG_SYS_ACK:                  "MSG_SYS_ACK",
+	MSG_SYS_LOGIN:                "MSG_SYS_LOGIN",
+	MSG_SYS_LOGOUT:               "MSG_SYS_LOGOUT",
+	MSG_SYS_PING:                 "MSG_SYS_PING",
+	MSG_SYS_CAST_BINARY:          "MSG_SYS_CAST_BINARY",
+	MSG_SYS_TIME:                 "MSG_SYS_TIME",
+	MSG_SYS_CASTED_BINARY:        "MSG_SYS_CASTED_BINARY",
+	MSG_SYS_ISSUE_LOGKEY:         "MSG_SYS_ISSUE_LOGKEY",
+	MSG_SYS_CREATE_STAGE:         "MSG_SYS_CREATE_STAGE",
+	MSG_SYS_ENTER_STAGE:          "MSG_SYS_ENTER_STAGE",
+	MSG_SYS_BACK_STAGE:           "MSG_SYS_BACK_STAGE",
+	MSG_SYS_MOVE_STAGE:           "MSG_SYS_MOVE_STAGE",
+	MSG_SYS_ENUMERATE_STAGE:      "MSG_SYS_ENUMERATE_STAGE",
+	MSG_SYS_INSERT_USER:          "MSG_SYS_INSERT_USER",
+	MSG_SYS_DELETE_USER:          "MSG_SYS_DELETE_USER",
+	MSG_SYS_UPDATE_RIGHT:         "MSG_SYS_UPDATE_RIGHT",
+	MSG_SYS_RIGHTS_RELOAD:        "MSG_SYS_RIGHTS_RELOAD",
+	MSG_MHF_SAVEDATA:             "MSG_MHF_SAVEDATA",
+	MSG_MHF_LOADDATA:             "MSG_MHF_LOADDATA",
+	MSG_MHF_SEND_MAIL:            "MSG_MHF_SEND_MAIL",
+	MSG_MHF_LIST_MAIL:            "MSG_MHF_LIST_MAIL",
+	MSG_MHF_CREATE_GUILD:         "MSG_MHF_CREATE_GUILD",
+	MSG_MHF_OPERATE_GUILD:        "MSG_MHF_OPERATE_GUILD",
+	MSG_MHF_OPERATE_GUILD_MEMBER: "MSG_MHF_OPERATE_GUILD_MEMBER",
+	MSG_MHF_ENUMERATE_QUEST:      "MSG_MHF_ENUMERATE_QUEST",
+	MSG_MHF_ENUMERATE_HOUSE:      "MSG_MHF_ENUMERATE_HOUSE",
+	MSG_MHF_LOAD_HOUSE:           "MSG_MHF_LOAD_HOUSE",
+	MSG_MHF_OPERATE_WAREHOUSE:    "MSG_MHF_OPERATE_WAREHOUSE",
+	MSG_MHF_ENUMERATE_WAREHOUSE:  "MSG_MHF_ENUMERATE_WAREHOUSE",
+	MSG_MHF_UPDATE_WAREHOUSE:     "MSG_MHF_UPDATE_WAREHOUSE",
+	MSG_MHF_GET_WEEKLY_SCHED:     "MSG_MHF_GET_WEEKLY_SCHEDULE",
+}
+
+// OpcodeName returns the name of a known opcode, or its hex value otherwise.
+func OpcodeName(opcode uint16) string {
+	if name, ok := opcodeNames[opcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04X", opcode)
+}
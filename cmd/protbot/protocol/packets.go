@@ -75,6 +75,61 @@ func BuildEnterStagePacket(ackHandle uint32, stageID string) []byte {
 	return bf.Data()
 }
 
+// BuildCreateStagePacket builds a MSG_SYS_CREATE_STAGE packet.
+// Layout mirrors Erupe's MsgSysCreateStage.Parse:
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint8  createType
+//	uint8  playerCount
+//	uint8  stageID length (including null terminator)
+//	null-terminated stageID
+//	0x00 0x10 terminator
+func BuildCreateStagePacket(ackHandle uint32, createType, playerCount uint8, stageID string) []byte {
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_SYS_CREATE_STAGE)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint8(createType)
+	bf.WriteUint8(playerCount)
+	bf.WriteUint8(uint8(len(stageID) + 1))
+	bf.WriteNullTerminatedBytes([]byte(stageID))
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
+// BuildMoveStagePacket builds a MSG_SYS_MOVE_STAGE packet.
+// Layout mirrors Erupe's MsgSysMoveStage.Parse:
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint8  unkBool (0)
+//	uint8  stageID length (including null terminator)
+//	null-terminated stageID
+//	0x00 0x10 terminator
+func BuildMoveStagePacket(ackHandle uint32, stageID string) []byte {
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_SYS_MOVE_STAGE)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint8(0) // UnkBool
+	bf.WriteUint8(uint8(len(stageID) + 1))
+	bf.WriteNullTerminatedBytes([]byte(stageID))
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
+// BuildBackStagePacket builds a MSG_SYS_BACK_STAGE packet.
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	0x00 0x10 terminator
+func BuildBackStagePacket(ackHandle uint32) []byte {
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_SYS_BACK_STAGE)
+	bf.WriteUint32(ackHandle)
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
 // BuildPingPacket builds a MSG_SYS_PING response packet.
 //
 //	uint16 opcode
@@ -146,6 +201,48 @@ func BuildLoaddataPacket(ackHandle uint32) []byte {
 	return bf.Data()
 }
 
+// BuildSavedataPacket builds a MSG_MHF_SAVEDATA packet carrying a full,
+// compressed save blob (saveType 0), as returned by MSG_MHF_LOADDATA.
+// Layout mirrors Erupe's MsgMhfSavedata.Parse for G1 and later:
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint32 allocMemSize
+//	uint8  saveType
+//	uint32 unk1 (always 0)
+//	uint32 dataSize
+//	[]byte payload
+//	0x00 0x10 terminator
+func BuildSavedataPacket(ackHandle uint32, saveType uint8, payload []byte) []byte {
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_MHF_SAVEDATA)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint32(uint32(len(payload))) // AllocMemSize
+	bf.WriteUint8(saveType)
+	bf.WriteUint32(0) // Unk1
+	bf.WriteUint32(uint32(len(payload)))
+	bf.WriteBytes(payload)
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
+// BuildListMailPacket builds a MSG_MHF_LIST_MAIL packet.
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint16 zeroed
+//	uint16 zeroed
+//	0x00 0x10 terminator
+func BuildListMailPacket(ackHandle uint32) []byte {
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_MHF_LIST_MAIL)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint16(0) // Zeroed
+	bf.WriteUint16(0) // Zeroed
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
 // BuildCastBinaryPacket builds a MSG_SYS_CAST_BINARY packet.
 // Layout mirrors Erupe's MsgSysCastBinary.Parse:
 //
@@ -227,3 +324,257 @@ func BuildGetWeeklySchedulePacket(ackHandle uint32) []byte {
 	bf.WriteBytes([]byte{0x00, 0x10})
 	return bf.Data()
 }
+
+// BuildCreateGuildPacket builds a MSG_MHF_CREATE_GUILD packet.
+// Layout mirrors Erupe's MsgMhfCreateGuild.Parse:
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint16 zeroed
+//	uint16 name length (SJIS bytes + null terminator)
+//	null-terminated SJIS name
+//	0x00 0x10 terminator
+func BuildCreateGuildPacket(ackHandle uint32, name string) []byte {
+	sjisName := stringsupport.UTF8ToSJIS(name)
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_MHF_CREATE_GUILD)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint16(0) // Zeroed
+	bf.WriteUint16(uint16(len(sjisName) + 1))
+	bf.WriteNullTerminatedBytes(sjisName)
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
+// BuildOperateGuildPacket builds a MSG_MHF_OPERATE_GUILD packet.
+// Layout mirrors Erupe's MsgMhfOperateGuild.Parse:
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint32 guildID
+//	uint8  action
+//	uint8  data2 length
+//	uint32 data1
+//	[]byte data2
+//	0x00 0x10 terminator
+func BuildOperateGuildPacket(ackHandle, guildID uint32, action uint8, data1 uint32, data2 []byte) []byte {
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_MHF_OPERATE_GUILD)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint32(guildID)
+	bf.WriteUint8(action)
+	bf.WriteUint8(uint8(len(data2)))
+	bf.WriteUint32(data1)
+	bf.WriteBytes(data2)
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
+// BuildOperateGuildMemberPacket builds a MSG_MHF_OPERATE_GUILD_MEMBER packet.
+// Layout mirrors Erupe's MsgMhfOperateGuildMember.Parse:
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint32 guildID
+//	uint32 charID
+//	uint8  action (1=accept, 2=reject, 3=kick)
+//	uint8  zeroed
+//	uint16 zeroed
+//	0x00 0x10 terminator
+func BuildOperateGuildMemberPacket(ackHandle, guildID, charID uint32, action uint8) []byte {
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_MHF_OPERATE_GUILD_MEMBER)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint32(guildID)
+	bf.WriteUint32(charID)
+	bf.WriteUint8(action)
+	bf.WriteUint8(0)  // Zeroed
+	bf.WriteUint16(0) // Zeroed
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
+// BuildSendMailPacket builds a MSG_MHF_SEND_MAIL packet. A recipientID of 0
+// sends guild mail.
+// Layout mirrors Erupe's MsgMhfSendMail.Parse:
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint32 recipientID
+//	uint16 subject length (SJIS bytes + null terminator)
+//	uint16 body length (SJIS bytes + null terminator)
+//	uint16 zeroed
+//	uint16 quantity
+//	uint16 itemID
+//	null-terminated SJIS subject
+//	null-terminated SJIS body
+//	0x00 0x10 terminator
+func BuildSendMailPacket(ackHandle, recipientID uint32, subject, body string, itemID, quantity uint16) []byte {
+	sjisSubject := stringsupport.UTF8ToSJIS(subject)
+	sjisBody := stringsupport.UTF8ToSJIS(body)
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_MHF_SEND_MAIL)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint32(recipientID)
+	bf.WriteUint16(uint16(len(sjisSubject) + 1))
+	bf.WriteUint16(uint16(len(sjisBody) + 1))
+	bf.WriteUint16(0) // Zeroed
+	bf.WriteUint16(quantity)
+	bf.WriteUint16(itemID)
+	bf.WriteNullTerminatedBytes(sjisSubject)
+	bf.WriteNullTerminatedBytes(sjisBody)
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
+// BuildOperateWarehousePacket builds a MSG_MHF_OPERATE_WAREHOUSE packet.
+// Operations: 0=get box names, 1=commit usage, 2=rename, 3=get usage limit.
+// Layout mirrors Erupe's MsgMhfOperateWarehouse.Parse:
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint8  operation
+//	uint8  boxType (0=items, 1=equipment)
+//	uint8  boxIndex
+//	uint8  name length (0 when no name)
+//	uint16 zeroed
+//	null-terminated SJIS name (only when name length > 0)
+//	0x00 0x10 terminator
+func BuildOperateWarehousePacket(ackHandle uint32, operation, boxType, boxIndex uint8, name string) []byte {
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_MHF_OPERATE_WAREHOUSE)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint8(operation)
+	bf.WriteUint8(boxType)
+	bf.WriteUint8(boxIndex)
+	if name == "" {
+		bf.WriteUint8(0)
+		bf.WriteUint16(0) // Zeroed
+	} else {
+		sjisName := stringsupport.UTF8ToSJIS(name)
+		bf.WriteUint8(uint8(len(sjisName) + 1))
+		bf.WriteUint16(0) // Zeroed
+		bf.WriteNullTerminatedBytes(sjisName)
+	}
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
+// BuildEnumerateWarehousePacket builds a MSG_MHF_ENUMERATE_WAREHOUSE packet.
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint8  boxType (0=items, 1=equipment)
+//	uint8  boxIndex
+//	uint8  zeroed
+//	uint8  zeroed
+//	0x00 0x10 terminator
+func BuildEnumerateWarehousePacket(ackHandle uint32, boxType, boxIndex uint8) []byte {
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_MHF_ENUMERATE_WAREHOUSE)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint8(boxType)
+	bf.WriteUint8(boxIndex)
+	bf.WriteUint8(0) // Zeroed
+	bf.WriteUint8(0) // Zeroed
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
+// WarehouseItem is an item stack in a warehouse box. A WarehouseID of 0
+// adds a new stack.
+type WarehouseItem struct {
+	WarehouseID uint32
+	ItemID      uint16
+	Quantity    uint16
+}
+
+// BuildUpdateWarehouseItemsPacket builds a MSG_MHF_UPDATE_WAREHOUSE packet
+// changing item stacks in an item box.
+// Layout mirrors Erupe's MsgMhfUpdateWarehouse.Parse for boxType 0:
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint8  boxType (0)
+//	uint8  boxIndex
+//	uint16 change count
+//	uint8  zeroed
+//	uint8  zeroed
+//	per change: uint32 warehouseID, uint16 itemID, uint16 quantity, uint32 unk0
+//	0x00 0x10 terminator
+func BuildUpdateWarehouseItemsPacket(ackHandle uint32, boxIndex uint8, items []WarehouseItem) []byte {
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_MHF_UPDATE_WAREHOUSE)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint8(0) // BoxType
+	bf.WriteUint8(boxIndex)
+	bf.WriteUint16(uint16(len(items)))
+	bf.WriteUint8(0) // Zeroed
+	bf.WriteUint8(0) // Zeroed
+	for _, item := range items {
+		bf.WriteUint32(item.WarehouseID)
+		bf.WriteUint16(item.ItemID)
+		bf.WriteUint16(item.Quantity)
+		bf.WriteUint32(0) // Unk0
+	}
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
+// BuildEnumerateHousePacket builds a MSG_MHF_ENUMERATE_HOUSE packet.
+// Methods: 1=friends, 2=guild members, 3=search by name, 4=by charID.
+// Layout mirrors Erupe's MsgMhfEnumerateHouse.Parse:
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint32 charID
+//	uint8  method
+//	uint16 zeroed
+//	uint8  name length (0 when no name)
+//	null-terminated SJIS name (only when name length > 0)
+//	0x00 0x10 terminator
+func BuildEnumerateHousePacket(ackHandle, charID uint32, method uint8, name string) []byte {
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_MHF_ENUMERATE_HOUSE)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint32(charID)
+	bf.WriteUint8(method)
+	bf.WriteUint16(0) // Zeroed
+	if name == "" {
+		bf.WriteUint8(0)
+	} else {
+		sjisName := stringsupport.UTF8ToSJIS(name)
+		bf.WriteUint8(uint8(len(sjisName) + 1))
+		bf.WriteNullTerminatedBytes(sjisName)
+	}
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
+
+// BuildLoadHousePacket builds a MSG_MHF_LOAD_HOUSE packet. The password is
+// checked when non-empty.
+// Layout mirrors Erupe's MsgMhfLoadHouse.Parse:
+//
+//	uint16 opcode
+//	uint32 ackHandle
+//	uint32 charID
+//	uint8  destination
+//	bool   checkPass
+//	uint16 zeroed
+//	uint8  password length (SJIS bytes + null terminator)
+//	null-terminated SJIS password
+//	0x00 0x10 terminator
+func BuildLoadHousePacket(ackHandle, charID uint32, destination uint8, password string) []byte {
+	sjisPass := stringsupport.UTF8ToSJIS(password)
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(MSG_MHF_LOAD_HOUSE)
+	bf.WriteUint32(ackHandle)
+	bf.WriteUint32(charID)
+	bf.WriteUint8(destination)
+	bf.WriteBool(password != "")
+	bf.WriteUint16(0) // Zeroed
+	bf.WriteUint8(uint8(len(sjisPass) + 1))
+	bf.WriteNullTerminatedBytes(sjisPass)
+	bf.WriteBytes([]byte{0x00, 0x10})
+	return bf.Data()
+}
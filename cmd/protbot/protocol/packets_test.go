@@ -379,6 +379,74 @@ func TestBuildGetWeeklySchedulePacket(t *testing.T) {
 }
 
 // TestOpcodeValues verifies opcode constants match Erupe's iota-based enum.
+func TestBuildMoveStagePacket(t *testing.T) {
+	stageID := "sl1Ns211p0a0u0"
+	pkt := BuildMoveStagePacket(9, stageID)
+	bf := byteframe.NewByteFrameFromBytes(pkt)
+
+	if opcode := bf.ReadUint16(); opcode != MSG_SYS_MOVE_STAGE {
+		t.Fatalf("opcode: got 0x%04X, want 0x%04X", opcode, MSG_SYS_MOVE_STAGE)
+	}
+	if gotAck := bf.ReadUint32(); gotAck != 9 {
+		t.Fatalf("ackHandle: got %d, want 9", gotAck)
+	}
+	_ = bf.ReadUint8() // UnkBool
+	stageLen := bf.ReadUint8()
+	if gotStage := string(bf.ReadBytes(uint(stageLen))); gotStage != stageID+"\x00" {
+		t.Fatalf("stageID: got %q, want %q", gotStage, stageID)
+	}
+	if term := bf.ReadBytes(2); term[0] != 0x00 || term[1] != 0x10 {
+		t.Fatalf("terminator: got %02X %02X, want 00 10", term[0], term[1])
+	}
+}
+
+func TestBuildSavedataPacket(t *testing.T) {
+	payload := []byte{0x01, 0x02, 0x03}
+	pkt := BuildSavedataPacket(4, 0, payload)
+	bf := byteframe.NewByteFrameFromBytes(pkt)
+
+	if opcode := bf.ReadUint16(); opcode != MSG_MHF_SAVEDATA {
+		t.Fatalf("opcode: got 0x%04X, want 0x%04X", opcode, MSG_MHF_SAVEDATA)
+	}
+	if gotAck := bf.ReadUint32(); gotAck != 4 {
+		t.Fatalf("ackHandle: got %d, want 4", gotAck)
+	}
+	if alloc := bf.ReadUint32(); alloc != uint32(len(payload)) {
+		t.Fatalf("allocMemSize: got %d, want %d", alloc, len(payload))
+	}
+	if saveType := bf.ReadUint8(); saveType != 0 {
+		t.Fatalf("saveType: got %d, want 0", saveType)
+	}
+	_ = bf.ReadUint32() // Unk1
+	size := bf.ReadUint32()
+	if size != uint32(len(payload)) {
+		t.Fatalf("dataSize: got %d, want %d", size, len(payload))
+	}
+	if got := bf.ReadBytes(uint(size)); string(got) != string(payload) {
+		t.Fatalf("payload: got %X, want %X", got, payload)
+	}
+	if term := bf.ReadBytes(2); term[0] != 0x00 || term[1] != 0x10 {
+		t.Fatalf("terminator: got %02X %02X, want 00 10", term[0], term[1])
+	}
+}
+
+func TestBuildListMailPacket(t *testing.T) {
+	pkt := BuildListMailPacket(11)
+	want := []byte{0x00, 0x6A, 0x00, 0x00, 0x00, 0x0B, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10}
+	if string(pkt) != string(want) {
+		t.Fatalf("got %X, want %X", pkt, want)
+	}
+}
+
+func TestOpcodeName(t *testing.T) {
+	if got := OpcodeName(MSG_MHF_LIST_MAIL); got != "MSG_MHF_LIST_MAIL" {
+		t.Errorf("OpcodeName(LIST_MAIL) = %q", got)
+	}
+	if got := OpcodeName(0x0FFF); got != "0x0FFF" {
+		t.Errorf("OpcodeName(0x0FFF) = %q", got)
+	}
+}
+
 func TestOpcodeValues(t *testing.T) {
 	_ = binary.BigEndian // ensure import used
 	tests := []struct {
@@ -394,13 +462,18 @@ func TestOpcodeValues(t *testing.T) {
 		{"MSG_SYS_TIME", MSG_SYS_TIME, 0x001A},
 		{"MSG_SYS_CASTED_BINARY", MSG_SYS_CASTED_BINARY, 0x001B},
 		{"MSG_SYS_ISSUE_LOGKEY", MSG_SYS_ISSUE_LOGKEY, 0x001D},
+		{"MSG_SYS_CREATE_STAGE", MSG_SYS_CREATE_STAGE, 0x0020},
 		{"MSG_SYS_ENTER_STAGE", MSG_SYS_ENTER_STAGE, 0x0022},
+		{"MSG_SYS_BACK_STAGE", MSG_SYS_BACK_STAGE, 0x0023},
+		{"MSG_SYS_MOVE_STAGE", MSG_SYS_MOVE_STAGE, 0x0024},
 		{"MSG_SYS_ENUMERATE_STAGE", MSG_SYS_ENUMERATE_STAGE, 0x002F},
 		{"MSG_SYS_INSERT_USER", MSG_SYS_INSERT_USER, 0x0050},
 		{"MSG_SYS_DELETE_USER", MSG_SYS_DELETE_USER, 0x0051},
 		{"MSG_SYS_UPDATE_RIGHT", MSG_SYS_UPDATE_RIGHT, 0x0058},
 		{"MSG_SYS_RIGHTS_RELOAD", MSG_SYS_RIGHTS_RELOAD, 0x005D},
+		{"MSG_MHF_SAVEDATA", MSG_MHF_SAVEDATA, 0x0060},
 		{"MSG_MHF_LOADDATA", MSG_MHF_LOADDATA, 0x0061},
+		{"MSG_MHF_LIST_MAIL", MSG_MHF_LIST_MAIL, 0x006A},
 		{"MSG_MHF_ENUMERATE_QUEST", MSG_MHF_ENUMERATE_QUEST, 0x009F},
 		{"MSG_MHF_GET_WEEKLY_SCHED", MSG_MHF_GET_WEEKLY_SCHED, 0x00E1},
 	}
@@ -0,0 +1,128 @@
+package protocol
+
+import (
+	"testing"
+
+	"erupe-ce/common/byteframe"
+	cfg "erupe-ce/config"
+	"erupe-ce/network"
+	"erupe-ce/network/clientctx"
+	"erupe-ce/network/mhfpacket"
+)
+
+// parseWithErupe runs a built packet through Erupe's own parser and checks
+// that it consumes everything up to the 0x00 0x10 terminator.
+func parseWithErupe(t *testing.T, pkt []byte) mhfpacket.MHFPacket {
+	t.Helper()
+	bf := byteframe.NewByteFrameFromBytes(pkt)
+	p := mhfpacket.FromOpcode(network.PacketID(bf.ReadUint16()))
+	if p == nil {
+		t.Fatalf("no parser for opcode 0x%04X", pkt[:2])
+	}
+	if err := p.Parse(bf, &clientctx.ClientContext{RealClientMode: cfg.ZZ}); err != nil {
+		t.Fatalf("%s: Parse: %v", p.Opcode(), err)
+	}
+	if bf.Err() != nil {
+		t.Fatalf("%s: read past end: %v", p.Opcode(), bf.Err())
+	}
+	if rest := pkt[bf.Index():]; len(rest) != 2 || rest[0] != 0x00 || rest[1] != 0x10 {
+		t.Fatalf("%s: %d unparsed bytes before terminator: %X", p.Opcode(), len(rest)-2, rest)
+	}
+	return p
+}
+
+func TestBuildCreateGuildMatchesParse(t *testing.T) {
+	p := parseWithErupe(t, BuildCreateGuildPacket(1, "QA Guild")).(*mhfpacket.MsgMhfCreateGuild)
+	if p.AckHandle != 1 || p.Name != "QA Guild" {
+		t.Errorf("parsed %+v", p)
+	}
+}
+
+func TestBuildOperateGuildMatchesParse(t *testing.T) {
+	p := parseWithErupe(t, BuildOperateGuildPacket(2, 77, mhfpacket.OperateGuildApply, 0, nil)).(*mhfpacket.MsgMhfOperateGuild)
+	if p.AckHandle != 2 || p.GuildID != 77 || p.Action != mhfpacket.OperateGuildApply {
+		t.Errorf("parsed %+v", p)
+	}
+}
+
+func TestBuildOperateGuildMemberMatchesParse(t *testing.T) {
+	p := parseWithErupe(t, BuildOperateGuildMemberPacket(3, 77, 500, mhfpacket.OPERATE_GUILD_MEMBER_ACTION_ACCEPT)).(*mhfpacket.MsgMhfOperateGuildMember)
+	if p.GuildID != 77 || p.CharID != 500 || p.Action != mhfpacket.OPERATE_GUILD_MEMBER_ACTION_ACCEPT {
+		t.Errorf("parsed %+v", p)
+	}
+}
+
+func TestBuildSendMailMatchesParse(t *testing.T) {
+	p := parseWithErupe(t, BuildSendMailPacket(4, 500, "Hi", "Body text", 7, 2)).(*mhfpacket.MsgMhfSendMail)
+	if p.RecipientID != 500 || p.Subject != "Hi" || p.Body != "Body text" || p.ItemID != 7 || p.Quantity != 2 {
+		t.Errorf("parsed %+v", p)
+	}
+}
+
+func TestBuildOperateWarehouseMatchesParse(t *testing.T) {
+	p := parseWithErupe(t, BuildOperateWarehousePacket(5, 2, 0, 3, "Potions")).(*mhfpacket.MsgMhfOperateWarehouse)
+	if p.Operation != 2 || p.BoxIndex != 3 || p.Name != "Potions" {
+		t.Errorf("parsed %+v", p)
+	}
+	p = parseWithErupe(t, BuildOperateWarehousePacket(6, 0, 0, 0, "")).(*mhfpacket.MsgMhfOperateWarehouse)
+	if p.Operation != 0 || p.Name != "" {
+		t.Errorf("parsed %+v", p)
+	}
+}
+
+func TestBuildEnumerateWarehouseMatchesParse(t *testing.T) {
+	p := parseWithErupe(t, BuildEnumerateWarehousePacket(7, 1, 4)).(*mhfpacket.MsgMhfEnumerateWarehouse)
+	if p.BoxType != 1 || p.BoxIndex != 4 {
+		t.Errorf("parsed %+v", p)
+	}
+}
+
+func TestBuildUpdateWarehouseItemsMatchesParse(t *testing.T) {
+	items := []WarehouseItem{{ItemID: 1, Quantity: 5}, {WarehouseID: 99, ItemID: 2, Quantity: 0}}
+	p := parseWithErupe(t, BuildUpdateWarehouseItemsPacket(8, 2, items)).(*mhfpacket.MsgMhfUpdateWarehouse)
+	if p.BoxType != 0 || p.BoxIndex != 2 || len(p.UpdatedItems) != 2 {
+		t.Fatalf("parsed %+v", p)
+	}
+	if p.UpdatedItems[0].Item.ItemID != 1 || p.UpdatedItems[0].Quantity != 5 || p.UpdatedItems[1].WarehouseID != 99 {
+		t.Errorf("items %+v", p.UpdatedItems)
+	}
+}
+
+func TestBuildEnumerateHouseMatchesParse(t *testing.T) {
+	p := parseWithErupe(t, BuildEnumerateHousePacket(9, 0, 3, "Hunter")).(*mhfpacket.MsgMhfEnumerateHouse)
+	if p.Method != 3 || p.Name != "Hunter" {
+		t.Errorf("parsed %+v", p)
+	}
+	p = parseWithErupe(t, BuildEnumerateHousePacket(10, 500, 4, "")).(*mhfpacket.MsgMhfEnumerateHouse)
+	if p.Method != 4 || p.CharID != 500 {
+		t.Errorf("parsed %+v", p)
+	}
+}
+
+func TestBuildLoadHouseMatchesParse(t *testing.T) {
+	p := parseWithErupe(t, BuildLoadHousePacket(11, 500, 1, "")).(*mhfpacket.MsgMhfLoadHouse)
+	if p.CharID != 500 || p.Destination != 1 || p.CheckPass || p.Password != "" {
+		t.Errorf("parsed %+v", p)
+	}
+	p = parseWithErupe(t, BuildLoadHousePacket(12, 500, 1, "1234")).(*mhfpacket.MsgMhfLoadHouse)
+	if !p.CheckPass || p.Password != "1234" {
+		t.Errorf("parsed %+v", p)
+	}
+}
+
+func TestBuildStagePacketsMatchParse(t *testing.T) {
+	c := parseWithErupe(t, BuildCreateStagePacket(13, 1, 4, "sl1Qs200p0a0u1")).(*mhfpacket.MsgSysCreateStage)
+	if c.StageID != "sl1Qs200p0a0u1" || c.PlayerCount != 4 {
+		t.Errorf("parsed %+v", c)
+	}
+	m := parseWithErupe(t, BuildMoveStagePacket(14, "sl1Ns211p0a0u0")).(*mhfpacket.MsgSysMoveStage)
+	if m.StageID != "sl1Ns211p0a0u0" {
+		t.Errorf("parsed %+v", m)
+	}
+	parseWithErupe(t, BuildBackStagePacket(15))
+	parseWithErupe(t, BuildListMailPacket(16))
+	s := parseWithErupe(t, BuildSavedataPacket(17, 0, []byte{1, 2, 3})).(*mhfpacket.MsgMhfSavedata)
+	if string(s.RawDataPayload) != "\x01\x02\x03" {
+		t.Errorf("parsed %+v", s)
+	}
+}
@@ -0,0 +1,95 @@
+package scenario
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"erupe-ce/cmd/protbot/protocol"
+)
+
+// Guild actions, from Erupe's mhfpacket.OperateGuildAction and
+// OperateGuildMemberAction.
+const (
+	operateGuildDisband = 1
+	operateGuildApply   = 2
+	operateGuildLeave   = 3
+
+	guildMemberAccept = 1
+	guildMemberReject = 2
+	guildMemberKick   = 3
+)
+
+// CreateGuild sends MSG_MHF_CREATE_GUILD. On success the first four bytes of
+// the ACK data hold the new guild's ID (see AckUint32).
+func CreateGuild(ch *protocol.ChannelConn, name string) (*protocol.AckResponse, error) {
+	ack := ch.NextAckHandle()
+	fmt.Printf("[guild] Sending MSG_MHF_CREATE_GUILD (name=%q, ackHandle=%d)...\n", name, ack)
+	resp, err := request(ch, ack, protocol.BuildCreateGuildPacket(ack, name), "create guild")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[guild] CREATE_GUILD ACK (error=%d, guildID=%d)\n", resp.ErrorCode, AckUint32(resp))
+	return resp, nil
+}
+
+// ApplyGuild applies to join a guild. On success the ACK data holds the
+// guild leader's character ID.
+func ApplyGuild(ch *protocol.ChannelConn, guildID uint32) (*protocol.AckResponse, error) {
+	return operateGuild(ch, guildID, operateGuildApply, "apply")
+}
+
+// LeaveGuild leaves a guild, or withdraws a pending application.
+func LeaveGuild(ch *protocol.ChannelConn, guildID uint32) (*protocol.AckResponse, error) {
+	return operateGuild(ch, guildID, operateGuildLeave, "leave")
+}
+
+// DisbandGuild disbands a guild the character leads.
+func DisbandGuild(ch *protocol.ChannelConn, guildID uint32) (*protocol.AckResponse, error) {
+	return operateGuild(ch, guildID, operateGuildDisband, "disband")
+}
+
+func operateGuild(ch *protocol.ChannelConn, guildID uint32, action uint8, name string) (*protocol.AckResponse, error) {
+	ack := ch.NextAckHandle()
+	fmt.Printf("[guild] Sending MSG_MHF_OPERATE_GUILD (%s, guildID=%d, ackHandle=%d)...\n", name, guildID, ack)
+	resp, err := request(ch, ack, protocol.BuildOperateGuildPacket(ack, guildID, action, 0, nil), "operate guild")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[guild] OPERATE_GUILD ACK (error=%d, result=%d)\n", resp.ErrorCode, AckUint32(resp))
+	return resp, nil
+}
+
+// AcceptGuildMember accepts a character's application, as the guild leader.
+func AcceptGuildMember(ch *protocol.ChannelConn, guildID, charID uint32) (*protocol.AckResponse, error) {
+	return operateGuildMember(ch, guildID, charID, guildMemberAccept, "accept")
+}
+
+// RejectGuildMember rejects a character's application, as the guild leader.
+func RejectGuildMember(ch *protocol.ChannelConn, guildID, charID uint32) (*protocol.AckResponse, error) {
+	return operateGuildMember(ch, guildID, charID, guildMemberReject, "reject")
+}
+
+// KickGuildMember removes a member from the guild, as the guild leader.
+func KickGuildMember(ch *protocol.ChannelConn, guildID, charID uint32) (*protocol.AckResponse, error) {
+	return operateGuildMember(ch, guildID, charID, guildMemberKick, "kick")
+}
+
+func operateGuildMember(ch *protocol.ChannelConn, guildID, charID uint32, action uint8, name string) (*protocol.AckResponse, error) {
+	ack := ch.NextAckHandle()
+	fmt.Printf("[guild] Sending MSG_MHF_OPERATE_GUILD_MEMBER (%s, guildID=%d, charID=%d, ackHandle=%d)...\n",
+		name, guildID, charID, ack)
+	resp, err := request(ch, ack, protocol.BuildOperateGuildMemberPacket(ack, guildID, charID, action), "operate guild member")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[guild] OPERATE_GUILD_MEMBER ACK (error=%d)\n", resp.ErrorCode)
+	return resp, nil
+}
+
+// AckUint32 returns the first four bytes of an ACK's data, or 0 if shorter.
+func AckUint32(resp *protocol.AckResponse) uint32 {
+	if resp == nil || len(resp.Data) < 4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(resp.Data)
+}
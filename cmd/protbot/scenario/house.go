@@ -0,0 +1,81 @@
+package scenario
+
+import (
+	"fmt"
+
+	"erupe-ce/common/byteframe"
+	"erupe-ce/common/stringsupport"
+
+	"erupe-ce/cmd/protbot/protocol"
+)
+
+// HouseEntry holds a parsed entry from the MSG_MHF_ENUMERATE_HOUSE response.
+type HouseEntry struct {
+	CharID   uint32
+	State    uint8
+	Password bool
+	HR       uint16
+	GR       uint16
+	Name     string
+}
+
+// FindHouse looks up the house of charID.
+func FindHouse(ch *protocol.ChannelConn, charID uint32) ([]HouseEntry, *protocol.AckResponse, error) {
+	ack := ch.NextAckHandle()
+	fmt.Printf("[house] Sending MSG_MHF_ENUMERATE_HOUSE (charID=%d, ackHandle=%d)...\n", charID, ack)
+	resp, err := request(ch, ack, protocol.BuildEnumerateHousePacket(ack, charID, 4, ""), "enumerate house")
+	if err != nil {
+		return nil, nil, err
+	}
+	houses := parseHouseList(resp.Data)
+	fmt.Printf("[house] ENUMERATE_HOUSE ACK (error=%d, %d house(s))\n", resp.ErrorCode, len(houses))
+	return houses, resp, nil
+}
+
+// VisitHouse loads the house of charID as a visitor. password is only sent
+// when non-empty.
+func VisitHouse(ch *protocol.ChannelConn, charID uint32, password string) (*protocol.AckResponse, error) {
+	ack := ch.NextAckHandle()
+	fmt.Printf("[house] Sending MSG_MHF_LOAD_HOUSE (charID=%d, ackHandle=%d)...\n", charID, ack)
+	resp, err := request(ch, ack, protocol.BuildLoadHousePacket(ack, charID, 1, password), "load house")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[house] LOAD_HOUSE ACK (error=%d, %d bytes)\n", resp.ErrorCode, len(resp.Data))
+	return resp, nil
+}
+
+// parseHouseList parses the ACK data from MSG_MHF_ENUMERATE_HOUSE, assuming a
+// G10 or later server (which sends GR).
+// Reference: Erupe server/channelserver/handlers_house.go (handleMsgMhfEnumerateHouse)
+func parseHouseList(data []byte) []HouseEntry {
+	if len(data) < 2 {
+		return nil
+	}
+	bf := byteframe.NewByteFrameFromBytes(data)
+	count := bf.ReadUint16()
+	var houses []HouseEntry
+	for i := uint16(0); i < count; i++ {
+		h := HouseEntry{}
+		h.CharID = bf.ReadUint32()
+		h.State = bf.ReadUint8()
+		h.Password = bf.ReadUint8() != 0
+		h.HR = bf.ReadUint16()
+		h.GR = bf.ReadUint16()
+		h.Name = readPascalString(bf)
+		if bf.Err() != nil {
+			break
+		}
+		houses = append(houses, h)
+	}
+	return houses
+}
+
+// readPascalString reads a uint8 length-prefixed, null-terminated SJIS string.
+func readPascalString(bf *byteframe.ByteFrame) string {
+	n := bf.ReadUint8()
+	if n == 0 {
+		return ""
+	}
+	return stringsupport.SJISToUTF8Lossy(trimNull(bf.ReadBytes(uint(n))))
+}
@@ -0,0 +1,89 @@
+package scenario
+
+import (
+	"fmt"
+
+	"erupe-ce/common/byteframe"
+	"erupe-ce/common/stringsupport"
+
+	"erupe-ce/cmd/protbot/protocol"
+)
+
+// MailEntry holds a parsed entry from the MSG_MHF_LIST_MAIL response.
+type MailEntry struct {
+	SenderID   uint32
+	SenderName string
+	Subject    string
+	Flags      uint8
+	ItemID     uint16
+	Quantity   uint16
+}
+
+// SendMail sends a mail to recipientID, or to the sender's guild when
+// recipientID is 0.
+func SendMail(ch *protocol.ChannelConn, recipientID uint32, subject, body string) (*protocol.AckResponse, error) {
+	ack := ch.NextAckHandle()
+	fmt.Printf("[mail] Sending MSG_MHF_SEND_MAIL (recipient=%d, subject=%q, ackHandle=%d)...\n", recipientID, subject, ack)
+	resp, err := request(ch, ack, protocol.BuildSendMailPacket(ack, recipientID, subject, body, 0, 0), "send mail")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[mail] SEND_MAIL ACK (error=%d)\n", resp.ErrorCode)
+	return resp, nil
+}
+
+// ListMail sends MSG_MHF_LIST_MAIL and parses the character's mail list.
+func ListMail(ch *protocol.ChannelConn) ([]MailEntry, *protocol.AckResponse, error) {
+	ack := ch.NextAckHandle()
+	fmt.Printf("[mail] Sending MSG_MHF_LIST_MAIL (ackHandle=%d)...\n", ack)
+	resp, err := request(ch, ack, protocol.BuildListMailPacket(ack), "list mail")
+	if err != nil {
+		return nil, nil, err
+	}
+	mail := parseMailList(resp.Data)
+	fmt.Printf("[mail] LIST_MAIL ACK (error=%d, %d mail)\n", resp.ErrorCode, len(mail))
+	return mail, resp, nil
+}
+
+// parseMailList parses the ACK data from MSG_MHF_LIST_MAIL.
+// Reference: Erupe server/channelserver/handlers_mail.go (handleMsgMhfListMail)
+func parseMailList(data []byte) []MailEntry {
+	if len(data) < 4 {
+		return nil
+	}
+	bf := byteframe.NewByteFrameFromBytes(data)
+	count := bf.ReadUint32()
+	var mail []MailEntry
+	for i := uint32(0); i < count; i++ {
+		m := MailEntry{}
+		m.SenderID = bf.ReadUint32()
+		_ = bf.ReadUint32() // Created at
+		_ = bf.ReadUint8()  // Acc index
+		_ = bf.ReadUint8()  // Index
+		m.Flags = bf.ReadUint8()
+		itemAttached := bf.ReadBool()
+		subjectLen := bf.ReadUint8()
+		senderLen := bf.ReadUint8()
+		m.Subject = stringsupport.SJISToUTF8Lossy(trimNull(bf.ReadBytes(uint(subjectLen))))
+		m.SenderName = stringsupport.SJISToUTF8Lossy(trimNull(bf.ReadBytes(uint(senderLen))))
+		if itemAttached {
+			m.Quantity = bf.ReadUint16()
+			m.ItemID = bf.ReadUint16()
+		}
+		if bf.Err() != nil {
+			break
+		}
+		mail = append(mail, m)
+	}
+	return mail
+}
+
+// trimNull cuts a padded string at its first null byte.
+func trimNull(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
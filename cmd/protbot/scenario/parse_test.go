@@ -0,0 +1,106 @@
+package scenario
+
+import (
+	"testing"
+
+	"erupe-ce/common/byteframe"
+	ps "erupe-ce/common/pascalstring"
+	"erupe-ce/common/stringsupport"
+)
+
+func TestParseMailList(t *testing.T) {
+	// Mirrors handleMsgMhfListMail.
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint32(2)
+	for i, subject := range []string{"Hello", "Item"} {
+		bf.WriteUint32(uint32(100 + i)) // Sender
+		bf.WriteUint32(0)               // Created at
+		bf.WriteUint8(uint8(i))
+		bf.WriteUint8(uint8(i))
+		bf.WriteUint8(0x01)
+		bf.WriteBool(i == 1)
+		bf.WriteUint8(16)
+		bf.WriteUint8(21)
+		bf.WriteBytes(stringsupport.PaddedString(subject, 16, true))
+		bf.WriteBytes(stringsupport.PaddedString("Sender", 21, true))
+		if i == 1 {
+			bf.WriteUint16(3) // Amount
+			bf.WriteUint16(7) // Item
+		}
+	}
+
+	mail := parseMailList(bf.Data())
+	if len(mail) != 2 {
+		t.Fatalf("got %d mail, want 2", len(mail))
+	}
+	if mail[0].Subject != "Hello" || mail[0].SenderName != "Sender" || mail[0].SenderID != 100 || mail[0].Flags != 1 {
+		t.Errorf("mail[0] = %+v", mail[0])
+	}
+	if mail[1].ItemID != 7 || mail[1].Quantity != 3 {
+		t.Errorf("mail[1] = %+v", mail[1])
+	}
+	if got := parseMailList([]byte{0, 0, 0, 5, 1}); len(got) != 0 {
+		t.Errorf("truncated list parsed %d mail", len(got))
+	}
+}
+
+func TestParseHouseList(t *testing.T) {
+	// Mirrors handleMsgMhfEnumerateHouse for G10 and later.
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(1)
+	bf.WriteUint32(500)
+	bf.WriteUint8(2)
+	bf.WriteUint8(3)
+	bf.WriteUint16(999)
+	bf.WriteUint16(50)
+	ps.Uint8(bf, "Hunter", true)
+
+	houses := parseHouseList(bf.Data())
+	if len(houses) != 1 {
+		t.Fatalf("got %d houses", len(houses))
+	}
+	h := houses[0]
+	if h.CharID != 500 || h.State != 2 || !h.Password || h.HR != 999 || h.GR != 50 || h.Name != "Hunter" {
+		t.Errorf("house = %+v", h)
+	}
+}
+
+func TestParseWarehouseItems(t *testing.T) {
+	// Mirrors mhfitem.SerializeWarehouseItems.
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint16(2)
+	bf.WriteUint16(0)
+	for i := uint16(1); i <= 2; i++ {
+		bf.WriteUint32(uint32(i) * 10)
+		bf.WriteUint16(i)
+		bf.WriteUint16(i * 5)
+		bf.WriteUint32(0)
+	}
+	items := parseWarehouseItems(bf.Data())
+	if len(items) != 2 || items[1].WarehouseID != 20 || items[1].ItemID != 2 || items[1].Quantity != 10 {
+		t.Errorf("items = %+v", items)
+	}
+	if got := parseWarehouseItems(make([]byte, 4)); len(got) != 0 {
+		t.Errorf("empty box parsed %d items", len(got))
+	}
+}
+
+func TestParseWarehouseNames(t *testing.T) {
+	// Mirrors the "get box names" case of handleMsgMhfOperateWarehouse.
+	bf := byteframe.NewByteFrame()
+	bf.WriteUint8(0)
+	bf.WriteUint32(0)
+	bf.WriteUint16(10000)
+	bf.WriteUint8(2)
+	bf.WriteUint8(0)
+	bf.WriteUint8(1)
+	ps.Uint8(bf, "Potions", true)
+	bf.WriteUint8(1)
+	bf.WriteUint8(3)
+	ps.Uint8(bf, "Armor", true)
+
+	names := parseWarehouseNames(bf.Data())
+	if len(names) != 2 || names["items/1"] != "Potions" || names["equipment/3"] != "Armor" {
+		t.Errorf("names = %v", names)
+	}
+}
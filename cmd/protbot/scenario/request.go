@@ -0,0 +1,21 @@
+package scenario
+
+import (
+	"fmt"
+	"time"
+
+	"erupe-ce/cmd/protbot/protocol"
+)
+
+// request sends pkt and waits for the ACK to ack. Only send failures and
+// timeouts are errors; the caller inspects the ACK's error code.
+func request(ch *protocol.ChannelConn, ack uint32, pkt []byte, name string) (*protocol.AckResponse, error) {
+	if err := ch.SendPacket(pkt); err != nil {
+		return nil, fmt.Errorf("%s send: %w", name, err)
+	}
+	resp, err := ch.WaitForAck(ack, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("%s ack: %w", name, err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,121 @@
+package scenario
+
+import (
+	"fmt"
+
+	"erupe-ce/common/byteframe"
+
+	"erupe-ce/cmd/protbot/protocol"
+)
+
+// Warehouse operations, from Erupe's handleMsgMhfOperateWarehouse.
+const (
+	warehouseGetNames = 0
+	warehouseRename   = 2
+)
+
+// EnumerateWarehouseItems lists the item stacks in an item box (0-10).
+func EnumerateWarehouseItems(ch *protocol.ChannelConn, boxIndex uint8) ([]protocol.WarehouseItem, *protocol.AckResponse, error) {
+	ack := ch.NextAckHandle()
+	fmt.Printf("[warehouse] Sending MSG_MHF_ENUMERATE_WAREHOUSE (box=%d, ackHandle=%d)...\n", boxIndex, ack)
+	resp, err := request(ch, ack, protocol.BuildEnumerateWarehousePacket(ack, 0, boxIndex), "enumerate warehouse")
+	if err != nil {
+		return nil, nil, err
+	}
+	items := parseWarehouseItems(resp.Data)
+	fmt.Printf("[warehouse] ENUMERATE_WAREHOUSE ACK (error=%d, %d stack(s))\n", resp.ErrorCode, len(items))
+	return items, resp, nil
+}
+
+// DepositWarehouseItem adds a new item stack to an item box.
+func DepositWarehouseItem(ch *protocol.ChannelConn, boxIndex uint8, itemID, quantity uint16) (*protocol.AckResponse, error) {
+	ack := ch.NextAckHandle()
+	fmt.Printf("[warehouse] Sending MSG_MHF_UPDATE_WAREHOUSE (box=%d, item=%d x%d, ackHandle=%d)...\n",
+		boxIndex, itemID, quantity, ack)
+	pkt := protocol.BuildUpdateWarehouseItemsPacket(ack, boxIndex, []protocol.WarehouseItem{{ItemID: itemID, Quantity: quantity}})
+	resp, err := request(ch, ack, pkt, "update warehouse")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[warehouse] UPDATE_WAREHOUSE ACK (error=%d)\n", resp.ErrorCode)
+	return resp, nil
+}
+
+// RenameWarehouseBox renames an item (boxType 0) or equipment (boxType 1) box.
+func RenameWarehouseBox(ch *protocol.ChannelConn, boxType, boxIndex uint8, name string) (*protocol.AckResponse, error) {
+	ack := ch.NextAckHandle()
+	fmt.Printf("[warehouse] Sending MSG_MHF_OPERATE_WAREHOUSE (rename, type=%d, box=%d, name=%q, ackHandle=%d)...\n",
+		boxType, boxIndex, name, ack)
+	resp, err := request(ch, ack, protocol.BuildOperateWarehousePacket(ack, warehouseRename, boxType, boxIndex, name), "operate warehouse")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[warehouse] OPERATE_WAREHOUSE ACK (error=%d)\n", resp.ErrorCode)
+	return resp, nil
+}
+
+// WarehouseBoxNames returns the custom names of the character's boxes, keyed
+// "items/<index>" or "equipment/<index>".
+func WarehouseBoxNames(ch *protocol.ChannelConn) (map[string]string, *protocol.AckResponse, error) {
+	ack := ch.NextAckHandle()
+	fmt.Printf("[warehouse] Sending MSG_MHF_OPERATE_WAREHOUSE (get names, ackHandle=%d)...\n", ack)
+	resp, err := request(ch, ack, protocol.BuildOperateWarehousePacket(ack, warehouseGetNames, 0, 0, ""), "operate warehouse")
+	if err != nil {
+		return nil, nil, err
+	}
+	names := parseWarehouseNames(resp.Data)
+	fmt.Printf("[warehouse] OPERATE_WAREHOUSE ACK (error=%d, %d named box(es))\n", resp.ErrorCode, len(names))
+	return names, resp, nil
+}
+
+// parseWarehouseItems parses the ACK data from MSG_MHF_ENUMERATE_WAREHOUSE
+// for an item box. Layout mirrors Erupe's mhfitem.SerializeWarehouseItems.
+func parseWarehouseItems(data []byte) []protocol.WarehouseItem {
+	if len(data) < 4 {
+		return nil
+	}
+	bf := byteframe.NewByteFrameFromBytes(data)
+	count := bf.ReadUint16()
+	_ = bf.ReadUint16() // Unused
+	var items []protocol.WarehouseItem
+	for i := uint16(0); i < count; i++ {
+		item := protocol.WarehouseItem{}
+		item.WarehouseID = bf.ReadUint32()
+		item.ItemID = bf.ReadUint16()
+		item.Quantity = bf.ReadUint16()
+		_ = bf.ReadUint32() // Unk0
+		if bf.Err() != nil {
+			break
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// parseWarehouseNames parses the ACK data of the "get box names" operation.
+// Reference: Erupe server/channelserver/handlers_house.go (handleMsgMhfOperateWarehouse)
+func parseWarehouseNames(data []byte) map[string]string {
+	names := make(map[string]string)
+	if len(data) < 8 {
+		return names
+	}
+	bf := byteframe.NewByteFrameFromBytes(data)
+	_ = bf.ReadUint8()  // Operation
+	_ = bf.ReadUint32() // Usage renewal time
+	_ = bf.ReadUint16() // Usages
+	count := bf.ReadUint8()
+	for i := uint8(0); i < count; i++ {
+		boxType := bf.ReadUint8()
+		index := bf.ReadUint8()
+		name := readPascalString(bf)
+		if bf.Err() != nil {
+			break
+		}
+		kind := "items"
+		if boxType == 1 {
+			kind = "equipment"
+		}
+		names[fmt.Sprintf("%s/%d", kind, index)] = name
+	}
+	return names
+}
@@ -0,0 +1,381 @@
+package script
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"erupe-ce/cmd/protbot/protocol"
+	"erupe-ce/cmd/protbot/scenario"
+)
+
+// action is a step implementation. needsSession is false only for steps
+// that do not talk to the server through the step's session.
+type action struct {
+	fn           func(r *Runner, sess *session, a args) (*Result, error)
+	needsSession bool
+}
+
+// actions maps each "do" value to its implementation.
+var actions = map[string]action{
+	"login":             {doLogin, false},
+	"setup_session":     {doSetupSession, true},
+	"enter_lobby":       {doEnterLobby, true},
+	"chat":              {doChat, true},
+	"wait_chat":         {doWaitChat, true},
+	"quests":            {doQuests, true},
+	"logout":            {doLogout, true},
+	"wait":              {doWait, false},
+	"guild_create":      {doGuildCreate, true},
+	"guild_apply":       {guildOp(scenario.ApplyGuild, "leader_id"), true},
+	"guild_leave":       {guildOp(scenario.LeaveGuild, "result"), true},
+	"guild_disband":     {guildOp(scenario.DisbandGuild, "result"), true},
+	"guild_accept":      {guildMemberOp(scenario.AcceptGuildMember), true},
+	"guild_reject":      {guildMemberOp(scenario.RejectGuildMember), true},
+	"guild_kick":        {guildMemberOp(scenario.KickGuildMember), true},
+	"mail_send":         {doMailSend, true},
+	"mail_list":         {doMailList, true},
+	"warehouse_list":    {doWarehouseList, true},
+	"warehouse_deposit": {doWarehouseDeposit, true},
+	"warehouse_rename":  {doWarehouseRename, true},
+	"warehouse_names":   {doWarehouseNames, true},
+	"house_find":        {doHouseFind, true},
+	"house_visit":       {doHouseVisit, true},
+}
+
+// Actions returns the supported "do" values, sorted.
+func Actions() []string {
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// doLogin signs in with user and pass (defaulting to the user and pass
+// variables) and connects to the first channel.
+func doLogin(r *Runner, sess *session, a args) (*Result, error) {
+	user := a.str("user", r.vars["user"])
+	pass := a.str("pass", r.vars["pass"])
+	if user == "" || pass == "" {
+		return nil, fmt.Errorf("with.user and with.pass (or the user and pass variables) are required")
+	}
+	login, err := scenario.Login(r.SignAddr, user, pass)
+	if err != nil {
+		return nil, err
+	}
+	sess.user = user
+	sess.ch = login.Channel
+	sess.charID = login.Sign.CharIDs[0]
+	sess.chat = make(chan scenario.ChatMessage, 64)
+	scenario.ListenChat(sess.ch, func(msg scenario.ChatMessage) {
+		select {
+		case sess.chat <- msg:
+		default:
+		}
+	})
+	r.addSession(sess)
+	res := okResult()
+	res.Fields["char_id"] = strconv.FormatUint(uint64(sess.charID), 10)
+	return res, nil
+}
+
+func doSetupSession(r *Runner, sess *session, a args) (*Result, error) {
+	data, err := scenario.SetupSession(sess.ch, sess.charID)
+	if err != nil {
+		return nil, err
+	}
+	res := okResult()
+	res.Fields["bytes"] = strconv.Itoa(len(data))
+	return res, nil
+}
+
+func doEnterLobby(r *Runner, sess *session, a args) (*Result, error) {
+	if err := scenario.EnterLobby(sess.ch); err != nil {
+		return nil, err
+	}
+	return okResult(), nil
+}
+
+// doChat sends message (with.message) to the stage, or with.broadcast 6 for
+// the world.
+func doChat(r *Runner, sess *session, a args) (*Result, error) {
+	msg, err := a.required("message")
+	if err != nil {
+		return nil, err
+	}
+	broadcast, err := a.uint("broadcast", 8, 0x03, false)
+	if err != nil {
+		return nil, err
+	}
+	chatType, err := a.uint("type", 8, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := scenario.SendChat(sess.ch, uint8(broadcast), uint8(chatType), msg, sess.user); err != nil {
+		return nil, err
+	}
+	return okResult(), nil
+}
+
+// doWaitChat waits up to with.timeout for a chat message containing
+// with.contains, optionally from with.from. OK is false if none arrives.
+func doWaitChat(r *Runner, sess *session, a args) (*Result, error) {
+	timeout, err := a.duration("timeout", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	contains, from := a.str("contains", ""), a.str("from", "")
+	deadline := time.After(timeout)
+	for {
+		select {
+		case msg := <-sess.chat:
+			if !strings.Contains(msg.Message, contains) || (from != "" && msg.SenderName != from) {
+				continue
+			}
+			res := okResult()
+			res.Fields["message"] = msg.Message
+			res.Fields["sender"] = msg.SenderName
+			return res, nil
+		case <-deadline:
+			return &Result{Fields: map[string]string{}}, nil
+		}
+	}
+}
+
+func doQuests(r *Runner, sess *session, a args) (*Result, error) {
+	world, err := a.uint("world", 8, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	counter, err := a.uint("counter", 16, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	data, err := scenario.EnumerateQuests(sess.ch, uint8(world), uint16(counter))
+	if err != nil {
+		return nil, err
+	}
+	res := okResult()
+	res.Fields["bytes"] = strconv.Itoa(len(data))
+	return res, nil
+}
+
+func doLogout(r *Runner, sess *session, a args) (*Result, error) {
+	delete(r.sessions, sess.name)
+	if err := scenario.Logout(sess.ch); err != nil {
+		return nil, err
+	}
+	return okResult(), nil
+}
+
+// doWait pauses for with.for.
+func doWait(r *Runner, sess *session, a args) (*Result, error) {
+	d, err := a.duration("for", 0)
+	if err != nil {
+		return nil, err
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("with.for is required")
+	}
+	time.Sleep(d)
+	return okResult(), nil
+}
+
+func doGuildCreate(r *Runner, sess *session, a args) (*Result, error) {
+	name, err := a.required("name")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := scenario.CreateGuild(sess.ch, name)
+	if err != nil {
+		return nil, err
+	}
+	res := ackResult(resp)
+	res.Fields["guild_id"] = strconv.FormatUint(uint64(scenario.AckUint32(resp)), 10)
+	return res, nil
+}
+
+// guildOp adapts a guild operation on with.guild_id, storing the ACK's
+// first four bytes as field.
+func guildOp(op func(*protocol.ChannelConn, uint32) (*protocol.AckResponse, error), field string) func(*Runner, *session, args) (*Result, error) {
+	return func(r *Runner, sess *session, a args) (*Result, error) {
+		guildID, err := a.uint("guild_id", 32, 0, true)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := op(sess.ch, uint32(guildID))
+		if err != nil {
+			return nil, err
+		}
+		res := ackResult(resp)
+		res.Fields[field] = strconv.FormatUint(uint64(scenario.AckUint32(resp)), 10)
+		return res, nil
+	}
+}
+
+// guildMemberOp adapts a leader's operation on with.char_id in with.guild_id.
+func guildMemberOp(op func(*protocol.ChannelConn, uint32, uint32) (*protocol.AckResponse, error)) func(*Runner, *session, args) (*Result, error) {
+	return func(r *Runner, sess *session, a args) (*Result, error) {
+		guildID, err := a.uint("guild_id", 32, 0, true)
+		if err != nil {
+			return nil, err
+		}
+		charID, err := a.uint("char_id", 32, 0, true)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := op(sess.ch, uint32(guildID), uint32(charID))
+		if err != nil {
+			return nil, err
+		}
+		return ackResult(resp), nil
+	}
+}
+
+// doMailSend mails with.to (0 for the sender's guild).
+func doMailSend(r *Runner, sess *session, a args) (*Result, error) {
+	to, err := a.uint("to", 32, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := a.required("subject")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := scenario.SendMail(sess.ch, uint32(to), subject, a.str("body", ""))
+	if err != nil {
+		return nil, err
+	}
+	return ackResult(resp), nil
+}
+
+func doMailList(r *Runner, sess *session, a args) (*Result, error) {
+	mail, resp, err := scenario.ListMail(sess.ch)
+	if err != nil {
+		return nil, err
+	}
+	res := ackResult(resp)
+	subjects := make([]string, len(mail))
+	senders := make([]string, len(mail))
+	for i, m := range mail {
+		subjects[i] = m.Subject
+		senders[i] = m.SenderName
+	}
+	res.Fields["count"] = strconv.Itoa(len(mail))
+	res.Fields["subjects"] = strings.Join(subjects, "|")
+	res.Fields["senders"] = strings.Join(senders, "|")
+	return res, nil
+}
+
+// doWarehouseList lists item box with.box. Besides count, each item ID
+// gets an item.<id> field holding its total quantity.
+func doWarehouseList(r *Runner, sess *session, a args) (*Result, error) {
+	box, err := a.uint("box", 8, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	items, resp, err := scenario.EnumerateWarehouseItems(sess.ch, uint8(box))
+	if err != nil {
+		return nil, err
+	}
+	res := ackResult(resp)
+	res.Fields["count"] = strconv.Itoa(len(items))
+	totals := make(map[uint16]int)
+	for _, item := range items {
+		totals[item.ItemID] += int(item.Quantity)
+	}
+	for id, qty := range totals {
+		res.Fields[fmt.Sprintf("item.%d", id)] = strconv.Itoa(qty)
+	}
+	return res, nil
+}
+
+func doWarehouseDeposit(r *Runner, sess *session, a args) (*Result, error) {
+	box, err := a.uint("box", 8, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	item, err := a.uint("item", 16, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	qty, err := a.uint("quantity", 16, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := scenario.DepositWarehouseItem(sess.ch, uint8(box), uint16(item), uint16(qty))
+	if err != nil {
+		return nil, err
+	}
+	return ackResult(resp), nil
+}
+
+func doWarehouseRename(r *Runner, sess *session, a args) (*Result, error) {
+	boxType, err := a.uint("type", 8, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	box, err := a.uint("box", 8, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	name, err := a.required("name")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := scenario.RenameWarehouseBox(sess.ch, uint8(boxType), uint8(box), name)
+	if err != nil {
+		return nil, err
+	}
+	return ackResult(resp), nil
+}
+
+// doWarehouseNames returns each named box as an items/<index> or
+// equipment/<index> field.
+func doWarehouseNames(r *Runner, sess *session, a args) (*Result, error) {
+	names, resp, err := scenario.WarehouseBoxNames(sess.ch)
+	if err != nil {
+		return nil, err
+	}
+	res := ackResult(resp)
+	res.Fields["count"] = strconv.Itoa(len(names))
+	for k, v := range names {
+		res.Fields[k] = v
+	}
+	return res, nil
+}
+
+func doHouseFind(r *Runner, sess *session, a args) (*Result, error) {
+	charID, err := a.uint("char_id", 32, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	houses, resp, err := scenario.FindHouse(sess.ch, uint32(charID))
+	if err != nil {
+		return nil, err
+	}
+	res := ackResult(resp)
+	res.Fields["count"] = strconv.Itoa(len(houses))
+	if len(houses) > 0 {
+		res.Fields["name"] = houses[0].Name
+		res.Fields["state"] = strconv.Itoa(int(houses[0].State))
+		res.Fields["password"] = strconv.FormatBool(houses[0].Password)
+	}
+	return res, nil
+}
+
+func doHouseVisit(r *Runner, sess *session, a args) (*Result, error) {
+	charID, err := a.uint("char_id", 32, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := scenario.VisitHouse(sess.ch, uint32(charID), a.str("password", ""))
+	if err != nil {
+		return nil, err
+	}
+	return ackResult(resp), nil
+}
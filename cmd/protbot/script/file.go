@@ -0,0 +1,220 @@
+// Package script runs declarative protbot scenarios from YAML or JSON files,
+// so end-to-end server checks can be written without Go.
+//
+// A scenario is a list of steps run in order. Each step names an action in
+// "do", the session it runs on in "as" (default "main"), its arguments in
+// "with", optional assertions in "expect", and result fields to keep as
+// variables in "save":
+//
+//	name: guild join
+//	vars:
+//	  guild: QA ${run}
+//	steps:
+//	  - {do: login, as: leader, with: {user: leader, pass: secret}}
+//	  - {do: setup_session, as: leader}
+//	  - do: guild_create
+//	    as: leader
+//	    with: {name: "${guild}"}
+//	    save: {guild_id: guild_id}
+//	  - {do: login, as: member, with: {user: member, pass: secret}}
+//	  - {do: guild_apply, as: member, with: {guild_id: "${guild_id}"}}
+//	  - do: guild_accept
+//	    as: leader
+//	    with: {guild_id: "${guild_id}", char_id: "${member.char_id}"}
+//	  - {do: wait, with: {for: 500ms}}
+//	  - {do: guild_disband, as: leader, with: {guild_id: "${guild_id}"}}
+//
+// Strings may reference variables as ${name}. Variables come from the file's
+// "vars", the command line, "save", and each login, which sets
+// <session>.char_id and <session>.user. ${run} is unique to each run.
+package script
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is a parsed scenario file.
+type File struct {
+	Name  string            `yaml:"name"`
+	Vars  map[string]string `yaml:"vars"`
+	Steps []Step            `yaml:"steps"`
+}
+
+// Step is one action of a scenario.
+type Step struct {
+	Name   string            `yaml:"name"` // Optional label shown in output
+	Do     string            `yaml:"do"`
+	As     string            `yaml:"as"`
+	With   map[string]string `yaml:"with"`
+	Expect *Expect           `yaml:"expect"`
+	Save   map[string]string `yaml:"save"` // Variable name -> result field
+}
+
+// Expect holds the assertions checked against a step's result. Without an
+// Expect block a step must succeed (OK true).
+type Expect struct {
+	// OK is whether the server acknowledged the request with error code 0.
+	OK *bool `yaml:"ok"`
+	// Fields compares result fields. A value may start with ==, !=, <, <=,
+	// > or >= to compare numerically (== and != also compare strings);
+	// otherwise it must match exactly.
+	Fields map[string]string `yaml:"fields"`
+	// Contains requires each result field to contain the given substring.
+	Contains map[string]string `yaml:"contains"`
+}
+
+// Load reads and validates a scenario file. JSON files are accepted as YAML.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates a scenario.
+func Parse(data []byte) (*File, error) {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse scenario: %w", err)
+	}
+	if len(f.Steps) == 0 {
+		return nil, fmt.Errorf("scenario has no steps")
+	}
+	for i := range f.Steps {
+		s := &f.Steps[i]
+		if s.As == "" {
+			s.As = "main"
+		}
+		if _, ok := actions[s.Do]; !ok {
+			return nil, fmt.Errorf("step %d: unknown action %q", i+1, s.Do)
+		}
+	}
+	return &f, nil
+}
+
+// Label names a step in output.
+func (s Step) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return fmt.Sprintf("%s (%s)", s.Do, s.As)
+}
+
+var varPattern = regexp.MustCompile(`\$\{([A-Za-z0-9_.]+)\}`)
+
+// expand replaces ${name} references with their values. Unknown variables
+// are an error, so typos fail the step instead of sending empty strings.
+func expand(s string, vars map[string]string) (string, error) {
+	var missing []string
+	out := varPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		v, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined variable(s): %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// check compares a result against the expectations, returning one message
+// per failed assertion.
+func (e *Expect) check(r *Result, vars map[string]string) ([]string, error) {
+	wantOK := true
+	if e != nil && e.OK != nil {
+		wantOK = *e.OK
+	}
+	var failures []string
+	if r.OK != wantOK {
+		failures = append(failures, fmt.Sprintf("ok = %v (error code %d), want %v", r.OK, r.ErrorCode, wantOK))
+	}
+	if e == nil {
+		return failures, nil
+	}
+	for field, want := range e.Fields {
+		field, want, err := expandPair(field, want, vars)
+		if err != nil {
+			return nil, err
+		}
+		got, ok := r.Fields[field]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("no result field %q", field))
+			continue
+		}
+		if msg := compare(got, want); msg != "" {
+			failures = append(failures, fmt.Sprintf("%s = %q, want %s", field, got, msg))
+		}
+	}
+	for field, want := range e.Contains {
+		field, want, err := expandPair(field, want, vars)
+		if err != nil {
+			return nil, err
+		}
+		got, ok := r.Fields[field]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("no result field %q", field))
+			continue
+		}
+		if !strings.Contains(got, want) {
+			failures = append(failures, fmt.Sprintf("%s = %q, want it to contain %q", field, got, want))
+		}
+	}
+	return failures, nil
+}
+
+// expandPair expands both a result field name and its expected value.
+func expandPair(field, want string, vars map[string]string) (string, string, error) {
+	field, err := expand(field, vars)
+	if err != nil {
+		return "", "", err
+	}
+	want, err = expand(want, vars)
+	return field, want, err
+}
+
+// compare checks got against an expectation, returning a description of the
+// expectation when it does not hold.
+func compare(got, want string) string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !strings.HasPrefix(want, op) {
+			continue
+		}
+		operand := strings.TrimSpace(want[len(op):])
+		g, gErr := strconv.ParseFloat(got, 64)
+		w, wErr := strconv.ParseFloat(operand, 64)
+		numeric := gErr == nil && wErr == nil
+		var holds bool
+		switch op {
+		case "==":
+			holds = got == operand || (numeric && g == w)
+		case "!=":
+			holds = got != operand && !(numeric && g == w)
+		case "<":
+			holds = numeric && g < w
+		case "<=":
+			holds = numeric && g <= w
+		case ">":
+			holds = numeric && g > w
+		case ">=":
+			holds = numeric && g >= w
+		}
+		if holds {
+			return ""
+		}
+		return op + " " + operand
+	}
+	if got == want {
+		return ""
+	}
+	return strconv.Quote(want)
+}
@@ -0,0 +1,198 @@
+package script
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"erupe-ce/cmd/protbot/protocol"
+	"erupe-ce/cmd/protbot/scenario"
+)
+
+// Result is the outcome of a step. Fields hold step-specific values that can
+// be asserted with Expect or saved as variables.
+type Result struct {
+	OK        bool
+	ErrorCode uint8
+	Fields    map[string]string
+}
+
+// ackResult builds a Result from an ACK.
+func ackResult(resp *protocol.AckResponse) *Result {
+	return &Result{
+		OK:        resp.ErrorCode == 0,
+		ErrorCode: resp.ErrorCode,
+		Fields: map[string]string{
+			"error_code": strconv.Itoa(int(resp.ErrorCode)),
+			"bytes":      strconv.Itoa(len(resp.Data)),
+		},
+	}
+}
+
+// okResult is the Result of a step that has no ACK of its own.
+func okResult() *Result {
+	return &Result{OK: true, Fields: map[string]string{}}
+}
+
+// session is a logged-in character, named by the steps' "as".
+type session struct {
+	name   string
+	user   string
+	ch     *protocol.ChannelConn
+	charID uint32
+	chat   chan scenario.ChatMessage
+}
+
+// Runner executes scenarios against a server.
+type Runner struct {
+	SignAddr string
+	// Vars override the scenario file's vars.
+	Vars map[string]string
+	// Out receives step progress; defaults to os.Stdout.
+	Out io.Writer
+
+	vars     map[string]string
+	sessions map[string]*session
+}
+
+// Run executes the steps of f in order and stops at the first step that
+// fails or whose expectations do not hold. All sessions are logged out
+// before it returns.
+func (r *Runner) Run(f *File) error {
+	if r.Out == nil {
+		r.Out = os.Stdout
+	}
+	r.sessions = make(map[string]*session)
+	defer r.closeAll()
+
+	r.vars = map[string]string{"run": strconv.FormatInt(time.Now().Unix(), 10)}
+	for k, v := range r.Vars {
+		r.vars[k] = v
+	}
+	for k, v := range f.Vars {
+		if _, overridden := r.Vars[k]; overridden {
+			continue
+		}
+		expanded, err := expand(v, r.vars)
+		if err != nil {
+			return fmt.Errorf("var %s: %w", k, err)
+		}
+		r.vars[k] = expanded
+	}
+
+	for i, step := range f.Steps {
+		prefix := fmt.Sprintf("step %d/%d %s", i+1, len(f.Steps), step.Label())
+		_, _ = fmt.Fprintf(r.Out, "[script] %s\n", prefix)
+		if err := r.runStep(step); err != nil {
+			_, _ = fmt.Fprintf(r.Out, "[script] FAIL %s: %v\n", prefix, err)
+			return fmt.Errorf("%s: %w", prefix, err)
+		}
+	}
+	_, _ = fmt.Fprintf(r.Out, "[script] PASS %s (%d steps)\n", f.Name, len(f.Steps))
+	return nil
+}
+
+func (r *Runner) runStep(step Step) error {
+	a := args{}
+	for k, v := range step.With {
+		expanded, err := expand(v, r.vars)
+		if err != nil {
+			return fmt.Errorf("with.%s: %w", k, err)
+		}
+		a[k] = expanded
+	}
+
+	act := actions[step.Do]
+	sess := r.sessions[step.As]
+	if act.needsSession && sess == nil {
+		return fmt.Errorf("session %q is not logged in", step.As)
+	}
+	if step.Do == "login" && sess != nil {
+		return fmt.Errorf("session %q is already logged in", step.As)
+	}
+	if sess == nil {
+		sess = &session{name: step.As}
+	}
+
+	res, err := act.fn(r, sess, a)
+	if err != nil {
+		return err
+	}
+	failures, err := step.Expect.check(res, r.vars)
+	if err != nil {
+		return fmt.Errorf("expect: %w", err)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("expectation failed: %s", strings.Join(failures, "; "))
+	}
+	for name, field := range step.Save {
+		v, ok := res.Fields[field]
+		if !ok {
+			return fmt.Errorf("save %s: no result field %q", name, field)
+		}
+		r.vars[name] = v
+	}
+	return nil
+}
+
+// addSession registers a logged-in session and its variables.
+func (r *Runner) addSession(sess *session) {
+	r.sessions[sess.name] = sess
+	r.vars[sess.name+".char_id"] = strconv.FormatUint(uint64(sess.charID), 10)
+	r.vars[sess.name+".user"] = sess.user
+}
+
+func (r *Runner) closeAll() {
+	for name, sess := range r.sessions {
+		_ = scenario.Logout(sess.ch)
+		delete(r.sessions, name)
+	}
+}
+
+// args are a step's expanded "with" values.
+type args map[string]string
+
+func (a args) str(key, def string) string {
+	if v, ok := a[key]; ok {
+		return v
+	}
+	return def
+}
+
+func (a args) required(key string) (string, error) {
+	v, ok := a[key]
+	if !ok || v == "" {
+		return "", fmt.Errorf("with.%s is required", key)
+	}
+	return v, nil
+}
+
+func (a args) uint(key string, bits int, def uint64, required bool) (uint64, error) {
+	v, ok := a[key]
+	if !ok || v == "" {
+		if required {
+			return 0, fmt.Errorf("with.%s is required", key)
+		}
+		return def, nil
+	}
+	n, err := strconv.ParseUint(v, 0, bits)
+	if err != nil {
+		return 0, fmt.Errorf("with.%s: %w", key, err)
+	}
+	return n, nil
+}
+
+func (a args) duration(key string, def time.Duration) (time.Duration, error) {
+	v, ok := a[key]
+	if !ok || v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("with.%s: %w", key, err)
+	}
+	return d, nil
+}
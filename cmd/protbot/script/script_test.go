@@ -0,0 +1,143 @@
+package script
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExampleScenariosParse(t *testing.T) {
+	paths, err := filepath.Glob("../scenarios/*.yaml")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no example scenarios: %v", err)
+	}
+	for _, path := range paths {
+		if _, err := Load(path); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	f, err := Parse([]byte(`{"name": "j", "steps": [{"do": "wait", "with": {"for": "1ms"}, "expect": {"fields": {"count": 3}}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Steps[0].As != "main" || f.Steps[0].Expect.Fields["count"] != "3" {
+		t.Errorf("step = %+v", f.Steps[0])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"name: empty",
+		"steps: [{do: fly}]",
+		"steps: [{do: [1]}]",
+	} {
+		if _, err := Parse([]byte(src)); err == nil {
+			t.Errorf("Parse(%q) succeeded", src)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"a": "1", "leader.char_id": "42"}
+	got, err := expand("x${a}-${leader.char_id}", vars)
+	if err != nil || got != "x1-42" {
+		t.Errorf("expand = %q, %v", got, err)
+	}
+	if _, err := expand("${missing}", vars); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected undefined variable error, got %v", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		got, want string
+		holds     bool
+	}{
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+		{"3", ">= 3", true},
+		{"3", "> 3", false},
+		{"2", "< 10", true},
+		{"10", "<= 9", false},
+		{"3.0", "== 3", true},
+		{"x", "!= y", true},
+		{"5", "!= 5", false},
+		{"x", "> 1", false},
+	}
+	for _, tt := range tests {
+		if msg := compare(tt.got, tt.want); (msg == "") != tt.holds {
+			t.Errorf("compare(%q, %q) = %q, want holds=%v", tt.got, tt.want, msg, tt.holds)
+		}
+	}
+}
+
+func TestExpectCheck(t *testing.T) {
+	res := &Result{OK: true, Fields: map[string]string{"count": "2", "subjects": "Hi|QA 7", "item.7": "3"}}
+	vars := map[string]string{"run": "7", "item": "7"}
+
+	var nilExpect *Expect
+	if failures, _ := nilExpect.check(res, vars); len(failures) != 0 {
+		t.Errorf("nil expect failures = %v", failures)
+	}
+	if failures, _ := nilExpect.check(&Result{ErrorCode: 1}, vars); len(failures) != 1 {
+		t.Errorf("failed ACK not reported: %v", failures)
+	}
+
+	e := &Expect{
+		Fields:   map[string]string{"count": ">= 2", "item.${item}": "3"},
+		Contains: map[string]string{"subjects": "QA ${run}"},
+	}
+	if failures, err := e.check(res, vars); err != nil || len(failures) != 0 {
+		t.Errorf("failures = %v, err = %v", failures, err)
+	}
+
+	notOK := false
+	e = &Expect{OK: &notOK, Fields: map[string]string{"missing": "1"}}
+	failures, err := e.check(res, vars)
+	if err != nil || len(failures) != 2 {
+		t.Errorf("failures = %v, err = %v", failures, err)
+	}
+}
+
+func TestRunnerStepsAndSessions(t *testing.T) {
+	f, err := Parse([]byte(`
+name: offline
+vars:
+  delay: 1ms
+  label: run-${run}
+steps:
+  - {do: wait, with: {for: "${delay}"}}
+  - {do: chat, as: nobody, with: {message: hi}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	r := &Runner{Out: &out, Vars: map[string]string{"delay": "2ms"}}
+	err = r.Run(f)
+	if err == nil || !strings.Contains(err.Error(), `session "nobody" is not logged in`) {
+		t.Fatalf("Run error = %v", err)
+	}
+	if r.vars["delay"] != "2ms" || !strings.HasPrefix(r.vars["label"], "run-") {
+		t.Errorf("vars = %v", r.vars)
+	}
+	if !strings.Contains(out.String(), "step 1/2 wait (main)") || !strings.Contains(out.String(), "FAIL step 2/2") {
+		t.Errorf("output:\n%s", out.String())
+	}
+}
+
+func TestRunnerSaveAndUndefinedVars(t *testing.T) {
+	r := &Runner{Out: &bytes.Buffer{}}
+	f, _ := Parse([]byte(`steps: [{do: wait, with: {for: "${nope}"}}]`))
+	if err := r.Run(f); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("Run error = %v", err)
+	}
+	f, _ = Parse([]byte(`steps: [{do: wait, with: {for: 1ms}, save: {x: missing}}]`))
+	if err := r.Run(f); err == nil || !strings.Contains(err.Error(), `no result field "missing"`) {
+		t.Errorf("Run error = %v", err)
+	}
+}
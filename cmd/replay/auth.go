@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	"erupe-ce/cmd/protbot/protocol"
+	"erupe-ce/common/byteframe"
+	"erupe-ce/network"
+	"erupe-ce/network/clientctx"
+	"erupe-ce/network/mhfpacket"
+	"erupe-ce/network/pcap"
+)
+
+// sessionPatcher rewrites captured client packets for a live session. The
+// login packet gets a fresh sign token, the captured character ID is replaced
+// with the one logged in, and ack handles are renumbered. Responses are
+// patched back so that they compare equal to the capture.
+//
+// Only the first packet of each captured record is patched; the client
+// rarely sends more than one packet per group.
+type sessionPatcher struct {
+	origCharID  uint32
+	charID      uint32
+	tokenID     uint32
+	token       string
+	patchCharID bool // Replace origCharID anywhere in client packets, not just MSG_SYS_LOGIN
+
+	nextAck  uint32
+	acks     map[uint32]uint32 // Captured handle → replayed handle
+	origAcks map[uint32]uint32 // Replayed handle → captured handle
+}
+
+func newSessionPatcher(origCharID, charID, tokenID uint32, token string, patchCharID bool) *sessionPatcher {
+	return &sessionPatcher{
+		origCharID:  origCharID,
+		charID:      charID,
+		tokenID:     tokenID,
+		token:       token,
+		patchCharID: patchCharID && origCharID != 0 && origCharID != charID,
+		acks:        make(map[uint32]uint32),
+		origAcks:    make(map[uint32]uint32),
+	}
+}
+
+// signIn authenticates with the sign server and returns a patcher for the
+// character to replay as: want if the account has it, else the account's
+// first character.
+func signIn(addr, user, pass string, want, origCharID uint32, patchCharID bool) (*sessionPatcher, error) {
+	sign, err := protocol.DoSign(addr, user, pass)
+	if err != nil {
+		return nil, err
+	}
+	if len(sign.CharIDs) == 0 {
+		return nil, fmt.Errorf("no characters on account %q", user)
+	}
+	charID := sign.CharIDs[0]
+	for _, id := range sign.CharIDs {
+		if id == want {
+			charID = id
+		}
+	}
+	return newSessionPatcher(origCharID, charID, sign.TokenID, sign.TokenString, patchCharID), nil
+}
+
+// capturedCharID returns the character a capture was recorded with, from its
+// metadata or else its MSG_SYS_LOGIN packet.
+func capturedCharID(meta pcap.SessionMetadata, records []pcap.PacketRecord) uint32 {
+	if meta.CharID != 0 {
+		return meta.CharID
+	}
+	for _, rec := range records {
+		if rec.Direction == pcap.DirClientToServer && rec.Opcode == uint16(network.MSG_SYS_LOGIN) && len(rec.Payload) >= 10 {
+			return binary.BigEndian.Uint32(rec.Payload[6:10])
+		}
+	}
+	return 0
+}
+
+// ackOpcodes caches whether each opcode's packet starts with an ack handle.
+var ackOpcodes = map[uint16]bool{}
+
+// hasAckHandle reports whether packets with the opcode start with an ack
+// handle, going by the AckHandle field of the mhfpacket struct.
+func hasAckHandle(opcode uint16) bool {
+	if has, ok := ackOpcodes[opcode]; ok {
+		return has
+	}
+	has := false
+	if pkt := mhfpacket.FromOpcode(network.PacketID(opcode)); pkt != nil {
+		t := reflect.TypeOf(pkt)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		has = t.Kind() == reflect.Struct && t.NumField() > 0 &&
+			t.Field(0).Name == "AckHandle" && t.Field(0).Type.Kind() == reflect.Uint32
+	}
+	ackOpcodes[opcode] = has
+	return has
+}
+
+// requestAckOpcodes maps the ack handles of captured client requests to their
+// opcodes.
+func requestAckOpcodes(records []pcap.PacketRecord) map[uint32]uint16 {
+	out := make(map[uint32]uint16)
+	for _, rec := range records {
+		if rec.Direction == pcap.DirClientToServer && len(rec.Payload) >= 6 && hasAckHandle(rec.Opcode) {
+			out[binary.BigEndian.Uint32(rec.Payload[2:6])] = rec.Opcode
+		}
+	}
+	return out
+}
+
+// patchRequest returns a copy of a captured client packet ready to be sent.
+func (p *sessionPatcher) patchRequest(rec pcap.PacketRecord) []byte {
+	payload := append([]byte(nil), rec.Payload...)
+	if len(payload) < 2 {
+		return payload
+	}
+	withAck := len(payload) >= 6 && hasAckHandle(rec.Opcode)
+	if rec.Opcode == uint16(network.MSG_SYS_LOGIN) {
+		payload = p.patchLogin(payload)
+	} else if p.patchCharID {
+		start := 2
+		if withAck {
+			start = 6
+		}
+		replaceUint32(payload[start:], p.origCharID, p.charID)
+	}
+	if withAck {
+		captured := binary.BigEndian.Uint32(payload[2:6])
+		replayed, ok := p.acks[captured]
+		if !ok {
+			p.nextAck++
+			replayed = p.nextAck
+			p.acks[captured] = replayed
+			p.origAcks[replayed] = captured
+		}
+		binary.BigEndian.PutUint32(payload[2:6], replayed)
+	}
+	return payload
+}
+
+// patchLogin rebuilds a MSG_SYS_LOGIN packet with the live character and
+// token, keeping the captured ack handle, request version and trailing data.
+func (p *sessionPatcher) patchLogin(payload []byte) []byte {
+	bf := byteframe.NewByteFrameFromBytes(payload[2:])
+	login := &mhfpacket.MsgSysLogin{}
+	if err := login.Parse(bf, &clientctx.ClientContext{}); err != nil || bf.Err() != nil {
+		return payload
+	}
+	rest := bf.DataFromCurrent()
+
+	out := byteframe.NewByteFrame()
+	out.WriteBytes(payload[:2])
+	out.WriteUint32(login.AckHandle)
+	out.WriteUint32(p.charID)
+	out.WriteUint32(p.tokenID)
+	out.WriteUint16(login.HardcodedZero0)
+	out.WriteUint16(login.RequestVersion)
+	out.WriteUint32(p.charID)
+	out.WriteUint16(0)
+	out.WriteUint16(11)
+	out.WriteNullTerminatedBytes([]byte(p.token))
+	out.WriteBytes(rest)
+	return out.Data()
+}
+
+// unpatchResponse maps a live server packet back to the capture's ack
+// handles and character ID so it can be compared with the recorded one.
+func (p *sessionPatcher) unpatchResponse(payload []byte) []byte {
+	payload = append([]byte(nil), payload...)
+	if len(payload) >= 6 && binary.BigEndian.Uint16(payload) == uint16(network.MSG_SYS_ACK) {
+		if captured, ok := p.origAcks[binary.BigEndian.Uint32(payload[2:6])]; ok {
+			binary.BigEndian.PutUint32(payload[2:6], captured)
+		}
+	}
+	if p.patchCharID && len(payload) > 6 {
+		replaceUint32(payload[6:], p.charID, p.origCharID)
+	}
+	return payload
+}
+
+// replaceUint32 replaces every big-endian occurrence of old in b with new.
+func replaceUint32(b []byte, old, new uint32) {
+	var from, to [4]byte
+	binary.BigEndian.PutUint32(from[:], old)
+	binary.BigEndian.PutUint32(to[:], new)
+	for i := 0; i+4 <= len(b); {
+		j := bytes.Index(b[i:], from[:])
+		if j < 0 {
+			return
+		}
+		copy(b[i+j:], to[:])
+		i += j + 4
+	}
+}
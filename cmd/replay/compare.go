@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bufio"
+	"encoding/binary"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"erupe-ce/network"
@@ -60,9 +64,146 @@ func (d PacketDiff) String() string {
 		d.Index, d.Expected.Opcode, network.PacketID(d.Expected.Opcode))
 }
 
+// ByteRange is an inclusive range of payload offsets.
+type ByteRange struct {
+	Start, End int
+}
+
+// IgnoreMask lists the parts of a response to skip when comparing. A mask
+// with All set ignores the payload entirely, including its size.
+type IgnoreMask struct {
+	All    bool
+	Ranges []ByteRange
+}
+
+// ignores reports whether the mask covers the payload offset.
+func (m *IgnoreMask) ignores(offset int) bool {
+	if m == nil {
+		return false
+	}
+	if m.All {
+		return true
+	}
+	for _, r := range m.Ranges {
+		if offset >= r.Start && offset <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoreMasks maps opcodes to their ignore masks. Masks for an ACK apply by
+// the opcode of the request it answers, so "MSG_SYS_TIME" masks both
+// MSG_SYS_TIME packets and the ACKs to client MSG_SYS_TIME requests.
+type IgnoreMasks map[uint16]IgnoreMask
+
+// Add parses a mask spec of the form OPCODE or OPCODE:RANGES, where OPCODE is
+// a packet name or a 0x-prefixed number and RANGES is a comma-separated list
+// of payload offsets or inclusive start-end ranges, e.g.
+// "MSG_MHF_GET_EARTH_STATUS:6-13,22".
+func (m IgnoreMasks) Add(spec string) error {
+	name, ranges, hasRanges := strings.Cut(strings.TrimSpace(spec), ":")
+	opcode, err := parseOpcode(name)
+	if err != nil {
+		return err
+	}
+	mask := m[opcode]
+	if !hasRanges {
+		mask.All = true
+		m[opcode] = mask
+		return nil
+	}
+	for _, part := range strings.Split(ranges, ",") {
+		startStr, endStr, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, err := strconv.Atoi(startStr)
+		if err != nil || start < 0 {
+			return fmt.Errorf("invalid offset %q in mask %q", part, spec)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(endStr)
+			if err != nil || end < start {
+				return fmt.Errorf("invalid range %q in mask %q", part, spec)
+			}
+		}
+		mask.Ranges = append(mask.Ranges, ByteRange{Start: start, End: end})
+	}
+	m[opcode] = mask
+	return nil
+}
+
+// LoadFile adds the mask specs in a file, one per line. Blank lines and lines
+// starting with # are skipped.
+func (m IgnoreMasks) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := m.Add(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseOpcode parses a packet name or a 0x-prefixed opcode number.
+func parseOpcode(s string) (uint16, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		v, err := strconv.ParseUint(s[2:], 16, 16)
+		if err != nil {
+			return 0, fmt.Errorf("invalid opcode %q", s)
+		}
+		return uint16(v), nil
+	}
+	id, ok := network.PacketIDFromString(s)
+	if !ok {
+		return 0, fmt.Errorf("unknown opcode %q", s)
+	}
+	return uint16(id), nil
+}
+
+// CompareOptions tunes ComparePacketsWith.
+type CompareOptions struct {
+	Masks IgnoreMasks
+	// AckOpcodes maps the ack handles of the captured requests to their
+	// opcodes, so that masks can be applied to the ACKs answering them.
+	AckOpcodes map[uint32]uint16
+}
+
+// maskFor returns the mask applying to an expected response, or nil.
+func (o CompareOptions) maskFor(rec pcap.PacketRecord) *IgnoreMask {
+	if len(o.Masks) == 0 {
+		return nil
+	}
+	if rec.Opcode == uint16(network.MSG_SYS_ACK) && len(rec.Payload) >= 6 {
+		if req, ok := o.AckOpcodes[binary.BigEndian.Uint32(rec.Payload[2:6])]; ok {
+			if mask, ok := o.Masks[req]; ok {
+				return &mask
+			}
+		}
+	}
+	if mask, ok := o.Masks[rec.Opcode]; ok {
+		return &mask
+	}
+	return nil
+}
+
 // ComparePackets compares expected server responses against actual responses.
 // Only compares S→C packets (server responses).
 func ComparePackets(expected, actual []pcap.PacketRecord) []PacketDiff {
+	return ComparePacketsWith(expected, actual, CompareOptions{})
+}
+
+// ComparePacketsWith compares expected server responses against actual
+// responses, skipping the parts covered by the ignore masks.
+func ComparePacketsWith(expected, actual []pcap.PacketRecord, opts CompareOptions) []PacketDiff {
 	expectedS2C := pcap.FilterByDirection(expected, pcap.DirServerToClient)
 	actualS2C := pcap.FilterByDirection(actual, pcap.DirServerToClient)
 
@@ -77,6 +218,7 @@ func ComparePackets(expected, actual []pcap.PacketRecord) []PacketDiff {
 			continue
 		}
 		act := actualS2C[i]
+		mask := opts.maskFor(exp)
 		if exp.Opcode != act.Opcode {
 			diffs = append(diffs, PacketDiff{
 				Index:          i,
@@ -84,6 +226,8 @@ func ComparePackets(expected, actual []pcap.PacketRecord) []PacketDiff {
 				Actual:         &act,
 				OpcodeMismatch: true,
 			})
+		} else if mask != nil && mask.All {
+			continue
 		} else if len(exp.Payload) != len(act.Payload) {
 			diffs = append(diffs, PacketDiff{
 				Index:     i,
@@ -93,7 +237,7 @@ func ComparePackets(expected, actual []pcap.PacketRecord) []PacketDiff {
 			})
 		} else {
 			// Same opcode and size — check for byte-level diffs.
-			byteDiffs := comparePayloads(exp.Payload, act.Payload)
+			byteDiffs := comparePayloadsMasked(exp.Payload, act.Payload, mask)
 			if len(byteDiffs) > 0 {
 				diffs = append(diffs, PacketDiff{
 					Index:        i,
@@ -121,9 +265,14 @@ func ComparePackets(expected, actual []pcap.PacketRecord) []PacketDiff {
 // comparePayloads returns byte-level diffs between two equal-length payloads.
 // Returns at most maxPayloadDiffs entries.
 func comparePayloads(expected, actual []byte) []ByteDiff {
+	return comparePayloadsMasked(expected, actual, nil)
+}
+
+// comparePayloadsMasked is comparePayloads skipping offsets covered by mask.
+func comparePayloadsMasked(expected, actual []byte, mask *IgnoreMask) []ByteDiff {
 	var diffs []ByteDiff
 	for i := 0; i < len(expected) && len(diffs) < maxPayloadDiffs; i++ {
-		if expected[i] != actual[i] {
+		if expected[i] != actual[i] && !mask.ignores(i) {
 			diffs = append(diffs, ByteDiff{
 				Offset:   i,
 				Expected: expected[i],
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"erupe-ce/network/pcap"
+)
+
+// runExportPCAPNG converts a .mhfr capture to pcapng.
+func runExportPCAPNG(path, out string) error {
+	r, f, err := openCapture(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	records, err := readAllPackets(r)
+	if err != nil {
+		return err
+	}
+
+	of, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("create output: %w", err)
+	}
+	bw := bufio.NewWriter(of)
+	if err := pcap.ExportPCAPNG(bw, r.Header, r.Meta, records); err != nil {
+		_ = of.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		_ = of.Close()
+		return err
+	}
+	if err := of.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d packets to %s\n", len(records), out)
+	return nil
+}
+
+// runImportPCAPNG converts a pcapng file, either exported by this tool or a
+// raw TCP capture of an MHF session, to a .mhfr capture.
+func runImportPCAPNG(path, out string, opts pcap.ImportOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open pcapng: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	hdr, meta, records, err := pcap.ImportPCAPNG(bufio.NewReader(f), opts)
+	if err != nil {
+		return err
+	}
+
+	of, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("create output: %w", err)
+	}
+	w, err := pcap.NewWriter(of, hdr, meta)
+	if err != nil {
+		_ = of.Close()
+		return err
+	}
+	for _, rec := range records {
+		if err := w.WritePacket(rec); err != nil {
+			_ = of.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = of.Close()
+		return err
+	}
+	if err := of.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d packets (%s server) to %s\n", len(records), hdr.ServerType, out)
+	return nil
+}
+
+// parseServerType parses a --server-type value; empty means detect.
+func parseServerType(s string) (pcap.ServerType, error) {
+	for _, st := range []pcap.ServerType{pcap.ServerTypeSign, pcap.ServerTypeEntrance, pcap.ServerTypeChannel} {
+		if s == st.String() {
+			return st, nil
+		}
+	}
+	if s == "" {
+		return 0, nil
+	}
+	return 0, fmt.Errorf("unknown server type %q", s)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"erupe-ce/common/byteframe"
+	cfg "erupe-ce/config"
+	"erupe-ce/network"
+	"erupe-ce/network/clientctx"
+	"erupe-ce/network/mhfpacket"
+	"erupe-ce/network/pcap"
+)
+
+// decodedPacket is one packet of a record parsed with its mhfpacket parser.
+// A record holds a packet group, so it may decode to several packets.
+type decodedPacket struct {
+	Offset int                 `json:"offset"` // Offset of the packet in the record payload
+	Opcode string              `json:"opcode"`
+	Fields mhfpacket.MHFPacket `json:"fields,omitempty"`
+	Error  string              `json:"error,omitempty"`
+	// StoppedAt is the payload offset where parsing stopped when Error is
+	// set, or where trailing bytes no parser consumed start.
+	StoppedAt int `json:"stopped_at,omitempty"`
+}
+
+// clientContextFor returns the parser context for a capture.
+func clientContextFor(hdr pcap.FileHeader) *clientctx.ClientContext {
+	mode := cfg.Mode(hdr.ClientMode)
+	if mode == 0 {
+		mode = cfg.ZZ
+	}
+	return &clientctx.ClientContext{RealClientMode: mode}
+}
+
+// decodeRecord parses the packets in a record, following handlePacketGroup:
+// each parser consumes its packet and any remaining bytes are parsed as the
+// next packet. Decoding stops at the first packet that fails to parse.
+func decodeRecord(payload []byte, ctx *clientctx.ClientContext) []decodedPacket {
+	var out []decodedPacket
+	offset := 0
+	for len(payload)-offset >= 2 {
+		bf := byteframe.NewByteFrameFromBytes(payload[offset:])
+		opcode := network.PacketID(bf.ReadUint16())
+		dp := decodedPacket{Offset: offset, Opcode: opcode.String()}
+		pkt := mhfpacket.FromOpcode(opcode)
+		if pkt == nil {
+			dp.Error = "no parser for opcode"
+			dp.StoppedAt = offset + 2
+			return append(out, dp)
+		}
+		if err := parseSafely(pkt, bf, ctx); err != nil {
+			dp.Error = err.Error()
+			dp.StoppedAt = offset + int(bf.Index())
+			return append(out, dp)
+		}
+		if err := bf.Err(); err != nil {
+			dp.Fields = pkt
+			dp.Error = "read past end of packet"
+			dp.StoppedAt = offset + int(bf.Index())
+			return append(out, dp)
+		}
+		dp.Fields = pkt
+		out = append(out, dp)
+		offset += int(bf.Index())
+	}
+	if offset < len(payload) && len(out) > 0 {
+		last := &out[len(out)-1]
+		last.Error = fmt.Sprintf("%d trailing bytes", len(payload)-offset)
+		last.StoppedAt = offset
+	}
+	return out
+}
+
+// parseSafely runs a parser, turning a panic on malformed data into an error.
+func parseSafely(pkt mhfpacket.MHFPacket, bf *byteframe.ByteFrame, ctx *clientctx.ClientContext) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("parser panicked: %v", r)
+		}
+	}()
+	return pkt.Parse(bf, ctx)
+}
+
+// String formats a decoded packet for the text dump.
+func (dp decodedPacket) String() string {
+	var sb strings.Builder
+	if dp.Fields != nil {
+		fmt.Fprintf(&sb, "%s %+v", dp.Opcode, reflect.Indirect(reflect.ValueOf(dp.Fields)).Interface())
+	} else {
+		sb.WriteString(dp.Opcode)
+	}
+	if dp.Error != "" {
+		fmt.Fprintf(&sb, "  !! %s (stopped at offset %d)", dp.Error, dp.StoppedAt)
+	}
+	return sb.String()
+}
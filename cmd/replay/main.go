@@ -2,10 +2,29 @@
 //
 // Usage:
 //
-//	replay --capture file.mhfr --mode dump     # Human-readable text output
+//	replay --capture file.mhfr --mode dump     # Human-readable text output with decoded fields
 //	replay --capture file.mhfr --mode json     # JSON export
 //	replay --capture file.mhfr --mode stats    # Opcode histogram, duration, counts
+//	replay --capture file.mhfr --mode to-pcapng --out file.pcapng      # Export for Wireshark
+//	replay --capture raw.pcapng --mode from-pcapng --out file.mhfr \
+//	       --server-port 54001                                         # Import a raw TCP capture
 //	replay --capture file.mhfr --mode replay --target 127.0.0.1:54001 --no-auth  # Replay against live server
+//	replay --capture file.mhfr --mode replay --target 127.0.0.1:54001 \
+//	       --sign-addr 127.0.0.1:53312 --user test --pass test             # Log in and patch the session
+//	replay --capture file.mhfr --mode replay --target 127.0.0.1:54001 --no-auth \
+//	       --assert --ignore MSG_SYS_TIME --ignore MSG_MHF_GET_EARTH_STATUS:6-13  # Fail on divergence
+//
+// Without --no-auth, channel captures are replayed as a freshly signed-in
+// character: the login packet gets the new token, the captured character ID
+// is replaced in client packets and ack handles are renumbered. Ignore masks
+// name an opcode, optionally followed by the payload offsets to skip; masks
+// on a request opcode also apply to the ACKs answering it.
+//
+// Exported pcapng files use link type 147 (USER0), each packet being a
+// direction byte followed by the decrypted packet, with the opcode name in
+// the packet comment. Importing accepts those files back, or raw Ethernet,
+// loopback, Linux SLL or IP captures whose MHF connection is reassembled
+// and decrypted.
 package main
 
 import (
@@ -20,6 +39,7 @@ import (
 	"time"
 
 	"erupe-ce/cmd/protbot/conn"
+	cfg "erupe-ce/config"
 	"erupe-ce/network"
 	"erupe-ce/network/pcap"
 )
@@ -29,11 +49,24 @@ const opcodeSysPing = 0x0017
 
 func main() {
 	capturePath := flag.String("capture", "", "Path to .mhfr capture file (required)")
-	mode := flag.String("mode", "dump", "Mode: dump, json, stats, replay")
+	mode := flag.String("mode", "dump", "Mode: dump, json, stats, replay, to-pcapng, from-pcapng")
+	out := flag.String("out", "", "Output file for to-pcapng and from-pcapng modes")
+	serverPort := flag.Uint("server-port", 0, "Server TCP port of the MHF connection for from-pcapng (default: first connection)")
+	serverType := flag.String("server-type", "", "Server type for from-pcapng: sign, entrance or channel (default: detected)")
+	decode := flag.Bool("decode", true, "Parse packets and show their fields in dump and json modes")
+	clientMode := flag.Uint("client-mode", uint(cfg.ZZ), "Client mode number for from-pcapng, as in the capture header")
 	target := flag.String("target", "", "Target server address for replay mode (host:port)")
 	speed := flag.Float64("speed", 1.0, "Replay speed multiplier (e.g. 2.0 = 2x faster)")
 	noAuth := flag.Bool("no-auth", false, "Skip auth token patching (requires DisableTokenCheck on server)")
-	_ = noAuth // currently only no-auth mode is supported
+	signAddr := flag.String("sign-addr", "", "Sign server address to log in through for replay mode (host:port)")
+	user := flag.String("user", "", "Account username for replay mode")
+	pass := flag.String("pass", "", "Account password for replay mode")
+	charID := flag.Uint("char-id", 0, "Character to replay as (default: the captured one if on the account, else the first)")
+	patchCharID := flag.Bool("patch-charid", true, "Replace the captured character ID anywhere in client packets")
+	assert := flag.Bool("assert", false, "Exit non-zero if responses diverge from the capture")
+	masks := IgnoreMasks{}
+	flag.Var(maskFlag{masks}, "ignore", "Ignore mask OPCODE[:START-END,...] for response comparison (repeatable)")
+	ignoreFile := flag.String("ignore-file", "", "File of ignore masks, one per line")
 	flag.Parse()
 
 	if *capturePath == "" {
@@ -44,12 +77,12 @@ func main() {
 
 	switch *mode {
 	case "dump":
-		if err := runDump(*capturePath); err != nil {
+		if err := runDump(*capturePath, *decode); err != nil {
 			fmt.Fprintf(os.Stderr, "dump failed: %v\n", err)
 			os.Exit(1)
 		}
 	case "json":
-		if err := runJSON(*capturePath); err != nil {
+		if err := runJSON(*capturePath, *decode); err != nil {
 			fmt.Fprintf(os.Stderr, "json failed: %v\n", err)
 			os.Exit(1)
 		}
@@ -63,10 +96,47 @@ func main() {
 			fmt.Fprintln(os.Stderr, "error: --target is required for replay mode")
 			os.Exit(1)
 		}
-		if err := runReplay(*capturePath, *target, *speed); err != nil {
+		if *ignoreFile != "" {
+			if err := masks.LoadFile(*ignoreFile); err != nil {
+				fmt.Fprintf(os.Stderr, "error: load ignore masks: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		opts := replayOptions{
+			target:      *target,
+			speed:       *speed,
+			noAuth:      *noAuth,
+			signAddr:    *signAddr,
+			user:        *user,
+			pass:        *pass,
+			charID:      uint32(*charID),
+			patchCharID: *patchCharID,
+			assert:      *assert,
+			masks:       masks,
+		}
+		if err := runReplay(*capturePath, opts); err != nil {
 			fmt.Fprintf(os.Stderr, "replay failed: %v\n", err)
 			os.Exit(1)
 		}
+	case "to-pcapng", "from-pcapng":
+		if *out == "" {
+			fmt.Fprintf(os.Stderr, "error: --out is required for %s mode\n", *mode)
+			os.Exit(1)
+		}
+		var err error
+		if *mode == "to-pcapng" {
+			err = runExportPCAPNG(*capturePath, *out)
+		} else {
+			opts := pcap.ImportOptions{ServerPort: uint16(*serverPort), ClientMode: byte(*clientMode)}
+			opts.ServerType, err = parseServerType(*serverType)
+			if err == nil {
+				err = runImportPCAPNG(*capturePath, *out, opts)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s failed: %v\n", *mode, err)
+			os.Exit(1)
+		}
 	default:
 		fmt.Fprintf(os.Stderr, "unknown mode: %s\n", *mode)
 		os.Exit(1)
@@ -101,7 +171,29 @@ func readAllPackets(r *pcap.Reader) ([]pcap.PacketRecord, error) {
 	return records, nil
 }
 
-func runReplay(path, target string, speed float64) error {
+// maskFlag adds each --ignore value to a set of ignore masks.
+type maskFlag struct{ masks IgnoreMasks }
+
+func (f maskFlag) String() string     { return "" }
+func (f maskFlag) Set(s string) error { return f.masks.Add(s) }
+
+// replayOptions configures runReplay.
+type replayOptions struct {
+	target string
+	speed  float64
+
+	noAuth      bool // Send client packets as captured
+	signAddr    string
+	user, pass  string
+	charID      uint32 // Preferred character; 0 for the captured one
+	patchCharID bool
+
+	assert bool // Fail if responses diverge from the capture
+	masks  IgnoreMasks
+}
+
+func runReplay(path string, opts replayOptions) error {
+	target, speed := opts.target, opts.speed
 	r, f, err := openCapture(path)
 	if err != nil {
 		return err
@@ -125,6 +217,27 @@ func runReplay(path, target string, speed float64) error {
 	fmt.Printf("Server type: %s  Target: %s  Speed: %.1fx\n", r.Header.ServerType, target, speed)
 	fmt.Printf("C→S packets to send: %d  Expected S→C responses: %d\n\n", len(c2s), len(expectedS2C))
 
+	// Sign in to get a live token for the capture's character.
+	var patcher *sessionPatcher
+	if !opts.noAuth && r.Header.ServerType == pcap.ServerTypeChannel {
+		if opts.signAddr == "" || opts.user == "" {
+			return fmt.Errorf("--sign-addr and --user are required unless --no-auth is set")
+		}
+		origCharID := capturedCharID(r.Meta, records)
+		want := opts.charID
+		if want == 0 {
+			want = origCharID
+		}
+		patcher, err = signIn(opts.signAddr, opts.user, opts.pass, want, origCharID, opts.patchCharID)
+		if err != nil {
+			return fmt.Errorf("sign in: %w", err)
+		}
+		if patcher.patchCharID && origCharID < 0x10000 {
+			fmt.Printf("[replay] warning: captured char ID %d is small, patching it may corrupt unrelated fields\n", origCharID)
+		}
+		fmt.Printf("[replay] Signed in as char %d (captured as %d)\n\n", patcher.charID, origCharID)
+	}
+
 	// Connect based on server type.
 	var mhf *conn.MHFConn
 	switch r.Header.ServerType {
@@ -160,6 +273,11 @@ func runReplay(path, target string, speed float64) error {
 				pong := buildPingResponse()
 				_ = mhf.SendPacket(pong)
 			}
+			if patcher != nil {
+				mu.Lock()
+				pkt = patcher.unpatchResponse(pkt)
+				mu.Unlock()
+			}
 
 			mu.Lock()
 			actualS2C = append(actualS2C, pcap.PacketRecord{
@@ -184,7 +302,13 @@ func runReplay(path, target string, speed float64) error {
 		lastTs = pkt.TimestampNs
 		opcodeName := network.PacketID(pkt.Opcode).String()
 		fmt.Printf("[replay] #%d sending 0x%04X %-30s (%d bytes)\n", i, pkt.Opcode, opcodeName, len(pkt.Payload))
-		if err := mhf.SendPacket(pkt.Payload); err != nil {
+		payload := pkt.Payload
+		if patcher != nil {
+			mu.Lock()
+			payload = patcher.patchRequest(pkt)
+			mu.Unlock()
+		}
+		if err := mhf.SendPacket(payload); err != nil {
 			fmt.Printf("[replay] send error: %v\n", err)
 			break
 		}
@@ -198,7 +322,10 @@ func runReplay(path, target string, speed float64) error {
 
 	// Compare.
 	mu.Lock()
-	diffs := ComparePackets(expectedS2C, actualS2C)
+	diffs := ComparePacketsWith(expectedS2C, actualS2C, CompareOptions{
+		Masks:      opts.masks,
+		AckOpcodes: requestAckOpcodes(c2s),
+	})
 	mu.Unlock()
 
 	// Report.
@@ -213,6 +340,8 @@ func runReplay(path, target string, speed float64) error {
 
 	if len(diffs) == 0 {
 		fmt.Println("All responses match!")
+	} else if opts.assert {
+		return fmt.Errorf("%d responses diverge from the capture", len(diffs))
 	}
 
 	return nil
@@ -224,7 +353,7 @@ func buildPingResponse() []byte {
 	return []byte{0x00, 0x17, 0x00, 0x10}
 }
 
-func runDump(path string) error {
+func runDump(path string, decode bool) error {
 	r, f, err := openCapture(path)
 	if err != nil {
 		return err
@@ -249,11 +378,17 @@ func runDump(path string) error {
 		return err
 	}
 
+	ctx := clientContextFor(r.Header)
 	for i, rec := range records {
 		elapsed := time.Duration(rec.TimestampNs - r.Header.SessionStartNs)
 		opcodeName := network.PacketID(rec.Opcode).String()
 		fmt.Printf("#%04d  +%-12s  %s  0x%04X %-30s  %d bytes\n",
 			i, elapsed, rec.Direction, rec.Opcode, opcodeName, len(rec.Payload))
+		if decode {
+			for _, dp := range decodeRecord(rec.Payload, ctx) {
+				fmt.Printf("       %s\n", dp)
+			}
+		}
 	}
 
 	fmt.Printf("\nTotal: %d packets\n", len(records))
@@ -281,9 +416,11 @@ type jsonPacket struct {
 	Opcode     uint16 `json:"opcode"`
 	OpcodeName string `json:"opcode_name"`
 	PayloadLen int    `json:"payload_len"`
+
+	Decoded []decodedPacket `json:"decoded,omitempty"`
 }
 
-func runJSON(path string) error {
+func runJSON(path string, decode bool) error {
 	r, f, err := openCapture(path)
 	if err != nil {
 		return err
@@ -306,7 +443,12 @@ func runJSON(path string) error {
 		Packets: make([]jsonPacket, len(records)),
 	}
 
+	ctx := clientContextFor(r.Header)
 	for i, rec := range records {
+		var decoded []decodedPacket
+		if decode {
+			decoded = decodeRecord(rec.Payload, ctx)
+		}
 		out.Packets[i] = jsonPacket{
 			Index:      i,
 			Timestamp:  time.Unix(0, rec.TimestampNs).Format(time.RFC3339Nano),
@@ -315,6 +457,7 @@ func runJSON(path string) error {
 			Opcode:     rec.Opcode,
 			OpcodeName: network.PacketID(rec.Opcode).String(),
 			PayloadLen: len(rec.Payload),
+			Decoded:    decoded,
 		}
 	}
 
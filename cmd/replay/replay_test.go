@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"erupe-ce/network"
 	"erupe-ce/network/pcap"
 )
 
@@ -48,7 +49,7 @@ func TestRunDump(t *testing.T) {
 		{TimestampNs: 1000000200, Direction: pcap.DirServerToClient, Opcode: 0x0012, Payload: []byte{0x00, 0x12, 0xFF}},
 	})
 	// Just verify it doesn't error.
-	if err := runDump(path); err != nil {
+	if err := runDump(path, true); err != nil {
 		t.Fatalf("runDump: %v", err)
 	}
 }
@@ -80,7 +81,7 @@ func TestRunJSON(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	if err := runJSON(path); err != nil {
+	if err := runJSON(path, true); err != nil {
 		os.Stdout = old
 		t.Fatalf("runJSON: %v", err)
 	}
@@ -267,7 +268,7 @@ func TestRunReplayWithMockServer(t *testing.T) {
 	})
 
 	// Run replay — the connection will fail (no Blowfish on mock), but it should not panic.
-	err = runReplay(path, ln.Addr().String(), 0)
+	err = runReplay(path, replayOptions{target: ln.Addr().String(), noAuth: true})
 	// We expect an error or graceful handling since the mock doesn't speak Blowfish.
 	// The important thing is no panic.
 	_ = err
@@ -310,3 +311,238 @@ func TestBuildPingResponse(t *testing.T) {
 		t.Errorf("opcode = 0x%04X, want 0x%04X", opcode, opcodeSysPing)
 	}
 }
+
+func TestIgnoreMasksAdd(t *testing.T) {
+	m := IgnoreMasks{}
+	for _, spec := range []string{"MSG_SYS_TIME", "msg_mhf_get_earth_status:6-9,12", "0x0012:2"} {
+		if err := m.Add(spec); err != nil {
+			t.Fatalf("Add(%q): %v", spec, err)
+		}
+	}
+	if !m[uint16(network.MSG_SYS_TIME)].All {
+		t.Error("bare opcode did not mask the whole payload")
+	}
+	earth := m[uint16(network.MSG_MHF_GET_EARTH_STATUS)]
+	if earth.All || !earth.ignores(6) || !earth.ignores(9) || earth.ignores(10) || !earth.ignores(12) {
+		t.Errorf("earth mask = %+v", earth)
+	}
+	if err := m.Add("0x0012:4"); err != nil || len(m[0x0012].Ranges) != 2 {
+		t.Errorf("repeated mask did not merge: %+v", m[0x0012])
+	}
+
+	for _, bad := range []string{"MSG_NOT_REAL", "0xZZ", "MSG_SYS_TIME:5-2", "MSG_SYS_TIME:x"} {
+		if err := m.Add(bad); err == nil {
+			t.Errorf("Add(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestIgnoreMasksLoadFile(t *testing.T) {
+	path := t.TempDir() + "/masks.txt"
+	content := "# timestamps\nMSG_SYS_TIME\n\n  MSG_MHF_GET_EARTH_STATUS:6-13\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := IgnoreMasks{}
+	if err := m.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(m) != 2 {
+		t.Errorf("loaded %d masks, want 2", len(m))
+	}
+}
+
+func TestComparePacketsWithMasks(t *testing.T) {
+	ack := func(handle uint32, data ...byte) pcap.PacketRecord {
+		payload := []byte{0x00, 0x12, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(payload[2:], handle)
+		return pcap.PacketRecord{Direction: pcap.DirServerToClient, Opcode: 0x0012, Payload: append(payload, data...)}
+	}
+	expected := []pcap.PacketRecord{ack(1, 0xAA, 0xBB), ack(2, 0xAA), ack(3, 0x01)}
+	actual := []pcap.PacketRecord{ack(1, 0xCC, 0xBB), ack(2, 0xAA, 0xFF), ack(3, 0x02)}
+
+	if diffs := ComparePacketsWith(expected, actual, CompareOptions{}); len(diffs) != 3 {
+		t.Fatalf("unmasked diffs = %d, want 3", len(diffs))
+	}
+
+	masks := IgnoreMasks{}
+	_ = masks.Add("MSG_MHF_GET_EARTH_STATUS:6")
+	_ = masks.Add("MSG_SYS_TIME")
+	diffs := ComparePacketsWith(expected, actual, CompareOptions{
+		Masks: masks,
+		AckOpcodes: map[uint32]uint16{
+			1: uint16(network.MSG_MHF_GET_EARTH_STATUS),
+			2: uint16(network.MSG_SYS_TIME),
+		},
+	})
+	if len(diffs) != 1 || diffs[0].Index != 2 {
+		t.Errorf("masked diffs = %v, want only the unmasked ACK", diffs)
+	}
+}
+
+func TestSessionPatcherLogin(t *testing.T) {
+	login := []byte{0x00, byte(network.MSG_SYS_LOGIN)}
+	login = binary.BigEndian.AppendUint32(login, 0x77)   // AckHandle
+	login = binary.BigEndian.AppendUint32(login, 100001) // CharID0
+	login = binary.BigEndian.AppendUint32(login, 5)      // LoginTokenNumber
+	login = append(login, 0x00, 0x00, 0x00, 0x0C)        // HardcodedZero0, RequestVersion
+	login = binary.BigEndian.AppendUint32(login, 100001) // CharID1
+	login = append(login, 0x00, 0x00, 0x00, 0x0B)
+	login = append(login, []byte("oldtoken\x00")...)
+	login = append(login, 0x00, 0x10)
+	rec := pcap.PacketRecord{Direction: pcap.DirClientToServer, Opcode: uint16(network.MSG_SYS_LOGIN), Payload: login}
+
+	if got := capturedCharID(pcap.SessionMetadata{}, []pcap.PacketRecord{rec}); got != 100001 {
+		t.Errorf("capturedCharID = %d, want 100001", got)
+	}
+
+	p := newSessionPatcher(100001, 200002, 9, "newtoken12345678", true)
+	out := p.patchRequest(rec)
+	if got := binary.BigEndian.Uint32(out[6:10]); got != 200002 {
+		t.Errorf("CharID0 = %d, want 200002", got)
+	}
+	if got := binary.BigEndian.Uint32(out[10:14]); got != 9 {
+		t.Errorf("LoginTokenNumber = %d, want 9", got)
+	}
+	if got := binary.BigEndian.Uint16(out[16:18]); got != 0x0C {
+		t.Errorf("RequestVersion = %d, want 12", got)
+	}
+	if !bytes.Contains(out, []byte("newtoken12345678\x00")) || bytes.Contains(out, []byte("oldtoken")) {
+		t.Errorf("token not replaced: % X", out)
+	}
+	if !bytes.HasSuffix(out, []byte{0x00, 0x10}) {
+		t.Error("trailing bytes dropped")
+	}
+	if !bytes.HasPrefix(rec.Payload[6:], binary.BigEndian.AppendUint32(nil, 100001)) {
+		t.Error("patchRequest modified the captured payload")
+	}
+}
+
+func TestSessionPatcherAckHandles(t *testing.T) {
+	p := newSessionPatcher(100001, 200002, 9, "token", true)
+	req := []byte{0x00, 0x00, 0x00, 0x00, 0x12, 0x34}
+	binary.BigEndian.PutUint16(req, uint16(network.MSG_MHF_LOADDATA))
+	req = binary.BigEndian.AppendUint32(req, 100001)
+	rec := pcap.PacketRecord{Direction: pcap.DirClientToServer, Opcode: uint16(network.MSG_MHF_LOADDATA), Payload: req}
+
+	out := p.patchRequest(rec)
+	handle := binary.BigEndian.Uint32(out[2:6])
+	if handle == 0x1234 {
+		t.Fatal("ack handle not renumbered")
+	}
+	if got := binary.BigEndian.Uint32(out[6:10]); got != 200002 {
+		t.Errorf("char ID in body = %d, want 200002", got)
+	}
+	if again := p.patchRequest(rec); binary.BigEndian.Uint32(again[2:6]) != handle {
+		t.Error("same captured handle renumbered twice")
+	}
+
+	resp := []byte{0x00, byte(network.MSG_SYS_ACK)}
+	resp = binary.BigEndian.AppendUint32(resp, handle)
+	resp = binary.BigEndian.AppendUint32(resp, 200002)
+	back := p.unpatchResponse(resp)
+	if got := binary.BigEndian.Uint32(back[2:6]); got != 0x1234 {
+		t.Errorf("response handle = %#x, want 0x1234", got)
+	}
+	if got := binary.BigEndian.Uint32(back[6:10]); got != 100001 {
+		t.Errorf("response char ID = %d, want 100001", got)
+	}
+
+	ping := pcap.PacketRecord{Opcode: opcodeSysPing, Payload: []byte{0x00, 0x17, 0x00, 0x10}}
+	if !hasAckHandle(uint16(network.MSG_MHF_LOADDATA)) || hasAckHandle(uint16(network.MSG_SYS_TIME)) {
+		t.Error("hasAckHandle misclassified opcodes")
+	}
+	if out := p.patchRequest(ping); !bytes.Equal(out, ping.Payload) {
+		t.Errorf("packet without ack handle changed: % X", out)
+	}
+}
+
+func TestRunReplayRequiresSignIn(t *testing.T) {
+	path := createTestCapture(t, []pcap.PacketRecord{
+		{TimestampNs: 1000000100, Direction: pcap.DirClientToServer, Opcode: 0x0013, Payload: []byte{0x00, 0x13}},
+	})
+	if err := runReplay(path, replayOptions{target: "127.0.0.1:1"}); err == nil || !strings.Contains(err.Error(), "--sign-addr") {
+		t.Errorf("runReplay without credentials = %v, want sign-in error", err)
+	}
+}
+
+func TestPCAPNGConversionRoundTrip(t *testing.T) {
+	records := []pcap.PacketRecord{
+		{TimestampNs: 1000000100, Direction: pcap.DirClientToServer, Opcode: 0x0014, Payload: []byte{0x00, 0x14, 0x01}},
+		{TimestampNs: 1000000200, Direction: pcap.DirServerToClient, Opcode: 0x0012, Payload: []byte{0x00, 0x12, 0xFF}},
+	}
+	path := createTestCapture(t, records)
+	dir := t.TempDir()
+	if err := runExportPCAPNG(path, dir+"/out.pcapng"); err != nil {
+		t.Fatalf("runExportPCAPNG: %v", err)
+	}
+	if err := runImportPCAPNG(dir+"/out.pcapng", dir+"/back.mhfr", pcap.ImportOptions{}); err != nil {
+		t.Fatalf("runImportPCAPNG: %v", err)
+	}
+
+	r, f, err := openCapture(dir + "/back.mhfr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	got, err := readAllPackets(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Header.ServerType != pcap.ServerTypeChannel || r.Meta.Port != 54001 {
+		t.Errorf("header = %+v, meta = %+v", r.Header, r.Meta)
+	}
+	if len(got) != len(records) {
+		t.Fatalf("got %d packets, want %d", len(got), len(records))
+	}
+	for i := range records {
+		if got[i].Opcode != records[i].Opcode || !bytes.Equal(got[i].Payload, records[i].Payload) {
+			t.Errorf("packet %d = %+v, want %+v", i, got[i], records[i])
+		}
+	}
+}
+
+func TestParseServerType(t *testing.T) {
+	if st, err := parseServerType("entrance"); err != nil || st != pcap.ServerTypeEntrance {
+		t.Errorf("parseServerType(entrance) = %v, %v", st, err)
+	}
+	if st, err := parseServerType(""); err != nil || st != 0 {
+		t.Errorf("parseServerType(\"\") = %v, %v", st, err)
+	}
+	if _, err := parseServerType("lobby"); err == nil {
+		t.Error("expected error for unknown server type")
+	}
+}
+
+func TestDecodeRecord(t *testing.T) {
+	ctx := clientContextFor(pcap.FileHeader{ClientMode: 40})
+
+	// MSG_SYS_PING (AckHandle) followed by the MSG_SYS_END terminator.
+	group := []byte{0x00, 0x17, 0x00, 0x00, 0x00, 0x2A, 0x00, 0x10}
+	decoded := decodeRecord(group, ctx)
+	if len(decoded) != 2 {
+		t.Fatalf("decoded %d packets, want 2: %+v", len(decoded), decoded)
+	}
+	if decoded[0].Opcode != "MSG_SYS_PING" || decoded[0].Error != "" || decoded[1].Offset != 6 {
+		t.Errorf("decoded = %+v", decoded)
+	}
+	if s := decoded[0].String(); !strings.Contains(s, "AckHandle:42") {
+		t.Errorf("String() = %q, want parsed fields", s)
+	}
+
+	// Truncated MSG_SYS_PING: the ack handle read overflows.
+	decoded = decodeRecord([]byte{0x00, 0x17, 0x00, 0x00}, ctx)
+	if len(decoded) != 1 || decoded[0].Error == "" || decoded[0].StoppedAt != 2 {
+		t.Errorf("truncated packet decoded = %+v, want error at offset 2", decoded)
+	}
+
+	decoded = decodeRecord([]byte{0xFF, 0xFF, 0x01}, ctx)
+	if len(decoded) != 1 || decoded[0].Error != "no parser for opcode" {
+		t.Errorf("unknown opcode decoded = %+v", decoded)
+	}
+
+	decoded = decodeRecord([]byte{0x00, 0x10, 0x01}, ctx)
+	if len(decoded) != 1 || decoded[0].StoppedAt != 2 || !strings.Contains(decoded[0].Error, "trailing") {
+		t.Errorf("trailing byte decoded = %+v", decoded)
+	}
+}
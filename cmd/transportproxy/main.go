@@ -0,0 +1,169 @@
+// transportproxy lets an unmodified MHF client play on a server whose
+// listeners use the secure transport (see package network/transport).
+//
+// It runs on the player's machine, listens on loopback for every server port
+// and forwards each connection to the same port on the server over TLS or
+// a PSK-secured stream:
+//
+//	transportproxy --server mhf.example.com --mode psk --psk "shared secret key"
+//	transportproxy --server mhf.example.com --mode tls --ca server.pem --ports 53312,53310,54001-54010
+//
+// Point the client's sign host at 127.0.0.1. The server recognises tunneled
+// connections and advertises loopback addresses for the entrance and channel
+// servers, so those connections pass through the proxy as well.
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"erupe-ce/network/transport"
+)
+
+func main() {
+	server := flag.String("server", "", "Server host to forward to")
+	ports := flag.String("ports", "53312,53310,54001-54010", "Ports to forward: sign, entrance and channels (comma-separated, ranges allowed)")
+	listen := flag.String("listen", "127.0.0.1", "Local address to listen on")
+	mode := flag.String("mode", transport.ModePSK, "Transport mode: tls or psk")
+	psk := flag.String("psk", "", "Pre-shared key (used with --mode psk)")
+	caFile := flag.String("ca", "", "PEM certificate(s) to trust for the server (used with --mode tls; system roots if empty)")
+	serverName := flag.String("server-name", "", "TLS server name to verify (defaults to --server)")
+	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification (testing only)")
+	flag.Parse()
+
+	if *server == "" {
+		log.Fatal("--server is required")
+	}
+	portList, err := parsePorts(*ports)
+	if err != nil {
+		log.Fatalf("--ports: %v", err)
+	}
+	cc := transport.ClientConfig{Mode: *mode, PSK: *psk}
+	if *mode == transport.ModeTLS {
+		cc.TLS, err = tlsConfig(*server, *serverName, *caFile, *insecure)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var listeners []net.Listener
+	for _, port := range portList {
+		l, err := net.Listen("tcp", net.JoinHostPort(*listen, strconv.Itoa(port)))
+		if err != nil {
+			log.Fatalf("listen on %d: %v", port, err)
+		}
+		listeners = append(listeners, l)
+		go serve(l, net.JoinHostPort(*server, strconv.Itoa(port)), cc)
+	}
+	log.Printf("forwarding %d port(s) to %s over %s", len(portList), *server, *mode)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+	for _, l := range listeners {
+		_ = l.Close()
+	}
+}
+
+// tlsConfig builds the client TLS config, trusting caFile when given.
+func tlsConfig(server, serverName, caFile string, insecure bool) (*tls.Config, error) {
+	if serverName == "" {
+		serverName = server
+	}
+	c := &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12, InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", caFile)
+		}
+	}
+	return c, nil
+}
+
+// serve forwards every connection accepted on l to addr.
+func serve(l net.Listener, addr string, cc transport.ClientConfig) {
+	for {
+		client, err := l.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("accept: %v", err)
+			}
+			return
+		}
+		go forward(client, addr, cc)
+	}
+}
+
+// forward copies between client and a transport connection to addr until
+// either side closes.
+func forward(client net.Conn, addr string, cc transport.ClientConfig) {
+	defer func() { _ = client.Close() }()
+	upstream, err := transport.Dial(addr, cc)
+	if err != nil {
+		log.Printf("dial %s: %v", addr, err)
+		return
+	}
+	defer func() { _ = upstream.Close() }()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(upstream, client)
+		_ = upstream.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(client, upstream)
+		_ = client.Close()
+	}()
+	wg.Wait()
+}
+
+// parsePorts parses a list such as "53312,53310,54001-54010".
+func parsePorts(s string) ([]int, error) {
+	var ports []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("bad port %q", part)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil {
+				return nil, fmt.Errorf("bad port %q", part)
+			}
+		}
+		if first < 1 || last > 65535 || first > last {
+			return nil, fmt.Errorf("bad port %q", part)
+		}
+		for p := first; p <= last; p++ {
+			ports = append(ports, p)
+		}
+	}
+	if len(ports) == 0 {
+		return nil, errors.New("no ports")
+	}
+	return ports, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePorts(t *testing.T) {
+	got, err := parsePorts("53312, 53310,54001-54003")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{53312, 53310, 54001, 54002, 54003}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePorts = %v, want %v", got, want)
+	}
+}
+
+func TestParsePortsErrors(t *testing.T) {
+	for _, s := range []string{"", "abc", "54003-54001", "0", "70000", "1-x"} {
+		if _, err := parsePorts(s); err == nil {
+			t.Errorf("parsePorts(%q) succeeded, want error", s)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package announcement
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"erupe-ce/common/cron"
+)
+
+// Announcement kinds.
+const (
+	Once      = "once"
+	Recurring = "recurring"
+	Countdown = "countdown"
+)
+
+// Validate checks that an announcement's fields are consistent with its kind.
+// sendAt is nil when no send time is set, and countdown holds the minutes
+// before sendAt at which a countdown announcement is sent.
+func Validate(kind, message string, sendAt *time.Time, schedule string, countdown []int64) error {
+	if strings.TrimSpace(message) == "" {
+		return errors.New("message is empty")
+	}
+	switch kind {
+	case Once:
+		if sendAt == nil {
+			return errors.New("once announcements need a send time")
+		}
+	case Recurring:
+		if _, err := cron.Parse(schedule); err != nil {
+			return err
+		}
+	case Countdown:
+		if sendAt == nil {
+			return errors.New("countdown announcements need an event time")
+		}
+		if len(countdown) == 0 {
+			return errors.New("countdown announcements need at least one offset")
+		}
+		for _, m := range countdown {
+			if m < 0 {
+				return fmt.Errorf("negative countdown offset %d", m)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown announcement kind %q", kind)
+	}
+	return nil
+}
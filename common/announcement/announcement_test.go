@@ -0,0 +1,38 @@
+package announcement
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		kind      string
+		message   string
+		sendAt    *time.Time
+		schedule  string
+		countdown []int64
+		wantErr   bool
+	}{
+		{"once", Once, "hi", &now, "", nil, false},
+		{"once without time", Once, "hi", nil, "", nil, true},
+		{"empty message", Once, " ", &now, "", nil, true},
+		{"recurring", Recurring, "hi", nil, "0 * * * *", nil, false},
+		{"recurring bad cron", Recurring, "hi", nil, "hourly", nil, true},
+		{"countdown", Countdown, "hi", &now, "", []int64{10, 5}, false},
+		{"countdown without time", Countdown, "hi", nil, "", []int64{10}, true},
+		{"countdown without offsets", Countdown, "hi", &now, "", nil, true},
+		{"countdown negative offset", Countdown, "hi", &now, "", []int64{-1}, true},
+		{"unknown kind", "weekly", "hi", &now, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.kind, tt.message, tt.sendAt, tt.schedule, tt.countdown)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
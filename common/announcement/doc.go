@@ -0,0 +1,4 @@
+// Package announcement holds the rules for scheduled server-wide
+// announcements shared by the channel and API servers: the announcement
+// kinds and which fields each kind needs.
+package announcement
@@ -0,0 +1,84 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-b * * * *",
+	}
+	for _, expr := range tests {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) should fail", expr)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	// 2026-10-18 is a Sunday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 30, 0, time.UTC)
+	}
+	tests := []struct {
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"* * * * *", at(18, 12, 34), true},
+		{"0 * * * *", at(18, 12, 0), true},
+		{"0 * * * *", at(18, 12, 1), false},
+		{"*/15 * * * *", at(18, 12, 45), true},
+		{"*/15 * * * *", at(18, 12, 50), false},
+		{"5/20 * * * *", at(18, 12, 25), true},
+		{"0 9-17/4 * * *", at(18, 13, 0), true},
+		{"0 9-17/4 * * *", at(18, 15, 0), false},
+		{"0 20 * * 0", at(18, 20, 0), true},
+		{"0 20 * * 7", at(18, 20, 0), true},
+		{"0 20 * * 1-5", at(18, 20, 0), false},
+		{"0 20 1,18 * *", at(18, 20, 0), true},
+		{"0 20 * 11 *", at(18, 20, 0), false},
+		// Day of month OR day of week when both are restricted.
+		{"0 20 1 * 0", at(18, 20, 0), true},
+		{"0 20 1 * 1", at(18, 20, 0), false},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+		}
+		if got := s.Matches(tt.t); got != tt.want {
+			t.Errorf("%q.Matches(%v) = %v, want %v", tt.expr, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestPrev(t *testing.T) {
+	s, err := Parse("30 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, time.October, 18, 12, 45, 10, 0, time.UTC)
+
+	got, ok := s.Prev(now, now.Add(-time.Hour))
+	want := time.Date(2026, time.October, 18, 12, 30, 0, 0, time.UTC)
+	if !ok || !got.Equal(want) {
+		t.Errorf("Prev() = %v, %v, want %v", got, ok, want)
+	}
+
+	if _, ok := s.Prev(now, now.Add(-10*time.Minute)); ok {
+		t.Error("Prev() should not look earlier than notBefore")
+	}
+}
@@ -0,0 +1,4 @@
+// Package cron parses standard five-field cron expressions (minute, hour,
+// day of month, month, day of week) and matches them against times at
+// minute resolution.
+package cron
@@ -0,0 +1,6 @@
+// Package guildicon rasterizes guild icons to PNG. A guild icon is a stack of
+// parts, each a sprite from the client's emblem sheets that is tinted,
+// scaled, rotated and positioned on a square canvas. Sprites are read from a
+// directory of PNG files, one per part, so servers can show emblems outside
+// the client.
+package guildicon
@@ -0,0 +1,252 @@
+package guildicon
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Layout of the stored part fields. These are inferred from the client's
+// icon editor and may need adjusting once confirmed against captures.
+const (
+	CanvasSize    = 256 // PosX and PosY address a square canvas of this size
+	sizeUnit      = 100 // Size is a percentage of the sprite's native size
+	rotationSteps = 256 // Rotation steps per full clockwise turn
+)
+
+// Part is one layer of a guild icon.
+type Part struct {
+	Index    uint16 `json:"index"` // Drawing order, lowest first
+	ID       uint16 `json:"id"`    // Sprite within Page
+	Page     uint8  `json:"page"`  // Sprite sheet
+	Size     uint8  `json:"size"`
+	Rotation uint8  `json:"rotation"`
+	Red      uint8  `json:"red"`
+	Green    uint8  `json:"green"`
+	Blue     uint8  `json:"blue"`
+	PosX     uint16 `json:"posX"` // Centre of the sprite on the canvas
+	PosY     uint16 `json:"posY"`
+}
+
+// Icon is a guild icon as stored in guilds.icon.
+type Icon struct {
+	Parts []Part
+}
+
+// Decode parses a stored guild icon. Empty data is an icon with no parts.
+func Decode(data []byte) (*Icon, error) {
+	icon := &Icon{}
+	if len(data) == 0 {
+		return icon, nil
+	}
+	if err := json.Unmarshal(data, icon); err != nil {
+		return nil, err
+	}
+	return icon, nil
+}
+
+// SpritePath returns where a part's sprite is read from within dir.
+func SpritePath(dir string, page uint8, id uint16) string {
+	return filepath.Join(dir, fmt.Sprintf("%d_%d.png", page, id))
+}
+
+type spriteKey struct {
+	page uint8
+	id   uint16
+}
+
+// Renderer draws guild icons from a sprite directory. Sprites are loaded once
+// and encoded PNGs are cached, so changes to the directory need a restart. It
+// is safe for concurrent use.
+type Renderer struct {
+	dir       string
+	size      int
+	cacheSize int
+
+	mu      sync.Mutex
+	sprites map[spriteKey]image.Image // nil for sprites missing from dir
+	pngs    map[string]*list.Element
+	lru     *list.List // of *cachedPNG, most recently used first
+}
+
+type cachedPNG struct {
+	key  string
+	data []byte
+}
+
+// NewRenderer returns a renderer drawing size×size icons from the sprites in
+// dir, caching up to cacheSize encoded icons.
+func NewRenderer(dir string, size, cacheSize int) *Renderer {
+	if size <= 0 {
+		size = CanvasSize
+	}
+	return &Renderer{
+		dir:       dir,
+		size:      size,
+		cacheSize: cacheSize,
+		sprites:   make(map[spriteKey]image.Image),
+		pngs:      make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// Key identifies an icon's rendering, for caching and HTTP ETags.
+func Key(icon *Icon) string {
+	data, _ := json.Marshal(icon.Parts)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// PNG returns the icon encoded as PNG, rendering it only if it is not
+// already cached.
+func (r *Renderer) PNG(icon *Icon) ([]byte, error) {
+	key := Key(icon)
+	r.mu.Lock()
+	if e, ok := r.pngs[key]; ok {
+		r.lru.MoveToFront(e)
+		data := e.Value.(*cachedPNG).data
+		r.mu.Unlock()
+		return data, nil
+	}
+	r.mu.Unlock()
+
+	img, err := r.Render(icon)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cacheSize > 0 {
+		if _, ok := r.pngs[key]; !ok {
+			r.pngs[key] = r.lru.PushFront(&cachedPNG{key: key, data: data})
+			for r.lru.Len() > r.cacheSize {
+				oldest := r.lru.Back()
+				r.lru.Remove(oldest)
+				delete(r.pngs, oldest.Value.(*cachedPNG).key)
+			}
+		}
+	}
+	return data, nil
+}
+
+// Render draws the icon. Parts whose sprite is missing from the sprite
+// directory are skipped.
+func (r *Renderer) Render(icon *Icon) (*image.RGBA, error) {
+	dst := image.NewRGBA(image.Rect(0, 0, r.size, r.size))
+	parts := make([]Part, len(icon.Parts))
+	copy(parts, icon.Parts)
+	sort.SliceStable(parts, func(i, j int) bool { return parts[i].Index < parts[j].Index })
+	for _, part := range parts {
+		sprite, err := r.sprite(part.Page, part.ID)
+		if err != nil {
+			return nil, err
+		}
+		if sprite != nil {
+			drawPart(dst, sprite, part, float64(r.size)/CanvasSize)
+		}
+	}
+	return dst, nil
+}
+
+// sprite returns a part's sprite, loading it on first use. It returns nil
+// without an error if the sprite file does not exist.
+func (r *Renderer) sprite(page uint8, id uint16) (image.Image, error) {
+	key := spriteKey{page, id}
+	r.mu.Lock()
+	img, ok := r.sprites[key]
+	r.mu.Unlock()
+	if ok {
+		return img, nil
+	}
+
+	f, err := os.Open(SpritePath(r.dir, page, id))
+	if errors.Is(err, fs.ErrNotExist) {
+		img = nil
+	} else if err != nil {
+		return nil, err
+	} else {
+		img, err = png.Decode(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("guild icon sprite %d_%d: %w", page, id, err)
+		}
+	}
+	r.mu.Lock()
+	r.sprites[key] = img
+	r.mu.Unlock()
+	return img, nil
+}
+
+// drawPart composites a tinted, scaled and rotated sprite over dst. k maps
+// canvas units to output pixels. Sampling is nearest-neighbour.
+func drawPart(dst *image.RGBA, sprite image.Image, part Part, k float64) {
+	scale := float64(part.Size) / sizeUnit * k
+	if scale <= 0 {
+		return
+	}
+	sb := sprite.Bounds()
+	halfW, halfH := float64(sb.Dx())/2, float64(sb.Dy())/2
+	cx, cy := float64(part.PosX)*k, float64(part.PosY)*k
+	theta := 2 * math.Pi * float64(part.Rotation) / rotationSteps
+	sin, cos := math.Sin(theta), math.Cos(theta)
+
+	// Bounding box of the transformed sprite, clipped to dst.
+	extX := (math.Abs(halfW*cos) + math.Abs(halfH*sin)) * scale
+	extY := (math.Abs(halfW*sin) + math.Abs(halfH*cos)) * scale
+	box := image.Rect(
+		int(math.Floor(cx-extX)), int(math.Floor(cy-extY)),
+		int(math.Ceil(cx+extX)), int(math.Ceil(cy+extY)),
+	).Intersect(dst.Bounds())
+
+	tint := [3]uint32{uint32(part.Red), uint32(part.Green), uint32(part.Blue)}
+	for y := box.Min.Y; y < box.Max.Y; y++ {
+		for x := box.Min.X; x < box.Max.X; x++ {
+			// Map the output pixel centre back into sprite space.
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			u := (dx*cos+dy*sin)/scale + halfW
+			v := (-dx*sin+dy*cos)/scale + halfH
+			if u < 0 || v < 0 || u >= 2*halfW || v >= 2*halfH {
+				continue
+			}
+			src := color.NRGBAModel.Convert(sprite.At(sb.Min.X+int(u), sb.Min.Y+int(v))).(color.NRGBA)
+			if src.A == 0 {
+				continue
+			}
+			blend(dst, x, y, [3]uint32{
+				uint32(src.R) * tint[0] / 255,
+				uint32(src.G) * tint[1] / 255,
+				uint32(src.B) * tint[2] / 255,
+			}, uint32(src.A))
+		}
+	}
+}
+
+// blend composites a non-premultiplied colour with alpha a over dst at x, y.
+func blend(dst *image.RGBA, x, y int, c [3]uint32, a uint32) {
+	i := dst.PixOffset(x, y)
+	pix := dst.Pix[i : i+4 : i+4]
+	inv := 255 - a
+	for ch := 0; ch < 3; ch++ {
+		pix[ch] = uint8((c[ch]*a + uint32(pix[ch])*inv) / 255)
+	}
+	pix[3] = uint8(a + uint32(pix[3])*inv/255)
+}
@@ -0,0 +1,140 @@
+package guildicon
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"testing"
+)
+
+// writeSprite writes a w×h white sprite to dir, with its left half opaque and
+// its right half transparent.
+func writeSprite(t *testing.T, dir string, page uint8, id uint16, w, h int) {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w/2; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{255, 255, 255, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(SpritePath(dir, page, id), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	icon, err := Decode([]byte(`{"Parts":[{"Index":1,"ID":100,"Page":2,"Size":3,"Rotation":4,"Red":255,"Green":128,"Blue":0,"PosX":50,"PosY":60}]}`))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	want := Part{Index: 1, ID: 100, Page: 2, Size: 3, Rotation: 4, Red: 255, Green: 128, PosX: 50, PosY: 60}
+	if len(icon.Parts) != 1 || icon.Parts[0] != want {
+		t.Errorf("parts = %+v, want [%+v]", icon.Parts, want)
+	}
+	if icon, err := Decode(nil); err != nil || len(icon.Parts) != 0 {
+		t.Errorf("Decode(nil) = %+v, %v", icon, err)
+	}
+	if _, err := Decode([]byte("{")); err == nil {
+		t.Error("Decode accepted invalid JSON")
+	}
+}
+
+func TestRenderTintsAndPlacesParts(t *testing.T) {
+	dir := t.TempDir()
+	writeSprite(t, dir, 0, 1, 64, 64)
+	r := NewRenderer(dir, CanvasSize, 4)
+
+	img, err := r.Render(&Icon{Parts: []Part{{ID: 1, Size: 100, Red: 255, Blue: 128, PosX: 128, PosY: 128}}})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got := img.RGBAAt(110, 128); got != (color.RGBA{255, 0, 128, 255}) {
+		t.Errorf("left of centre = %v, want the tinted sprite", got)
+	}
+	if got := img.RGBAAt(140, 128); got.A != 0 {
+		t.Errorf("right of centre = %v, want the sprite's transparent half", got)
+	}
+	if got := img.RGBAAt(10, 10); got.A != 0 {
+		t.Errorf("corner = %v, want transparent", got)
+	}
+
+	// Half a turn puts the opaque half on the right.
+	img, _ = r.Render(&Icon{Parts: []Part{{ID: 1, Size: 100, Rotation: rotationSteps / 2, Red: 255, PosX: 128, PosY: 128}}})
+	if img.RGBAAt(110, 128).A != 0 || img.RGBAAt(140, 128).A != 255 {
+		t.Error("rotated sprite not mirrored about its centre")
+	}
+
+	// At half size the sprite spans 32 canvas units.
+	img, _ = r.Render(&Icon{Parts: []Part{{ID: 1, Size: 50, Red: 255, PosX: 128, PosY: 128}}})
+	if img.RGBAAt(100, 128).A != 0 || img.RGBAAt(120, 128).A != 255 {
+		t.Error("half size sprite drawn at the wrong extent")
+	}
+}
+
+func TestRenderOrderAndMissingSprites(t *testing.T) {
+	dir := t.TempDir()
+	writeSprite(t, dir, 0, 1, 64, 64)
+	writeSprite(t, dir, 1, 2, 64, 64)
+	r := NewRenderer(dir, 64, 4)
+
+	img, err := r.Render(&Icon{Parts: []Part{
+		{Index: 2, Page: 1, ID: 2, Size: 100, Green: 255, PosX: 128, PosY: 128},
+		{Index: 1, ID: 1, Size: 100, Red: 255, PosX: 128, PosY: 128},
+		{Index: 3, ID: 99, Size: 100, Blue: 255, PosX: 128, PosY: 128},
+	}})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if img.Bounds().Dx() != 64 {
+		t.Errorf("width = %d, want 64", img.Bounds().Dx())
+	}
+	if got := img.RGBAAt(28, 32); got != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("pixel = %v, want the higher index part on top", got)
+	}
+}
+
+func TestPNGCaches(t *testing.T) {
+	dir := t.TempDir()
+	writeSprite(t, dir, 0, 1, 8, 8)
+	r := NewRenderer(dir, 32, 1)
+	icon := &Icon{Parts: []Part{{ID: 1, Size: 100, Red: 255, PosX: 128, PosY: 128}}}
+
+	first, err := r.PNG(icon)
+	if err != nil {
+		t.Fatalf("PNG: %v", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(first)); err != nil {
+		t.Fatalf("output is not a PNG: %v", err)
+	}
+	if err := os.WriteFile(SpritePath(dir, 0, 1), []byte("corrupt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	again, err := r.PNG(icon)
+	if err != nil || !bytes.Equal(first, again) {
+		t.Errorf("cached PNG = %d bytes, %v; want the first rendering", len(again), err)
+	}
+
+	// A second icon evicts the first from a one-entry cache.
+	if _, err := r.PNG(&Icon{}); err != nil {
+		t.Fatalf("PNG: %v", err)
+	}
+	if _, ok := r.pngs[Key(icon)]; ok {
+		t.Error("oldest icon not evicted")
+	}
+}
+
+func TestRenderCorruptSprite(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(SpritePath(dir, 0, 1), []byte("corrupt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRenderer(dir, 32, 0).Render(&Icon{Parts: []Part{{ID: 1, Size: 100}}}); err == nil {
+		t.Error("expected an error for a corrupt sprite")
+	}
+}
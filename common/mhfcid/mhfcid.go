@@ -52,3 +52,15 @@ func ConvertCID(ID string) (r uint32) {
 	}
 	return
 }
+
+// ConvertToCID converts an integer character ID to its MHF Character ID String.
+// It is the inverse of ConvertCID for IDs below 32^6.
+func ConvertToCID(id uint32) string {
+	const alphabet = "123456789ABCDEFGHJKLMNPQRTUVWXYZ"
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = alphabet[id%32]
+		id /= 32
+	}
+	return string(b)
+}
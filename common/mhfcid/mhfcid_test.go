@@ -225,6 +225,30 @@ func TestConvertCID_SkippedCharacters(t *testing.T) {
 	}
 }
 
+func TestConvertToCID(t *testing.T) {
+	tests := []struct {
+		id   uint32
+		want string
+	}{
+		{0, "111111"},
+		{1, "211111"},
+		{1 + 32 + 1024 + 32768 + 1048576 + 33554432, "222222"},
+	}
+	for _, tt := range tests {
+		if got := ConvertToCID(tt.id); got != tt.want {
+			t.Errorf("ConvertToCID(%d) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestConvertToCID_RoundTrip(t *testing.T) {
+	for _, cid := range []string{"123456", "ABCDEF", "1A2B3C", "ZZZZZZ", "N1P1Q1"} {
+		if got := ConvertToCID(ConvertCID(cid)); got != cid {
+			t.Errorf("ConvertToCID(ConvertCID(%q)) = %q", cid, got)
+		}
+	}
+}
+
 func BenchmarkConvertCID(b *testing.B) {
 	testCID := "A1B2C3"
 	b.ResetTimer()
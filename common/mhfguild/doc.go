@@ -0,0 +1,4 @@
+// Package mhfguild holds guild rules shared by the channel and API servers,
+// such as how a guild's rank follows from its rank RP and how many members
+// that rank allows, and the kinds of error guild workflows report to the API.
+package mhfguild
@@ -0,0 +1,16 @@
+package mhfguild
+
+import "errors"
+
+// Kinds of failure a guild workflow reports to callers outside the channel
+// server, such as the API, which cannot match the channel server's own
+// errors. The returned error wraps both the kind and the underlying error.
+var (
+	// ErrForbidden means the acting character may not perform the action.
+	ErrForbidden = errors.New("guild action forbidden")
+	// ErrNotFound means a guild, alliance or application does not exist.
+	ErrNotFound = errors.New("guild action target not found")
+	// ErrConflict means the action does not fit the current state, such as
+	// applying to a full or closed alliance.
+	ErrConflict = errors.New("guild action conflicts with current state")
+)
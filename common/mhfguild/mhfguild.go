@@ -0,0 +1,114 @@
+package mhfguild
+
+import cfg "erupe-ce/config"
+
+// MaxMembers is the most members any guild can hold.
+const MaxMembers = 100
+
+// Tier is one row of a rank table: the rank RP needed to reach the rank and
+// how many members a guild of that rank may hold.
+type Tier struct {
+	RP      uint32
+	Members uint8
+}
+
+// Table lists the ranks in ascending order, starting at rank 0.
+type Table []Tier
+
+// defaultRankRP holds the rank RP needed to reach ranks 1 to 17.
+var defaultRankRP = []uint32{
+	24, 48, 96, 144, 192, 240, 288, 360, 432,
+	504, 600, 696, 792, 888, 984, 1080, 1200,
+}
+
+// defaultRankRPZ2 replaces defaultRankRP for Z2 and older clients.
+var defaultRankRPZ2 = []uint32{
+	3500, 6000, 8500, 11000, 13500, 16000, 20000, 24000, 28000,
+	33000, 38000, 43000, 48000, 55000, 70000, 90000, 120000,
+}
+
+// DefaultTable returns the built-in rank table for the client mode, with
+// member caps taken from memberLimits as in GameplayOptions.ClanMemberLimits.
+func DefaultTable(mode cfg.Mode, memberLimits [][]uint8) Table {
+	rp := defaultRankRP
+	if mode <= cfg.Z2 {
+		rp = defaultRankRPZ2
+	}
+	table := Table{{RP: 0, Members: memberLimit(memberLimits, 0)}}
+	for i, u := range rp {
+		table = append(table, Tier{RP: u, Members: memberLimit(memberLimits, uint16(i+1))})
+	}
+	return table
+}
+
+// ConfiguredTable returns the rank table in Guild.Ranks, or the built-in one
+// if none is configured.
+func ConfiguredTable(c *cfg.Config) Table {
+	if len(c.Guild.Ranks) == 0 {
+		return DefaultTable(c.RealClientMode, c.GameplayOptions.ClanMemberLimits)
+	}
+	table := make(Table, len(c.Guild.Ranks))
+	for i, r := range c.Guild.Ranks {
+		table[i] = Tier{RP: r.RP, Members: min(r.Members, MaxMembers)}
+	}
+	return table
+}
+
+// memberLimit reads the member cap for a rank from [rank, members] rows in
+// ascending rank order; the first row applies below every rank.
+func memberLimit(limits [][]uint8, rank uint16) uint8 {
+	if len(limits) == 0 || len(limits[0]) < 2 {
+		return MaxMembers
+	}
+	limit := limits[0][1]
+	for _, row := range limits {
+		if len(row) >= 2 && rank >= uint16(row[0]) {
+			limit = row[1]
+		}
+	}
+	return min(limit, MaxMembers)
+}
+
+// MaxRank returns the highest rank the client mode can show.
+func MaxRank(mode cfg.Mode) uint16 {
+	switch {
+	case mode <= cfg.S6:
+		return 12
+	case mode <= cfg.F5:
+		return 13
+	case mode <= cfg.G32:
+		return 14
+	}
+	return 17
+}
+
+// Rank returns the rank reached with rankRP.
+func (t Table) Rank(rankRP uint32, mode cfg.Mode) uint16 {
+	var rank uint16
+	for i := 1; i < len(t); i++ {
+		if rankRP < t[i].RP {
+			break
+		}
+		rank = uint16(i)
+	}
+	return t.Clamp(rank, mode)
+}
+
+// Clamp limits a rank to the table and to what the client mode can show.
+func (t Table) Clamp(rank uint16, mode cfg.Mode) uint16 {
+	if len(t) > 0 && int(rank) >= len(t) {
+		rank = uint16(len(t) - 1)
+	}
+	return min(rank, MaxRank(mode))
+}
+
+// MemberLimit returns how many members a guild of the given rank may hold.
+func (t Table) MemberLimit(rank uint16) uint8 {
+	if len(t) == 0 {
+		return MaxMembers
+	}
+	if int(rank) >= len(t) {
+		rank = uint16(len(t) - 1)
+	}
+	return t[rank].Members
+}
@@ -0,0 +1,79 @@
+package mhfguild
+
+import (
+	"testing"
+
+	cfg "erupe-ce/config"
+)
+
+func TestDefaultTableRank(t *testing.T) {
+	tests := []struct {
+		rp   uint32
+		mode cfg.Mode
+		want uint16
+	}{
+		{0, cfg.ZZ, 0},
+		{23, cfg.ZZ, 0},
+		{24, cfg.ZZ, 1},
+		{48, cfg.ZZ, 2},
+		{5000, cfg.ZZ, 17},
+		{5000, cfg.Z2, 1},
+		{200000, cfg.G32, 14},
+		{200000, cfg.F5, 13},
+		{200000, cfg.S6, 12},
+	}
+	for _, tt := range tests {
+		if got := DefaultTable(tt.mode, nil).Rank(tt.rp, tt.mode); got != tt.want {
+			t.Errorf("Rank(%d, %v) = %d, want %d", tt.rp, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultTableMemberLimit(t *testing.T) {
+	table := DefaultTable(cfg.ZZ, [][]uint8{{0, 30}, {3, 40}, {7, 50}, {10, 200}})
+	tests := []struct {
+		rank uint16
+		want uint8
+	}{
+		{0, 30},
+		{2, 30},
+		{3, 40},
+		{9, 50},
+		{17, MaxMembers},
+		{40, MaxMembers},
+	}
+	for _, tt := range tests {
+		if got := table.MemberLimit(tt.rank); got != tt.want {
+			t.Errorf("MemberLimit(rank %d) = %d, want %d", tt.rank, got, tt.want)
+		}
+	}
+	if got := DefaultTable(cfg.ZZ, nil).MemberLimit(5); got != MaxMembers {
+		t.Errorf("MemberLimit without limits = %d, want %d", got, MaxMembers)
+	}
+}
+
+func TestConfiguredTable(t *testing.T) {
+	c := &cfg.Config{RealClientMode: cfg.ZZ}
+	c.GameplayOptions.ClanMemberLimits = [][]uint8{{0, 30}}
+	if got := ConfiguredTable(c); len(got) != 18 || got[0].Members != 30 {
+		t.Errorf("empty config gave %d ranks, want the 18 built-in", len(got))
+	}
+
+	c.Guild.Ranks = []cfg.GuildRankTier{{RP: 0, Members: 10}, {RP: 100, Members: 20}, {RP: 500, Members: 250}}
+	table := ConfiguredTable(c)
+	if got := table.Rank(99, cfg.ZZ); got != 0 {
+		t.Errorf("Rank(99) = %d, want 0", got)
+	}
+	if got := table.Rank(100000, cfg.ZZ); got != 2 {
+		t.Errorf("Rank(100000) = %d, want the top configured rank 2", got)
+	}
+	if got := table.MemberLimit(1); got != 20 {
+		t.Errorf("MemberLimit(1) = %d, want 20", got)
+	}
+	if got := table.MemberLimit(2); got != MaxMembers {
+		t.Errorf("MemberLimit(2) = %d, want capped at %d", got, MaxMembers)
+	}
+	if got := table.Clamp(9, cfg.ZZ); got != 2 {
+		t.Errorf("Clamp(9) = %d, want 2", got)
+	}
+}
@@ -67,6 +67,7 @@ type Config struct {
 	Host                   string `mapstructure:"Host"`
 	BinPath                string `mapstructure:"BinPath"`
 	Language               string
+	LocalesPath            string   // Directory of JSON/TOML locale files overriding or adding to the built-in languages
 	DisableSoftCrash       bool     // Disables the 'Press Return to exit' dialog allowing scripts to reboot the server automatically
 	HideLoginNotice        bool     // Hide the Erupe notice on login
 	LoginNotices           []string // MHFML string of the login notices displayed
@@ -78,7 +79,8 @@ type Config struct {
 	QuestCacheExpiry       int    // Number of seconds to keep quest data cached
 	CommandPrefix          string // The prefix for commands
 	AutoCreateAccount      bool   // Automatically create accounts if they don't exist
-	LoopDelay              int    // Delay in milliseconds between each loop iteration
+	LoopDelay              int    // Deprecated: unused, session I/O no longer polls
+	CoalescePackets        bool   // Send packets queued together in a single encrypted write
 	DefaultCourses         []uint16
 	EarthStatus            int32
 	EarthID                int32
@@ -86,6 +88,9 @@ type Config struct {
 	SaveDumps              SaveDumpOptions
 	Screenshots            ScreenshotsOptions
 	Capture                CaptureOptions
+	Chat                   ChatOptions
+	RateLimit              RateLimitOptions
+	Guild                  GuildOptions
 
 	DebugOptions    DebugOptions
 	GameplayOptions GameplayOptions
@@ -123,22 +128,62 @@ type CaptureOptions struct {
 	CaptureChannel  bool     // Capture channel server sessions
 }
 
+// ChatOptions controls chat persistence and moderation.
+type ChatOptions struct {
+	Log    bool              // Persist chat messages of every broadcast type to the chat_logs table
+	Filter ChatFilterOptions // Word and pattern filter applied before chat is broadcast
+}
+
+// ChatFilterOptions configures the chat moderation filter.
+type ChatFilterOptions struct {
+	Enabled  bool
+	Action   string   // "mask" replaces matches with asterisks, "drop" discards the message
+	Words    []string // Case-insensitive words or phrases to match
+	Patterns []string // Regular expressions to match
+}
+
+// RateLimitOptions configures per-session, per-opcode packet rate limits on
+// the channel servers.
+type RateLimitOptions struct {
+	Enabled bool
+	Default RateLimitRule            // Applies to opcodes without a rule of their own; a zero Rate means unlimited
+	Opcodes map[string]RateLimitRule // Per-opcode rules keyed by name, e.g. "MSG_SYS_CAST_BINARY"
+	Penalty RateLimitPenalty
+}
+
+// RateLimitRule is a token bucket: Burst packets at once, refilled at Rate
+// packets per second.
+type RateLimitRule struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitPenalty decides what happens to packets over the limit.
+type RateLimitPenalty struct {
+	Action     string // "warn" handles them anyway, "drop" discards them, "disconnect" and "ban" also escalate
+	Threshold  int    // Violations within Window before a disconnect or ban
+	Window     int    // Seconds
+	BanMinutes int    // Length of the temporary ban
+}
+
 // DebugOptions holds various debug/temporary options for use while developing Erupe.
 type DebugOptions struct {
-	CleanDB             bool   // Automatically wipes the DB on server reset.
-	MaxLauncherHR       bool   // Sets the HR returned in the launcher to HR7 so that you can join non-beginner worlds.
-	LogInboundMessages  bool   // Log all messages sent to the server
-	LogOutboundMessages bool   // Log all messages sent to the clients
-	LogMessageData      bool   // Log all bytes transferred as a hexdump
-	MaxHexdumpLength    int    // Maximum number of bytes printed when logs are enabled
-	DivaOverride        int    // Diva Defense event status
-	FestaOverride       int    // Hunter's Festa event status
-	TournamentOverride  int    // VS Tournament event status
-	DisableTokenCheck   bool   // Disables checking login token exists in the DB (security risk!)
-	QuestTools          bool   // Enable various quest debug logs
-	AutoQuestBackport   bool   // Automatically backport quest files
-	ProxyPort           uint16 // Forces the game to connect to a channel server proxy
-	CapLink             CapLinkOptions
+	CleanDB              bool     // Automatically wipes the DB on server reset.
+	MaxLauncherHR        bool     // Sets the HR returned in the launcher to HR7 so that you can join non-beginner worlds.
+	LogInboundMessages   bool     // Log all messages sent to the server
+	LogOutboundMessages  bool     // Log all messages sent to the clients
+	LogMessageData       bool     // Log all bytes transferred as a hexdump
+	MaxHexdumpLength     int      // Maximum number of bytes printed when logs are enabled
+	DivaOverride         int      // Diva Defense event status
+	FestaOverride        int      // Hunter's Festa event status
+	TournamentOverride   int      // VS Tournament event status
+	DisableTokenCheck    bool     // Disables checking login token exists in the DB (security risk!)
+	QuestTools           bool     // Enable various quest debug logs
+	AutoQuestBackport    bool     // Automatically backport quest files
+	ProxyPort            uint16   // Forces the game to connect to a channel server proxy
+	SlowHandlerThreshold int      // Log packet handlers taking at least this many milliseconds, 0 disables
+	TraceOpcodes         []string // Log the parsed contents of these opcodes, e.g. "MSG_MHF_SAVEDATA"
+	CapLink              CapLinkOptions
 }
 
 type CapLinkOptions struct {
@@ -204,6 +249,83 @@ type GameplayOptions struct {
 	SeasonOverride                 bool    // Overrides the Quest Season with the current Mezeporta Season
 }
 
+// GuildOptions holds guild feature settings.
+type GuildOptions struct {
+	Missions             GuildMissions
+	AuditLogDays         int // Days to keep guild audit log entries; 0 keeps them forever
+	ItemWithdrawalLimits GuildWithdrawalLimits
+	WeeklyBonus          GuildWeeklyBonus
+	Icons                GuildIconOptions
+	Ranks                []GuildRankTier // Rank table from rank 0 up; empty uses the built-in RP thresholds and GameplayOptions.ClanMemberLimits
+	Scouts               GuildScoutOptions
+}
+
+// GuildScoutOptions limits guild scout invitations. 0 disables each limit.
+type GuildScoutOptions struct {
+	ExpiryHours  int // Hours before an unanswered invitation is withdrawn and its sender mailed
+	MaxPerGuild  int // Outstanding invitations a guild may have
+	MaxPerTarget int // Outstanding invitations a character may hold from all guilds
+}
+
+// GuildRankTier is one guild rank: the rank RP needed to reach it and the
+// members a guild of that rank may hold (at most 100).
+type GuildRankTier struct {
+	RP      uint32
+	Members uint8
+}
+
+// GuildIconOptions configures guild icon rendering for the API.
+type GuildIconOptions struct {
+	SpriteDir string // Directory of part sprites named <page>_<id>.png
+	Size      int    // Width and height of rendered icons in pixels
+	CacheSize int    // Rendered icons kept in memory
+}
+
+// GuildWeeklyBonus sets how the guild weekly bonus tier is earned. A member
+// counts as active once they play MinQuests quests or hunt MinKills large
+// monsters in the week. The client does not report quest results, so failed
+// quests count towards MinQuests too.
+type GuildWeeklyBonus struct {
+	MinQuests int // Quests a member must play in a week to count as active
+	MinKills  int // Large monsters a member must hunt in a week to count as active; 0 ignores kills
+}
+
+// GuildWithdrawalLimits caps how many items each guild role may take out of
+// the guild item box per day, counted across all items. 0 is unlimited.
+type GuildWithdrawalLimits struct {
+	Leader    int
+	SubLeader int
+	Recruiter int
+	Member    int
+}
+
+// GuildMissions holds the guild mission catalogue and its rotation.
+type GuildMissions struct {
+	RotationDays int                 // Days between changes of the offered missions
+	Catalogue    []GuildMissionEntry // Missions to offer; empty uses the built-in list, which pays no rewards
+}
+
+// GuildMissionEntry is one guild mission. Every field but Rewards is sent to
+// the client as is.
+type GuildMissionEntry struct {
+	ID          uint32
+	Unk         uint32
+	Type        uint16 // 0 hunts the monster in Goal, 1 gathers the item in Goal
+	Goal        uint16
+	Quantity    uint16 // Count the guild must reach together
+	SkipTickets uint16
+	GR          bool
+	RewardType  uint16
+	RewardLevel uint16
+	Rewards     []GuildMissionReward // Items paid into the guild item box on completion
+}
+
+// GuildMissionReward is an item stack paid out for a completed guild mission.
+type GuildMissionReward struct {
+	ItemID   uint16
+	Quantity uint16
+}
+
 // Discord holds the discord integration config.
 type Discord struct {
 	Enabled      bool
@@ -242,8 +364,20 @@ type Database struct {
 
 // Sign holds the sign server config.
 type Sign struct {
-	Enabled bool
-	Port    int
+	Enabled   bool
+	Port      int
+	Transport Transport
+}
+
+// Transport holds a listener's optional secure transport (see package
+// network/transport). Unmodified clients reach a secured listener through the
+// transportproxy companion.
+type Transport struct {
+	Mode     string // "" for plain only, "tls" or "psk"
+	Required bool   // Reject plain clients instead of serving both
+	CertFile string // PEM certificate for "tls"
+	KeyFile  string // PEM private key for "tls"
+	PSK      string // Pre-shared key for "psk", at least 16 characters
 }
 
 // API holds server config
@@ -255,6 +389,7 @@ type API struct {
 	Messages    []APISignMessage
 	Links       []APISignLink
 	LandingPage LandingPage
+	LiveCapture bool // Stream channel session packets to operators at /capture/live
 }
 
 // LandingPage holds config for the browser-facing landing page at /.
@@ -283,14 +418,16 @@ type APISignLink struct {
 }
 
 type Channel struct {
-	Enabled bool
+	Enabled   bool
+	Transport Transport // Applies to every channel listener
 }
 
 // Entrance holds the entrance server config.
 type Entrance struct {
-	Enabled bool
-	Port    uint16
-	Entries []EntranceServerInfo
+	Enabled   bool
+	Port      uint16
+	Transport Transport
+	Entries   []EntranceServerInfo
 }
 
 // EntranceServerInfo represents an entry in the serverlist.
@@ -343,6 +480,7 @@ func getOutboundIP4() (net.IP, error) {
 func registerDefaults() {
 	// Top-level settings
 	viper.SetDefault("Language", "jp")
+	viper.SetDefault("LocalesPath", "locales")
 	viper.SetDefault("BinPath", "bin")
 	viper.SetDefault("HideLoginNotice", true)
 	viper.SetDefault("LoginNotices", []string{
@@ -379,10 +517,24 @@ func registerDefaults() {
 		CaptureChannel:  true,
 	})
 
+	// Chat
+	viper.SetDefault("Chat", ChatOptions{
+		Filter: ChatFilterOptions{Action: "mask"},
+	})
+
+	// RateLimit (dot-notation for per-field merge)
+	viper.SetDefault("RateLimit.Enabled", true)
+	viper.SetDefault("RateLimit.Default", RateLimitRule{Rate: 50, Burst: 100})
+	viper.SetDefault("RateLimit.Penalty.Action", "warn")
+	viper.SetDefault("RateLimit.Penalty.Threshold", 200)
+	viper.SetDefault("RateLimit.Penalty.Window", 10)
+	viper.SetDefault("RateLimit.Penalty.BanMinutes", 60)
+
 	// DebugOptions (dot-notation for per-field merge)
 	viper.SetDefault("DebugOptions.MaxHexdumpLength", 256)
 	viper.SetDefault("DebugOptions.FestaOverride", -1)
 	viper.SetDefault("DebugOptions.AutoQuestBackport", true)
+	viper.SetDefault("DebugOptions.SlowHandlerThreshold", 1000)
 	viper.SetDefault("DebugOptions.CapLink", CapLinkOptions{
 		Values: []uint16{51728, 20000, 51729, 1, 20000},
 		Port:   80,
@@ -437,6 +589,8 @@ func registerDefaults() {
 		{Name: "Ban", Enabled: false, Description: "Ban/Temp Ban a user", Prefix: "ban"},
 		{Name: "Timer", Enabled: true, Description: "Toggle the Quest timer", Prefix: "timer"},
 		{Name: "Playtime", Enabled: true, Description: "Show your playtime", Prefix: "playtime"},
+		{Name: "Language", Enabled: true, Description: "Show or set your preferred language", Prefix: "lang"},
+		{Name: "Announce", Enabled: false, Description: "Manage scheduled announcements", Prefix: "announce"},
 	})
 
 	// Courses
@@ -454,6 +608,17 @@ func registerDefaults() {
 		{Name: "EXRenewing", Enabled: true},
 	})
 
+	// Guild
+	viper.SetDefault("Guild.Missions.RotationDays", 7)
+	viper.SetDefault("Guild.AuditLogDays", 90)
+	viper.SetDefault("Guild.WeeklyBonus.MinQuests", 1)
+	viper.SetDefault("Guild.Icons.SpriteDir", "guildicons")
+	viper.SetDefault("Guild.Icons.Size", 128)
+	viper.SetDefault("Guild.Icons.CacheSize", 256)
+	viper.SetDefault("Guild.Scouts.ExpiryHours", 72)
+	viper.SetDefault("Guild.Scouts.MaxPerGuild", 20)
+	viper.SetDefault("Guild.Scouts.MaxPerTarget", 5)
+
 	// Database (Password deliberately has no default)
 	viper.SetDefault("Database.Host", "localhost")
 	viper.SetDefault("Database.Port", 5432)
@@ -566,5 +731,21 @@ func LoadConfig() (*Config, error) {
 		c.GameplayOptions.MinFeatureWeapons = c.GameplayOptions.MaxFeatureWeapons
 	}
 
+	if err := validateGuildRanks(c.Guild.Ranks); err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
+
+// validateGuildRanks rejects a rank table whose RP thresholds are not
+// strictly ascending, since guild ranks are looked up by walking it in order.
+func validateGuildRanks(ranks []GuildRankTier) error {
+	for i := 1; i < len(ranks); i++ {
+		if ranks[i].RP <= ranks[i-1].RP {
+			return fmt.Errorf("Guild.Ranks: rank %d needs more RP than rank %d (%d <= %d)",
+				i, i-1, ranks[i].RP, ranks[i-1].RP)
+		}
+	}
+	return nil
+}
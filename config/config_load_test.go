@@ -551,8 +551,8 @@ func TestMinimalConfigDefaults(t *testing.T) {
 	}
 
 	// Commands should be present
-	if len(cfg.Commands) != 12 {
-		t.Errorf("Commands = %d, want 12", len(cfg.Commands))
+	if len(cfg.Commands) != 14 {
+		t.Errorf("Commands = %d, want 14", len(cfg.Commands))
 	}
 
 	// Courses should be present
@@ -643,8 +643,8 @@ func TestFullConfigBackwardCompat(t *testing.T) {
 	if len(cfg.Entrance.Entries) != 6 {
 		t.Errorf("Entrance.Entries = %d, want 6", len(cfg.Entrance.Entries))
 	}
-	if len(cfg.Commands) != 12 {
-		t.Errorf("Commands = %d, want 12", len(cfg.Commands))
+	if len(cfg.Commands) != 14 {
+		t.Errorf("Commands = %d, want 14", len(cfg.Commands))
 	}
 	if cfg.GameplayOptions.MaximumNP != 100000 {
 		t.Errorf("MaximumNP = %d, want 100000", cfg.GameplayOptions.MaximumNP)
@@ -688,3 +688,46 @@ func TestSingleFieldOverride(t *testing.T) {
 		t.Errorf("GCPMultiplier = %v, want 1.0 (should retain default)", cfg.GameplayOptions.GCPMultiplier)
 	}
 }
+
+func TestLoadConfigRejectsUnorderedGuildRanks(t *testing.T) {
+	viper.Reset()
+	dir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(origDir) }()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	writeMinimalConfig(t, dir, `{
+		"Database": { "Password": "test" },
+		"Guild": { "Ranks": [
+			{ "RP": 0, "Members": 30 },
+			{ "RP": 5000, "Members": 40 },
+			{ "RP": 2000, "Members": 50 }
+		] }
+	}`)
+
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "Guild.Ranks") {
+		t.Errorf("LoadConfig() error = %v, want a Guild.Ranks error", err)
+	}
+}
+
+func TestValidateGuildRanks(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranks   []GuildRankTier
+		wantErr bool
+	}{
+		{"empty", nil, false},
+		{"ascending", []GuildRankTier{{RP: 0}, {RP: 100}, {RP: 200}}, false},
+		{"descending", []GuildRankTier{{RP: 0}, {RP: 200}, {RP: 100}}, true},
+		{"duplicate", []GuildRankTier{{RP: 0}, {RP: 100}, {RP: 100}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateGuildRanks(tt.ranks); (err != nil) != tt.wantErr {
+				t.Errorf("validateGuildRanks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"erupe-ce/common/gametime"
+	"erupe-ce/network/pcap"
 	"erupe-ce/server/api"
 	"erupe-ce/server/channelserver"
 	"erupe-ce/server/discordbot"
@@ -213,7 +214,6 @@ func main() {
 		}
 		_ = db.MustExec("DELETE FROM servers WHERE server_id IN (" + idList + ")")
 	}
-	_ = db.MustExec(`UPDATE guild_characters SET treasure_hunt=NULL`)
 
 	// Clean the DB if the option is on.
 	if config.DebugOptions.CleanDB {
@@ -273,6 +273,12 @@ func main() {
 		logger.Info("Sign: Disabled")
 	}
 
+	// Live packet streaming from channel sessions to the API
+	var packetHub *pcap.Hub
+	if config.API.Enabled && config.API.LiveCapture {
+		packetHub = pcap.NewHub()
+	}
+
 	// New Sign server
 	var ApiServer *api.APIServer
 	if config.API.Enabled {
@@ -281,6 +287,12 @@ func main() {
 				Logger:      logger.Named("sign"),
 				ErupeConfig: config,
 				DB:          db,
+				PacketHub:   packetHub,
+				Guilds: channelserver.NewGuildOps(&channelserver.Config{
+					Logger:      logger.Named("guild"),
+					ErupeConfig: config,
+					DB:          db,
+				}),
 			})
 		err = ApiServer.Start()
 		if err != nil {
@@ -313,6 +325,7 @@ func main() {
 					ErupeConfig: config,
 					DB:          db,
 					DiscordBot:  discordBot,
+					PacketHub:   packetHub,
 				})
 				if ee.IP == "" {
 					c.IP = config.Host
@@ -1,5 +1,7 @@
 package network
 
+import "strings"
+
 //revive:disable
 
 // PacketID identifies an MHF network message type.
@@ -442,3 +444,14 @@ const (
 )
 
 //revive:enable
+
+// PacketIDFromString returns the packet ID with the given name, such as
+// "MSG_SYS_CAST_BINARY". Names are matched case-insensitively.
+func PacketIDFromString(name string) (PacketID, bool) {
+	for id := MSG_HEAD; id <= MSG_SYS_reserve1AF; id++ {
+		if strings.EqualFold(id.String(), name) {
+			return id, true
+		}
+	}
+	return 0, false
+}
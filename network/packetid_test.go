@@ -209,3 +209,24 @@ func TestGuildPacketIDs(t *testing.T) {
 		}
 	}
 }
+
+func TestPacketIDFromString(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   PacketID
+		wantOK bool
+	}{
+		{"MSG_SYS_CAST_BINARY", MSG_SYS_CAST_BINARY, true},
+		{"msg_sys_position_object", MSG_SYS_POSITION_OBJECT, true},
+		{"MSG_HEAD", MSG_HEAD, true},
+		{"MSG_SYS_reserve1AF", MSG_SYS_reserve1AF, true},
+		{"MSG_NOT_A_PACKET", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := PacketIDFromString(tt.name)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("PacketIDFromString(%q) = %v, %v; want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
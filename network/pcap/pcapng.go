@@ -0,0 +1,363 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"erupe-ce/network"
+)
+
+// LinkTypeMHF is the pcapng link type of exported captures. It is
+// LINKTYPE_USER0, reserved for private use; Wireshark can be told to decode
+// it with a custom dissector through its DLT_USER preferences.
+//
+// Each packet is [1B Direction][NB decrypted packet bytes].
+const LinkTypeMHF uint16 = 147
+
+// pcapng block types and options.
+const (
+	blockSectionHeader    uint32 = 0x0A0D0D0A
+	blockInterfaceDesc    uint32 = 0x00000001
+	blockSimplePacket     uint32 = 0x00000003
+	blockEnhancedPacket   uint32 = 0x00000006
+	byteOrderMagic        uint32 = 0x1A2B3C4D
+	optEndOfOpt           uint16 = 0
+	optComment            uint16 = 1
+	optSHBUserAppl        uint16 = 4
+	optIfName             uint16 = 2
+	optIfTsResol          uint16 = 9
+	maxPCAPNGBlockSize           = 16 << 20
+	defaultTsResolPerTick        = 1000 // Microseconds, in nanoseconds
+)
+
+// pcapngSession is the JSON comment on the section header of exported files,
+// carrying what the link type has no room for.
+type pcapngSession struct {
+	ServerType     ServerType      `json:"server_type"`
+	ClientMode     byte            `json:"client_mode"`
+	SessionStartNs int64           `json:"session_start_ns"`
+	Metadata       SessionMetadata `json:"metadata"`
+}
+
+// ExportPCAPNG writes a capture as a pcapng file with a single LinkTypeMHF
+// interface. Each packet carries a comment with its direction and opcode name.
+func ExportPCAPNG(w io.Writer, hdr FileHeader, meta SessionMetadata, records []PacketRecord) error {
+	session, err := json.Marshal(pcapngSession{
+		ServerType:     hdr.ServerType,
+		ClientMode:     hdr.ClientMode,
+		SessionStartNs: hdr.SessionStartNs,
+		Metadata:       meta,
+	})
+	if err != nil {
+		return fmt.Errorf("pcapng: marshal session: %w", err)
+	}
+
+	var shb bytes.Buffer
+	_ = binary.Write(&shb, binary.LittleEndian, byteOrderMagic)
+	_ = binary.Write(&shb, binary.LittleEndian, uint16(1)) // Major version
+	_ = binary.Write(&shb, binary.LittleEndian, uint16(0)) // Minor version
+	_ = binary.Write(&shb, binary.LittleEndian, int64(-1)) // Section length unknown
+	writeOption(&shb, optSHBUserAppl, []byte("erupe-ce replay"))
+	writeOption(&shb, optComment, session)
+	writeOption(&shb, optEndOfOpt, nil)
+	if err := writeBlock(w, blockSectionHeader, shb.Bytes()); err != nil {
+		return err
+	}
+
+	var idb bytes.Buffer
+	_ = binary.Write(&idb, binary.LittleEndian, LinkTypeMHF)
+	_ = binary.Write(&idb, binary.LittleEndian, uint16(0)) // Reserved
+	_ = binary.Write(&idb, binary.LittleEndian, uint32(0)) // No snap length
+	writeOption(&idb, optIfName, []byte("mhf-"+hdr.ServerType.String()))
+	writeOption(&idb, optIfTsResol, []byte{9}) // Nanoseconds
+	writeOption(&idb, optEndOfOpt, nil)
+	if err := writeBlock(w, blockInterfaceDesc, idb.Bytes()); err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		data := append([]byte{byte(rec.Direction)}, rec.Payload...)
+		var epb bytes.Buffer
+		_ = binary.Write(&epb, binary.LittleEndian, uint32(0)) // Interface ID
+		_ = binary.Write(&epb, binary.LittleEndian, uint32(uint64(rec.TimestampNs)>>32))
+		_ = binary.Write(&epb, binary.LittleEndian, uint32(rec.TimestampNs))
+		_ = binary.Write(&epb, binary.LittleEndian, uint32(len(data)))
+		_ = binary.Write(&epb, binary.LittleEndian, uint32(len(data)))
+		epb.Write(data)
+		epb.Write(make([]byte, pad4(len(data))))
+		comment := fmt.Sprintf("%s %s (0x%04X)", rec.Direction, network.PacketID(rec.Opcode), rec.Opcode)
+		writeOption(&epb, optComment, []byte(comment))
+		writeOption(&epb, optEndOfOpt, nil)
+		if err := writeBlock(w, blockEnhancedPacket, epb.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pad4(n int) int {
+	return (4 - n%4) % 4
+}
+
+func writeOption(buf *bytes.Buffer, code uint16, value []byte) {
+	_ = binary.Write(buf, binary.LittleEndian, code)
+	_ = binary.Write(buf, binary.LittleEndian, uint16(len(value)))
+	buf.Write(value)
+	buf.Write(make([]byte, pad4(len(value))))
+}
+
+func writeBlock(w io.Writer, blockType uint32, body []byte) error {
+	total := uint32(12 + len(body))
+	block := make([]byte, 0, total)
+	block = binary.LittleEndian.AppendUint32(block, blockType)
+	block = binary.LittleEndian.AppendUint32(block, total)
+	block = append(block, body...)
+	block = binary.LittleEndian.AppendUint32(block, total)
+	if _, err := w.Write(block); err != nil {
+		return fmt.Errorf("pcapng: write block: %w", err)
+	}
+	return nil
+}
+
+// pcapngInterface is an interface description from a pcapng section.
+type pcapngInterface struct {
+	linkType uint16
+	tsResol  byte // if_tsresol: 10^-n seconds, or 2^-n with the high bit set
+}
+
+// timestampNs converts a timestamp in the interface's resolution.
+func (i pcapngInterface) timestampNs(ticks uint64) int64 {
+	switch {
+	case i.tsResol == 0:
+		return int64(ticks * defaultTsResolPerTick)
+	case i.tsResol&0x80 != 0:
+		return int64(float64(ticks) * 1e9 / math.Exp2(float64(i.tsResol&0x7F)))
+	case i.tsResol <= 9:
+		return int64(ticks * uint64(math.Pow10(9-int(i.tsResol))))
+	default:
+		return int64(ticks / uint64(math.Pow10(int(i.tsResol)-9)))
+	}
+}
+
+// pcapngPacket is a packet read from a pcapng file.
+type pcapngPacket struct {
+	linkType    uint16
+	timestampNs int64
+	data        []byte
+	comment     string
+}
+
+// pcapngReader reads the packets of a pcapng file, across sections.
+type pcapngReader struct {
+	r          io.Reader
+	order      binary.ByteOrder
+	ifaces     []pcapngInterface
+	shbComment string
+}
+
+func newPCAPNGReader(r io.Reader) *pcapngReader {
+	return &pcapngReader{r: r}
+}
+
+// next returns the next packet, or io.EOF at the end of the file.
+func (pr *pcapngReader) next() (pcapngPacket, error) {
+	for {
+		var head [8]byte
+		if _, err := io.ReadFull(pr.r, head[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return pcapngPacket{}, errors.New("pcapng: truncated block header")
+			}
+			return pcapngPacket{}, err
+		}
+		blockType := binary.LittleEndian.Uint32(head[:4])
+		if blockType == blockSectionHeader {
+			// The section header's byte-order magic decides how to read the
+			// rest of the section, including this block's length.
+			var bom [4]byte
+			if _, err := io.ReadFull(pr.r, bom[:]); err != nil {
+				return pcapngPacket{}, fmt.Errorf("pcapng: read byte-order magic: %w", err)
+			}
+			switch {
+			case binary.LittleEndian.Uint32(bom[:]) == byteOrderMagic:
+				pr.order = binary.LittleEndian
+			case binary.BigEndian.Uint32(bom[:]) == byteOrderMagic:
+				pr.order = binary.BigEndian
+			default:
+				return pcapngPacket{}, errors.New("pcapng: invalid byte-order magic")
+			}
+			body, err := pr.readBody(pr.order.Uint32(head[4:]), 4)
+			if err != nil {
+				return pcapngPacket{}, err
+			}
+			pr.ifaces = nil
+			pr.shbComment = ""
+			if len(body) >= 12 {
+				pr.shbComment = string(pr.option(body[12:], optComment))
+			}
+			continue
+		}
+		if pr.order == nil {
+			return pcapngPacket{}, errors.New("pcapng: file does not start with a section header")
+		}
+		blockType = pr.order.Uint32(head[:4])
+		body, err := pr.readBody(pr.order.Uint32(head[4:]), 0)
+		if err != nil {
+			return pcapngPacket{}, err
+		}
+
+		switch blockType {
+		case blockInterfaceDesc:
+			if len(body) < 8 {
+				return pcapngPacket{}, errors.New("pcapng: short interface description")
+			}
+			iface := pcapngInterface{linkType: pr.order.Uint16(body[:2])}
+			if resol := pr.option(body[8:], optIfTsResol); len(resol) == 1 {
+				iface.tsResol = resol[0]
+			}
+			pr.ifaces = append(pr.ifaces, iface)
+		case blockEnhancedPacket:
+			if len(body) < 20 {
+				return pcapngPacket{}, errors.New("pcapng: short enhanced packet")
+			}
+			id := pr.order.Uint32(body[:4])
+			if int(id) >= len(pr.ifaces) {
+				return pcapngPacket{}, fmt.Errorf("pcapng: packet on undeclared interface %d", id)
+			}
+			ticks := uint64(pr.order.Uint32(body[4:8]))<<32 | uint64(pr.order.Uint32(body[8:12]))
+			capLen := int(pr.order.Uint32(body[12:16]))
+			if capLen > len(body)-20 {
+				return pcapngPacket{}, errors.New("pcapng: packet data exceeds block")
+			}
+			optStart := 20 + capLen + pad4(capLen)
+			var comment []byte
+			if optStart < len(body) {
+				comment = pr.option(body[optStart:], optComment)
+			}
+			return pcapngPacket{
+				linkType:    pr.ifaces[id].linkType,
+				timestampNs: pr.ifaces[id].timestampNs(ticks),
+				data:        body[20 : 20+capLen],
+				comment:     string(comment),
+			}, nil
+		case blockSimplePacket:
+			if len(pr.ifaces) == 0 || len(body) < 4 {
+				return pcapngPacket{}, errors.New("pcapng: simple packet without interface")
+			}
+			origLen := int(pr.order.Uint32(body[:4]))
+			if origLen > len(body)-4 {
+				origLen = len(body) - 4
+			}
+			return pcapngPacket{linkType: pr.ifaces[0].linkType, data: body[4 : 4+origLen]}, nil
+		}
+		// Other blocks (name resolution, statistics, ...) are skipped.
+	}
+}
+
+// readBody reads the rest of a block of the given total length, of which
+// consumed body bytes were already read, and checks the trailing length. The
+// length is validated before anything is allocated, so a corrupt header
+// cannot cause a huge allocation or an out-of-range slice.
+func (pr *pcapngReader) readBody(total uint32, consumed int) ([]byte, error) {
+	if total < uint32(12+consumed) || total%4 != 0 || total > maxPCAPNGBlockSize {
+		return nil, fmt.Errorf("pcapng: invalid block length %d", total)
+	}
+	rest := make([]byte, int(total)-8-consumed)
+	if _, err := io.ReadFull(pr.r, rest); err != nil {
+		return nil, fmt.Errorf("pcapng: read block: %w", err)
+	}
+	if pr.order.Uint32(rest[len(rest)-4:]) != total {
+		return nil, errors.New("pcapng: block length mismatch")
+	}
+	return rest[:len(rest)-4], nil
+}
+
+// option returns the value of the first option with the given code.
+func (pr *pcapngReader) option(opts []byte, code uint16) []byte {
+	for len(opts) >= 4 {
+		c, n := pr.order.Uint16(opts[:2]), int(pr.order.Uint16(opts[2:4]))
+		if c == optEndOfOpt || 4+n > len(opts) {
+			return nil
+		}
+		if c == code {
+			return opts[4 : 4+n]
+		}
+		opts = opts[4+n+pad4(n):]
+	}
+	return nil
+}
+
+// ImportOptions configures ImportPCAPNG for raw TCP captures. It is unused
+// for files exported with ExportPCAPNG.
+type ImportOptions struct {
+	// ServerPort selects the MHF connection. If zero, the first connection
+	// seen is used, with the side that opened it taken as the client.
+	ServerPort uint16
+	// ServerType defaults to sign when the client sends the 8 NULL byte
+	// connection init, and channel otherwise.
+	ServerType ServerType
+	// ClientMode selects the packet framing; defaults to ZZ.
+	ClientMode byte
+}
+
+// ImportPCAPNG reads an MHF session from a pcapng file. Files exported with
+// ExportPCAPNG are read back as they were; raw TCP captures are reassembled
+// and decrypted.
+func ImportPCAPNG(r io.Reader, opts ImportOptions) (FileHeader, SessionMetadata, []PacketRecord, error) {
+	pr := newPCAPNGReader(r)
+	var records []PacketRecord
+	var tcp *tcpSession
+	for {
+		pkt, err := pr.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return FileHeader{}, SessionMetadata{}, nil, err
+		}
+		if pkt.linkType == LinkTypeMHF {
+			if len(pkt.data) < 1 {
+				continue
+			}
+			rec := PacketRecord{
+				TimestampNs: pkt.timestampNs,
+				Direction:   Direction(pkt.data[0]),
+				Payload:     append([]byte(nil), pkt.data[1:]...),
+			}
+			if len(rec.Payload) >= 2 {
+				rec.Opcode = binary.BigEndian.Uint16(rec.Payload)
+			}
+			records = append(records, rec)
+			continue
+		}
+		if tcp == nil {
+			tcp = newTCPSession(opts.ServerPort)
+		}
+		if err := tcp.add(pkt); err != nil {
+			return FileHeader{}, SessionMetadata{}, nil, err
+		}
+	}
+
+	if tcp == nil {
+		hdr := FileHeader{Version: FormatVersion, ServerType: ServerTypeChannel}
+		var meta SessionMetadata
+		var session pcapngSession
+		if json.Unmarshal([]byte(pr.shbComment), &session) == nil {
+			hdr.ServerType = session.ServerType
+			hdr.ClientMode = session.ClientMode
+			hdr.SessionStartNs = session.SessionStartNs
+			meta = session.Metadata
+		} else if len(records) > 0 {
+			hdr.SessionStartNs = records[0].TimestampNs
+		}
+		return hdr, meta, records, nil
+	}
+	if len(records) > 0 {
+		return FileHeader{}, SessionMetadata{}, nil, errors.New("pcapng: file mixes MHF and raw TCP interfaces")
+	}
+	return tcp.decrypt(opts)
+}
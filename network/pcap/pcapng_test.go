@@ -0,0 +1,237 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	cfg "erupe-ce/config"
+	"erupe-ce/network"
+)
+
+func TestPCAPNGRoundTrip(t *testing.T) {
+	hdr := FileHeader{
+		Version:        FormatVersion,
+		ServerType:     ServerTypeChannel,
+		ClientMode:     40,
+		SessionStartNs: 1700000000000000000,
+	}
+	meta := SessionMetadata{Host: "127.0.0.1", Port: 54001, CharID: 42}
+	records := []PacketRecord{
+		{TimestampNs: 1700000000000000100, Direction: DirClientToServer, Opcode: 0x0014, Payload: []byte{0x00, 0x14, 0x01}},
+		{TimestampNs: 1700000000000000200, Direction: DirServerToClient, Opcode: 0x0012, Payload: []byte{0x00, 0x12, 0xAA, 0xBB, 0xCC}},
+	}
+
+	var buf bytes.Buffer
+	if err := ExportPCAPNG(&buf, hdr, meta, records); err != nil {
+		t.Fatalf("ExportPCAPNG: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("MSG_SYS_LOGIN")) {
+		t.Error("export has no opcode name comments")
+	}
+
+	gotHdr, gotMeta, got, err := ImportPCAPNG(bytes.NewReader(buf.Bytes()), ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportPCAPNG: %v", err)
+	}
+	if gotHdr.ServerType != hdr.ServerType || gotHdr.ClientMode != hdr.ClientMode || gotHdr.SessionStartNs != hdr.SessionStartNs {
+		t.Errorf("header = %+v, want %+v", gotHdr, hdr)
+	}
+	if gotMeta != meta {
+		t.Errorf("metadata = %+v, want %+v", gotMeta, meta)
+	}
+	if len(got) != len(records) {
+		t.Fatalf("got %d records, want %d", len(got), len(records))
+	}
+	for i := range records {
+		if got[i].TimestampNs != records[i].TimestampNs || got[i].Direction != records[i].Direction ||
+			got[i].Opcode != records[i].Opcode || !bytes.Equal(got[i].Payload, records[i].Payload) {
+			t.Errorf("record %d = %+v, want %+v", i, got[i], records[i])
+		}
+	}
+}
+
+func TestImportPCAPNGRejectsGarbage(t *testing.T) {
+	if _, _, _, err := ImportPCAPNG(strings.NewReader("not a pcapng file"), ImportOptions{}); err == nil {
+		t.Error("expected error for invalid file")
+	}
+}
+
+func TestImportPCAPNGRejectsMalformedBlockLength(t *testing.T) {
+	block := func(blockType, total uint32, body ...byte) []byte {
+		b := binary.LittleEndian.AppendUint32(nil, blockType)
+		b = binary.LittleEndian.AppendUint32(b, total)
+		return append(b, body...)
+	}
+	shb := func(total uint32) []byte {
+		return block(blockSectionHeader, total, 0x4D, 0x3C, 0x2B, 0x1A)
+	}
+	// afterSHB prefixes a block with a valid, empty section header.
+	afterSHB := func(b []byte) []byte {
+		data := binary.LittleEndian.AppendUint32(shb(16), 16)
+		return append(data, b...)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"section header shorter than its byte-order magic", shb(12)},
+		{"section header length below minimum", shb(8)},
+		{"section header length not a multiple of 4", shb(18)},
+		{"block length below minimum", afterSHB(block(blockEnhancedPacket, 4))},
+		{"block length zero", afterSHB(block(blockEnhancedPacket, 0))},
+		{"block length not a multiple of 4", afterSHB(block(blockEnhancedPacket, 13))},
+		{"block length too large", afterSHB(block(blockEnhancedPacket, maxPCAPNGBlockSize+4))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := ImportPCAPNG(bytes.NewReader(tt.data), ImportOptions{}); err == nil {
+				t.Error("expected error for malformed block length")
+			}
+		})
+	}
+}
+
+// encryptConn collects what a CryptConn writes.
+type encryptConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *encryptConn) Write(b []byte) (int, error) { return c.buf.Write(b) }
+
+func encryptPackets(t *testing.T, packets ...[]byte) [][]byte {
+	t.Helper()
+	conn := &encryptConn{}
+	cc := network.NewCryptConn(conn, cfg.ZZ, nil)
+	var frames [][]byte
+	for _, p := range packets {
+		if err := cc.SendPacket(p); err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, append([]byte(nil), conn.buf.Bytes()...))
+		conn.buf.Reset()
+	}
+	return frames
+}
+
+// ethernetTCP builds an Ethernet/IPv4/TCP frame.
+func ethernetTCP(src, dst [4]byte, srcPort, dstPort uint16, seq uint32, flags byte, payload []byte) []byte {
+	frame := make([]byte, 14, 14+40+len(payload))
+	binary.BigEndian.PutUint16(frame[12:], 0x0800)
+	ip := make([]byte, 20)
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(40+len(payload)))
+	ip[8], ip[9] = 64, 6
+	copy(ip[12:], src[:])
+	copy(ip[16:], dst[:])
+	tcp := make([]byte, 20)
+	binary.BigEndian.PutUint16(tcp[0:], srcPort)
+	binary.BigEndian.PutUint16(tcp[2:], dstPort)
+	binary.BigEndian.PutUint32(tcp[4:], seq)
+	tcp[12], tcp[13] = 5<<4, flags
+	frame = append(append(append(frame, ip...), tcp...), payload...)
+	return frame
+}
+
+// writeRawPCAPNG writes Ethernet frames with microsecond timestamps.
+func writeRawPCAPNG(t *testing.T, frames [][]byte) []byte {
+	t.Helper()
+	var buf, shb, idb bytes.Buffer
+	_ = binary.Write(&shb, binary.LittleEndian, byteOrderMagic)
+	_ = binary.Write(&shb, binary.LittleEndian, []uint16{1, 0})
+	_ = binary.Write(&shb, binary.LittleEndian, int64(-1))
+	_ = writeBlock(&buf, blockSectionHeader, shb.Bytes())
+	_ = binary.Write(&idb, binary.LittleEndian, []uint16{linkTypeEthernet, 0})
+	_ = binary.Write(&idb, binary.LittleEndian, uint32(65535))
+	_ = writeBlock(&buf, blockInterfaceDesc, idb.Bytes())
+	for i, frame := range frames {
+		var epb bytes.Buffer
+		_ = binary.Write(&epb, binary.LittleEndian, []uint32{0, 0, uint32(1000 + i), uint32(len(frame)), uint32(len(frame))})
+		epb.Write(frame)
+		epb.Write(make([]byte, pad4(len(frame))))
+		_ = writeBlock(&buf, blockEnhancedPacket, epb.Bytes())
+	}
+	return buf.Bytes()
+}
+
+func TestImportPCAPNGRawTCP(t *testing.T) {
+	client, server := [4]byte{192, 168, 1, 10}, [4]byte{10, 0, 0, 1}
+	const cport, sport = 50000, 54001
+
+	login := []byte{0x00, 0x14, 0x00, 0x00, 0x00, 0x01, 0x00, 0x01, 0x86, 0xA1, 0x00, 0x10}
+	ping := []byte{0x00, 0x17, 0x00, 0x00, 0x00, 0x02, 0x00, 0x10}
+	c2s := encryptPackets(t, login, ping)
+	s2c := encryptPackets(t, []byte{0x00, 0x12, 0x00, 0x00, 0x00, 0x01, 0x00, 0x10})
+
+	cseq, sseq := uint32(1000), uint32(9000)
+	split := len(c2s[0]) / 2
+	frames := [][]byte{
+		ethernetTCP(client, server, 12345, 80, 1, 0x18, []byte("other connection")),
+		ethernetTCP(client, server, cport, sport, cseq-1, 0x02, nil),                      // SYN
+		ethernetTCP(server, client, sport, cport, sseq-1, 0x12, nil),                      // SYN-ACK
+		ethernetTCP(client, server, cport, sport, cseq, 0x18, c2s[0][:split]),             // First half of login
+		ethernetTCP(client, server, cport, sport, cseq, 0x18, c2s[0][:split]),             // Retransmission
+		ethernetTCP(client, server, cport, sport, cseq+uint32(len(c2s[0])), 0x18, c2s[1]), // Ping, out of order
+		ethernetTCP(client, server, cport, sport, cseq+uint32(split), 0x18, c2s[0][split:]),
+		ethernetTCP(server, client, sport, cport, sseq, 0x18, s2c[0]),
+	}
+
+	hdr, meta, records, err := ImportPCAPNG(bytes.NewReader(writeRawPCAPNG(t, frames)), ImportOptions{ServerPort: sport})
+	if err != nil {
+		t.Fatalf("ImportPCAPNG: %v", err)
+	}
+	if hdr.ServerType != ServerTypeChannel || hdr.ClientMode != byte(cfg.ZZ) {
+		t.Errorf("header = %+v", hdr)
+	}
+	if meta.Host != "10.0.0.1" || meta.Port != sport || meta.RemoteAddr != "192.168.1.10:50000" || meta.CharID != 100001 {
+		t.Errorf("metadata = %+v", meta)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3: %+v", len(records), records)
+	}
+	want := []struct {
+		dir     Direction
+		opcode  uint16
+		payload []byte
+	}{
+		{DirClientToServer, 0x0014, login},
+		{DirClientToServer, 0x0017, ping},
+		{DirServerToClient, 0x0012, nil},
+	}
+	for i, w := range want {
+		if records[i].Direction != w.dir || records[i].Opcode != w.opcode {
+			t.Errorf("record %d = %s 0x%04X, want %s 0x%04X", i, records[i].Direction, records[i].Opcode, w.dir, w.opcode)
+		}
+		if w.payload != nil && !bytes.Equal(records[i].Payload, w.payload) {
+			t.Errorf("record %d payload = % X, want % X", i, records[i].Payload, w.payload)
+		}
+	}
+	// Both client packets became readable with the segment at index 6.
+	for i := 0; i < 2; i++ {
+		if records[i].TimestampNs != 1006*1000 {
+			t.Errorf("record %d timestamp = %d, want %d", i, records[i].TimestampNs, 1006*1000)
+		}
+	}
+}
+
+func TestImportPCAPNGSignInit(t *testing.T) {
+	client, server := [4]byte{127, 0, 0, 1}, [4]byte{127, 0, 0, 1}
+	frames := encryptPackets(t, []byte("DSGN:100"))
+	stream := append(make([]byte, 8), frames[0]...)
+	raw := writeRawPCAPNG(t, [][]byte{ethernetTCP(client, server, 40000, 53312, 1, 0x18, stream)})
+
+	hdr, _, records, err := ImportPCAPNG(bytes.NewReader(raw), ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportPCAPNG: %v", err)
+	}
+	if hdr.ServerType != ServerTypeSign {
+		t.Errorf("ServerType = %s, want sign", hdr.ServerType)
+	}
+	if len(records) != 1 || string(records[0].Payload) != "DSGN:100" {
+		t.Errorf("records = %+v", records)
+	}
+}
@@ -0,0 +1,153 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"erupe-ce/network"
+)
+
+// Hub streams the packets of live sessions to subscribers watching a
+// character. Sessions publish through a TapConn; publishing for a character
+// nobody watches costs a map lookup.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[uint32][]*Subscription
+}
+
+// NewHub creates an empty Hub.
+func NewHub() *Hub {
+	return &Hub{subs: make(map[uint32][]*Subscription)}
+}
+
+// Subscription receives the packets of one character. Packets are dropped
+// rather than delaying the session when the subscriber falls behind.
+type Subscription struct {
+	C <-chan PacketRecord
+
+	c       chan PacketRecord
+	hub     *Hub
+	charID  uint32
+	opcodes []uint16
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// Subscribe starts streaming the packets of charID, limited to opcodes if
+// any are given. buffer is the number of packets held for a slow reader.
+func (h *Hub) Subscribe(charID uint32, opcodes []uint16, buffer int) *Subscription {
+	c := make(chan PacketRecord, buffer)
+	sub := &Subscription{C: c, c: c, hub: h, charID: charID, opcodes: opcodes}
+	h.mu.Lock()
+	h.subs[charID] = append(h.subs[charID], sub)
+	h.mu.Unlock()
+	return sub
+}
+
+// Watching reports whether anyone subscribed to charID.
+func (h *Hub) Watching(charID uint32) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs[charID]) > 0
+}
+
+// Publish sends a packet of charID to its subscribers.
+func (h *Hub) Publish(charID uint32, rec PacketRecord) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, sub := range h.subs[charID] {
+		if len(sub.opcodes) > 0 && len(FilterByOpcode([]PacketRecord{rec}, sub.opcodes...)) == 0 {
+			continue
+		}
+		select {
+		case sub.c <- rec:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns the number of packets skipped because the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close stops the subscription and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		h := s.hub
+		h.mu.Lock()
+		subs := h.subs[s.charID]
+		for i, sub := range subs {
+			if sub == s {
+				subs = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+		if len(subs) == 0 {
+			delete(h.subs, s.charID)
+		} else {
+			h.subs[s.charID] = subs
+		}
+		h.mu.Unlock()
+		close(s.c)
+	})
+}
+
+// TapConn wraps a network.Conn and publishes its packets to a Hub once the
+// session's character is known. It is safe for concurrent use from separate
+// send/recv goroutines.
+type TapConn struct {
+	inner  network.Conn
+	hub    *Hub
+	charID atomic.Uint32
+}
+
+// NewTapConn wraps inner, publishing to hub.
+func NewTapConn(inner network.Conn, hub *Hub) *TapConn {
+	return &TapConn{inner: inner, hub: hub}
+}
+
+// SetCharID sets the character whose subscribers receive the packets. It is
+// called after login.
+func (tc *TapConn) SetCharID(charID uint32) {
+	tc.charID.Store(charID)
+}
+
+// ReadPacket reads from the inner connection and publishes the packet as client-to-server.
+func (tc *TapConn) ReadPacket() ([]byte, error) {
+	data, err := tc.inner.ReadPacket()
+	if err != nil {
+		return data, err
+	}
+	tc.publish(DirClientToServer, data)
+	return data, nil
+}
+
+// SendPacket sends via the inner connection and publishes the packet as server-to-client.
+func (tc *TapConn) SendPacket(data []byte) error {
+	if err := tc.inner.SendPacket(data); err != nil {
+		return err
+	}
+	tc.publish(DirServerToClient, data)
+	return nil
+}
+
+func (tc *TapConn) publish(dir Direction, data []byte) {
+	charID := tc.charID.Load()
+	if charID == 0 || !tc.hub.Watching(charID) {
+		return
+	}
+	var opcode uint16
+	if len(data) >= 2 {
+		opcode = binary.BigEndian.Uint16(data[:2])
+	}
+	tc.hub.Publish(charID, PacketRecord{
+		TimestampNs: time.Now().UnixNano(),
+		Direction:   dir,
+		Opcode:      opcode,
+		Payload:     append([]byte(nil), data...),
+	})
+}
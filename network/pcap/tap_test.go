@@ -0,0 +1,64 @@
+package pcap
+
+import (
+	"testing"
+)
+
+func TestTapConnPublishesAfterLogin(t *testing.T) {
+	hub := NewHub()
+	mock := &mockConn{readData: [][]byte{{0x00, 0x13, 0x01}, {0x00, 0x14, 0x02}}}
+	tc := NewTapConn(mock, hub)
+	sub := hub.Subscribe(42, nil, 8)
+	defer sub.Close()
+
+	// Before login the session's character is unknown.
+	if _, err := tc.ReadPacket(); err != nil {
+		t.Fatal(err)
+	}
+	tc.SetCharID(42)
+	if _, err := tc.ReadPacket(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.SendPacket([]byte{0x00, 0x12, 0x03}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sub.C) != 2 {
+		t.Fatalf("got %d packets, want 2", len(sub.C))
+	}
+	rec := <-sub.C
+	if rec.Direction != DirClientToServer || rec.Opcode != 0x0014 {
+		t.Errorf("first packet = %+v", rec)
+	}
+	rec = <-sub.C
+	if rec.Direction != DirServerToClient || rec.Opcode != 0x0012 {
+		t.Errorf("second packet = %+v", rec)
+	}
+	if len(mock.sent) != 1 {
+		t.Errorf("inner conn sent %d packets, want 1", len(mock.sent))
+	}
+}
+
+func TestHubOpcodeFilterAndDrops(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(7, []uint16{0x0012}, 1)
+
+	hub.Publish(7, PacketRecord{Opcode: 0x0013})
+	hub.Publish(8, PacketRecord{Opcode: 0x0012})
+	hub.Publish(7, PacketRecord{Opcode: 0x0012})
+	hub.Publish(7, PacketRecord{Opcode: 0x0012}) // Buffer full
+	if len(sub.C) != 1 || sub.Dropped() != 1 {
+		t.Errorf("buffered = %d, dropped = %d; want 1, 1", len(sub.C), sub.Dropped())
+	}
+
+	sub.Close()
+	sub.Close()
+	if hub.Watching(7) {
+		t.Error("hub still watching after Close")
+	}
+	<-sub.C
+	if _, ok := <-sub.C; ok {
+		t.Error("channel not closed")
+	}
+	hub.Publish(7, PacketRecord{Opcode: 0x0012}) // Must not panic
+}
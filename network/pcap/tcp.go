@@ -0,0 +1,354 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"time"
+
+	cfg "erupe-ce/config"
+	"erupe-ce/network"
+)
+
+// Link types of raw captures that ImportPCAPNG can decode.
+const (
+	linkTypeNull     uint16 = 0
+	linkTypeEthernet uint16 = 1
+	linkTypeRaw      uint16 = 101
+	linkTypeLinuxSLL uint16 = 113
+	linkTypeIPv4     uint16 = 228
+	linkTypeIPv6     uint16 = 229
+	linkTypeLoop     uint16 = 108
+)
+
+// tcpSegment is a decoded TCP segment.
+type tcpSegment struct {
+	srcIP, dstIP     net.IP
+	srcPort, dstPort uint16
+	seq              uint32
+	syn, ack         bool
+	payload          []byte
+}
+
+// decodeTCP extracts the TCP segment from a link-layer frame. It returns
+// false for anything that is not TCP over IPv4 or IPv6.
+func decodeTCP(linkType uint16, frame []byte) (tcpSegment, bool, error) {
+	var ip []byte
+	switch linkType {
+	case linkTypeNull, linkTypeLoop:
+		if len(frame) < 4 {
+			return tcpSegment{}, false, nil
+		}
+		ip = frame[4:] // Address family in the capturing host's byte order
+	case linkTypeEthernet:
+		if len(frame) < 14 {
+			return tcpSegment{}, false, nil
+		}
+		etherType, off := binary.BigEndian.Uint16(frame[12:14]), 14
+		for etherType == 0x8100 && len(frame) >= off+4 { // 802.1Q VLAN tags
+			etherType, off = binary.BigEndian.Uint16(frame[off+2:off+4]), off+4
+		}
+		if etherType != 0x0800 && etherType != 0x86DD {
+			return tcpSegment{}, false, nil
+		}
+		ip = frame[off:]
+	case linkTypeLinuxSLL:
+		if len(frame) < 16 {
+			return tcpSegment{}, false, nil
+		}
+		ip = frame[16:]
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		ip = frame
+	default:
+		return tcpSegment{}, false, fmt.Errorf("pcapng: unsupported link type %d", linkType)
+	}
+	if len(ip) < 1 {
+		return tcpSegment{}, false, nil
+	}
+
+	var seg tcpSegment
+	var tcp []byte
+	switch ip[0] >> 4 {
+	case 4:
+		if len(ip) < 20 {
+			return tcpSegment{}, false, nil
+		}
+		ihl := int(ip[0]&0x0F) * 4
+		total := int(binary.BigEndian.Uint16(ip[2:4]))
+		if ip[9] != 6 || ihl < 20 || total < ihl || total > len(ip) {
+			return tcpSegment{}, false, nil
+		}
+		if binary.BigEndian.Uint16(ip[6:8])&0x3FFF != 0 {
+			return tcpSegment{}, false, errors.New("pcapng: fragmented IPv4 packets are not supported")
+		}
+		seg.srcIP, seg.dstIP = net.IP(ip[12:16]), net.IP(ip[16:20])
+		tcp = ip[ihl:total] // Drop Ethernet padding
+	case 6:
+		if len(ip) < 40 || ip[6] != 6 {
+			return tcpSegment{}, false, nil
+		}
+		end := 40 + int(binary.BigEndian.Uint16(ip[4:6]))
+		if end > len(ip) {
+			return tcpSegment{}, false, nil
+		}
+		seg.srcIP, seg.dstIP = net.IP(ip[8:24]), net.IP(ip[24:40])
+		tcp = ip[40:end]
+	default:
+		return tcpSegment{}, false, nil
+	}
+
+	if len(tcp) < 20 {
+		return tcpSegment{}, false, nil
+	}
+	dataOff := int(tcp[12]>>4) * 4
+	if dataOff < 20 || dataOff > len(tcp) {
+		return tcpSegment{}, false, nil
+	}
+	seg.srcPort = binary.BigEndian.Uint16(tcp[0:2])
+	seg.dstPort = binary.BigEndian.Uint16(tcp[2:4])
+	seg.seq = binary.BigEndian.Uint32(tcp[4:8])
+	seg.syn = tcp[13]&0x02 != 0
+	seg.ack = tcp[13]&0x10 != 0
+	seg.payload = tcp[dataOff:]
+	return seg, true, nil
+}
+
+// streamMark records the capture time of the segment ending at end.
+type streamMark struct {
+	end         int
+	timestampNs int64
+}
+
+// tcpHalf reassembles one direction of a TCP connection.
+type tcpHalf struct {
+	started bool
+	next    uint32 // Next expected sequence number
+	data    []byte
+	marks   []streamMark
+	pending map[uint32][]byte // Out-of-order segments by sequence number
+}
+
+func (h *tcpHalf) syn(seq uint32) {
+	h.started = true
+	h.next = seq + 1
+}
+
+// add appends the payload's new bytes, buffering segments that arrive early.
+// Buffered bytes take the timestamp of the segment that fills the gap, as
+// that is when they became readable.
+func (h *tcpHalf) add(seq uint32, payload []byte, ts int64) {
+	if len(payload) == 0 {
+		return
+	}
+	if !h.started {
+		// Capture began mid-connection.
+		h.started = true
+		h.next = seq
+	}
+	if ahead := int32(seq - h.next); ahead > 0 {
+		if h.pending == nil {
+			h.pending = make(map[uint32][]byte)
+		}
+		h.pending[seq] = append([]byte(nil), payload...)
+		return
+	}
+	h.appendInOrder(seq, payload, ts)
+	for len(h.pending) > 0 {
+		progressed := false
+		for pseq, p := range h.pending {
+			if int32(pseq-h.next) <= 0 {
+				delete(h.pending, pseq)
+				h.appendInOrder(pseq, p, ts)
+				progressed = true
+			}
+		}
+		if !progressed {
+			return
+		}
+	}
+}
+
+// appendInOrder appends the part of a segment at or before h.next that has
+// not been seen yet; retransmitted bytes are dropped.
+func (h *tcpHalf) appendInOrder(seq uint32, payload []byte, ts int64) {
+	seen := int(h.next - seq)
+	if seen >= len(payload) {
+		return
+	}
+	h.data = append(h.data, payload[seen:]...)
+	h.next += uint32(len(payload) - seen)
+	h.marks = append(h.marks, streamMark{end: len(h.data), timestampNs: ts})
+}
+
+// timestampAt returns the capture time of the segment holding offset-1, the
+// last byte of a packet ending at offset.
+func (h *tcpHalf) timestampAt(offset int) int64 {
+	i := sort.Search(len(h.marks), func(i int) bool { return h.marks[i].end >= offset })
+	if i == len(h.marks) {
+		i = len(h.marks) - 1
+	}
+	return h.marks[i].timestampNs
+}
+
+// tcpSession follows the one TCP connection of an MHF session in a raw capture.
+type tcpSession struct {
+	serverPort uint16
+	locked     bool
+	client     tcpEndpoint
+	server     tcpEndpoint
+	startNs    int64
+	fromClient tcpHalf
+	fromServer tcpHalf
+}
+
+type tcpEndpoint struct {
+	ip   string
+	port uint16
+}
+
+func newTCPSession(serverPort uint16) *tcpSession {
+	return &tcpSession{serverPort: serverPort}
+}
+
+// add feeds a captured frame to the session. Frames of other connections
+// are ignored.
+func (t *tcpSession) add(pkt pcapngPacket) error {
+	seg, ok, err := decodeTCP(pkt.linkType, pkt.data)
+	if err != nil || !ok {
+		return err
+	}
+	src := tcpEndpoint{seg.srcIP.String(), seg.srcPort}
+	dst := tcpEndpoint{seg.dstIP.String(), seg.dstPort}
+
+	if !t.locked {
+		switch {
+		case t.serverPort != 0 && seg.dstPort == t.serverPort:
+			t.client, t.server = src, dst
+		case t.serverPort != 0 && seg.srcPort == t.serverPort:
+			t.client, t.server = dst, src
+		case t.serverPort == 0 && seg.syn && !seg.ack:
+			t.client, t.server = src, dst
+		case t.serverPort == 0 && len(seg.payload) > 0:
+			// No handshake in the capture: the client speaks first.
+			t.client, t.server = src, dst
+		default:
+			return nil
+		}
+		t.locked = true
+		t.startNs = pkt.timestampNs
+	}
+
+	var half *tcpHalf
+	switch {
+	case src == t.client && dst == t.server:
+		half = &t.fromClient
+	case src == t.server && dst == t.client:
+		half = &t.fromServer
+	default:
+		return nil
+	}
+	if seg.syn {
+		half.syn(seg.seq)
+		seg.seq++
+	}
+	half.add(seg.seq, seg.payload, pkt.timestampNs)
+	return nil
+}
+
+// decrypt decrypts both directions of the connection into a capture.
+func (t *tcpSession) decrypt(opts ImportOptions) (FileHeader, SessionMetadata, []PacketRecord, error) {
+	if !t.locked {
+		return FileHeader{}, SessionMetadata{}, nil, errors.New("pcapng: no MHF connection found")
+	}
+	hdr := FileHeader{
+		Version:        FormatVersion,
+		ServerType:     opts.ServerType,
+		ClientMode:     opts.ClientMode,
+		SessionStartNs: t.startNs,
+	}
+	if hdr.ClientMode == 0 {
+		hdr.ClientMode = byte(cfg.ZZ)
+	}
+
+	// Sign and entrance clients open with 8 NULL bytes before the first
+	// encrypted packet; channel clients do not.
+	clientStart := 0
+	if len(t.fromClient.data) >= 8 && bytes.Equal(t.fromClient.data[:8], make([]byte, 8)) {
+		clientStart = 8
+		if hdr.ServerType == 0 {
+			hdr.ServerType = ServerTypeSign
+		}
+	} else if hdr.ServerType == 0 {
+		hdr.ServerType = ServerTypeChannel
+	}
+
+	mode := cfg.Mode(hdr.ClientMode)
+	c2s, err := decryptHalf(&t.fromClient, clientStart, DirClientToServer, mode)
+	if err != nil {
+		return FileHeader{}, SessionMetadata{}, nil, fmt.Errorf("pcapng: decrypt client stream: %w", err)
+	}
+	s2c, err := decryptHalf(&t.fromServer, 0, DirServerToClient, mode)
+	if err != nil {
+		return FileHeader{}, SessionMetadata{}, nil, fmt.Errorf("pcapng: decrypt server stream: %w", err)
+	}
+	records := append(c2s, s2c...)
+	sort.SliceStable(records, func(i, j int) bool { return records[i].TimestampNs < records[j].TimestampNs })
+
+	meta := SessionMetadata{
+		Host:       t.server.ip,
+		Port:       int(t.server.port),
+		RemoteAddr: net.JoinHostPort(t.client.ip, fmt.Sprint(t.client.port)),
+	}
+	for _, rec := range c2s {
+		if rec.Opcode == uint16(network.MSG_SYS_LOGIN) && len(rec.Payload) >= 10 {
+			meta.CharID = binary.BigEndian.Uint32(rec.Payload[6:10])
+			break
+		}
+	}
+	return hdr, meta, records, nil
+}
+
+// decryptHalf runs one direction's byte stream through a CryptConn, which
+// tracks the key rotation across packets. A packet cut off by the end of
+// the capture is dropped.
+func decryptHalf(h *tcpHalf, start int, dir Direction, mode cfg.Mode) ([]PacketRecord, error) {
+	if start >= len(h.data) {
+		return nil, nil
+	}
+	stream := &streamConn{r: bytes.NewReader(h.data[start:])}
+	cc := network.NewCryptConn(stream, mode, nil)
+	var records []PacketRecord
+	for {
+		data, err := cc.ReadPacket()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		offset := start + int(stream.r.Size()) - stream.r.Len()
+		rec := PacketRecord{TimestampNs: h.timestampAt(offset), Direction: dir, Payload: data}
+		if len(data) >= 2 {
+			rec.Opcode = binary.BigEndian.Uint16(data)
+		}
+		records = append(records, rec)
+	}
+}
+
+// streamConn is a read-only net.Conn over a reassembled byte stream.
+type streamConn struct {
+	r *bytes.Reader
+}
+
+func (c *streamConn) Read(b []byte) (int, error)         { return c.r.Read(b) }
+func (c *streamConn) Write(b []byte) (int, error)        { return 0, errors.New("pcap: stream is read-only") }
+func (c *streamConn) Close() error                       { return nil }
+func (c *streamConn) LocalAddr() net.Addr                { return nil }
+func (c *streamConn) RemoteAddr() net.Addr               { return nil }
+func (c *streamConn) SetDeadline(t time.Time) error      { return nil }
+func (c *streamConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *streamConn) SetWriteDeadline(t time.Time) error { return nil }
@@ -0,0 +1,202 @@
+package transport
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// PSK handshake, all fields fixed size:
+//
+//	client → server: "ERPT" | version (1) | client nonce (32)
+//	server → client: server nonce (32) | HMAC-SHA256(psk, "server" | nonces)
+//	client → server: HMAC-SHA256(psk, "client" | nonces)
+//
+// Both sides prove knowledge of the PSK before any MHF data flows. Each
+// direction then uses its own AES-256-GCM key derived with HKDF from the PSK
+// and both nonces. Records are a big-endian uint16 ciphertext length followed
+// by the ciphertext; the length is authenticated and the GCM nonce is the
+// record's sequence number, so dropped, reordered or replayed records fail.
+var pskMagic = []byte("ERPT")
+
+const (
+	pskVersion    = 1
+	pskNonceSize  = 32
+	maxRecordSize = 16 * 1024 // Plaintext bytes per record
+)
+
+var errPSKAuth = errors.New("transport: PSK authentication failed")
+
+func pskMAC(psk []byte, label string, clientNonce, serverNonce []byte) []byte {
+	m := hmac.New(sha256.New, psk)
+	m.Write([]byte(label))
+	m.Write(clientNonce)
+	m.Write(serverNonce)
+	return m.Sum(nil)
+}
+
+// pskKeys derives the client-to-server and server-to-client AEADs.
+func pskKeys(psk, clientNonce, serverNonce []byte) (c2s, s2c cipher.AEAD, err error) {
+	salt := append(append([]byte{}, clientNonce...), serverNonce...)
+	newAEAD := func(info string) (cipher.AEAD, error) {
+		key, err := hkdf.Key(sha256.New, psk, salt, info, 32)
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	}
+	if c2s, err = newAEAD("erupe transport c2s"); err != nil {
+		return nil, nil, err
+	}
+	if s2c, err = newAEAD("erupe transport s2c"); err != nil {
+		return nil, nil, err
+	}
+	return c2s, s2c, nil
+}
+
+// pskServer runs the server half of the handshake on conn, whose first byte
+// has already been identified as the start of the magic.
+func pskServer(conn net.Conn, psk []byte) (net.Conn, error) {
+	hello := make([]byte, len(pskMagic)+1+pskNonceSize)
+	if _, err := io.ReadFull(conn, hello); err != nil {
+		return nil, fmt.Errorf("transport: read PSK hello: %w", err)
+	}
+	if string(hello[:len(pskMagic)]) != string(pskMagic) {
+		return nil, errors.New("transport: bad PSK magic")
+	}
+	if v := hello[len(pskMagic)]; v != pskVersion {
+		return nil, fmt.Errorf("transport: unsupported PSK version %d", v)
+	}
+	clientNonce := hello[len(pskMagic)+1:]
+
+	serverNonce := make([]byte, pskNonceSize)
+	if _, err := rand.Read(serverNonce); err != nil {
+		return nil, err
+	}
+	reply := append(append([]byte{}, serverNonce...), pskMAC(psk, "server", clientNonce, serverNonce)...)
+	if _, err := conn.Write(reply); err != nil {
+		return nil, err
+	}
+
+	proof := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(conn, proof); err != nil {
+		return nil, fmt.Errorf("transport: read PSK proof: %w", err)
+	}
+	if !hmac.Equal(proof, pskMAC(psk, "client", clientNonce, serverNonce)) {
+		return nil, errPSKAuth
+	}
+	c2s, s2c, err := pskKeys(psk, clientNonce, serverNonce)
+	if err != nil {
+		return nil, err
+	}
+	return &pskConn{Conn: conn, read: c2s, write: s2c}, nil
+}
+
+// pskClient runs the client half of the handshake on conn.
+func pskClient(conn net.Conn, psk []byte) (net.Conn, error) {
+	clientNonce := make([]byte, pskNonceSize)
+	if _, err := rand.Read(clientNonce); err != nil {
+		return nil, err
+	}
+	hello := append(append(append([]byte{}, pskMagic...), pskVersion), clientNonce...)
+	if _, err := conn.Write(hello); err != nil {
+		return nil, err
+	}
+
+	reply := make([]byte, pskNonceSize+sha256.Size)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, fmt.Errorf("transport: read PSK reply: %w", err)
+	}
+	serverNonce := reply[:pskNonceSize]
+	if !hmac.Equal(reply[pskNonceSize:], pskMAC(psk, "server", clientNonce, serverNonce)) {
+		return nil, errPSKAuth
+	}
+	if _, err := conn.Write(pskMAC(psk, "client", clientNonce, serverNonce)); err != nil {
+		return nil, err
+	}
+	c2s, s2c, err := pskKeys(psk, clientNonce, serverNonce)
+	if err != nil {
+		return nil, err
+	}
+	return &pskConn{Conn: conn, read: s2c, write: c2s}, nil
+}
+
+// pskConn seals and opens records with the keys from the PSK handshake.
+type pskConn struct {
+	net.Conn
+	read, write       cipher.AEAD
+	readMu, writeMu   sync.Mutex
+	readSeq, writeSeq uint64
+	pending           []byte // Opened plaintext not yet returned by Read
+}
+
+func seqNonce(aead cipher.AEAD, seq uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+func (p *pskConn) Read(b []byte) (int, error) {
+	p.readMu.Lock()
+	defer p.readMu.Unlock()
+	if len(p.pending) == 0 {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(p.Conn, header); err != nil {
+			return 0, err
+		}
+		size := int(binary.BigEndian.Uint16(header))
+		if size < p.read.Overhead() || size > maxRecordSize+p.read.Overhead() {
+			return 0, fmt.Errorf("transport: bad record size %d", size)
+		}
+		record := make([]byte, size)
+		if _, err := io.ReadFull(p.Conn, record); err != nil {
+			return 0, err
+		}
+		plain, err := p.read.Open(record[:0], seqNonce(p.read, p.readSeq), record, header)
+		if err != nil {
+			return 0, errPSKAuth
+		}
+		p.readSeq++
+		p.pending = plain
+	}
+	n := copy(b, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+func (p *pskConn) Write(b []byte) (int, error) {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	written := 0
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > maxRecordSize {
+			chunk = chunk[:maxRecordSize]
+		}
+		size := len(chunk) + p.write.Overhead()
+		header := make([]byte, 2)
+		binary.BigEndian.PutUint16(header, uint16(size))
+		record := append(make([]byte, 0, 2+size), header...)
+		record = p.write.Seal(record, seqNonce(p.write, p.writeSeq), chunk, header)
+		if _, err := p.Conn.Write(record); err != nil {
+			return written, err
+		}
+		p.writeSeq++
+		written += len(chunk)
+		b = b[len(chunk):]
+	}
+	return written, nil
+}
@@ -0,0 +1,233 @@
+// Package transport provides an optional secure layer beneath the MHF packet
+// protocol, for listeners that should not expose the client's weak Blowfish
+// cipher to the open internet.
+//
+// A listener wrapped with [Listen] inspects the first byte a client sends:
+//
+//   - 0x16 starts a TLS handshake (Mode "tls").
+//   - "ERPT" starts a pre-shared key handshake (Mode "psk"), after which
+//     traffic is sealed with AES-256-GCM using keys derived from the PSK.
+//   - Anything else is a plain MHF client. Sign and entrance clients open
+//     with NULL bytes and channel packets with a crypt header byte whose low
+//     two bits are always set, so neither can be mistaken for the above.
+//
+// Plain clients are served unchanged unless the listener requires the
+// transport. Unmodified game clients reach a secured listener through a
+// local companion proxy (cmd/transportproxy) that dials with [Dial].
+package transport
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	cfg "erupe-ce/config"
+)
+
+// Transport modes.
+const (
+	ModeNone = ""
+	ModeTLS  = "tls"
+	ModePSK  = "psk"
+)
+
+// minPSKLength is the shortest accepted pre-shared key.
+const minPSKLength = 16
+
+// handshakeTimeout bounds how long a client may take to identify itself and
+// finish a TLS or PSK handshake.
+const handshakeTimeout = 10 * time.Second
+
+// tlsRecordHandshake is the first byte of a TLS ClientHello.
+const tlsRecordHandshake = 0x16
+
+// ErrPlainRejected is returned by reads and writes on a connection whose
+// client did not use the transport a listener requires.
+var ErrPlainRejected = errors.New("transport: plain connection rejected")
+
+// Listener accepts connections that may use the secure transport. The
+// handshake runs on the first Read or Write, so a slow client never blocks
+// Accept.
+type Listener struct {
+	net.Listener
+	required bool
+	tls      *tls.Config
+	psk      []byte
+}
+
+// Listen wraps l according to c. With no mode configured l is returned as
+// is, so the listener stays wire-compatible with every existing client.
+func Listen(l net.Listener, c cfg.Transport) (net.Listener, error) {
+	tl := &Listener{Listener: l, required: c.Required}
+	switch c.Mode {
+	case ModeNone, "none":
+		return l, nil
+	case ModeTLS:
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("transport: load TLS key pair: %w", err)
+		}
+		tl.tls = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	case ModePSK:
+		if len(c.PSK) < minPSKLength {
+			return nil, fmt.Errorf("transport: PSK must be at least %d characters", minPSKLength)
+		}
+		tl.psk = []byte(c.PSK)
+	default:
+		return nil, fmt.Errorf("transport: unknown mode %q", c.Mode)
+	}
+	return tl, nil
+}
+
+// Accept waits for the next connection. The returned *Conn identifies its
+// transport lazily.
+func (l *Listener) Accept() (net.Conn, error) {
+	raw, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: raw, l: l}, nil
+}
+
+// Conn is a server-side connection accepted by a Listener. Before the
+// handshake it delegates to the raw connection; afterwards reads and writes
+// go through the negotiated transport.
+type Conn struct {
+	net.Conn
+	l       *Listener
+	once    sync.Once
+	err     error
+	inner   net.Conn
+	secured bool
+}
+
+// handshake identifies the client's transport and completes its handshake.
+func (c *Conn) handshake() error {
+	c.once.Do(func() {
+		_ = c.Conn.SetDeadline(time.Now().Add(handshakeTimeout))
+		defer func() { _ = c.Conn.SetDeadline(time.Time{}) }()
+
+		br := bufio.NewReader(c.Conn)
+		first, err := br.Peek(1)
+		if err != nil {
+			c.err = err
+			return
+		}
+		pc := &peekedConn{Conn: c.Conn, r: br}
+		switch {
+		case first[0] == tlsRecordHandshake && c.l.tls != nil:
+			tc := tls.Server(pc, c.l.tls)
+			if err := tc.Handshake(); err != nil {
+				c.err = fmt.Errorf("transport: TLS handshake: %w", err)
+				return
+			}
+			c.inner, c.secured = tc, true
+		case first[0] == pskMagic[0] && c.l.psk != nil:
+			sc, err := pskServer(pc, c.l.psk)
+			if err != nil {
+				c.err = err
+				return
+			}
+			c.inner, c.secured = sc, true
+		case c.l.required:
+			c.err = ErrPlainRejected
+		default:
+			c.inner = pc
+		}
+	})
+	return c.err
+}
+
+// Read reads from the negotiated transport, running the handshake first.
+func (c *Conn) Read(b []byte) (int, error) {
+	if err := c.handshake(); err != nil {
+		return 0, err
+	}
+	return c.inner.Read(b)
+}
+
+// Write writes to the negotiated transport, running the handshake first.
+func (c *Conn) Write(b []byte) (int, error) {
+	if err := c.handshake(); err != nil {
+		return 0, err
+	}
+	return c.inner.Write(b)
+}
+
+// Secured reports whether the client completed a TLS or PSK handshake.
+func (c *Conn) Secured() bool {
+	return c.handshake() == nil && c.secured
+}
+
+// Secured reports whether conn was accepted over the secure transport.
+// Servers use it to advertise loopback addresses, which the client's
+// companion proxy forwards.
+func Secured(conn net.Conn) bool {
+	tc, ok := conn.(*Conn)
+	return ok && tc.Secured()
+}
+
+// peekedConn replays bytes buffered while identifying the transport.
+type peekedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (p *peekedConn) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+// ClientConfig configures the client side of the transport.
+type ClientConfig struct {
+	Mode string
+	// PSK is the pre-shared key for Mode "psk".
+	PSK string
+	// TLS configures Mode "tls". It must verify the server, for example with
+	// RootCAs holding the server's self-signed certificate.
+	TLS *tls.Config
+}
+
+// Dial connects to addr and performs the client handshake for c.Mode. With
+// no mode it returns a plain TCP connection.
+func Dial(addr string, c ClientConfig) (net.Conn, error) {
+	raw, err := net.DialTimeout("tcp", addr, handshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := Client(raw, c)
+	if err != nil {
+		_ = raw.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Client runs the client handshake over an established connection.
+func Client(raw net.Conn, c ClientConfig) (net.Conn, error) {
+	_ = raw.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer func() { _ = raw.SetDeadline(time.Time{}) }()
+	switch c.Mode {
+	case ModeNone, "none":
+		return raw, nil
+	case ModeTLS:
+		if c.TLS == nil {
+			return nil, errors.New("transport: TLS mode needs a TLS config")
+		}
+		tc := tls.Client(raw, c.TLS)
+		if err := tc.Handshake(); err != nil {
+			return nil, fmt.Errorf("transport: TLS handshake: %w", err)
+		}
+		return tc, nil
+	case ModePSK:
+		if len(c.PSK) < minPSKLength {
+			return nil, fmt.Errorf("transport: PSK must be at least %d characters", minPSKLength)
+		}
+		return pskClient(raw, []byte(c.PSK))
+	default:
+		return nil, fmt.Errorf("transport: unknown mode %q", c.Mode)
+	}
+}
@@ -0,0 +1,256 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cfg "erupe-ce/config"
+)
+
+const testPSK = "correct horse battery staple"
+
+// echoServer wraps a loopback listener with c and echoes every connection.
+// Each accepted connection's Secured result is sent on the returned channel.
+func echoServer(t *testing.T, c cfg.Transport) (string, <-chan bool) {
+	t.Helper()
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := Listen(raw, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	secured := make(chan bool, 4)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				buf := make([]byte, 64*1024)
+				n, err := conn.Read(buf)
+				secured <- Secured(conn)
+				if err != nil {
+					return
+				}
+				for {
+					if _, err := conn.Write(buf[:n]); err != nil {
+						return
+					}
+					if n, err = conn.Read(buf); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return raw.Addr().String(), secured
+}
+
+// roundTrip writes msg and reads it back.
+func roundTrip(t *testing.T, conn net.Conn, msg []byte) {
+	t.Helper()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(msg); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got := make([]byte, len(msg))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Fatalf("echo mismatch: got %d bytes, want %d", len(got), len(msg))
+	}
+}
+
+func TestListenNoModeReturnsListenerUnchanged(t *testing.T) {
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = raw.Close() }()
+	l, err := Listen(raw, cfg.Transport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l != raw {
+		t.Error("Listen with no mode should return the listener as is")
+	}
+}
+
+func TestListenRejectsBadConfig(t *testing.T) {
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = raw.Close() }()
+	for _, c := range []cfg.Transport{
+		{Mode: "rot13"},
+		{Mode: ModePSK, PSK: "short"},
+		{Mode: ModeTLS, CertFile: "missing.pem", KeyFile: "missing.key"},
+	} {
+		if _, err := Listen(raw, c); err == nil {
+			t.Errorf("Listen(%+v) succeeded, want error", c)
+		}
+	}
+}
+
+func TestPSKRoundTrip(t *testing.T) {
+	addr, secured := echoServer(t, cfg.Transport{Mode: ModePSK, PSK: testPSK})
+	conn, err := Dial(addr, ClientConfig{Mode: ModePSK, PSK: testPSK})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	roundTrip(t, conn, []byte{0, 0, 0, 0, 0, 0, 0, 0})
+	if !<-secured {
+		t.Error("server did not report the connection as secured")
+	}
+	// Larger than one record.
+	big := bytes.Repeat([]byte("mhf"), maxRecordSize)
+	roundTrip(t, conn, big)
+}
+
+func TestPSKWrongKeyRejected(t *testing.T) {
+	addr, _ := echoServer(t, cfg.Transport{Mode: ModePSK, PSK: testPSK})
+	_, err := Dial(addr, ClientConfig{Mode: ModePSK, PSK: "not the right key at all"})
+	if !errors.Is(err, errPSKAuth) {
+		t.Errorf("Dial with wrong PSK: err = %v, want %v", err, errPSKAuth)
+	}
+}
+
+func TestPSKTamperedRecordRejected(t *testing.T) {
+	client, server := net.Pipe()
+	defer func() { _ = client.Close() }()
+	defer func() { _ = server.Close() }()
+
+	serverConn := make(chan net.Conn, 1)
+	go func() {
+		sc, err := pskServer(server, []byte(testPSK))
+		if err != nil {
+			serverConn <- nil
+			return
+		}
+		serverConn <- sc
+	}()
+	cc, err := pskClient(client, []byte(testPSK))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := <-serverConn
+	if sc == nil {
+		t.Fatal("server handshake failed")
+	}
+
+	// Seal a record, flip a ciphertext bit and send it raw.
+	pc := cc.(*pskConn)
+	record := pc.write.Seal(nil, seqNonce(pc.write, 0), []byte("hello"), []byte{0, 21})
+	record[3] ^= 1
+	go func() { _, _ = client.Write(append([]byte{0, 21}, record...)) }()
+	if _, err := sc.Read(make([]byte, 16)); !errors.Is(err, errPSKAuth) {
+		t.Errorf("Read of tampered record: err = %v, want %v", err, errPSKAuth)
+	}
+}
+
+func TestTLSRoundTrip(t *testing.T) {
+	certFile, keyFile, pool := selfSigned(t)
+	addr, secured := echoServer(t, cfg.Transport{Mode: ModeTLS, CertFile: certFile, KeyFile: keyFile})
+	conn, err := Dial(addr, ClientConfig{Mode: ModeTLS, TLS: &tls.Config{RootCAs: pool, ServerName: "localhost"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	roundTrip(t, conn, []byte("channel packet"))
+	if !<-secured {
+		t.Error("server did not report the connection as secured")
+	}
+}
+
+func TestPlainClientAcceptedWhenOptional(t *testing.T) {
+	addr, secured := echoServer(t, cfg.Transport{Mode: ModePSK, PSK: testPSK})
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	// Sign and entrance clients open with 8 NULL bytes.
+	roundTrip(t, conn, make([]byte, 8))
+	if <-secured {
+		t.Error("plain connection reported as secured")
+	}
+}
+
+func TestPlainClientRejectedWhenRequired(t *testing.T) {
+	addr, _ := echoServer(t, cfg.Transport{Mode: ModePSK, PSK: testPSK, Required: true})
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(make([]byte, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("required listener answered a plain client")
+	}
+}
+
+// selfSigned writes a certificate for localhost and returns its files and a
+// pool trusting it.
+func selfSigned(t *testing.T) (certFile, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pool = x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+	return certFile, keyFile, pool
+}
@@ -2,7 +2,9 @@ package api
 
 import (
 	"context"
+	"erupe-ce/common/guildicon"
 	cfg "erupe-ce/config"
+	"erupe-ce/network/pcap"
 	"fmt"
 	"net/http"
 	"os"
@@ -20,6 +22,30 @@ type Config struct {
 	Logger      *zap.Logger
 	DB          *sqlx.DB
 	ErupeConfig *cfg.Config
+	PacketHub   *pcap.Hub    // Live packets of channel sessions; nil disables /capture/live
+	Guilds      GuildActions // Guild workflows shared with the game; nil disables /guild/apply, /guild/rank and /guild/alliance/*
+}
+
+// GuildActions changes guilds through the same service code as the channel
+// server, so the API applies the same rules, audit log entries and mail as
+// the game. channelserver.GuildOps implements it. Errors the caller can act
+// on wrap mhfguild.ErrForbidden, mhfguild.ErrNotFound or mhfguild.ErrConflict.
+type GuildActions interface {
+	// ForceRank pins a guild to a rank, or returns it to its RP rank when
+	// rank is nil.
+	ForceRank(guildID uint32, rank *uint16) error
+	// ApplyToGuild files a character's application to a guild.
+	ApplyToGuild(charID, guildID uint32) error
+	// ApplyToAlliance files an application from the guild led by charID to
+	// join an alliance.
+	ApplyToAlliance(charID, guildID, allianceID uint32) error
+	// WithdrawAllianceApplication withdraws that application again.
+	WithdrawAllianceApplication(charID, guildID, allianceID uint32) error
+	// AnswerAllianceApplication accepts or declines a guild's application
+	// to the alliance whose parent guild charID leads.
+	AnswerAllianceApplication(charID, allianceID, guildID uint32, accept bool) error
+	// SetAllianceRecruiting opens or closes that alliance to applications.
+	SetAllianceRecruiting(charID, allianceID uint32, recruiting bool) error
 }
 
 // APIServer is Erupes Standard API interface
@@ -31,6 +57,12 @@ type APIServer struct {
 	userRepo       APIUserRepo
 	charRepo       APICharacterRepo
 	sessionRepo    APISessionRepo
+	announceRepo   APIAnnouncementRepo
+	chatRepo       APIChatRepo
+	guildRepo      APIGuildRepo
+	guildIcons     *guildicon.Renderer
+	packetHub      *pcap.Hub
+	guilds         GuildActions
 	httpServer     *http.Server
 	isShuttingDown bool
 }
@@ -41,12 +73,21 @@ func NewAPIServer(config *Config) *APIServer {
 		logger:      config.Logger,
 		db:          config.DB,
 		erupeConfig: config.ErupeConfig,
+		packetHub:   config.PacketHub,
+		guilds:      config.Guilds,
 		httpServer:  &http.Server{},
 	}
+	if config.ErupeConfig != nil {
+		icons := config.ErupeConfig.Guild.Icons
+		s.guildIcons = guildicon.NewRenderer(icons.SpriteDir, icons.Size, icons.CacheSize)
+	}
 	if config.DB != nil {
 		s.userRepo = NewAPIUserRepository(config.DB)
 		s.charRepo = NewAPICharacterRepository(config.DB)
 		s.sessionRepo = NewAPISessionRepository(config.DB)
+		s.announceRepo = NewAPIAnnouncementRepository(config.DB)
+		s.chatRepo = NewAPIChatRepository(config.DB)
+		s.guildRepo = NewAPIGuildRepository(config.DB)
 	}
 	return s
 }
@@ -61,6 +102,27 @@ func (s *APIServer) Start() error {
 	r.HandleFunc("/character/create", s.CreateCharacter)
 	r.HandleFunc("/character/delete", s.DeleteCharacter)
 	r.HandleFunc("/character/export", s.ExportSave)
+	r.HandleFunc("/character/language", s.SetLanguage)
+	r.HandleFunc("/announcement/list", s.ListAnnouncements)
+	r.HandleFunc("/announcement/create", s.CreateAnnouncement)
+	r.HandleFunc("/announcement/delete", s.DeleteAnnouncement)
+	r.HandleFunc("/chat/search", s.SearchChat)
+	r.HandleFunc("/guild/log", s.GuildLog)
+	r.HandleFunc("/guild/items/log", s.GuildItemLog)
+	r.HandleFunc("/guild/list", s.ListGuilds)
+	if s.guilds != nil {
+		r.HandleFunc("/guild/apply", s.ApplyToGuild)
+		r.HandleFunc("/guild/rank", s.SetGuildRank)
+		r.HandleFunc("/guild/alliance/apply", s.ApplyToAlliance)
+		r.HandleFunc("/guild/alliance/withdraw", s.WithdrawAllianceApplication)
+		r.HandleFunc("/guild/alliance/answer", s.AnswerAllianceApplication)
+		r.HandleFunc("/guild/alliance/recruiting", s.SetAllianceRecruiting)
+	}
+	r.HandleFunc("/guild/{id}/icon.png", s.GuildIcon)
+	if s.packetHub != nil {
+		r.HandleFunc("/capture/live", s.LiveCapture)
+		r.HandleFunc("/capture/inspector", s.CaptureInspector)
+	}
 	r.HandleFunc("/api/ss/bbs/upload.php", s.ScreenShot)
 	r.HandleFunc("/api/ss/bbs/{id}", s.ScreenShotGet)
 	r.HandleFunc("/", s.LandingPage)
@@ -0,0 +1,170 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"erupe-ce/network"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+//go:embed capture_inspector.html
+var captureInspectorHTML []byte
+
+// liveCaptureBuffer is the number of packets held for a slow websocket client
+// before packets are dropped.
+const liveCaptureBuffer = 256
+
+// liveCaptureWriteTimeout bounds each websocket write, so a stalled client
+// cannot pin the stream open.
+const liveCaptureWriteTimeout = 10 * time.Second
+
+// liveCaptureProtocol is the websocket subprotocol of /capture/live.
+// Browsers cannot set headers on a websocket, so they pass the operator token
+// as a second subprotocol prefixed with liveCaptureTokenPrefix.
+const (
+	liveCaptureProtocol    = "erupe-capture"
+	liveCaptureTokenPrefix = "token."
+)
+
+var liveCaptureUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	Subprotocols:    []string{liveCaptureProtocol},
+}
+
+// LivePacket is a packet streamed by /capture/live.
+type LivePacket struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Direction  string    `json:"direction"`
+	Opcode     uint16    `json:"opcode"`
+	OpcodeName string    `json:"opcodeName"`
+	Payload    string    `json:"payload"` // Hex-encoded, including the opcode
+	Dropped    uint64    `json:"dropped"` // Packets skipped so far because the client fell behind
+}
+
+// LiveCapture handles GET /capture/live, upgrading to a websocket that streams
+// the packets of one character's channel session as they are sent and
+// received. The operator login token is read from an "Authorization: Bearer"
+// header or a "token."-prefixed subprotocol, never the query string, so it
+// stays out of access logs. Query parameters: charID, and an optional
+// comma-separated opcodes filter of names or 0x-prefixed numbers.
+func (s *APIServer) LiveCapture(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	token := liveCaptureToken(r)
+	if token == "" {
+		w.WriteHeader(401)
+		return
+	}
+	if _, ok := s.opFromToken(r.Context(), w, token); !ok {
+		return
+	}
+	charID, err := strconv.ParseUint(q.Get("charID"), 10, 32)
+	if err != nil || charID == 0 {
+		http.Error(w, "invalid charID", http.StatusBadRequest)
+		return
+	}
+	opcodes, err := parseOpcodeList(q.Get("opcodes"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := liveCaptureUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Warn("Live capture upgrade failed", zap.Error(err))
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	sub := s.packetHub.Subscribe(uint32(charID), opcodes, liveCaptureBuffer)
+	defer sub.Close()
+	s.logger.Info("Live capture started", zap.Uint64("charID", charID), zap.String("remote", r.RemoteAddr))
+
+	// The client sends nothing; reading detects when it goes away.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			s.logger.Info("Live capture stopped", zap.Uint64("charID", charID))
+			return
+		case rec, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(liveCaptureWriteTimeout))
+			if err := conn.WriteJSON(LivePacket{
+				Timestamp:  time.Unix(0, rec.TimestampNs),
+				Direction:  rec.Direction.String(),
+				Opcode:     rec.Opcode,
+				OpcodeName: network.PacketID(rec.Opcode).String(),
+				Payload:    hex.EncodeToString(rec.Payload),
+				Dropped:    sub.Dropped(),
+			}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// liveCaptureToken returns the operator token of a /capture/live request.
+func liveCaptureToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	for _, protocol := range websocket.Subprotocols(r) {
+		if strings.HasPrefix(protocol, liveCaptureTokenPrefix) {
+			return strings.TrimPrefix(protocol, liveCaptureTokenPrefix)
+		}
+	}
+	return ""
+}
+
+// CaptureInspector handles GET /capture/inspector, serving a page that
+// connects to /capture/live and lists the streamed packets.
+func (s *APIServer) CaptureInspector(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(captureInspectorHTML)
+}
+
+// parseOpcodeList parses a comma-separated list of packet names or
+// 0x-prefixed opcode numbers.
+func parseOpcodeList(list string) ([]uint16, error) {
+	var opcodes []uint16
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if strings.HasPrefix(name, "0x") || strings.HasPrefix(name, "0X") {
+			v, err := strconv.ParseUint(name[2:], 16, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid opcode %q", name)
+			}
+			opcodes = append(opcodes, uint16(v))
+			continue
+		}
+		id, ok := network.PacketIDFromString(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown opcode %q", name)
+		}
+		opcodes = append(opcodes, uint16(id))
+	}
+	return opcodes, nil
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"erupe-ce/network"
+	"erupe-ce/network/pcap"
+
+	"github.com/gorilla/websocket"
+)
+
+func newLiveCaptureServer(t *testing.T, op bool) (*APIServer, *httptest.Server) {
+	t.Helper()
+	s := &APIServer{
+		logger:      NewTestLogger(t),
+		erupeConfig: NewTestConfig(),
+		userRepo:    &mockAPIUserRepo{isOp: op},
+		sessionRepo: &mockAPISessionRepo{userID: 1},
+		packetHub:   pcap.NewHub(),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(s.LiveCapture))
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// getLiveCapture requests /capture/live with the operator token in an
+// Authorization header.
+func getLiveCapture(t *testing.T, url, token string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	return resp
+}
+
+func TestLiveCaptureRejectsNonOp(t *testing.T) {
+	_, ts := newLiveCaptureServer(t, false)
+	resp := getLiveCapture(t, ts.URL+"?charID=5", "t")
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestLiveCaptureIgnoresQueryToken(t *testing.T) {
+	s, ts := newLiveCaptureServer(t, true)
+	resp := getLiveCapture(t, ts.URL+"?token=t&charID=5", "")
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if got := s.sessionRepo.(*mockAPISessionRepo).gotToken; got != "" {
+		t.Errorf("looked up query token %q", got)
+	}
+}
+
+func TestLiveCaptureBadParams(t *testing.T) {
+	_, ts := newLiveCaptureServer(t, true)
+	for _, query := range []string{"", "?charID=x", "?charID=5&opcodes=MSG_NOT_REAL"} {
+		resp := getLiveCapture(t, ts.URL+query, "t")
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want %d", query, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestLiveCaptureToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{"bearer header", http.Header{"Authorization": {"Bearer abc"}}, "abc"},
+		{"subprotocol", http.Header{"Sec-Websocket-Protocol": {"erupe-capture, token.abc"}}, "abc"},
+		{"other scheme", http.Header{"Authorization": {"Basic abc"}}, ""},
+		{"none", http.Header{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/capture/live?token=query", nil)
+			r.Header = tt.header
+			if got := liveCaptureToken(r); got != tt.want {
+				t.Errorf("liveCaptureToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLiveCaptureStreamsPackets(t *testing.T) {
+	s, ts := newLiveCaptureServer(t, true)
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "?charID=5&opcodes=MSG_SYS_ACK"
+	dialer := websocket.Dialer{Subprotocols: []string{liveCaptureProtocol, liveCaptureTokenPrefix + "t"}}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if conn.Subprotocol() != liveCaptureProtocol {
+		t.Errorf("subprotocol = %q, want %q", conn.Subprotocol(), liveCaptureProtocol)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !s.packetHub.Watching(5) {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription not registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	s.packetHub.Publish(5, pcap.PacketRecord{Direction: pcap.DirClientToServer, Opcode: uint16(network.MSG_SYS_PING), Payload: []byte{0x00, 0x17}})
+	s.packetHub.Publish(5, pcap.PacketRecord{Direction: pcap.DirServerToClient, Opcode: uint16(network.MSG_SYS_ACK), Payload: []byte{0x00, 0x12, 0xAB}})
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var pkt LivePacket
+	if err := conn.ReadJSON(&pkt); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	if pkt.OpcodeName != "MSG_SYS_ACK" || pkt.Direction != "S→C" || pkt.Payload != "0012ab" {
+		t.Errorf("packet = %+v, want the filtered MSG_SYS_ACK", pkt)
+	}
+
+	_ = conn.Close()
+	deadline = time.Now().Add(2 * time.Second)
+	for s.packetHub.Watching(5) {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription not closed after disconnect")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestParseOpcodeList(t *testing.T) {
+	got, err := parseOpcodeList("msg_sys_ping, 0x0012,")
+	if err != nil || len(got) != 2 || got[0] != uint16(network.MSG_SYS_PING) || got[1] != 0x12 {
+		t.Errorf("parseOpcodeList = %v, %v", got, err)
+	}
+	if _, err := parseOpcodeList("0xZZ"); err == nil {
+		t.Error("expected error for invalid number")
+	}
+}
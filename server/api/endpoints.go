@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"errors"
+	"erupe-ce/common/announcement"
 	"erupe-ce/common/gametime"
+	"erupe-ce/common/guildicon"
+	"erupe-ce/common/mhfguild"
 	cfg "erupe-ce/config"
 	"fmt"
 	"image"
@@ -16,6 +19,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -59,6 +64,120 @@ type Character struct {
 	LastLogin int32  `json:"lastLogin" db:"last_login"`
 }
 
+// Announcement is a scheduled server-wide chat message, worldcast by the
+// channel servers. See the announcements migration for the meaning of each
+// kind.
+type Announcement struct {
+	ID         uint32        `json:"id"`
+	Kind       string        `json:"kind"`
+	Message    string        `json:"message"`
+	SendAt     *time.Time    `json:"sendAt,omitempty" db:"send_at"`
+	Schedule   string        `json:"schedule,omitempty"`
+	Countdown  pq.Int64Array `json:"countdown,omitempty"`
+	Enabled    bool          `json:"enabled"`
+	LastSentAt *time.Time    `json:"lastSentAt,omitempty" db:"last_sent_at"`
+	CreatedBy  string        `json:"createdBy" db:"created_by"`
+}
+
+// ChatLog is a recorded chat message. Message is the text as sent; FilterAction
+// records whether the chat filter masked or dropped it.
+type ChatLog struct {
+	ID           uint64        `json:"id"`
+	CharID       uint32        `json:"charId" db:"char_id"`
+	SenderName   string        `json:"senderName" db:"sender_name"`
+	ChatType     string        `json:"chatType" db:"chat_type"`
+	ChannelID    uint16        `json:"channelId" db:"channel_id"`
+	StageID      string        `json:"stageId" db:"stage_id"`
+	Targets      pq.Int64Array `json:"targets,omitempty"`
+	Message      string        `json:"message"`
+	FilterAction string        `json:"filterAction,omitempty" db:"filter_action"`
+	CreatedAt    time.Time     `json:"createdAt" db:"created_at"`
+}
+
+// ChatLogQuery filters a chat log search. Zero values match everything.
+type ChatLogQuery struct {
+	CharID    uint32     `json:"charId"`
+	Name      string     `json:"name"`     // Substring of the sender name
+	ChatType  string     `json:"chatType"` // world, stage, guild, alliance, party or whisper
+	ChannelID uint16     `json:"channelId"`
+	StageID   string     `json:"stageId"`
+	Query     string     `json:"query"` // Substring of the message
+	Since     *time.Time `json:"since"`
+	Until     *time.Time `json:"until"`
+	Limit     int        `json:"limit"`
+}
+
+// Chat search result limits.
+const (
+	chatSearchDefaultLimit = 100
+	chatSearchMaxLimit     = 1000
+)
+
+// GuildEvent is one guild audit log entry. TargetCharID is the character the
+// action was applied to, if any, and TargetGuildID the guild, for alliance
+// actions.
+type GuildEvent struct {
+	ID              uint64    `json:"id"`
+	ActorID         uint32    `json:"actorId" db:"actor_id"`
+	ActorName       string    `json:"actorName" db:"actor_name"`
+	TargetCharID    uint32    `json:"targetCharId,omitempty" db:"target_char_id"`
+	TargetCharName  string    `json:"targetCharName,omitempty" db:"target_char_name"`
+	TargetGuildID   uint32    `json:"targetGuildId,omitempty" db:"target_guild_id"`
+	TargetGuildName string    `json:"targetGuildName,omitempty" db:"target_guild_name"`
+	Action          string    `json:"action"`
+	Detail          string    `json:"detail,omitempty"`
+	CreatedAt       time.Time `json:"createdAt" db:"created_at"`
+}
+
+// GuildItemTransaction is one deposit to (positive quantity) or withdrawal
+// from (negative quantity) a guild item box.
+type GuildItemTransaction struct {
+	ID        uint64    `json:"id"`
+	CharID    uint32    `json:"charId" db:"character_id"`
+	CharName  string    `json:"charName" db:"char_name"`
+	ItemID    uint16    `json:"itemId" db:"item_id"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Guild audit log and item history result limits.
+const (
+	guildLogDefaultLimit = 100
+	guildLogMaxLimit     = 1000
+)
+
+// GuildListing is a guild as shown in the public guild list. Rank and
+// MemberLimit are derived from the guild's rank RP.
+type GuildListing struct {
+	ID          uint32           `json:"id"`
+	Name        string           `json:"name"`
+	LeaderID    uint32           `json:"leaderId" db:"leader_id"`
+	LeaderName  string           `json:"leaderName" db:"leader_name"`
+	RankRP      uint32           `json:"-" db:"rank_rp"`
+	ForcedRank  *uint16          `json:"forcedRank" db:"forced_rank"`
+	Rank        uint16           `json:"rank" db:"-"`
+	MemberCount int              `json:"memberCount" db:"member_count"`
+	MemberLimit uint8            `json:"memberLimit" db:"-"`
+	Recruiting  bool             `json:"recruiting"`
+	Comment     string           `json:"comment"`
+	IconData    []byte           `json:"-" db:"icon"`
+	Icon        []guildicon.Part `json:"icon" db:"-"`
+}
+
+// GuildListQuery filters the public guild list.
+type GuildListQuery struct {
+	Name       string // Substring of the guild name, case-insensitive
+	Recruiting bool   // Only guilds accepting applications
+	Limit      int
+	Offset     int
+}
+
+// Guild list result limits.
+const (
+	guildListDefaultLimit = 50
+	guildListMaxLimit     = 200
+)
+
 // MezFes represents the current Mezeporta Festival event schedule and ticket configuration.
 type MezFes struct {
 	ID           uint32   `json:"id"`
@@ -330,6 +449,545 @@ func (s *APIServer) ExportSave(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(save)
 }
 
+// languagePattern matches locale codes, i.e. locale file names without their
+// extension. An empty code resets the character to the server default.
+var languagePattern = regexp.MustCompile(`^[a-z0-9_-]{0,16}$`)
+
+// SetLanguage handles POST /character/language, setting the language the
+// channel server uses for a character's server messages. Unknown codes fall
+// back to the server default in game. Takes effect on next login.
+func (s *APIServer) SetLanguage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var reqData struct {
+		Token    string `json:"token"`
+		CharID   uint32 `json:"charId"`
+		Language string `json:"language"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		s.logger.Error("JSON decode error", zap.Error(err))
+		w.WriteHeader(400)
+		return
+	}
+	language := strings.ToLower(reqData.Language)
+	if !languagePattern.MatchString(language) {
+		w.WriteHeader(400)
+		return
+	}
+	userID, err := s.userIDFromToken(ctx, reqData.Token)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	if err := s.charRepo.SetLanguage(ctx, userID, reqData.CharID, language); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(404)
+			return
+		}
+		s.logger.Error("Failed to set character language", zap.Error(err), zap.Uint32("charID", reqData.CharID))
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct{}{})
+}
+
+// opFromToken resolves a session token to an operator's user ID, writing a
+// 401 or 403 response and returning false if the token is invalid or the
+// user is not an operator.
+func (s *APIServer) opFromToken(ctx context.Context, w http.ResponseWriter, token string) (uint32, bool) {
+	userID, err := s.userIDFromToken(ctx, token)
+	if err != nil {
+		w.WriteHeader(401)
+		return 0, false
+	}
+	op, err := s.userRepo.IsOp(userID)
+	if err != nil || !op {
+		w.WriteHeader(403)
+		return 0, false
+	}
+	return userID, true
+}
+
+// ListAnnouncements handles POST /announcement/list, returning every
+// scheduled announcement. Operators only.
+func (s *APIServer) ListAnnouncements(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var reqData struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		s.logger.Error("JSON decode error", zap.Error(err))
+		w.WriteHeader(400)
+		return
+	}
+	if _, ok := s.opFromToken(ctx, w, reqData.Token); !ok {
+		return
+	}
+	announcements, err := s.announceRepo.List(ctx)
+	if err != nil {
+		s.logger.Error("Failed to list announcements", zap.Error(err))
+		w.WriteHeader(500)
+		return
+	}
+	if announcements == nil {
+		announcements = []Announcement{}
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(announcements)
+}
+
+// CreateAnnouncement handles POST /announcement/create, scheduling a once,
+// recurring (cron) or countdown announcement. Operators only.
+func (s *APIServer) CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var reqData struct {
+		Token string `json:"token"`
+		Announcement
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		s.logger.Error("JSON decode error", zap.Error(err))
+		w.WriteHeader(400)
+		return
+	}
+	userID, ok := s.opFromToken(ctx, w, reqData.Token)
+	if !ok {
+		return
+	}
+	a := reqData.Announcement
+	if err := announcement.Validate(a.Kind, a.Message, a.SendAt, a.Schedule, a.Countdown); err != nil {
+		w.WriteHeader(400)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	a.Enabled = true
+	a.CreatedBy = fmt.Sprintf("user:%d", userID)
+	id, err := s.announceRepo.Create(ctx, a)
+	if err != nil {
+		s.logger.Error("Failed to create announcement", zap.Error(err))
+		w.WriteHeader(500)
+		return
+	}
+	s.logger.Info("Announcement created", zap.Uint32("id", id), zap.Uint32("userID", userID))
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		ID uint32 `json:"id"`
+	}{id})
+}
+
+// DeleteAnnouncement handles POST /announcement/delete. Operators only.
+func (s *APIServer) DeleteAnnouncement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var reqData struct {
+		Token string `json:"token"`
+		ID    uint32 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		s.logger.Error("JSON decode error", zap.Error(err))
+		w.WriteHeader(400)
+		return
+	}
+	if _, ok := s.opFromToken(ctx, w, reqData.Token); !ok {
+		return
+	}
+	if err := s.announceRepo.Delete(ctx, reqData.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(404)
+			return
+		}
+		s.logger.Error("Failed to delete announcement", zap.Error(err))
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct{}{})
+}
+
+// SearchChat handles POST /chat/search, returning recorded chat messages
+// matching the query, newest first. Operators only.
+func (s *APIServer) SearchChat(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var reqData struct {
+		Token string `json:"token"`
+		ChatLogQuery
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		s.logger.Error("JSON decode error", zap.Error(err))
+		w.WriteHeader(400)
+		return
+	}
+	if _, ok := s.opFromToken(ctx, w, reqData.Token); !ok {
+		return
+	}
+	q := reqData.ChatLogQuery
+	if q.Limit <= 0 {
+		q.Limit = chatSearchDefaultLimit
+	} else if q.Limit > chatSearchMaxLimit {
+		q.Limit = chatSearchMaxLimit
+	}
+	logs, err := s.chatRepo.Search(ctx, q)
+	if err != nil {
+		s.logger.Error("Failed to search chat logs", zap.Error(err))
+		w.WriteHeader(500)
+		return
+	}
+	if logs == nil {
+		logs = []ChatLog{}
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(logs)
+}
+
+// guildLogRequest is the request body of the guild leader log endpoints.
+type guildLogRequest struct {
+	Token  string     `json:"token"`
+	CharID uint32     `json:"charId"`
+	Before *time.Time `json:"before"`
+	Limit  int        `json:"limit"`
+}
+
+// limit returns the requested page size clamped to the guild log limits.
+func (q guildLogRequest) limit() int {
+	if q.Limit <= 0 {
+		return guildLogDefaultLimit
+	} else if q.Limit > guildLogMaxLimit {
+		return guildLogMaxLimit
+	}
+	return q.Limit
+}
+
+// ledGuildFromRequest decodes a guild log request and resolves the guild led
+// by its character, writing a 400, 401, 403 or 500 response and returning
+// false if the request is invalid or the character leads no guild.
+func (s *APIServer) ledGuildFromRequest(w http.ResponseWriter, r *http.Request) (guildLogRequest, uint32, bool) {
+	var reqData guildLogRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		s.logger.Error("JSON decode error", zap.Error(err))
+		w.WriteHeader(400)
+		return reqData, 0, false
+	}
+	guildID, ok := s.ledGuild(r.Context(), w, reqData.Token, reqData.CharID)
+	return reqData, guildID, ok
+}
+
+// ledGuild resolves the guild led by one of the token's user's characters,
+// writing a 401, 403 or 500 response and returning false if the token is
+// invalid or the character leads no guild.
+func (s *APIServer) ledGuild(ctx context.Context, w http.ResponseWriter, token string, charID uint32) (uint32, bool) {
+	userID, err := s.userIDFromToken(ctx, token)
+	if err != nil {
+		w.WriteHeader(401)
+		return 0, false
+	}
+	guildID, err := s.guildRepo.LedGuild(ctx, userID, charID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(403)
+			return 0, false
+		}
+		s.logger.Error("Failed to look up led guild", zap.Error(err), zap.Uint32("charID", charID))
+		w.WriteHeader(500)
+		return 0, false
+	}
+	return guildID, true
+}
+
+// GuildLog handles POST /guild/log, returning the audit log of the guild led
+// by the given character, newest first. Only the guild leader may read it.
+func (s *APIServer) GuildLog(w http.ResponseWriter, r *http.Request) {
+	reqData, guildID, ok := s.ledGuildFromRequest(w, r)
+	if !ok {
+		return
+	}
+	events, err := s.guildRepo.ListEvents(r.Context(), guildID, reqData.Before, reqData.limit())
+	if err != nil {
+		s.logger.Error("Failed to list guild audit log", zap.Error(err), zap.Uint32("guildID", guildID))
+		w.WriteHeader(500)
+		return
+	}
+	if events == nil {
+		events = []GuildEvent{}
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(events)
+}
+
+// GuildItemLog handles POST /guild/items/log, returning the deposits to and
+// withdrawals from the item box of the guild led by the given character,
+// newest first. Only the guild leader may read it.
+func (s *APIServer) GuildItemLog(w http.ResponseWriter, r *http.Request) {
+	reqData, guildID, ok := s.ledGuildFromRequest(w, r)
+	if !ok {
+		return
+	}
+	txs, err := s.guildRepo.ListItemTransactions(r.Context(), guildID, reqData.Before, reqData.limit())
+	if err != nil {
+		s.logger.Error("Failed to list guild item transactions", zap.Error(err), zap.Uint32("guildID", guildID))
+		w.WriteHeader(500)
+		return
+	}
+	if txs == nil {
+		txs = []GuildItemTransaction{}
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(txs)
+}
+
+// fillGuildListing derives a listing's rank, member limit and icon parts from
+// its stored rank RP and icon.
+func (s *APIServer) fillGuildListing(g *GuildListing) {
+	table := mhfguild.ConfiguredTable(s.erupeConfig)
+	if g.ForcedRank != nil {
+		g.Rank = table.Clamp(*g.ForcedRank, s.erupeConfig.RealClientMode)
+	} else {
+		g.Rank = table.Rank(g.RankRP, s.erupeConfig.RealClientMode)
+	}
+	g.MemberLimit = table.MemberLimit(g.Rank)
+	g.Icon = []guildicon.Part{}
+	if icon, err := guildicon.Decode(g.IconData); err != nil {
+		s.logger.Warn("Failed to decode guild icon", zap.Error(err), zap.Uint32("guildID", g.ID))
+	} else if icon.Parts != nil {
+		g.Icon = icon.Parts
+	}
+}
+
+// ListGuilds handles GET /guild/list, the public guild list. Optional query
+// parameters: name (substring), recruiting (true for guilds accepting
+// applications only), limit and offset.
+func (s *APIServer) ListGuilds(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := GuildListQuery{Name: query.Get("name"), Limit: guildListDefaultLimit}
+	q.Recruiting, _ = strconv.ParseBool(query.Get("recruiting"))
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 {
+		q.Limit = min(limit, guildListMaxLimit)
+	}
+	if offset, err := strconv.Atoi(query.Get("offset")); err == nil && offset > 0 {
+		q.Offset = offset
+	}
+	guilds, err := s.guildRepo.ListGuilds(r.Context(), q)
+	if err != nil {
+		s.logger.Error("Failed to list guilds", zap.Error(err))
+		w.WriteHeader(500)
+		return
+	}
+	if guilds == nil {
+		guilds = []GuildListing{}
+	}
+	for i := range guilds {
+		s.fillGuildListing(&guilds[i])
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(guilds)
+}
+
+// ApplyToGuild handles POST /guild/apply, filing an application from one of
+// the user's characters to a guild through the same checks as applying in
+// game. The guild leader answers it in game like any other application.
+func (s *APIServer) ApplyToGuild(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var reqData struct {
+		Token   string `json:"token"`
+		CharID  uint32 `json:"charId"`
+		GuildID uint32 `json:"guildId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		s.logger.Error("JSON decode error", zap.Error(err))
+		w.WriteHeader(400)
+		return
+	}
+	userID, err := s.userIDFromToken(ctx, reqData.Token)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	characters, err := s.charRepo.GetForUser(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get characters", zap.Error(err), zap.Uint32("userID", userID))
+		w.WriteHeader(500)
+		return
+	}
+	if !slices.ContainsFunc(characters, func(c Character) bool { return c.ID == reqData.CharID }) {
+		w.WriteHeader(404)
+		return
+	}
+	if err := s.guilds.ApplyToGuild(reqData.CharID, reqData.GuildID); err != nil {
+		s.writeGuildActionError(w, err, "Failed to apply to guild",
+			zap.Uint32("charID", reqData.CharID), zap.Uint32("guildID", reqData.GuildID))
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct{}{})
+}
+
+// writeGuildActionError answers a failed GuildActions call with the status
+// that matches the error's mhfguild kind, logging errors of no known kind.
+func (s *APIServer) writeGuildActionError(w http.ResponseWriter, err error, msg string, fields ...zap.Field) {
+	switch {
+	case errors.Is(err, mhfguild.ErrForbidden):
+		w.WriteHeader(403)
+	case errors.Is(err, mhfguild.ErrNotFound):
+		w.WriteHeader(404)
+	case errors.Is(err, mhfguild.ErrConflict):
+		w.WriteHeader(409)
+	default:
+		s.logger.Error(msg, append(fields, zap.Error(err))...)
+		w.WriteHeader(500)
+	}
+}
+
+// SetGuildRank handles POST /guild/rank, pinning a guild to a rank regardless
+// of its rank RP, or returning it to its RP rank when rank is null. Members
+// are mailed and the audit log updated if the shown rank changes, as in game.
+// Operators only.
+func (s *APIServer) SetGuildRank(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var reqData struct {
+		Token   string  `json:"token"`
+		GuildID uint32  `json:"guildId"`
+		Rank    *uint16 `json:"rank"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		s.logger.Error("JSON decode error", zap.Error(err))
+		w.WriteHeader(400)
+		return
+	}
+	if _, ok := s.opFromToken(ctx, w, reqData.Token); !ok {
+		return
+	}
+	guild, err := s.guildRepo.GetGuild(ctx, reqData.GuildID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(404)
+			return
+		}
+		s.logger.Error("Failed to get guild", zap.Error(err), zap.Uint32("guildID", reqData.GuildID))
+		w.WriteHeader(500)
+		return
+	}
+	if err := s.guilds.ForceRank(guild.ID, reqData.Rank); err != nil {
+		s.logger.Error("Failed to set guild rank", zap.Error(err), zap.Uint32("guildID", guild.ID))
+		w.WriteHeader(500)
+		return
+	}
+	if guild, err = s.guildRepo.GetGuild(ctx, guild.ID); err != nil {
+		s.logger.Error("Failed to get guild", zap.Error(err), zap.Uint32("guildID", reqData.GuildID))
+		w.WriteHeader(500)
+		return
+	}
+	s.fillGuildListing(&guild)
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(guild)
+}
+
+// allianceRequest is the request body of the alliance endpoints. CharID must
+// be one of the user's characters and lead a guild.
+type allianceRequest struct {
+	Token      string `json:"token"`
+	CharID     uint32 `json:"charId"`
+	AllianceID uint32 `json:"allianceId"`
+	GuildID    uint32 `json:"guildId"`
+	Accept     bool   `json:"accept"`
+	Recruiting bool   `json:"recruiting"`
+}
+
+// allianceAction decodes an alliance request, resolves the guild its
+// character leads and runs action, answering errors as
+// writeGuildActionError does.
+func (s *APIServer) allianceAction(w http.ResponseWriter, r *http.Request, action func(req allianceRequest, guildID uint32) error) {
+	var reqData allianceRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		s.logger.Error("JSON decode error", zap.Error(err))
+		w.WriteHeader(400)
+		return
+	}
+	guildID, ok := s.ledGuild(r.Context(), w, reqData.Token, reqData.CharID)
+	if !ok {
+		return
+	}
+	if err := action(reqData, guildID); err != nil {
+		s.writeGuildActionError(w, err, "Failed to update alliance",
+			zap.Uint32("charID", reqData.CharID), zap.Uint32("allianceID", reqData.AllianceID))
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct{}{})
+}
+
+// ApplyToAlliance handles POST /guild/alliance/apply, filing an application
+// from the character's guild to join an alliance. The alliance must be
+// recruiting and have a free slot; its parent guild leader is mailed.
+func (s *APIServer) ApplyToAlliance(w http.ResponseWriter, r *http.Request) {
+	s.allianceAction(w, r, func(req allianceRequest, guildID uint32) error {
+		return s.guilds.ApplyToAlliance(req.CharID, guildID, req.AllianceID)
+	})
+}
+
+// WithdrawAllianceApplication handles POST /guild/alliance/withdraw, removing
+// the character's guild's pending application to an alliance.
+func (s *APIServer) WithdrawAllianceApplication(w http.ResponseWriter, r *http.Request) {
+	s.allianceAction(w, r, func(req allianceRequest, guildID uint32) error {
+		return s.guilds.WithdrawAllianceApplication(req.CharID, guildID, req.AllianceID)
+	})
+}
+
+// AnswerAllianceApplication handles POST /guild/alliance/answer, accepting or
+// declining guildId's application. Only the alliance's parent guild leader
+// may answer; the applicant guild's leader is mailed.
+func (s *APIServer) AnswerAllianceApplication(w http.ResponseWriter, r *http.Request) {
+	s.allianceAction(w, r, func(req allianceRequest, _ uint32) error {
+		return s.guilds.AnswerAllianceApplication(req.CharID, req.AllianceID, req.GuildID, req.Accept)
+	})
+}
+
+// SetAllianceRecruiting handles POST /guild/alliance/recruiting, opening or
+// closing an alliance to applications. Only the alliance's parent guild
+// leader may change it.
+func (s *APIServer) SetAllianceRecruiting(w http.ResponseWriter, r *http.Request) {
+	s.allianceAction(w, r, func(req allianceRequest, _ uint32) error {
+		return s.guilds.SetAllianceRecruiting(req.CharID, req.AllianceID, req.Recruiting)
+	})
+}
+
+// GuildIcon handles GET /guild/{id}/icon.png, rendering a guild's icon from
+// the sprites in Guild.Icons.SpriteDir. Guilds without an icon get a
+// transparent image.
+func (s *APIServer) GuildIcon(w http.ResponseWriter, r *http.Request) {
+	guildID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	guild, err := s.guildRepo.GetGuild(r.Context(), uint32(guildID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(404)
+			return
+		}
+		s.logger.Error("Failed to get guild", zap.Error(err), zap.Uint64("guildID", guildID))
+		w.WriteHeader(500)
+		return
+	}
+	icon, err := guildicon.Decode(guild.IconData)
+	if err != nil {
+		s.logger.Warn("Failed to decode guild icon", zap.Error(err), zap.Uint64("guildID", guildID))
+		icon = &guildicon.Icon{}
+	}
+	etag := `"` + guildicon.Key(icon) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(304)
+		return
+	}
+	data, err := s.guildIcons.PNG(icon)
+	if err != nil {
+		s.logger.Error("Failed to render guild icon", zap.Error(err), zap.Uint64("guildID", guildID))
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(data)
+}
+
 // ScreenShotGet handles GET /api/ss/bbs/{id}, serving a previously uploaded
 // screenshot image by its token ID.
 func (s *APIServer) ScreenShotGet(w http.ResponseWriter, r *http.Request) {
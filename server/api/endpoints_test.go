@@ -2,8 +2,12 @@ package api
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
+	"fmt"
+	"image/png"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -11,7 +15,11 @@ import (
 	"time"
 
 	"erupe-ce/common/gametime"
+	"erupe-ce/common/guildicon"
+	"erupe-ce/common/mhfguild"
 	cfg "erupe-ce/config"
+
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
@@ -324,6 +332,310 @@ func TestExportSaveEndpointInvalidJSON(t *testing.T) {
 	}
 }
 
+// TestSetLanguageEndpoint tests setting a character's language preference
+func TestSetLanguageEndpoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		sessionErr error
+		repoErr    error
+		wantStatus int
+		wantSaved  string
+	}{
+		{"sets language", `{"token":"t","charId":1,"language":"JP"}`, nil, nil, http.StatusOK, "jp"},
+		{"clears language", `{"token":"t","charId":1,"language":""}`, nil, nil, http.StatusOK, ""},
+		{"invalid JSON", `{"token":`, nil, nil, http.StatusBadRequest, ""},
+		{"invalid code", `{"token":"t","charId":1,"language":"../en"}`, nil, nil, http.StatusBadRequest, ""},
+		{"invalid token", `{"token":"t","charId":1,"language":"en"}`, sql.ErrNoRows, nil, http.StatusUnauthorized, ""},
+		{"not owned", `{"token":"t","charId":1,"language":"en"}`, nil, sql.ErrNoRows, http.StatusNotFound, "en"},
+		{"database error", `{"token":"t","charId":1,"language":"en"}`, nil, errors.New("db down"), http.StatusInternalServerError, "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := NewTestLogger(t)
+			defer func() { _ = logger.Sync() }()
+
+			charRepo := &mockAPICharacterRepo{setLanguageErr: tt.repoErr}
+			server := &APIServer{
+				logger:      logger,
+				erupeConfig: NewTestConfig(),
+				charRepo:    charRepo,
+				sessionRepo: &mockAPISessionRepo{userID: 1, userIDErr: tt.sessionErr},
+			}
+
+			req := httptest.NewRequest("POST", "/character/language", strings.NewReader(tt.body))
+			recorder := httptest.NewRecorder()
+
+			server.SetLanguage(recorder, req)
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if charRepo.setLanguage != tt.wantSaved {
+				t.Errorf("saved language = %q, want %q", charRepo.setLanguage, tt.wantSaved)
+			}
+		})
+	}
+}
+
+// newAnnouncementTestServer returns a server whose token resolves to a user
+// with the given operator status.
+func newAnnouncementTestServer(t *testing.T, op bool) (*APIServer, *mockAPIAnnouncementRepo) {
+	repo := &mockAPIAnnouncementRepo{}
+	return &APIServer{
+		logger:       NewTestLogger(t),
+		erupeConfig:  NewTestConfig(),
+		userRepo:     &mockAPIUserRepo{isOp: op},
+		sessionRepo:  &mockAPISessionRepo{userID: 1},
+		announceRepo: repo,
+	}, repo
+}
+
+// TestAnnouncementEndpointsRequireOp tests that non-operators are rejected
+func TestAnnouncementEndpointsRequireOp(t *testing.T) {
+	server, repo := newAnnouncementTestServer(t, false)
+	handlers := map[string]http.HandlerFunc{
+		"/announcement/list":   server.ListAnnouncements,
+		"/announcement/create": server.CreateAnnouncement,
+		"/announcement/delete": server.DeleteAnnouncement,
+	}
+	for path, handler := range handlers {
+		body := `{"token":"t","kind":"recurring","schedule":"0 * * * *","message":"hi","id":1}`
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("%s status = %d, want %d", path, recorder.Code, http.StatusForbidden)
+		}
+	}
+	if len(repo.announcements) != 0 {
+		t.Error("non-operator created an announcement")
+	}
+
+	server.sessionRepo = &mockAPISessionRepo{userIDErr: sql.ErrNoRows}
+	recorder := httptest.NewRecorder()
+	server.ListAnnouncements(recorder, httptest.NewRequest("POST", "/announcement/list", strings.NewReader(`{"token":"bad"}`)))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("invalid token status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+}
+
+// TestCreateAnnouncementEndpoint tests announcement validation and creation
+func TestCreateAnnouncementEndpoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"once", `{"token":"t","kind":"once","sendAt":"2026-10-18T20:00:00+09:00","message":"<C_4>Maintenance"}`, http.StatusOK},
+		{"recurring", `{"token":"t","kind":"recurring","schedule":"0 20 * * 5","message":"Event tonight"}`, http.StatusOK},
+		{"countdown", `{"token":"t","kind":"countdown","sendAt":"2026-10-18T20:00:00Z","countdown":[30,5],"message":"{minutes} min"}`, http.StatusOK},
+		{"invalid JSON", `{"token":`, http.StatusBadRequest},
+		{"missing sendAt", `{"token":"t","kind":"once","message":"hi"}`, http.StatusBadRequest},
+		{"bad cron", `{"token":"t","kind":"recurring","schedule":"often","message":"hi"}`, http.StatusBadRequest},
+		{"negative countdown", `{"token":"t","kind":"countdown","sendAt":"2026-10-18T20:00:00Z","countdown":[-5],"message":"hi"}`, http.StatusBadRequest},
+		{"empty message", `{"token":"t","kind":"recurring","schedule":"* * * * *","message":""}`, http.StatusBadRequest},
+		{"unknown kind", `{"token":"t","kind":"weekly","message":"hi"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, repo := newAnnouncementTestServer(t, true)
+			recorder := httptest.NewRecorder()
+
+			server.CreateAnnouncement(recorder, httptest.NewRequest("POST", "/announcement/create", strings.NewReader(tt.body)))
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(repo.announcements) != 0 {
+					t.Error("invalid announcement was created")
+				}
+				return
+			}
+			if len(repo.announcements) != 1 {
+				t.Fatalf("announcements = %d, want 1", len(repo.announcements))
+			}
+			a := repo.announcements[0]
+			if a.Kind != tt.name || !a.Enabled || a.CreatedBy != "user:1" {
+				t.Errorf("announcement = %+v", a)
+			}
+		})
+	}
+}
+
+// TestListAndDeleteAnnouncementEndpoints tests listing and deleting announcements
+func TestListAndDeleteAnnouncementEndpoints(t *testing.T) {
+	server, repo := newAnnouncementTestServer(t, true)
+
+	recorder := httptest.NewRecorder()
+	server.ListAnnouncements(recorder, httptest.NewRequest("POST", "/announcement/list", strings.NewReader(`{"token":"t"}`)))
+	if recorder.Code != http.StatusOK || strings.TrimSpace(recorder.Body.String()) != "[]" {
+		t.Errorf("empty list = %d %q, want 200 []", recorder.Code, recorder.Body.String())
+	}
+
+	repo.announcements = []Announcement{{ID: 1, Kind: "recurring", Schedule: "0 * * * *", Message: "hi", Enabled: true}}
+	recorder = httptest.NewRecorder()
+	server.ListAnnouncements(recorder, httptest.NewRequest("POST", "/announcement/list", strings.NewReader(`{"token":"t"}`)))
+	var listed []Announcement
+	if err := json.NewDecoder(recorder.Body).Decode(&listed); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(listed) != 1 || listed[0].Schedule != "0 * * * *" {
+		t.Errorf("listed = %+v", listed)
+	}
+
+	recorder = httptest.NewRecorder()
+	server.DeleteAnnouncement(recorder, httptest.NewRequest("POST", "/announcement/delete", strings.NewReader(`{"token":"t","id":1}`)))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("delete status = %d, want 200", recorder.Code)
+	}
+
+	repo.deleteErr = sql.ErrNoRows
+	recorder = httptest.NewRecorder()
+	server.DeleteAnnouncement(recorder, httptest.NewRequest("POST", "/announcement/delete", strings.NewReader(`{"token":"t","id":9}`)))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("missing delete status = %d, want 404", recorder.Code)
+	}
+}
+
+// TestSearchChatEndpoint tests chat log search access and query handling
+func TestSearchChatEndpoint(t *testing.T) {
+	newServer := func(op bool) (*APIServer, *mockAPIChatRepo) {
+		repo := &mockAPIChatRepo{}
+		return &APIServer{
+			logger:      NewTestLogger(t),
+			erupeConfig: NewTestConfig(),
+			userRepo:    &mockAPIUserRepo{isOp: op},
+			sessionRepo: &mockAPISessionRepo{userID: 1},
+			chatRepo:    repo,
+		}, repo
+	}
+	search := func(server *APIServer, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.SearchChat(recorder, httptest.NewRequest("POST", "/chat/search", strings.NewReader(body)))
+		return recorder
+	}
+
+	server, _ := newServer(false)
+	if code := search(server, `{"token":"t"}`).Code; code != http.StatusForbidden {
+		t.Errorf("non-op status = %d, want %d", code, http.StatusForbidden)
+	}
+
+	server, repo := newServer(true)
+	if code := search(server, `{"token":`).Code; code != http.StatusBadRequest {
+		t.Errorf("invalid JSON status = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	recorder := search(server, `{"token":"t"}`)
+	if recorder.Code != http.StatusOK || strings.TrimSpace(recorder.Body.String()) != "[]" {
+		t.Errorf("empty search = %d %q, want 200 []", recorder.Code, recorder.Body.String())
+	}
+	if repo.lastQuery.Limit != chatSearchDefaultLimit {
+		t.Errorf("default limit = %d, want %d", repo.lastQuery.Limit, chatSearchDefaultLimit)
+	}
+
+	repo.logs = []ChatLog{{ID: 1, CharID: 7, SenderName: "Hunter", ChatType: "world", Message: "hi"}}
+	recorder = search(server, `{"token":"t","charId":7,"chatType":"world","query":"hi","since":"2026-10-01T00:00:00Z","limit":5000}`)
+	var logs []ChatLog
+	if err := json.NewDecoder(recorder.Body).Decode(&logs); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(logs) != 1 || logs[0].SenderName != "Hunter" {
+		t.Errorf("logs = %+v", logs)
+	}
+	q := repo.lastQuery
+	if q.CharID != 7 || q.ChatType != "world" || q.Query != "hi" || q.Since == nil || q.Limit != chatSearchMaxLimit {
+		t.Errorf("query = %+v", q)
+	}
+
+	repo.searchErr = errors.New("db down")
+	if code := search(server, `{"token":"t"}`).Code; code != http.StatusInternalServerError {
+		t.Errorf("db error status = %d, want %d", code, http.StatusInternalServerError)
+	}
+}
+
+// TestGuildLogEndpoint tests guild audit log access and paging
+func TestGuildLogEndpoint(t *testing.T) {
+	repo := &mockAPIGuildRepo{ledGuildID: 10}
+	server := &APIServer{
+		logger:      NewTestLogger(t),
+		erupeConfig: NewTestConfig(),
+		sessionRepo: &mockAPISessionRepo{userID: 1},
+		guildRepo:   repo,
+	}
+	guildLog := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.GuildLog(recorder, httptest.NewRequest("POST", "/guild/log", strings.NewReader(body)))
+		return recorder
+	}
+
+	if code := guildLog(`{"token":`).Code; code != http.StatusBadRequest {
+		t.Errorf("invalid JSON status = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	repo.events = []GuildEvent{{ID: 2, ActorID: 7, ActorName: "Leader", TargetCharID: 8, TargetCharName: "Kicked", Action: "kick"}}
+	recorder := guildLog(`{"token":"t","charId":7,"limit":5000}`)
+	var events []GuildEvent
+	if err := json.NewDecoder(recorder.Body).Decode(&events); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(events) != 1 || events[0].Action != "kick" || events[0].ActorName != "Leader" || events[0].TargetCharName != "Kicked" {
+		t.Errorf("events = %+v", events)
+	}
+	if repo.lastGuildID != 10 || repo.lastLimit != guildLogMaxLimit {
+		t.Errorf("listed guild %d with limit %d, want 10 and %d", repo.lastGuildID, repo.lastLimit, guildLogMaxLimit)
+	}
+
+	repo.ledGuildErr = sql.ErrNoRows
+	if code := guildLog(`{"token":"t","charId":8}`).Code; code != http.StatusForbidden {
+		t.Errorf("non-leader status = %d, want %d", code, http.StatusForbidden)
+	}
+
+	server.sessionRepo = &mockAPISessionRepo{userIDErr: sql.ErrNoRows}
+	if code := guildLog(`{"token":"bad","charId":7}`).Code; code != http.StatusUnauthorized {
+		t.Errorf("bad token status = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
+
+// TestGuildItemLogEndpoint tests guild item history access
+func TestGuildItemLogEndpoint(t *testing.T) {
+	repo := &mockAPIGuildRepo{ledGuildID: 10}
+	server := &APIServer{
+		logger:      NewTestLogger(t),
+		erupeConfig: NewTestConfig(),
+		sessionRepo: &mockAPISessionRepo{userID: 1},
+		guildRepo:   repo,
+	}
+	itemLog := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.GuildItemLog(recorder, httptest.NewRequest("POST", "/guild/items/log", strings.NewReader(body)))
+		return recorder
+	}
+
+	recorder := itemLog(`{"token":"t","charId":7}`)
+	if recorder.Code != http.StatusOK || strings.TrimSpace(recorder.Body.String()) != "[]" {
+		t.Errorf("empty history = %d %q, want 200 []", recorder.Code, recorder.Body.String())
+	}
+	if repo.lastLimit != guildLogDefaultLimit {
+		t.Errorf("default limit = %d, want %d", repo.lastLimit, guildLogDefaultLimit)
+	}
+
+	repo.itemTxs = []GuildItemTransaction{{ID: 1, CharID: 8, CharName: "Taker", ItemID: 1630, Quantity: -2}}
+	var txs []GuildItemTransaction
+	if err := json.NewDecoder(itemLog(`{"token":"t","charId":7}`).Body).Decode(&txs); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(txs) != 1 || txs[0].CharName != "Taker" || txs[0].Quantity != -2 {
+		t.Errorf("transactions = %+v", txs)
+	}
+
+	repo.ledGuildErr = sql.ErrNoRows
+	if code := itemLog(`{"token":"t","charId":8}`).Code; code != http.StatusForbidden {
+		t.Errorf("non-leader status = %d, want %d", code, http.StatusForbidden)
+	}
+}
+
 // TestScreenShotEndpointDisabled tests screenshot endpoint when disabled
 func TestScreenShotEndpointDisabled(t *testing.T) {
 	logger := NewTestLogger(t)
@@ -661,3 +973,317 @@ func BenchmarkNewAuthData(b *testing.B) {
 		_ = server.newAuthData(1, 0, 1, "token", characters)
 	}
 }
+
+// TestListGuildsEndpoint tests the public guild list
+func TestListGuildsEndpoint(t *testing.T) {
+	repo := &mockAPIGuildRepo{guilds: []GuildListing{{
+		ID: 3, Name: "Hunters", LeaderName: "Leader", RankRP: 100, MemberCount: 12, Recruiting: true,
+		IconData: []byte(`{"Parts":[{"Index":0,"ID":5,"Red":255,"PosX":40}]}`),
+	}}}
+	c := NewTestConfig()
+	c.RealClientMode = cfg.ZZ
+	c.GameplayOptions.ClanMemberLimits = [][]uint8{{0, 30}, {3, 40}}
+	server := &APIServer{logger: NewTestLogger(t), erupeConfig: c, guildRepo: repo}
+
+	recorder := httptest.NewRecorder()
+	server.ListGuilds(recorder, httptest.NewRequest("GET", "/guild/list?name=hunt&recruiting=true&limit=5000&offset=20", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+	if q := repo.lastQuery; q.Name != "hunt" || !q.Recruiting || q.Limit != guildListMaxLimit || q.Offset != 20 {
+		t.Errorf("query = %+v", q)
+	}
+	var guilds []GuildListing
+	if err := json.NewDecoder(recorder.Body).Decode(&guilds); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(guilds) != 1 {
+		t.Fatalf("got %d guilds, want 1", len(guilds))
+	}
+	g := guilds[0]
+	if g.Rank != 3 || g.MemberLimit != 40 || g.MemberCount != 12 || !g.Recruiting {
+		t.Errorf("listing = %+v, want rank 3 with 12/40 members", g)
+	}
+	if len(g.Icon) != 1 || g.Icon[0].ID != 5 || g.Icon[0].Red != 255 || g.Icon[0].PosX != 40 {
+		t.Errorf("icon = %+v", g.Icon)
+	}
+
+	repo.guilds = nil
+	recorder = httptest.NewRecorder()
+	server.ListGuilds(recorder, httptest.NewRequest("GET", "/guild/list", nil))
+	if strings.TrimSpace(recorder.Body.String()) != "[]" || repo.lastQuery.Limit != guildListDefaultLimit {
+		t.Errorf("empty list = %q with limit %d", recorder.Body.String(), repo.lastQuery.Limit)
+	}
+}
+
+// TestApplyToGuildEndpoint tests applying to a guild from the website
+func TestApplyToGuildEndpoint(t *testing.T) {
+	newServer := func() (*APIServer, *mockGuildActions) {
+		actions := &mockGuildActions{repo: &mockAPIGuildRepo{}}
+		return &APIServer{
+			logger:      NewTestLogger(t),
+			erupeConfig: NewTestConfig(),
+			sessionRepo: &mockAPISessionRepo{userID: 1},
+			charRepo:    &mockAPICharacterRepo{characters: []Character{{ID: 7}}},
+			guilds:      actions,
+		}, actions
+	}
+	apply := func(server *APIServer, body string) int {
+		recorder := httptest.NewRecorder()
+		server.ApplyToGuild(recorder, httptest.NewRequest("POST", "/guild/apply", strings.NewReader(body)))
+		return recorder.Code
+	}
+
+	server, actions := newServer()
+	if code := apply(server, `{"token":"t","charId":7,"guildId":3}`); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if len(actions.applied) != 2 || actions.applied[0] != 7 || actions.applied[1] != 3 {
+		t.Errorf("applied = %v, want character 7 to guild 3", actions.applied)
+	}
+
+	tests := []struct {
+		name  string
+		setup func(*APIServer, *mockGuildActions)
+		body  string
+		want  int
+	}{
+		{"invalid JSON", nil, `{"token":`, http.StatusBadRequest},
+		{"bad token", func(s *APIServer, _ *mockGuildActions) {
+			s.sessionRepo = &mockAPISessionRepo{userIDErr: sql.ErrNoRows}
+		}, `{"token":"bad","charId":7,"guildId":3}`, http.StatusUnauthorized},
+		{"not own character", nil, `{"token":"t","charId":8,"guildId":3}`, http.StatusNotFound},
+		{"character lookup error", func(s *APIServer, _ *mockGuildActions) {
+			s.charRepo = &mockAPICharacterRepo{charactersErr: errors.New("db down")}
+		}, `{"token":"t","charId":7,"guildId":3}`, http.StatusInternalServerError},
+		{"unknown guild", func(_ *APIServer, a *mockGuildActions) {
+			a.err = fmt.Errorf("%w: guild not found", mhfguild.ErrNotFound)
+		}, `{"token":"t","charId":7,"guildId":3}`, http.StatusNotFound},
+		{"not recruiting, full or already applied", func(_ *APIServer, a *mockGuildActions) {
+			a.err = fmt.Errorf("%w: guild is full", mhfguild.ErrConflict)
+		}, `{"token":"t","charId":7,"guildId":3}`, http.StatusConflict},
+		{"database error", func(_ *APIServer, a *mockGuildActions) {
+			a.err = errors.New("db down")
+		}, `{"token":"t","charId":7,"guildId":3}`, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, actions := newServer()
+			if tt.setup != nil {
+				tt.setup(server, actions)
+			}
+			if code := apply(server, tt.body); code != tt.want {
+				t.Errorf("status = %d, want %d", code, tt.want)
+			}
+			if len(actions.applied) != 0 {
+				t.Error("rejected request should not file an application")
+			}
+		})
+	}
+}
+
+// TestSetGuildRankEndpoint tests forcing a guild's rank
+func TestSetGuildRankEndpoint(t *testing.T) {
+	c := NewTestConfig()
+	c.RealClientMode = cfg.ZZ
+	newServer := func(repo *mockAPIGuildRepo, op bool) (*APIServer, *mockGuildActions) {
+		actions := &mockGuildActions{repo: repo}
+		return &APIServer{
+			logger:      NewTestLogger(t),
+			erupeConfig: c,
+			sessionRepo: &mockAPISessionRepo{userID: 1},
+			userRepo:    &mockAPIUserRepo{isOp: op},
+			guildRepo:   repo,
+			guilds:      actions,
+		}, actions
+	}
+	setRank := func(server *APIServer, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.SetGuildRank(recorder, httptest.NewRequest("POST", "/guild/rank", strings.NewReader(body)))
+		return recorder
+	}
+
+	server, actions := newServer(&mockAPIGuildRepo{guilds: []GuildListing{{ID: 3, Name: "Hunters"}}}, true)
+	recorder := setRank(server, `{"token":"t","guildId":3,"rank":5}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+	if actions.forcedRank == nil || *actions.forcedRank != 5 {
+		t.Errorf("forced rank = %v, want 5", actions.forcedRank)
+	}
+	var guild GuildListing
+	if err := json.NewDecoder(recorder.Body).Decode(&guild); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if guild.Rank != 5 {
+		t.Errorf("rank = %d, want 5", guild.Rank)
+	}
+
+	forced := uint16(0)
+	server, actions = newServer(&mockAPIGuildRepo{guilds: []GuildListing{{ID: 3, ForcedRank: &forced}}}, true)
+	if code := setRank(server, `{"token":"t","guildId":3,"rank":null}`).Code; code != http.StatusOK {
+		t.Fatalf("clear status = %d, want 200", code)
+	}
+	if !actions.forcedRankSet || actions.forcedRank != nil {
+		t.Errorf("forced rank = %v, want cleared", actions.forcedRank)
+	}
+
+	server, actions = newServer(&mockAPIGuildRepo{guilds: []GuildListing{{ID: 3}}}, true)
+	actions.err = errors.New("db down")
+	if code := setRank(server, `{"token":"t","guildId":3,"rank":5}`).Code; code != http.StatusInternalServerError {
+		t.Errorf("failed update status = %d, want %d", code, http.StatusInternalServerError)
+	}
+
+	tests := []struct {
+		name string
+		repo *mockAPIGuildRepo
+		op   bool
+		body string
+		want int
+	}{
+		{"invalid JSON", &mockAPIGuildRepo{}, true, `{"token":`, http.StatusBadRequest},
+		{"not an operator", &mockAPIGuildRepo{guilds: []GuildListing{{ID: 3}}}, false, `{"token":"t","guildId":3,"rank":5}`, http.StatusForbidden},
+		{"unknown guild", &mockAPIGuildRepo{}, true, `{"token":"t","guildId":3,"rank":5}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, actions := newServer(tt.repo, tt.op)
+			if code := setRank(server, tt.body).Code; code != tt.want {
+				t.Errorf("status = %d, want %d", code, tt.want)
+			}
+			if actions.forcedRankSet {
+				t.Error("rejected request should not change the guild")
+			}
+		})
+	}
+}
+
+// TestAllianceEndpoints tests the alliance application endpoints
+func TestAllianceEndpoints(t *testing.T) {
+	newServer := func() (*APIServer, *mockAPIGuildRepo, *mockGuildActions) {
+		repo := &mockAPIGuildRepo{ledGuildID: 20}
+		actions := &mockGuildActions{repo: repo}
+		return &APIServer{
+			logger:      NewTestLogger(t),
+			erupeConfig: NewTestConfig(),
+			sessionRepo: &mockAPISessionRepo{userID: 1},
+			guildRepo:   repo,
+			guilds:      actions,
+		}, repo, actions
+	}
+	call := func(handler http.HandlerFunc, body string) int {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest("POST", "/guild/alliance", strings.NewReader(body)))
+		return recorder.Code
+	}
+
+	server, _, actions := newServer()
+	requests := []struct {
+		handler http.HandlerFunc
+		body    string
+		want    allianceCall
+	}{
+		{server.ApplyToAlliance, `{"token":"t","charId":2,"allianceId":5}`, allianceCall{"apply", 2, 20, 5, false}},
+		{server.WithdrawAllianceApplication, `{"token":"t","charId":2,"allianceId":5}`, allianceCall{"withdraw", 2, 20, 5, false}},
+		{server.AnswerAllianceApplication, `{"token":"t","charId":1,"allianceId":5,"guildId":30,"accept":true}`, allianceCall{"answer", 1, 30, 5, true}},
+		{server.SetAllianceRecruiting, `{"token":"t","charId":1,"allianceId":5,"recruiting":true}`, allianceCall{"recruiting", 1, 0, 5, true}},
+	}
+	for _, req := range requests {
+		if code := call(req.handler, req.body); code != http.StatusOK {
+			t.Errorf("%s status = %d, want 200", req.want.method, code)
+		}
+	}
+	if len(actions.allianceCalls) != len(requests) {
+		t.Fatalf("calls = %+v, want %d", actions.allianceCalls, len(requests))
+	}
+	for i, req := range requests {
+		if actions.allianceCalls[i] != req.want {
+			t.Errorf("call %d = %+v, want %+v", i, actions.allianceCalls[i], req.want)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		setup func(*APIServer, *mockAPIGuildRepo, *mockGuildActions)
+		body  string
+		want  int
+	}{
+		{"invalid JSON", nil, `{"token":`, http.StatusBadRequest},
+		{"bad token", func(s *APIServer, _ *mockAPIGuildRepo, _ *mockGuildActions) {
+			s.sessionRepo = &mockAPISessionRepo{userIDErr: sql.ErrNoRows}
+		}, `{"token":"bad","charId":2,"allianceId":5}`, http.StatusUnauthorized},
+		{"not a guild leader", func(_ *APIServer, r *mockAPIGuildRepo, _ *mockGuildActions) {
+			r.ledGuildErr = sql.ErrNoRows
+		}, `{"token":"t","charId":3,"allianceId":5}`, http.StatusForbidden},
+		{"forbidden", func(_ *APIServer, _ *mockAPIGuildRepo, a *mockGuildActions) {
+			a.err = fmt.Errorf("%w: unauthorized", mhfguild.ErrForbidden)
+		}, `{"token":"t","charId":2,"allianceId":5}`, http.StatusForbidden},
+		{"unknown alliance", func(_ *APIServer, _ *mockAPIGuildRepo, a *mockGuildActions) {
+			a.err = fmt.Errorf("%w: alliance not found", mhfguild.ErrNotFound)
+		}, `{"token":"t","charId":2,"allianceId":5}`, http.StatusNotFound},
+		{"closed alliance", func(_ *APIServer, _ *mockAPIGuildRepo, a *mockGuildActions) {
+			a.err = fmt.Errorf("%w: closed", mhfguild.ErrConflict)
+		}, `{"token":"t","charId":2,"allianceId":5}`, http.StatusConflict},
+		{"db error", func(_ *APIServer, _ *mockAPIGuildRepo, a *mockGuildActions) {
+			a.err = errors.New("db down")
+		}, `{"token":"t","charId":2,"allianceId":5}`, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, repo, actions := newServer()
+			if tt.setup != nil {
+				tt.setup(server, repo, actions)
+			}
+			if code := call(server.ApplyToAlliance, tt.body); code != tt.want {
+				t.Errorf("status = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+// TestGuildIconEndpoint tests guild icon rendering
+func TestGuildIconEndpoint(t *testing.T) {
+	repo := &mockAPIGuildRepo{guilds: []GuildListing{
+		{ID: 3, IconData: []byte(`{"Parts":[{"Index":0,"ID":1,"Size":100,"Red":255,"PosX":128,"PosY":128}]}`)},
+	}}
+	server := &APIServer{
+		logger:      NewTestLogger(t),
+		erupeConfig: NewTestConfig(),
+		guildRepo:   repo,
+		guildIcons:  guildicon.NewRenderer(t.TempDir(), 32, 4),
+	}
+	icon := func(id, etag string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest("GET", "/guild/"+id+"/icon.png", nil), map[string]string{"id": id})
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		recorder := httptest.NewRecorder()
+		server.GuildIcon(recorder, req)
+		return recorder
+	}
+
+	recorder := icon("3", "")
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("status = %d, content type %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	img, err := png.Decode(recorder.Body)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if img.Bounds().Dx() != 32 {
+		t.Errorf("width = %d, want 32", img.Bounds().Dx())
+	}
+	etag := recorder.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if code := icon("3", etag).Code; code != http.StatusNotModified {
+		t.Errorf("matching ETag status = %d, want %d", code, http.StatusNotModified)
+	}
+
+	for _, id := range []string{"4", "x"} {
+		if code := icon(id, "").Code; code != http.StatusNotFound {
+			t.Errorf("guild %s status = %d, want %d", id, code, http.StatusNotFound)
+		}
+	}
+}
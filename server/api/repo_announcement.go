@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// APIAnnouncementRepository implements APIAnnouncementRepo with PostgreSQL.
+type APIAnnouncementRepository struct {
+	db *sqlx.DB
+}
+
+// NewAPIAnnouncementRepository creates a new APIAnnouncementRepository.
+func NewAPIAnnouncementRepository(db *sqlx.DB) *APIAnnouncementRepository {
+	return &APIAnnouncementRepository{db: db}
+}
+
+func (r *APIAnnouncementRepository) List(ctx context.Context) ([]Announcement, error) {
+	var announcements []Announcement
+	err := r.db.SelectContext(ctx, &announcements, `SELECT id, kind, message, send_at, COALESCE(schedule, '') AS schedule,
+		countdown, enabled, last_sent_at, created_by FROM announcements ORDER BY id`)
+	return announcements, err
+}
+
+func (r *APIAnnouncementRepository) Create(ctx context.Context, a Announcement) (uint32, error) {
+	var id uint32
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO announcements (kind, message, send_at, schedule, countdown, enabled, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7) RETURNING id`,
+		a.Kind, a.Message, a.SendAt, a.Schedule, a.Countdown, a.Enabled, a.CreatedBy,
+	).Scan(&id)
+	return id, err
+}
+
+func (r *APIAnnouncementRepository) Delete(ctx context.Context, id uint32) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM announcements WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -85,3 +86,14 @@ func (r *APICharacterRepository) ExportSave(ctx context.Context, userID, charID
 	}
 	return result, nil
 }
+
+func (r *APICharacterRepository) SetLanguage(ctx context.Context, userID, charID uint32, language string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE characters SET language = NULLIF($1, '') WHERE id = $2 AND user_id = $3", language, charID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// APIChatRepository implements APIChatRepo with PostgreSQL.
+type APIChatRepository struct {
+	db *sqlx.DB
+}
+
+// NewAPIChatRepository creates a new APIChatRepository.
+func NewAPIChatRepository(db *sqlx.DB) *APIChatRepository {
+	return &APIChatRepository{db: db}
+}
+
+func (r *APIChatRepository) Search(ctx context.Context, q ChatLogQuery) ([]ChatLog, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if q.CharID != 0 {
+		add("char_id = $%d", q.CharID)
+	}
+	if q.Name != "" {
+		add("sender_name ILIKE $%d", "%"+q.Name+"%")
+	}
+	if q.ChatType != "" {
+		add("chat_type = $%d", q.ChatType)
+	}
+	if q.ChannelID != 0 {
+		add("channel_id = $%d", q.ChannelID)
+	}
+	if q.StageID != "" {
+		add("stage_id = $%d", q.StageID)
+	}
+	if q.Query != "" {
+		add("message ILIKE $%d", "%"+q.Query+"%")
+	}
+	if q.Since != nil {
+		add("created_at >= $%d", *q.Since)
+	}
+	if q.Until != nil {
+		add("created_at < $%d", *q.Until)
+	}
+
+	query := `SELECT id, char_id, sender_name, chat_type, channel_id, stage_id, targets, message, filter_action, created_at FROM chat_logs`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, q.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	var logs []ChatLog
+	err := r.db.SelectContext(ctx, &logs, query, args...)
+	return logs, err
+}
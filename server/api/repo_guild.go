@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// APIGuildRepository implements APIGuildRepo with PostgreSQL.
+type APIGuildRepository struct {
+	db *sqlx.DB
+}
+
+// NewAPIGuildRepository creates a new APIGuildRepository.
+func NewAPIGuildRepository(db *sqlx.DB) *APIGuildRepository {
+	return &APIGuildRepository{db: db}
+}
+
+func (r *APIGuildRepository) LedGuild(ctx context.Context, userID, charID uint32) (uint32, error) {
+	var guildID uint32
+	err := r.db.QueryRowContext(ctx, `
+		SELECT g.id FROM guilds g JOIN characters c ON c.id = g.leader_id
+		WHERE c.id = $1 AND c.user_id = $2 AND c.deleted = false`,
+		charID, userID).Scan(&guildID)
+	return guildID, err
+}
+
+func (r *APIGuildRepository) ListEvents(ctx context.Context, guildID uint32, before *time.Time, limit int) ([]GuildEvent, error) {
+	var events []GuildEvent
+	err := r.db.SelectContext(ctx, &events, `
+		SELECT l.id, l.actor_id, COALESCE(a.name, '') AS actor_name,
+			l.target_char_id, COALESCE(tc.name, '') AS target_char_name,
+			l.target_guild_id, COALESCE(tg.name, '') AS target_guild_name,
+			l.action, l.detail, l.created_at
+		FROM guild_audit_log l
+		LEFT JOIN characters a ON a.id = l.actor_id
+		LEFT JOIN characters tc ON tc.id = l.target_char_id
+		LEFT JOIN guilds tg ON tg.id = l.target_guild_id
+		WHERE l.guild_id = $1 AND ($2::timestamptz IS NULL OR l.created_at < $2)
+		ORDER BY l.created_at DESC, l.id DESC LIMIT $3`,
+		guildID, before, limit)
+	return events, err
+}
+
+func (r *APIGuildRepository) ListItemTransactions(ctx context.Context, guildID uint32, before *time.Time, limit int) ([]GuildItemTransaction, error) {
+	var txs []GuildItemTransaction
+	err := r.db.SelectContext(ctx, &txs, `
+		SELECT t.id, t.character_id, COALESCE(c.name, '') AS char_name, t.item_id, t.quantity, t.created_at
+		FROM guild_item_transactions t
+		LEFT JOIN characters c ON c.id = t.character_id
+		WHERE t.guild_id = $1 AND ($2::timestamptz IS NULL OR t.created_at < $2)
+		ORDER BY t.created_at DESC, t.id DESC LIMIT $3`,
+		guildID, before, limit)
+	return txs, err
+}
+
+const guildListingSelectSQL = `
+	SELECT g.id, COALESCE(g.name, '') AS name, g.leader_id, COALESCE(c.name, '') AS leader_name,
+		g.rank_rp, g.forced_rank, g.recruiting, g.comment, g.icon,
+		(SELECT COUNT(*) FROM guild_characters gc WHERE gc.guild_id = g.id) AS member_count
+	FROM guilds g
+	LEFT JOIN characters c ON c.id = g.leader_id`
+
+func (r *APIGuildRepository) ListGuilds(ctx context.Context, q GuildListQuery) ([]GuildListing, error) {
+	var guilds []GuildListing
+	err := r.db.SelectContext(ctx, &guilds, guildListingSelectSQL+`
+		WHERE ($1 = '' OR g.name ILIKE '%' || $1 || '%') AND (NOT $2 OR g.recruiting)
+		ORDER BY g.rank_rp DESC, g.id LIMIT $3 OFFSET $4`,
+		q.Name, q.Recruiting, q.Limit, q.Offset)
+	return guilds, err
+}
+
+func (r *APIGuildRepository) GetGuild(ctx context.Context, guildID uint32) (GuildListing, error) {
+	var guild GuildListing
+	err := r.db.GetContext(ctx, &guild, guildListingSelectSQL+` WHERE g.id = $1`, guildID)
+	return guild, err
+}
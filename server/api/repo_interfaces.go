@@ -22,6 +22,8 @@ type APIUserRepo interface {
 	UpdateReturnExpiry(uid uint32, expiry time.Time) error
 	// UpdateLastLogin sets the user's last login time.
 	UpdateLastLogin(uid uint32, loginTime time.Time) error
+	// IsOp returns whether the user has operator privileges.
+	IsOp(uid uint32) (bool, error)
 }
 
 // APICharacterRepo defines the contract for character-related data access.
@@ -42,6 +44,9 @@ type APICharacterRepo interface {
 	GetForUser(ctx context.Context, userID uint32) ([]Character, error)
 	// ExportSave returns the full character row as a map.
 	ExportSave(ctx context.Context, userID, charID uint32) (map[string]interface{}, error)
+	// SetLanguage sets a character's preferred language; empty clears it.
+	// Returns sql.ErrNoRows if the character does not belong to the user.
+	SetLanguage(ctx context.Context, userID, charID uint32, language string) error
 }
 
 // APISessionRepo defines the contract for session/token data access.
@@ -51,3 +56,36 @@ type APISessionRepo interface {
 	// GetUserIDByToken returns the user ID for a given session token.
 	GetUserIDByToken(ctx context.Context, token string) (uint32, error)
 }
+
+// APIAnnouncementRepo defines the contract for scheduled announcement data access.
+type APIAnnouncementRepo interface {
+	// List returns every announcement ordered by ID.
+	List(ctx context.Context) ([]Announcement, error)
+	// Create inserts an announcement and returns its ID.
+	Create(ctx context.Context, a Announcement) (uint32, error)
+	// Delete removes an announcement, returning sql.ErrNoRows if it does not exist.
+	Delete(ctx context.Context, id uint32) error
+}
+
+// APIGuildRepo defines the contract for guild data access.
+type APIGuildRepo interface {
+	// LedGuild returns the ID of the guild led by one of the user's
+	// characters, or sql.ErrNoRows if the character leads no guild.
+	LedGuild(ctx context.Context, userID, charID uint32) (uint32, error)
+	// ListEvents returns a guild's audit log entries created before the given
+	// time (or any time if nil), newest first.
+	ListEvents(ctx context.Context, guildID uint32, before *time.Time, limit int) ([]GuildEvent, error)
+	// ListItemTransactions returns a guild's item box deposits and
+	// withdrawals made before the given time (or any time if nil), newest first.
+	ListItemTransactions(ctx context.Context, guildID uint32, before *time.Time, limit int) ([]GuildItemTransaction, error)
+	// ListGuilds returns the guilds matching the query, highest rank RP first.
+	ListGuilds(ctx context.Context, q GuildListQuery) ([]GuildListing, error)
+	// GetGuild returns a guild's listing, or sql.ErrNoRows if it does not exist.
+	GetGuild(ctx context.Context, guildID uint32) (GuildListing, error)
+}
+
+// APIChatRepo defines the contract for chat log data access.
+type APIChatRepo interface {
+	// Search returns chat log entries matching the query, newest first.
+	Search(ctx context.Context, q ChatLogQuery) ([]ChatLog, error)
+}
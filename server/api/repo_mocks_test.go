@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"time"
 )
 
@@ -24,6 +25,13 @@ type mockAPIUserRepo struct {
 
 	updateReturnExpiryErr error
 	updateLastLoginErr    error
+
+	isOp    bool
+	isOpErr error
+}
+
+func (m *mockAPIUserRepo) IsOp(_ uint32) (bool, error) {
+	return m.isOp, m.isOpErr
 }
 
 func (m *mockAPIUserRepo) Register(_ context.Context, _, _ string, _ time.Time) (uint32, uint32, error) {
@@ -72,6 +80,9 @@ type mockAPICharacterRepo struct {
 
 	exportResult map[string]interface{}
 	exportErr    error
+
+	setLanguage    string
+	setLanguageErr error
 }
 
 func (m *mockAPICharacterRepo) GetNewCharacter(_ context.Context, _ uint32) (Character, error) {
@@ -106,6 +117,11 @@ func (m *mockAPICharacterRepo) ExportSave(_ context.Context, _, _ uint32) (map[s
 	return m.exportResult, m.exportErr
 }
 
+func (m *mockAPICharacterRepo) SetLanguage(_ context.Context, _, _ uint32, language string) error {
+	m.setLanguage = language
+	return m.setLanguageErr
+}
+
 // mockAPISessionRepo implements APISessionRepo for testing.
 type mockAPISessionRepo struct {
 	createTokenID  uint32
@@ -113,12 +129,161 @@ type mockAPISessionRepo struct {
 
 	userID    uint32
 	userIDErr error
+	gotToken  string
 }
 
 func (m *mockAPISessionRepo) CreateToken(_ context.Context, _ uint32, _ string) (uint32, error) {
 	return m.createTokenID, m.createTokenErr
 }
 
-func (m *mockAPISessionRepo) GetUserIDByToken(_ context.Context, _ string) (uint32, error) {
+func (m *mockAPISessionRepo) GetUserIDByToken(_ context.Context, tkn string) (uint32, error) {
+	m.gotToken = tkn
 	return m.userID, m.userIDErr
 }
+
+// mockAPIAnnouncementRepo implements APIAnnouncementRepo for testing.
+type mockAPIAnnouncementRepo struct {
+	announcements []Announcement
+	listErr       error
+	createErr     error
+	deleteErr     error
+}
+
+func (m *mockAPIAnnouncementRepo) List(_ context.Context) ([]Announcement, error) {
+	return m.announcements, m.listErr
+}
+
+func (m *mockAPIAnnouncementRepo) Create(_ context.Context, a Announcement) (uint32, error) {
+	if m.createErr != nil {
+		return 0, m.createErr
+	}
+	a.ID = uint32(len(m.announcements) + 1)
+	m.announcements = append(m.announcements, a)
+	return a.ID, nil
+}
+
+func (m *mockAPIAnnouncementRepo) Delete(_ context.Context, _ uint32) error {
+	return m.deleteErr
+}
+
+// mockAPIChatRepo implements APIChatRepo for testing.
+type mockAPIChatRepo struct {
+	logs      []ChatLog
+	searchErr error
+	lastQuery ChatLogQuery
+}
+
+func (m *mockAPIChatRepo) Search(_ context.Context, q ChatLogQuery) ([]ChatLog, error) {
+	m.lastQuery = q
+	return m.logs, m.searchErr
+}
+
+// mockAPIGuildRepo implements APIGuildRepo for testing.
+type mockAPIGuildRepo struct {
+	ledGuildID  uint32
+	ledGuildErr error
+	events      []GuildEvent
+	itemTxs     []GuildItemTransaction
+	listErr     error
+	lastGuildID uint32
+	lastLimit   int
+
+	guilds    []GuildListing
+	lastQuery GuildListQuery
+	getErr    error
+}
+
+func (m *mockAPIGuildRepo) LedGuild(_ context.Context, _, _ uint32) (uint32, error) {
+	return m.ledGuildID, m.ledGuildErr
+}
+
+func (m *mockAPIGuildRepo) ListEvents(_ context.Context, guildID uint32, _ *time.Time, limit int) ([]GuildEvent, error) {
+	m.lastGuildID, m.lastLimit = guildID, limit
+	return m.events, m.listErr
+}
+
+func (m *mockAPIGuildRepo) ListItemTransactions(_ context.Context, guildID uint32, _ *time.Time, limit int) ([]GuildItemTransaction, error) {
+	m.lastGuildID, m.lastLimit = guildID, limit
+	return m.itemTxs, m.listErr
+}
+
+func (m *mockAPIGuildRepo) ListGuilds(_ context.Context, q GuildListQuery) ([]GuildListing, error) {
+	m.lastQuery = q
+	return m.guilds, m.listErr
+}
+
+func (m *mockAPIGuildRepo) GetGuild(_ context.Context, guildID uint32) (GuildListing, error) {
+	if m.getErr != nil {
+		return GuildListing{}, m.getErr
+	}
+	for _, g := range m.guilds {
+		if g.ID == guildID {
+			return g, nil
+		}
+	}
+	return GuildListing{}, sql.ErrNoRows
+}
+
+// mockGuildActions implements GuildActions, applying forced ranks to the
+// listings of a mockAPIGuildRepo and recording applications and alliance
+// calls.
+type mockGuildActions struct {
+	repo *mockAPIGuildRepo
+	err  error
+
+	forcedRank    *uint16
+	forcedRankSet bool
+	applied       []uint32 // charID, guildID pairs
+	allianceCalls []allianceCall
+}
+
+func (m *mockGuildActions) ApplyToGuild(charID, guildID uint32) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.applied = append(m.applied, charID, guildID)
+	return nil
+}
+
+// allianceCall records the arguments of one alliance GuildActions call.
+type allianceCall struct {
+	method     string
+	charID     uint32
+	guildID    uint32
+	allianceID uint32
+	flag       bool
+}
+
+func (m *mockGuildActions) recordAlliance(call allianceCall) error {
+	m.allianceCalls = append(m.allianceCalls, call)
+	return m.err
+}
+
+func (m *mockGuildActions) ApplyToAlliance(charID, guildID, allianceID uint32) error {
+	return m.recordAlliance(allianceCall{"apply", charID, guildID, allianceID, false})
+}
+
+func (m *mockGuildActions) WithdrawAllianceApplication(charID, guildID, allianceID uint32) error {
+	return m.recordAlliance(allianceCall{"withdraw", charID, guildID, allianceID, false})
+}
+
+func (m *mockGuildActions) AnswerAllianceApplication(charID, allianceID, guildID uint32, accept bool) error {
+	return m.recordAlliance(allianceCall{"answer", charID, guildID, allianceID, accept})
+}
+
+func (m *mockGuildActions) SetAllianceRecruiting(charID, allianceID uint32, recruiting bool) error {
+	return m.recordAlliance(allianceCall{"recruiting", charID, 0, allianceID, recruiting})
+}
+
+func (m *mockGuildActions) ForceRank(guildID uint32, rank *uint16) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.forcedRank, m.forcedRankSet = rank, true
+	for i := range m.repo.guilds {
+		if m.repo.guilds[i].ID == guildID {
+			m.repo.guilds[i].ForcedRank = rank
+		}
+	}
+	return nil
+}
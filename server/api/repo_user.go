@@ -64,3 +64,9 @@ func (r *APIUserRepository) UpdateLastLogin(uid uint32, loginTime time.Time) err
 	_, err := r.db.Exec("UPDATE users SET last_login=$1 WHERE id=$2", loginTime, uid)
 	return err
 }
+
+func (r *APIUserRepository) IsOp(uid uint32) (bool, error) {
+	var op bool
+	err := r.db.QueryRow("SELECT op FROM users WHERE id=$1", uid).Scan(&op)
+	return op, err
+}
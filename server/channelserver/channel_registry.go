@@ -12,6 +12,10 @@ type ChannelRegistry interface {
 	// Worldcast broadcasts a packet to all sessions across all channels.
 	Worldcast(pkt mhfpacket.MHFPacket, ignoredSession *Session, ignoredChannel *Server)
 
+	// WorldcastLocalized broadcasts a packet built in each recipient's
+	// language to all sessions across all channels.
+	WorldcastLocalized(build func(lang *i18n) mhfpacket.MHFPacket, ignoredSession *Session, ignoredChannel *Server)
+
 	// FindSessionByCharID looks up a session by character ID across all channels.
 	FindSessionByCharID(charID uint32) *Session
 
@@ -32,6 +36,18 @@ type ChannelRegistry interface {
 
 	// NotifyMailToCharID finds the session for charID and sends a mail notification.
 	NotifyMailToCharID(charID uint32, sender *Session, mail *Mail)
+
+	// ListChannels returns a snapshot of every channel and its player count.
+	ListChannels() []ChannelSnapshot
+}
+
+// ChannelSnapshot is an immutable copy of channel data taken under lock.
+type ChannelSnapshot struct {
+	ID         uint16
+	GlobalID   string
+	ServerIP   net.IP
+	ServerPort uint16
+	Players    int
 }
 
 // SessionSnapshot is an immutable copy of session data taken under lock.
@@ -25,6 +25,15 @@ func (r *LocalChannelRegistry) Worldcast(pkt mhfpacket.MHFPacket, ignoredSession
 	}
 }
 
+func (r *LocalChannelRegistry) WorldcastLocalized(build func(lang *i18n) mhfpacket.MHFPacket, ignoredSession *Session, ignoredChannel *Server) {
+	for _, c := range r.channels {
+		if c == ignoredChannel {
+			continue
+		}
+		c.BroadcastLocalizedMHF(build, ignoredSession)
+	}
+}
+
 func (r *LocalChannelRegistry) FindSessionByCharID(charID uint32) *Session {
 	for _, c := range r.channels {
 		c.Lock()
@@ -145,6 +154,22 @@ func (r *LocalChannelRegistry) SearchStages(stagePrefix string, max int) []Stage
 	return results
 }
 
+func (r *LocalChannelRegistry) ListChannels() []ChannelSnapshot {
+	results := make([]ChannelSnapshot, 0, len(r.channels))
+	for _, c := range r.channels {
+		c.Lock()
+		results = append(results, ChannelSnapshot{
+			ID:         c.ID,
+			GlobalID:   c.GlobalID,
+			ServerIP:   net.ParseIP(c.IP).To4(),
+			ServerPort: c.Port,
+			Players:    len(c.sessions),
+		})
+		c.Unlock()
+	}
+	return results
+}
+
 func (r *LocalChannelRegistry) NotifyMailToCharID(charID uint32, sender *Session, mail *Mail) {
 	session := r.FindSessionByCharID(charID)
 	if session != nil {
@@ -184,3 +184,24 @@ func TestLocalRegistryConcurrentAccess(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestLocalRegistryListChannels(t *testing.T) {
+	channels := createTestChannels(2)
+	reg := NewLocalChannelRegistry(channels)
+
+	conn := &mockConn{}
+	channels[1].Lock()
+	channels[1].sessions[conn] = createTestSessionForServer(channels[1], conn, 100, "Alice")
+	channels[1].Unlock()
+
+	got := reg.ListChannels()
+	if len(got) != 2 {
+		t.Fatalf("ListChannels() returned %d channels, want 2", len(got))
+	}
+	if got[0].ServerPort != 54001 || got[0].Players != 0 {
+		t.Errorf("channel 0 = %+v, want port 54001 with 0 players", got[0])
+	}
+	if got[1].ServerPort != 54002 || got[1].Players != 1 {
+		t.Errorf("channel 1 = %+v, want port 54002 with 1 player", got[1])
+	}
+}
@@ -3,6 +3,7 @@ package channelserver
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"erupe-ce/common/mhfguild"
 	cfg "erupe-ce/config"
 	"time"
 )
@@ -57,6 +58,7 @@ type Guild struct {
 	AllianceID    uint32        `db:"alliance_id"`
 	Icon          *GuildIcon    `db:"icon"`
 	RPResetAt     time.Time     `db:"rp_reset_at"`
+	ForcedRank    *uint16       `db:"forced_rank"` // Set by operators to override the RP rank
 
 	GuildLeader
 }
@@ -111,35 +113,11 @@ func (gi *GuildIcon) Value() (valuer driver.Value, err error) {
 	return json.Marshal(gi)
 }
 
-func (g *Guild) Rank(mode cfg.Mode) uint16 {
-	rpMap := []uint32{
-		24, 48, 96, 144, 192, 240, 288, 360, 432,
-		504, 600, 696, 792, 888, 984, 1080, 1200,
+// Rank returns the guild's forced rank if it has one, otherwise the rank its
+// rank RP reaches in table.
+func (g *Guild) Rank(table mhfguild.Table, mode cfg.Mode) uint16 {
+	if g.ForcedRank != nil {
+		return table.Clamp(*g.ForcedRank, mode)
 	}
-	if mode <= cfg.Z2 {
-		rpMap = []uint32{
-			3500, 6000, 8500, 11000, 13500, 16000, 20000, 24000, 28000,
-			33000, 38000, 43000, 48000, 55000, 70000, 90000, 120000,
-		}
-	}
-	for i, u := range rpMap {
-		if g.RankRP < u {
-			if mode <= cfg.S6 && i >= 12 {
-				return 12
-			} else if mode <= cfg.F5 && i >= 13 {
-				return 13
-			} else if mode <= cfg.G32 && i >= 14 {
-				return 14
-			}
-			return uint16(i)
-		}
-	}
-	if mode <= cfg.S6 {
-		return 12
-	} else if mode <= cfg.F5 {
-		return 13
-	} else if mode <= cfg.G32 {
-		return 14
-	}
-	return 17
+	return table.Rank(g.RankRP, mode)
 }
@@ -0,0 +1,100 @@
+package channelserver
+
+import (
+	"errors"
+	"fmt"
+
+	"erupe-ce/common/mhfguild"
+	cfg "erupe-ce/config"
+)
+
+// GuildOps runs guild workflows for callers outside the game, such as the API
+// server. It goes through the same GuildService as the packet handlers, so
+// checks, audit log entries and mail stay identical, and it needs no running
+// channel.
+type GuildOps struct {
+	erupeConfig  *cfg.Config
+	guildRepo    GuildRepo
+	guildService *GuildService
+	locales      map[string]*i18n
+	i18n         i18n
+}
+
+// NewGuildOps creates a GuildOps. Only the Logger, ErupeConfig and DB of
+// config are used.
+func NewGuildOps(config *Config) *GuildOps {
+	o := &GuildOps{
+		erupeConfig: config.ErupeConfig,
+		guildRepo:   NewGuildRepository(config.DB),
+	}
+	mailService := NewMailService(NewMailRepository(config.DB), o.guildRepo, config.Logger)
+	o.guildService = NewGuildService(o.guildRepo, mailService, NewCharacterRepository(config.DB), config.Logger)
+	o.locales, o.i18n = loadConfiguredLocales(config.ErupeConfig, config.Logger)
+	return o
+}
+
+// opsError wraps the service errors a caller can act on with the matching
+// mhfguild error kind, leaving other errors as they are.
+func opsError(err error) error {
+	var kind error
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrUnauthorized):
+		kind = mhfguild.ErrForbidden
+	case errors.Is(err, ErrGuildNotFound), errors.Is(err, ErrAllianceNotFound), errors.Is(err, ErrApplicationMissing):
+		kind = mhfguild.ErrNotFound
+	case errors.Is(err, ErrAllianceClosed), errors.Is(err, ErrAllianceFull), errors.Is(err, ErrAlreadyAllied),
+		errors.Is(err, ErrGuildNotRecruiting), errors.Is(err, ErrGuildFull),
+		errors.Is(err, ErrAlreadyInGuild), errors.Is(err, ErrAlreadyApplied):
+		kind = mhfguild.ErrConflict
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
+// ForceRank pins a guild to a rank, or returns it to its RP rank when rank is
+// nil, on behalf of an operator. It returns ErrGuildNotFound if the guild
+// does not exist.
+func (o *GuildOps) ForceRank(guildID uint32, rank *uint16) error {
+	guild, err := o.guildRepo.GetByID(guildID)
+	if err != nil {
+		return err
+	}
+	if guild == nil {
+		return opsError(ErrGuildNotFound)
+	}
+	return o.guildService.ForceRank(guild, rank, 0, mhfguild.ConfiguredTable(o.erupeConfig),
+		o.erupeConfig.RealClientMode, rankChangeStrings(o.locales, &o.i18n))
+}
+
+// ApplyToGuild files a character's application to a guild, with the same
+// checks as applying in game.
+func (o *GuildOps) ApplyToGuild(charID, guildID uint32) error {
+	return opsError(o.guildService.ApplyToGuild(charID, guildID, mhfguild.ConfiguredTable(o.erupeConfig), o.erupeConfig.RealClientMode))
+}
+
+// ApplyToAlliance files an application from the guild led by charID to join
+// an alliance.
+func (o *GuildOps) ApplyToAlliance(charID, guildID, allianceID uint32) error {
+	return opsError(o.guildService.ApplyToAlliance(charID, guildID, allianceID))
+}
+
+// WithdrawAllianceApplication withdraws the pending application of the guild
+// led by charID to an alliance.
+func (o *GuildOps) WithdrawAllianceApplication(charID, guildID, allianceID uint32) error {
+	return opsError(o.guildService.WithdrawAllianceApplication(charID, guildID, allianceID))
+}
+
+// AnswerAllianceApplication accepts or declines a guild's application to the
+// alliance whose parent guild charID leads.
+func (o *GuildOps) AnswerAllianceApplication(charID, allianceID, guildID uint32, accept bool) error {
+	return opsError(o.guildService.AnswerAllianceApplication(charID, allianceID, guildID, accept))
+}
+
+// SetAllianceRecruiting opens or closes the alliance whose parent guild charID
+// leads to new applications.
+func (o *GuildOps) SetAllianceRecruiting(charID, allianceID uint32, recruiting bool) error {
+	return opsError(o.guildService.SetAllianceRecruiting(charID, allianceID, recruiting))
+}
@@ -0,0 +1,101 @@
+package channelserver
+
+import (
+	"errors"
+	"testing"
+
+	"erupe-ce/common/mhfguild"
+	cfg "erupe-ce/config"
+)
+
+// newTestGuildOps returns a GuildOps backed by mock repos.
+func newTestGuildOps(guildMock *mockGuildRepo, mailMock *mockMailRepo) *GuildOps {
+	server := createMockServer()
+	server.erupeConfig.RealClientMode = cfg.ZZ
+	return &GuildOps{
+		erupeConfig:  server.erupeConfig,
+		guildRepo:    guildMock,
+		guildService: newTestGuildService(guildMock, mailMock),
+		locales:      server.locales,
+		i18n:         server.i18n,
+	}
+}
+
+func TestGuildOps_ForceRank(t *testing.T) {
+	guildMock := &mockGuildRepo{guild: &Guild{ID: 10, Name: "Hunters"}}
+	mailMock := &mockMailRepo{}
+	ops := newTestGuildOps(guildMock, mailMock)
+
+	rank := uint16(5)
+	if err := ops.ForceRank(10, &rank); err != nil {
+		t.Fatalf("ForceRank failed: %v", err)
+	}
+	if guildMock.forcedRank == nil || *guildMock.forcedRank != 5 {
+		t.Errorf("forced rank = %v, want 5", guildMock.forcedRank)
+	}
+	if len(mailMock.guildMails) != 1 || len(mailMock.guildMails[0].localized) == 0 {
+		t.Errorf("guild mails = %+v, want one localized rank change mail", mailMock.guildMails)
+	}
+	if len(guildMock.events) != 1 || guildMock.events[0].ActorID != 0 {
+		t.Errorf("events = %+v, want one rank change by the operator", guildMock.events)
+	}
+}
+
+func TestGuildOps_ForceRank_UnknownGuild(t *testing.T) {
+	guildMock := &mockGuildRepo{}
+	ops := newTestGuildOps(guildMock, &mockMailRepo{})
+
+	rank := uint16(5)
+	if err := ops.ForceRank(10, &rank); !errors.Is(err, ErrGuildNotFound) || !errors.Is(err, mhfguild.ErrNotFound) {
+		t.Errorf("ForceRank = %v, want ErrGuildNotFound and mhfguild.ErrNotFound", err)
+	}
+	if guildMock.forcedRankSet {
+		t.Error("unknown guild should not be changed")
+	}
+}
+
+func TestGuildOps_AllianceApplication(t *testing.T) {
+	guildMock := newAllianceTestRepo()
+	mailMock := &mockMailRepo{}
+	ops := newTestGuildOps(guildMock, mailMock)
+
+	if err := ops.SetAllianceRecruiting(2, 5, false); !errors.Is(err, mhfguild.ErrForbidden) {
+		t.Errorf("SetAllianceRecruiting by non-leader = %v, want mhfguild.ErrForbidden", err)
+	}
+	if err := ops.ApplyToAlliance(2, 20, 5); err != nil {
+		t.Fatalf("ApplyToAlliance failed: %v", err)
+	}
+	if err := ops.WithdrawAllianceApplication(2, 20, 5); err != nil {
+		t.Fatalf("WithdrawAllianceApplication failed: %v", err)
+	}
+	if err := ops.AnswerAllianceApplication(1, 5, 20, true); !errors.Is(err, mhfguild.ErrNotFound) {
+		t.Errorf("AnswerAllianceApplication after withdrawal = %v, want mhfguild.ErrNotFound", err)
+	}
+	if err := ops.SetAllianceRecruiting(1, 5, false); err != nil {
+		t.Fatalf("SetAllianceRecruiting failed: %v", err)
+	}
+	if err := ops.ApplyToAlliance(2, 20, 5); !errors.Is(err, mhfguild.ErrConflict) || !errors.Is(err, ErrAllianceClosed) {
+		t.Errorf("ApplyToAlliance to a closed alliance = %v, want mhfguild.ErrConflict", err)
+	}
+}
+
+func TestGuildOps_ApplyToGuild(t *testing.T) {
+	guildMock := &mockGuildRepo{guild: &Guild{ID: 10}}
+	ops := newTestGuildOps(guildMock, &mockMailRepo{})
+
+	if err := ops.ApplyToGuild(2, 10); err != nil {
+		t.Fatalf("ApplyToGuild failed: %v", err)
+	}
+	if guildMock.appliedArgs == nil {
+		t.Error("application should be filed through the repo")
+	}
+	for _, repoErr := range []error{ErrGuildNotRecruiting, ErrGuildFull, ErrAlreadyInGuild, ErrAlreadyApplied} {
+		guildMock.applyErr = repoErr
+		if err := ops.ApplyToGuild(2, 10); !errors.Is(err, mhfguild.ErrConflict) || !errors.Is(err, repoErr) {
+			t.Errorf("ApplyToGuild = %v, want mhfguild.ErrConflict wrapping %v", err, repoErr)
+		}
+	}
+	if err := ops.ApplyToGuild(2, 11); !errors.Is(err, mhfguild.ErrNotFound) {
+		t.Errorf("ApplyToGuild for an unknown guild = %v, want mhfguild.ErrNotFound", err)
+	}
+}
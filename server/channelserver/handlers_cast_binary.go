@@ -49,7 +49,7 @@ func handleMsgSysCastBinary(s *Session, p mhfpacket.MHFPacket) {
 				_ = tmp.ReadBytes(9)
 				tmp.SetLE()
 				frame := tmp.ReadUint32()
-				sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.timer, frame/30/60/60, frame/30/60, frame/30%60, int(math.Round(float64(frame%30*100)/3)), frame))
+				sendServerChatMessage(s, fmt.Sprintf(s.lang().timer, frame/30/60/60, frame/30/60, frame/30%60, int(math.Round(float64(frame%30*100)/3)), frame))
 			}
 		}
 	}
@@ -89,6 +89,17 @@ func handleMsgSysCastBinary(s *Session, p mhfpacket.MHFPacket) {
 			return
 		}
 		realPayload = msgBinTargeted.RawDataPayload
+		if pkt.MessageType == BinaryMessageTypeChat {
+			bf := byteframe.NewByteFrameFromBytes(realPayload)
+			bf.SetLE()
+			chatMessage := &binpacket.MsgBinChat{}
+			_ = chatMessage.Parse(bf)
+			var send bool
+			realPayload, send = s.moderateChat(chatMessage, realPayload, msgBinTargeted.TargetCharIDs)
+			if !send {
+				return
+			}
+		}
 	} else if pkt.MessageType == BinaryMessageTypeChat {
 		if message == "@dice" {
 			returnToSender = true
@@ -111,6 +122,11 @@ func handleMsgSysCastBinary(s *Session, p mhfpacket.MHFPacket) {
 				parseChatCommand(s, chatMessage.Message)
 				return
 			}
+			var send bool
+			realPayload, send = s.moderateChat(chatMessage, realPayload, nil)
+			if !send {
+				return
+			}
 			if (pkt.BroadcastType == BroadcastTypeStage && s.stage.id == "sl1Ns200p0a0u0") || pkt.BroadcastType == BroadcastTypeWorld {
 				s.server.DiscordChannelSend(chatMessage.SenderName, chatMessage.Message)
 			}
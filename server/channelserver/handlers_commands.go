@@ -2,7 +2,9 @@ package channelserver
 
 import (
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
+	"errors"
 	"erupe-ce/common/byteframe"
 	"erupe-ce/common/mhfcid"
 	"erupe-ce/common/mhfcourse"
@@ -41,7 +43,7 @@ func initCommands(cmds []cfg.Command, logger *zap.Logger) {
 }
 
 func sendDisabledCommandMessage(s *Session, cmd cfg.Command) {
-	sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.disabled, cmd.Name))
+	sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.disabled, cmd.Name))
 }
 
 const chatFlagServer = 0x80 // marks a message as server-originated
@@ -68,6 +70,66 @@ func sendServerChatMessage(s *Session, message string) {
 	s.QueueSendMHFNonBlocking(castedBin)
 }
 
+// parseBanLength converts a ban length argument such as "30d" or "2hours" into
+// an absolute expiry time. Unrecognised units yield a zero time, which is
+// treated as a permanent ban.
+func parseBanLength(arg string) (time.Time, error) {
+	var expiry time.Time
+	var length int
+	var unit string
+	n, err := fmt.Sscanf(arg, `%d%s`, &length, &unit)
+	if err != nil || n != 2 {
+		return expiry, fmt.Errorf("invalid ban length %q", arg)
+	}
+	switch unit {
+	case "s", "second", "seconds":
+		expiry = time.Now().Add(time.Duration(length) * time.Second)
+	case "m", "mi", "minute", "minutes":
+		expiry = time.Now().Add(time.Duration(length) * time.Minute)
+	case "h", "hour", "hours":
+		expiry = time.Now().Add(time.Duration(length) * time.Hour)
+	case "d", "day", "days":
+		expiry = time.Now().Add(time.Duration(length) * time.Hour * 24)
+	case "mo", "month", "months":
+		expiry = time.Now().Add(time.Duration(length) * time.Hour * 24 * 30)
+	case "y", "year", "years":
+		expiry = time.Now().Add(time.Duration(length) * time.Hour * 24 * 365)
+	}
+	return expiry, nil
+}
+
+// banCharacter bans the account owning the given character and disconnects
+// all of its sessions. A zero expiry bans permanently. It returns the banned
+// account's username, or an error if the character has no owning account.
+func (s *Server) banCharacter(cid uint32, expiry time.Time) (string, error) {
+	uid, uname, err := s.userRepo.GetByIDAndUsername(cid)
+	if err != nil {
+		return "", err
+	}
+	if expiry.IsZero() {
+		if err := s.userRepo.BanUser(uid, nil); err != nil {
+			s.logger.Error("Failed to ban user", zap.Error(err))
+		}
+	} else {
+		if err := s.userRepo.BanUser(uid, &expiry); err != nil {
+			s.logger.Error("Failed to ban user with expiry", zap.Error(err))
+		}
+	}
+	s.DisconnectUser(uid)
+	return uname, nil
+}
+
+// kickCharacter disconnects all sessions of the account owning the given
+// character. It returns the account's username.
+func (s *Server) kickCharacter(cid uint32) (string, error) {
+	uid, uname, err := s.userRepo.GetByIDAndUsername(cid)
+	if err != nil {
+		return "", err
+	}
+	s.DisconnectUser(uid)
+	return uname, nil
+}
+
 func parseChatCommand(s *Session, command string) {
 	args := strings.Split(command[len(s.server.erupeConfig.CommandPrefix):], " ")
 	switch args[0] {
@@ -76,56 +138,39 @@ func parseChatCommand(s *Session, command string) {
 			if len(args) > 1 {
 				var expiry time.Time
 				if len(args) > 2 {
-					var length int
-					var unit string
-					n, err := fmt.Sscanf(args[2], `%d%s`, &length, &unit)
-					if err == nil && n == 2 {
-						switch unit {
-						case "s", "second", "seconds":
-							expiry = time.Now().Add(time.Duration(length) * time.Second)
-						case "m", "mi", "minute", "minutes":
-							expiry = time.Now().Add(time.Duration(length) * time.Minute)
-						case "h", "hour", "hours":
-							expiry = time.Now().Add(time.Duration(length) * time.Hour)
-						case "d", "day", "days":
-							expiry = time.Now().Add(time.Duration(length) * time.Hour * 24)
-						case "mo", "month", "months":
-							expiry = time.Now().Add(time.Duration(length) * time.Hour * 24 * 30)
-						case "y", "year", "years":
-							expiry = time.Now().Add(time.Duration(length) * time.Hour * 24 * 365)
-						}
-					} else {
-						sendServerChatMessage(s, s.server.i18n.commands.ban.error)
+					var err error
+					expiry, err = parseBanLength(args[2])
+					if err != nil {
+						sendServerChatMessage(s, s.lang().commands.ban.error)
 						return
 					}
 				}
 				cid := mhfcid.ConvertCID(args[1])
 				if cid > 0 {
-					uid, uname, err := s.server.userRepo.GetByIDAndUsername(cid)
+					uname, err := s.server.banCharacter(cid, expiry)
 					if err == nil {
 						if expiry.IsZero() {
-							if err := s.server.userRepo.BanUser(uid, nil); err != nil {
-								s.logger.Error("Failed to ban user", zap.Error(err))
-							}
-							sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.ban.success, uname))
+							sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.ban.success, uname))
 						} else {
-							if err := s.server.userRepo.BanUser(uid, &expiry); err != nil {
-								s.logger.Error("Failed to ban user with expiry", zap.Error(err))
-							}
-							sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.ban.success, uname)+fmt.Sprintf(s.server.i18n.commands.ban.length, expiry.Format(time.DateTime)))
+							sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.ban.success, uname)+fmt.Sprintf(s.lang().commands.ban.length, expiry.Format(time.DateTime)))
 						}
-						s.server.DisconnectUser(uid)
 					} else {
-						sendServerChatMessage(s, s.server.i18n.commands.ban.noUser)
+						sendServerChatMessage(s, s.lang().commands.ban.noUser)
 					}
 				} else {
-					sendServerChatMessage(s, s.server.i18n.commands.ban.invalid)
+					sendServerChatMessage(s, s.lang().commands.ban.invalid)
 				}
 			} else {
-				sendServerChatMessage(s, s.server.i18n.commands.ban.error)
+				sendServerChatMessage(s, s.lang().commands.ban.error)
 			}
 		} else {
-			sendServerChatMessage(s, s.server.i18n.commands.noOp)
+			sendServerChatMessage(s, s.lang().commands.noOp)
+		}
+	case commands["Announce"].Prefix:
+		if s.isOp() {
+			parseAnnounceCommand(s, args)
+		} else {
+			sendServerChatMessage(s, s.lang().commands.noOp)
 		}
 	case commands["Timer"].Prefix:
 		if commands["Timer"].Enabled || s.isOp() {
@@ -137,9 +182,9 @@ func parseChatCommand(s *Session, command string) {
 				s.logger.Error("Failed to update timer setting", zap.Error(err))
 			}
 			if state {
-				sendServerChatMessage(s, s.server.i18n.commands.timer.disabled)
+				sendServerChatMessage(s, s.lang().commands.timer.disabled)
 			} else {
-				sendServerChatMessage(s, s.server.i18n.commands.timer.enabled)
+				sendServerChatMessage(s, s.lang().commands.timer.enabled)
 			}
 		} else {
 			sendDisabledCommandMessage(s, commands["Timer"])
@@ -154,20 +199,20 @@ func parseChatCommand(s *Session, command string) {
 				if exists == 0 {
 					err := s.server.userRepo.SetPSNID(s.userID, args[1])
 					if err == nil {
-						sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.psn.success, args[1]))
+						sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.psn.success, args[1]))
 					}
 				} else {
-					sendServerChatMessage(s, s.server.i18n.commands.psn.exists)
+					sendServerChatMessage(s, s.lang().commands.psn.exists)
 				}
 			} else {
-				sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.psn.error, commands["PSN"].Prefix))
+				sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.psn.error, commands["PSN"].Prefix))
 			}
 		} else {
 			sendDisabledCommandMessage(s, commands["PSN"])
 		}
 	case commands["Reload"].Prefix:
 		if commands["Reload"].Enabled || s.isOp() {
-			sendServerChatMessage(s, s.server.i18n.commands.reload)
+			sendServerChatMessage(s, s.lang().commands.reload)
 			var temp mhfpacket.MHFPacket
 			deleteNotif := byteframe.NewByteFrame()
 			for _, object := range s.stage.objects {
@@ -229,24 +274,24 @@ func parseChatCommand(s *Session, command string) {
 	case commands["KeyQuest"].Prefix:
 		if commands["KeyQuest"].Enabled || s.isOp() {
 			if s.server.erupeConfig.RealClientMode < cfg.G10 {
-				sendServerChatMessage(s, s.server.i18n.commands.kqf.version)
+				sendServerChatMessage(s, s.lang().commands.kqf.version)
 			} else {
 				if len(args) > 1 {
 					switch args[1] {
 					case "get":
-						sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.kqf.get, s.kqf))
+						sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.kqf.get, s.kqf))
 					case "set":
 						if len(args) > 2 && len(args[2]) == 16 {
 							hexd, err := hex.DecodeString(args[2])
 							if err != nil {
-								sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.kqf.set.error, commands["KeyQuest"].Prefix))
+								sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.kqf.set.error, commands["KeyQuest"].Prefix))
 								return
 							}
 							s.kqf = hexd
 							s.kqfOverride = true
-							sendServerChatMessage(s, s.server.i18n.commands.kqf.set.success)
+							sendServerChatMessage(s, s.lang().commands.kqf.set.success)
 						} else {
-							sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.kqf.set.error, commands["KeyQuest"].Prefix))
+							sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.kqf.set.error, commands["KeyQuest"].Prefix))
 						}
 					}
 				}
@@ -259,17 +304,17 @@ func parseChatCommand(s *Session, command string) {
 			if len(args) > 1 {
 				v, err := strconv.Atoi(args[1])
 				if err != nil {
-					sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.rights.error, commands["Rights"].Prefix))
+					sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.rights.error, commands["Rights"].Prefix))
 					return
 				}
 				err = s.server.userRepo.SetRights(s.userID, uint32(v))
 				if err == nil {
-					sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.rights.success, v))
+					sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.rights.success, v))
 				} else {
-					sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.rights.error, commands["Rights"].Prefix))
+					sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.rights.error, commands["Rights"].Prefix))
 				}
 			} else {
-				sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.rights.error, commands["Rights"].Prefix))
+				sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.rights.error, commands["Rights"].Prefix))
 			}
 		} else {
 			sendDisabledCommandMessage(s, commands["Rights"])
@@ -293,11 +338,11 @@ func parseChatCommand(s *Session, command string) {
 									})
 									if ei != -1 {
 										delta = uint32(-1 * math.Pow(2, float64(course.ID)))
-										sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.course.disabled, course.Aliases()[0]))
+										sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.course.disabled, course.Aliases()[0]))
 									}
 								} else {
 									delta = uint32(math.Pow(2, float64(course.ID)))
-									sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.course.enabled, course.Aliases()[0]))
+									sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.course.enabled, course.Aliases()[0]))
 								}
 								rightsInt, err := s.server.userRepo.GetRights(s.userID)
 								if err == nil {
@@ -307,14 +352,14 @@ func parseChatCommand(s *Session, command string) {
 								}
 								updateRights(s)
 							} else {
-								sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.course.locked, course.Aliases()[0]))
+								sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.course.locked, course.Aliases()[0]))
 							}
 							return
 						}
 					}
 				}
 			} else {
-				sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.course.error, commands["Course"].Prefix))
+				sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.course.error, commands["Course"].Prefix))
 			}
 		} else {
 			sendDisabledCommandMessage(s, commands["Course"])
@@ -327,45 +372,45 @@ func parseChatCommand(s *Session, command string) {
 					case "start":
 						if s.server.raviente.register[1] == 0 {
 							s.server.raviente.register[1] = s.server.raviente.register[3]
-							sendServerChatMessage(s, s.server.i18n.commands.ravi.start.success)
+							sendServerChatMessage(s, s.lang().commands.ravi.start.success)
 							s.notifyRavi()
 						} else {
-							sendServerChatMessage(s, s.server.i18n.commands.ravi.start.error)
+							sendServerChatMessage(s, s.lang().commands.ravi.start.error)
 						}
 					case "cm", "check", "checkmultiplier", "multiplier":
-						sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.ravi.multiplier, s.server.GetRaviMultiplier()))
+						sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.ravi.multiplier, s.server.GetRaviMultiplier()))
 					case "sr", "sendres", "resurrection", "ss", "sendsed", "rs", "reqsed":
 						if s.server.erupeConfig.RealClientMode == cfg.ZZ {
 							switch args[1] {
 							case "sr", "sendres", "resurrection":
 								if s.server.raviente.state[28] > 0 {
-									sendServerChatMessage(s, s.server.i18n.commands.ravi.res.success)
+									sendServerChatMessage(s, s.lang().commands.ravi.res.success)
 									s.server.raviente.state[28] = 0
 								} else {
-									sendServerChatMessage(s, s.server.i18n.commands.ravi.res.error)
+									sendServerChatMessage(s, s.lang().commands.ravi.res.error)
 								}
 							case "ss", "sendsed":
-								sendServerChatMessage(s, s.server.i18n.commands.ravi.sed.success)
+								sendServerChatMessage(s, s.lang().commands.ravi.sed.success)
 								// Total BerRavi HP
 								HP := s.server.raviente.state[0] + s.server.raviente.state[1] + s.server.raviente.state[2] + s.server.raviente.state[3] + s.server.raviente.state[4]
 								s.server.raviente.support[1] = HP
 							case "rs", "reqsed":
-								sendServerChatMessage(s, s.server.i18n.commands.ravi.request)
+								sendServerChatMessage(s, s.lang().commands.ravi.request)
 								// Total BerRavi HP
 								HP := s.server.raviente.state[0] + s.server.raviente.state[1] + s.server.raviente.state[2] + s.server.raviente.state[3] + s.server.raviente.state[4]
 								s.server.raviente.support[1] = HP + 1
 							}
 						} else {
-							sendServerChatMessage(s, s.server.i18n.commands.ravi.version)
+							sendServerChatMessage(s, s.lang().commands.ravi.version)
 						}
 					default:
-						sendServerChatMessage(s, s.server.i18n.commands.ravi.error)
+						sendServerChatMessage(s, s.lang().commands.ravi.error)
 					}
 				} else {
-					sendServerChatMessage(s, s.server.i18n.commands.ravi.noPlayers)
+					sendServerChatMessage(s, s.lang().commands.ravi.noPlayers)
 				}
 			} else {
-				sendServerChatMessage(s, s.server.i18n.commands.ravi.error)
+				sendServerChatMessage(s, s.lang().commands.ravi.error)
 			}
 		} else {
 			sendDisabledCommandMessage(s, commands["Raviente"])
@@ -375,12 +420,12 @@ func parseChatCommand(s *Session, command string) {
 			if len(args) > 2 {
 				x, err := strconv.ParseInt(args[1], 10, 16)
 				if err != nil {
-					sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.teleport.error, commands["Teleport"].Prefix))
+					sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.teleport.error, commands["Teleport"].Prefix))
 					return
 				}
 				y, err := strconv.ParseInt(args[2], 10, 16)
 				if err != nil {
-					sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.teleport.error, commands["Teleport"].Prefix))
+					sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.teleport.error, commands["Teleport"].Prefix))
 					return
 				}
 				payload := byteframe.NewByteFrame()
@@ -394,9 +439,9 @@ func parseChatCommand(s *Session, command string) {
 					MessageType:    BinaryMessageTypeState,
 					RawDataPayload: payloadBytes,
 				})
-				sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.teleport.success, x, y))
+				sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.teleport.success, x, y))
 			} else {
-				sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.teleport.error, commands["Teleport"].Prefix))
+				sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.teleport.error, commands["Teleport"].Prefix))
 			}
 		} else {
 			sendDisabledCommandMessage(s, commands["Teleport"])
@@ -412,17 +457,38 @@ func parseChatCommand(s *Session, command string) {
 					s.logger.Error("Failed to update discord token", zap.Error(err))
 				}
 			}
-			sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.discord.success, _token))
+			sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.discord.success, _token))
 		} else {
 			sendDisabledCommandMessage(s, commands["Discord"])
 		}
 	case commands["Playtime"].Prefix:
 		if commands["Playtime"].Enabled || s.isOp() {
 			playtime := s.playtime + uint32(time.Since(s.playtimeTime).Seconds())
-			sendServerChatMessage(s, fmt.Sprintf(s.server.i18n.commands.playtime, playtime/60/60, playtime/60%60, playtime%60))
+			sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.playtime, playtime/60/60, playtime/60%60, playtime%60))
 		} else {
 			sendDisabledCommandMessage(s, commands["Playtime"])
 		}
+	case commands["Language"].Prefix:
+		if commands["Language"].Enabled || s.isOp() {
+			available := strings.Join(s.server.localeCodes(), ", ")
+			if len(args) < 2 {
+				sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.lang.current, s.lang().language, available))
+				return
+			}
+			code := strings.ToLower(args[1])
+			locale, ok := s.server.locales[code]
+			if !ok {
+				sendServerChatMessage(s, fmt.Sprintf(s.lang().commands.lang.error, available))
+				return
+			}
+			if err := s.server.charRepo.SaveString(s.charID, "language", code); err != nil {
+				s.logger.Error("Failed to save language preference", zap.Error(err))
+			}
+			s.setLanguage(code)
+			sendServerChatMessage(s, fmt.Sprintf(locale.commands.lang.success, locale.language))
+		} else {
+			sendDisabledCommandMessage(s, commands["Language"])
+		}
 	case commands["Help"].Prefix:
 		if commands["Help"].Enabled || s.isOp() {
 			for _, command := range commands {
@@ -435,3 +501,114 @@ func parseChatCommand(s *Session, command string) {
 		}
 	}
 }
+
+// parseAnnounceCommand manages scheduled announcements:
+//
+//	list
+//	once <time> <message>
+//	cron <minute> <hour> <day> <month> <weekday> <message>
+//	countdown <time> <minutes,...> <message>
+//	delete <id>
+func parseAnnounceCommand(s *Session, args []string) {
+	lang := s.lang().commands.announce
+	usage := fmt.Sprintf(lang.error, s.server.erupeConfig.CommandPrefix+commands["Announce"].Prefix)
+	if len(args) < 2 {
+		sendServerChatMessage(s, usage)
+		return
+	}
+
+	a := Announcement{Kind: args[1], Enabled: true, CreatedBy: fmt.Sprintf("char:%d", s.charID)}
+	var msgStart int
+	switch args[1] {
+	case "list":
+		announcements, err := s.server.announcementRepo.List()
+		if err != nil {
+			s.logger.Error("Failed to list announcements", zap.Error(err))
+			return
+		}
+		if len(announcements) == 0 {
+			sendServerChatMessage(s, lang.empty)
+		}
+		for _, a := range announcements {
+			var when string
+			switch a.Kind {
+			case AnnouncementRecurring:
+				when = a.Schedule
+			case AnnouncementCountdown:
+				when = fmt.Sprintf("%s %v", a.SendAt.Time.Local().Format("2006-01-02T15:04"), []int64(a.Countdown))
+			default:
+				when = a.SendAt.Time.Local().Format("2006-01-02T15:04")
+			}
+			sendServerChatMessage(s, fmt.Sprintf(lang.list, a.ID, a.Kind, when, a.Message))
+		}
+		return
+	case "delete":
+		if len(args) < 3 {
+			sendServerChatMessage(s, usage)
+			return
+		}
+		id, err := strconv.ParseUint(args[2], 10, 32)
+		if err != nil {
+			sendServerChatMessage(s, usage)
+			return
+		}
+		if err := s.server.announcementRepo.Delete(uint32(id)); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				s.logger.Error("Failed to delete announcement", zap.Error(err))
+			}
+			sendServerChatMessage(s, fmt.Sprintf(lang.notFound, id))
+			return
+		}
+		sendServerChatMessage(s, fmt.Sprintf(lang.deleted, id))
+		return
+	case "once":
+		a.Kind = AnnouncementOnce
+		msgStart = 3
+	case "cron":
+		a.Kind = AnnouncementRecurring
+		msgStart = 7
+		if len(args) > msgStart {
+			a.Schedule = strings.Join(args[2:7], " ")
+		}
+	case "countdown":
+		a.Kind = AnnouncementCountdown
+		msgStart = 4
+		if len(args) > msgStart {
+			for _, m := range strings.Split(args[3], ",") {
+				minutes, err := strconv.ParseInt(m, 10, 64)
+				if err != nil {
+					sendServerChatMessage(s, fmt.Sprintf(lang.invalid, err))
+					return
+				}
+				a.Countdown = append(a.Countdown, minutes)
+			}
+		}
+	default:
+		sendServerChatMessage(s, usage)
+		return
+	}
+	if len(args) <= msgStart {
+		sendServerChatMessage(s, usage)
+		return
+	}
+	if a.Kind != AnnouncementRecurring {
+		t, err := parseAnnouncementTime(args[2])
+		if err != nil {
+			sendServerChatMessage(s, fmt.Sprintf(lang.invalid, err))
+			return
+		}
+		a.SendAt = sql.NullTime{Time: t, Valid: true}
+	}
+	a.Message = strings.Join(args[msgStart:], " ")
+	if err := a.Validate(); err != nil {
+		sendServerChatMessage(s, fmt.Sprintf(lang.invalid, err))
+		return
+	}
+	id, err := s.server.announcementRepo.Create(&a)
+	if err != nil {
+		s.logger.Error("Failed to create announcement", zap.Error(err))
+		return
+	}
+	s.logger.Info("Announcement created", zap.Uint32("id", id), zap.Uint32("charID", s.charID))
+	sendServerChatMessage(s, fmt.Sprintf(lang.created, id))
+}
@@ -99,6 +99,8 @@ func setupCommandsMap(allEnabled bool) {
 		"Teleport": {Name: "Teleport", Prefix: "tp", Enabled: allEnabled},
 		"Discord":  {Name: "Discord", Prefix: "discord", Enabled: allEnabled},
 		"Playtime": {Name: "Playtime", Prefix: "playtime", Enabled: allEnabled},
+		"Language": {Name: "Language", Prefix: "lang", Enabled: allEnabled},
+		"Announce": {Name: "Announce", Prefix: "announce", Enabled: allEnabled},
 		"Help":     {Name: "Help", Prefix: "help", Enabled: allEnabled},
 	}
 }
@@ -314,6 +316,180 @@ func TestParseChatCommand_Playtime(t *testing.T) {
 	}
 }
 
+// --- Language ---
+
+func TestParseChatCommand_Language_ShowsCurrent(t *testing.T) {
+	setupCommandsMap(true)
+	s := createCommandSession(&mockUserRepoCommands{})
+
+	parseChatCommand(s, "!lang")
+
+	if n := drainChatResponses(s); n != 1 {
+		t.Errorf("chat responses = %d, want 1", n)
+	}
+	if s.languageCode() != "" {
+		t.Errorf("language = %q, want unchanged", s.languageCode())
+	}
+}
+
+func TestParseChatCommand_Language_Sets(t *testing.T) {
+	setupCommandsMap(true)
+	s := createCommandSession(&mockUserRepoCommands{})
+	charRepo := s.server.charRepo.(*mockCharacterRepo)
+
+	parseChatCommand(s, "!lang JP")
+
+	if s.languageCode() != "jp" {
+		t.Errorf("language = %q, want jp", s.languageCode())
+	}
+	if charRepo.strings["language"] != "jp" {
+		t.Errorf("saved language = %q, want jp", charRepo.strings["language"])
+	}
+	if s.lang().language != "日本語" {
+		t.Errorf("lang().language = %q, want 日本語", s.lang().language)
+	}
+	if n := drainChatResponses(s); n != 1 {
+		t.Errorf("chat responses = %d, want 1", n)
+	}
+}
+
+func TestParseChatCommand_Language_Unknown(t *testing.T) {
+	setupCommandsMap(true)
+	s := createCommandSession(&mockUserRepoCommands{})
+	charRepo := s.server.charRepo.(*mockCharacterRepo)
+
+	parseChatCommand(s, "!lang xx")
+
+	if s.languageCode() != "" {
+		t.Errorf("language = %q, want unchanged", s.languageCode())
+	}
+	if _, ok := charRepo.strings["language"]; ok {
+		t.Error("unknown language should not be saved")
+	}
+	if n := drainChatResponses(s); n != 1 {
+		t.Errorf("chat responses = %d, want 1", n)
+	}
+}
+
+func TestParseChatCommand_Language_Disabled(t *testing.T) {
+	setupCommandsMap(false)
+	s := createCommandSession(&mockUserRepoCommands{opResult: false})
+
+	parseChatCommand(s, "!lang jp")
+
+	if s.languageCode() != "" {
+		t.Errorf("language = %q, want unchanged when disabled", s.languageCode())
+	}
+}
+
+// --- Announce ---
+
+func createAnnounceSession(op bool) (*Session, *mockAnnouncementRepo) {
+	setupCommandsMap(true)
+	s := createCommandSession(&mockUserRepoCommands{opResult: op})
+	repo := &mockAnnouncementRepo{}
+	s.server.announcementRepo = repo
+	return s, repo
+}
+
+func TestParseChatCommand_Announce_NoOp(t *testing.T) {
+	s, repo := createAnnounceSession(false)
+
+	parseChatCommand(s, "!announce once 2026-10-18T20:00 hello")
+
+	if len(repo.announcements) != 0 {
+		t.Error("non-op should not create announcements")
+	}
+	if n := drainChatResponses(s); n != 1 {
+		t.Errorf("chat responses = %d, want 1", n)
+	}
+}
+
+func TestParseChatCommand_Announce_Create(t *testing.T) {
+	tests := []struct {
+		command  string
+		kind     string
+		message  string
+		schedule string
+	}{
+		{"!announce once 2026-10-18T20:00 <C_4>Maintenance soon", AnnouncementOnce, "<C_4>Maintenance soon", ""},
+		{"!announce cron 0 */2 * * * Event reminder", AnnouncementRecurring, "Event reminder", "0 */2 * * *"},
+		{"!announce countdown 2026-10-18T20:00 30,10,1 Restart in {minutes} min", AnnouncementCountdown, "Restart in {minutes} min", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			s, repo := createAnnounceSession(true)
+
+			parseChatCommand(s, tt.command)
+
+			if len(repo.announcements) != 1 {
+				t.Fatalf("announcements = %d, want 1", len(repo.announcements))
+			}
+			a := repo.announcements[0]
+			if a.Kind != tt.kind || a.Message != tt.message || a.Schedule != tt.schedule || !a.Enabled {
+				t.Errorf("announcement = %+v", a)
+			}
+			if tt.kind == AnnouncementCountdown && len(a.Countdown) != 3 {
+				t.Errorf("countdown = %v, want 3 offsets", a.Countdown)
+			}
+			if n := drainChatResponses(s); n != 1 {
+				t.Errorf("chat responses = %d, want 1", n)
+			}
+		})
+	}
+}
+
+func TestParseChatCommand_Announce_Invalid(t *testing.T) {
+	for _, command := range []string{
+		"!announce",
+		"!announce once",
+		"!announce once tomorrow hello",
+		"!announce cron 0 * * * hello",
+		"!announce cron 99 * * * * hello",
+		"!announce countdown 2026-10-18T20:00 ten hello",
+		"!announce weekly hello",
+		"!announce delete abc",
+	} {
+		s, repo := createAnnounceSession(true)
+
+		parseChatCommand(s, command)
+
+		if len(repo.announcements) != 0 {
+			t.Errorf("%q created an announcement", command)
+		}
+		if n := drainChatResponses(s); n != 1 {
+			t.Errorf("%q: chat responses = %d, want 1", command, n)
+		}
+	}
+}
+
+func TestParseChatCommand_Announce_ListAndDelete(t *testing.T) {
+	s, repo := createAnnounceSession(true)
+
+	parseChatCommand(s, "!announce list")
+	if n := drainChatResponses(s); n != 1 {
+		t.Errorf("empty list responses = %d, want 1", n)
+	}
+
+	parseChatCommand(s, "!announce cron 0 * * * * one")
+	parseChatCommand(s, "!announce cron 30 * * * * two")
+	drainChatResponses(s)
+
+	parseChatCommand(s, "!announce list")
+	if n := drainChatResponses(s); n != 2 {
+		t.Errorf("list responses = %d, want 2", n)
+	}
+
+	parseChatCommand(s, "!announce delete 1")
+	if len(repo.announcements) != 1 || repo.announcements[0].ID != 2 {
+		t.Errorf("announcements after delete = %+v", repo.announcements)
+	}
+	parseChatCommand(s, "!announce delete 1")
+	if n := drainChatResponses(s); n != 2 {
+		t.Errorf("delete responses = %d, want 2", n)
+	}
+}
+
 // --- Help ---
 
 func TestParseChatCommand_Help_ListsCommands(t *testing.T) {
@@ -599,6 +775,53 @@ func TestParseChatCommand_Course_MissingArgs(t *testing.T) {
 	}
 }
 
+// --- Ban helpers ---
+
+func TestParseBanLength(t *testing.T) {
+	tests := []struct {
+		arg     string
+		wantErr bool
+		zero    bool
+	}{
+		{"30d", false, false},
+		{"2hours", false, false},
+		{"5x", false, true},
+		{"badformat", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			expiry, err := parseBanLength(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBanLength(%q) error = %v, wantErr %v", tt.arg, err, tt.wantErr)
+			}
+			if expiry.IsZero() != tt.zero {
+				t.Errorf("parseBanLength(%q) = %v, zero = %v, want zero %v", tt.arg, expiry, expiry.IsZero(), tt.zero)
+			}
+		})
+	}
+}
+
+func TestKickCharacter(t *testing.T) {
+	repo := &mockUserRepoCommands{foundUID: 42, foundName: "TestUser"}
+	s := createCommandSession(repo)
+
+	uname, err := s.server.kickCharacter(1)
+	if err != nil {
+		t.Fatalf("kickCharacter() error = %v", err)
+	}
+	if uname != "TestUser" {
+		t.Errorf("kickCharacter() = %q, want TestUser", uname)
+	}
+	if repo.bannedUID != 0 {
+		t.Error("kick should not ban the user")
+	}
+
+	repo.findErr = errors.New("not found")
+	if _, err := s.server.kickCharacter(1); err == nil {
+		t.Error("kickCharacter() should fail when the user is not found")
+	}
+}
+
 // --- Ban (additional) ---
 
 func TestParseChatCommand_Ban_InvalidDurationFormat(t *testing.T) {
@@ -45,6 +45,7 @@ func TestEquipSkinHistSize_OlderMode(t *testing.T) {
 
 func TestHandleMsgMhfAddGuildMissionCount(t *testing.T) {
 	server := createMockServer()
+	server.guildRepo = &mockGuildRepo{}
 	session := createMockSession(1, server)
 
 	handleMsgMhfAddGuildMissionCount(session, &mhfpacket.MsgMhfAddGuildMissionCount{
@@ -63,6 +64,7 @@ func TestHandleMsgMhfAddGuildMissionCount(t *testing.T) {
 
 func TestHandleMsgMhfSetGuildMissionTarget(t *testing.T) {
 	server := createMockServer()
+	server.guildRepo = &mockGuildRepo{}
 	session := createMockSession(1, server)
 
 	handleMsgMhfSetGuildMissionTarget(session, &mhfpacket.MsgMhfSetGuildMissionTarget{
@@ -81,6 +83,7 @@ func TestHandleMsgMhfSetGuildMissionTarget(t *testing.T) {
 
 func TestHandleMsgMhfCancelGuildMissionTarget(t *testing.T) {
 	server := createMockServer()
+	server.guildRepo = &mockGuildRepo{}
 	session := createMockSession(1, server)
 
 	handleMsgMhfCancelGuildMissionTarget(session, &mhfpacket.MsgMhfCancelGuildMissionTarget{
@@ -99,6 +102,7 @@ func TestHandleMsgMhfCancelGuildMissionTarget(t *testing.T) {
 
 func TestHandleMsgMhfGetGuildMissionRecord(t *testing.T) {
 	server := createMockServer()
+	server.guildRepo = &mockGuildRepo{}
 	session := createMockSession(1, server)
 
 	handleMsgMhfGetGuildMissionRecord(session, &mhfpacket.MsgMhfGetGuildMissionRecord{
@@ -117,6 +121,7 @@ func TestHandleMsgMhfGetGuildMissionRecord(t *testing.T) {
 
 func TestHandleMsgMhfAcquireGuildTresureSouvenir(t *testing.T) {
 	server := createMockServer()
+	server.guildRepo = &mockGuildRepo{}
 	session := createMockSession(1, server)
 
 	handleMsgMhfAcquireGuildTresureSouvenir(session, &mhfpacket.MsgMhfAcquireGuildTresureSouvenir{
@@ -1,99 +1,285 @@
 package channelserver
 
 import (
+	"erupe-ce/common/mhfcid"
+	"erupe-ce/common/stringsupport"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+	"sort"
 	"strings"
-	"unicode"
+	"time"
 )
 
+// discordRespond replies to an interaction with an ephemeral message.
+func discordRespond(ds *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	_ = ds.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// discordUserID returns the ID of the Discord user who sent an interaction,
+// whether it was sent from a guild channel or a direct message.
+func discordUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// discordOptions maps the options of a slash command by name.
+func discordOptions(i *discordgo.InteractionCreate) map[string]*discordgo.ApplicationCommandInteractionDataOption {
+	opts := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range i.ApplicationCommandData().Options {
+		opts[opt.Name] = opt
+	}
+	return opts
+}
+
+// discordIsOp reports whether the Discord user is linked to an Erupe account
+// with operator rights.
+func (s *Server) discordIsOp(discordID string) bool {
+	if discordID == "" {
+		return false
+	}
+	uid, err := s.userRepo.GetByDiscordID(discordID)
+	if err != nil {
+		return false
+	}
+	op, err := s.userRepo.IsOp(uid)
+	if err != nil {
+		return false
+	}
+	return op
+}
+
 // onInteraction handles slash commands
 func (s *Server) onInteraction(ds *discordgo.Session, i *discordgo.InteractionCreate) {
-	switch i.Interaction.ApplicationCommandData().Name {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	opts := discordOptions(i)
+	switch i.ApplicationCommandData().Name {
 	case "link":
-		_, err := s.userRepo.LinkDiscord(i.Member.User.ID, i.ApplicationCommandData().Options[0].StringValue())
+		_, err := s.userRepo.LinkDiscord(discordUserID(i), opts["token"].StringValue())
 		if err == nil {
-			_ = ds.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "Your Erupe account was linked successfully.",
-					Flags:   discordgo.MessageFlagsEphemeral,
-				},
-			})
+			discordRespond(ds, i, "Your Erupe account was linked successfully.")
 		} else {
-			_ = ds.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "Failed to link Erupe account.",
-					Flags:   discordgo.MessageFlagsEphemeral,
-				},
-			})
+			discordRespond(ds, i, "Failed to link Erupe account.")
 		}
 	case "password":
-		password, err := bcrypt.GenerateFromPassword([]byte(i.ApplicationCommandData().Options[0].StringValue()), 10)
+		password, err := bcrypt.GenerateFromPassword([]byte(opts["password"].StringValue()), 10)
 		if err != nil {
-			_ = ds.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "Failed to hash password.",
-					Flags:   discordgo.MessageFlagsEphemeral,
-				},
-			})
+			discordRespond(ds, i, "Failed to hash password.")
 			return
 		}
-		err = s.userRepo.SetPasswordByDiscordID(i.Member.User.ID, password)
+		err = s.userRepo.SetPasswordByDiscordID(discordUserID(i), password)
 		if err == nil {
-			_ = ds.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "Your Erupe account password has been updated.",
-					Flags:   discordgo.MessageFlagsEphemeral,
-				},
-			})
+			discordRespond(ds, i, "Your Erupe account password has been updated.")
+		} else {
+			discordRespond(ds, i, "Failed to update Erupe account password.")
+		}
+	case "status":
+		discordRespond(ds, i, s.discordStatusMessage())
+	case "who":
+		discordRespond(ds, i, s.discordWhoMessage())
+	case "character":
+		c, err := s.charRepo.FindByName(opts["name"].StringValue())
+		if err != nil {
+			discordRespond(ds, i, "Could not find a character with that name.")
+			return
+		}
+		online := "Offline"
+		if s.FindSessionByCharID(c.ID) != nil {
+			online = "Online"
+		}
+		discordRespond(ds, i, fmt.Sprintf("**%s** (%s)\nHR %d / GR %d\nWeapon type %d, ID %d\n%s",
+			c.Name, mhfcid.ConvertToCID(c.ID), c.HR, c.GR, c.WeaponType, c.WeaponID, online))
+	case "ban":
+		if !s.discordIsOp(discordUserID(i)) {
+			discordRespond(ds, i, "You don't have permission to use this command.")
+			return
+		}
+		var expiry time.Time
+		if opt, ok := opts["length"]; ok {
+			var err error
+			expiry, err = parseBanLength(opt.StringValue())
+			if err != nil {
+				discordRespond(ds, i, "Invalid ban length. Example: 30m, 12h, 7d")
+				return
+			}
+		}
+		cid := mhfcid.ConvertCID(opts["id"].StringValue())
+		if cid == 0 {
+			discordRespond(ds, i, "Invalid Character ID.")
+			return
+		}
+		uname, err := s.banCharacter(cid, expiry)
+		if err != nil {
+			discordRespond(ds, i, "Could not find user.")
+			return
+		}
+		s.logger.Info("Discord ban issued", zap.String("discordID", discordUserID(i)), zap.String("username", uname), zap.Time("expiry", expiry))
+		if expiry.IsZero() {
+			discordRespond(ds, i, fmt.Sprintf("Successfully banned %s.", uname))
 		} else {
-			_ = ds.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "Failed to update Erupe account password.",
-					Flags:   discordgo.MessageFlagsEphemeral,
-				},
-			})
+			discordRespond(ds, i, fmt.Sprintf("Successfully banned %s until %s.", uname, expiry.Format(time.DateTime)))
+		}
+	case "kick":
+		if !s.discordIsOp(discordUserID(i)) {
+			discordRespond(ds, i, "You don't have permission to use this command.")
+			return
 		}
+		cid := mhfcid.ConvertCID(opts["id"].StringValue())
+		if cid == 0 {
+			discordRespond(ds, i, "Invalid Character ID.")
+			return
+		}
+		uname, err := s.kickCharacter(cid)
+		if err != nil {
+			discordRespond(ds, i, "Could not find user.")
+			return
+		}
+		s.logger.Info("Discord kick issued", zap.String("discordID", discordUserID(i)), zap.String("username", uname))
+		discordRespond(ds, i, fmt.Sprintf("Successfully kicked %s.", uname))
+	case "mute", "unmute":
+		if !s.discordIsOp(discordUserID(i)) {
+			discordRespond(ds, i, "You don't have permission to use this command.")
+			return
+		}
+		target := opts["user"].UserValue(nil)
+		var err error
+		if i.ApplicationCommandData().Name == "mute" {
+			err = s.discordRepo.Mute(target.ID, discordUserID(i))
+		} else {
+			err = s.discordRepo.Unmute(target.ID)
+		}
+		if err != nil {
+			s.logger.Error("Failed to update Discord relay mute", zap.Error(err))
+			discordRespond(ds, i, "Failed to update relay mute.")
+			return
+		}
+		if i.ApplicationCommandData().Name == "mute" {
+			discordRespond(ds, i, fmt.Sprintf("<@%s> will no longer be relayed in-game.", target.ID))
+		} else {
+			discordRespond(ds, i, fmt.Sprintf("<@%s> will be relayed in-game again.", target.ID))
+		}
+	case "announce":
+		if !s.discordIsOp(discordUserID(i)) {
+			discordRespond(ds, i, "You don't have permission to use this command.")
+			return
+		}
+		message := opts["message"].StringValue()
+		s.WorldcastChatMessage(message)
+		s.logger.Info("Discord announcement sent", zap.String("discordID", discordUserID(i)), zap.String("message", message))
+		discordRespond(ds, i, "Announcement sent.")
 	}
 }
 
+// discordStatusMessage lists the player count of every channel.
+func (s *Server) discordStatusMessage() string {
+	channels := s.Registry.ListChannels()
+	var sb strings.Builder
+	total := 0
+	for _, c := range channels {
+		total += c.Players
+		fmt.Fprintf(&sb, "Channel %s (%d): %d players\n", c.GlobalID, c.ServerPort, c.Players)
+	}
+	fmt.Fprintf(&sb, "**Total: %d players**", total)
+	return sb.String()
+}
+
+// discordWhoMessageLimit caps the number of characters listed by /who to stay
+// within Discord's message length limit.
+const discordWhoMessageLimit = 100
+
+// discordWhoMessage lists the characters currently online.
+func (s *Server) discordWhoMessage() string {
+	online := s.Registry.SearchSessions(func(snap SessionSnapshot) bool {
+		return snap.CharID != 0
+	}, discordWhoMessageLimit)
+	if len(online) == 0 {
+		return "No one is online."
+	}
+	names := make([]string, len(online))
+	for j, snap := range online {
+		names[j] = snap.Name
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("**%d online:** %s", len(names), strings.Join(names, ", "))
+}
+
+// discordRelayLineLength is the maximum number of Shift-JIS bytes relayed on
+// a single line of in-game chat.
+const discordRelayLineLength = 61
+
+// formatDiscordRelay renders a Discord message as one or more in-game chat
+// lines. Characters that cannot be encoded in Shift-JIS are dropped. It
+// returns nil if nothing is left to relay or the encoded message is longer
+// than maxLength bytes.
+func formatDiscordRelay(author, content string, maxLength int) []string {
+	content = strings.TrimSpace(stringsupport.SJISToUTF8Lossy(stringsupport.UTF8ToSJIS(content)))
+	if content == "" {
+		return nil
+	}
+	name := strings.TrimSpace(stringsupport.SJISToUTF8Lossy(stringsupport.UTF8ToSJIS(author)))
+	if name == "" {
+		name = "Discord"
+	}
+	for len(stringsupport.UTF8ToSJIS(name)) < 8 {
+		name += " "
+	}
+	message := fmt.Sprintf("[D] %s > %s", name, content)
+	if len(stringsupport.UTF8ToSJIS(message)) > maxLength {
+		return nil
+	}
+
+	var lines []string
+	var line strings.Builder
+	lineBytes := 0
+	for _, r := range message {
+		n := len(stringsupport.UTF8ToSJIS(string(r)))
+		if lineBytes+n > discordRelayLineLength {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineBytes = 0
+		}
+		line.WriteRune(r)
+		lineBytes += n
+	}
+	if line.Len() > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
 // onDiscordMessage handles receiving messages from discord and forwarding them ingame.
 func (s *Server) onDiscordMessage(ds *discordgo.Session, m *discordgo.MessageCreate) {
 	// Ignore messages from bots, or messages that are not in the correct channel.
-	if m.Author.Bot || m.ChannelID != s.erupeConfig.Discord.RelayChannel.RelayChannelID {
+	relay := s.erupeConfig.Discord.RelayChannel
+	if !relay.Enabled || m.Author == nil || m.Author.Bot || m.ChannelID != relay.RelayChannelID {
 		return
 	}
 
-	paddedName := strings.TrimSpace(strings.Map(func(r rune) rune {
-		if r > unicode.MaxASCII {
-			return -1
-		}
-		return r
-	}, m.Author.Username))
-	for i := 0; i < 8-len(m.Author.Username); i++ {
-		paddedName += " "
+	muted, err := s.discordRepo.IsMuted(m.Author.ID)
+	if err != nil {
+		s.logger.Error("Failed to check Discord relay mute", zap.Error(err))
+		return
 	}
-	message := s.discordBot.NormalizeDiscordMessage(fmt.Sprintf("[D] %s > %s", paddedName, m.Content))
-	if len(message) > s.erupeConfig.Discord.RelayChannel.MaxMessageLength {
+	if muted {
 		return
 	}
 
-	var messages []string
-	lineLength := 61
-	for i := 0; i < len(message); i += lineLength {
-		end := i + lineLength
-		if end > len(message) {
-			end = len(message)
-		}
-		messages = append(messages, message[i:end])
-	}
-	for i := range messages {
-		s.BroadcastChatMessage(messages[i])
+	content := s.discordBot.NormalizeDiscordMessage(m.Content)
+	for _, line := range formatDiscordRelay(m.Author.Username, content, relay.MaxMessageLength) {
+		s.WorldcastChatMessage(line)
 	}
 }
@@ -1 +1,197 @@
 package channelserver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"erupe-ce/common/stringsupport"
+	cfg "erupe-ce/config"
+	"erupe-ce/server/discordbot"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type mockUserRepoDiscord struct {
+	mockUserRepoForItems
+
+	discordUID uint32
+	discordErr error
+	op         bool
+}
+
+func (m *mockUserRepoDiscord) GetByDiscordID(_ string) (uint32, error) {
+	return m.discordUID, m.discordErr
+}
+func (m *mockUserRepoDiscord) IsOp(_ uint32) (bool, error) { return m.op, nil }
+
+func TestDiscordIsOp(t *testing.T) {
+	tests := []struct {
+		name      string
+		discordID string
+		repo      *mockUserRepoDiscord
+		want      bool
+	}{
+		{"linked op", "123", &mockUserRepoDiscord{discordUID: 1, op: true}, true},
+		{"linked non-op", "123", &mockUserRepoDiscord{discordUID: 1, op: false}, false},
+		{"not linked", "123", &mockUserRepoDiscord{discordErr: errors.New("no rows"), op: true}, false},
+		{"empty discord ID", "", &mockUserRepoDiscord{discordUID: 1, op: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createMockServer()
+			server.userRepo = tt.repo
+			if got := server.discordIsOp(tt.discordID); got != tt.want {
+				t.Errorf("discordIsOp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscordStatusMessage(t *testing.T) {
+	channels := createTestChannels(2)
+	reg := NewLocalChannelRegistry(channels)
+	channels[0].Registry = reg
+	channels[1].GlobalID = "0102"
+
+	for i, name := range []string{"Alice", "Bob"} {
+		conn := &mockConn{}
+		channels[0].sessions[conn] = createTestSessionForServer(channels[0], conn, uint32(i+1), name)
+	}
+
+	msg := channels[0].discordStatusMessage()
+	if !strings.Contains(msg, "Channel 0101 (54001): 2 players") {
+		t.Errorf("status message missing first channel: %q", msg)
+	}
+	if !strings.Contains(msg, "Channel 0102 (54002): 0 players") {
+		t.Errorf("status message missing second channel: %q", msg)
+	}
+	if !strings.Contains(msg, "Total: 2 players") {
+		t.Errorf("status message missing total: %q", msg)
+	}
+}
+
+func TestDiscordWhoMessage(t *testing.T) {
+	channels := createTestChannels(2)
+	reg := NewLocalChannelRegistry(channels)
+	channels[0].Registry = reg
+
+	if msg := channels[0].discordWhoMessage(); msg != "No one is online." {
+		t.Errorf("empty who message = %q", msg)
+	}
+
+	conn1 := &mockConn{}
+	channels[0].sessions[conn1] = createTestSessionForServer(channels[0], conn1, 1, "Zed")
+	conn2 := &mockConn{}
+	channels[1].sessions[conn2] = createTestSessionForServer(channels[1], conn2, 2, "Amy")
+	// Sessions that have not logged in yet are not listed.
+	conn3 := &mockConn{}
+	channels[1].sessions[conn3] = createTestSessionForServer(channels[1], conn3, 0, "")
+
+	msg := channels[0].discordWhoMessage()
+	if msg != "**2 online:** Amy, Zed" {
+		t.Errorf("who message = %q", msg)
+	}
+}
+
+func TestFormatDiscordRelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		author  string
+		content string
+		max     int
+		want    []string
+	}{
+		{"short message", "Bob", "hello", 183, []string{"[D] Bob      > hello"}},
+		{"japanese kept", "ハンター", "こんにちは", 183, []string{"[D] ハンター > こんにちは"}},
+		{"emoji dropped", "Bob", "hi 😀", 183, []string{"[D] Bob      > hi"}},
+		{"only emoji", "Bob", "😀😀", 183, nil},
+		{"unencodable name", "😀", "hi", 183, []string{"[D] Discord  > hi"}},
+		{"too long", "Bob", strings.Repeat("a", 200), 183, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatDiscordRelay(tt.author, tt.content, tt.max)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("formatDiscordRelay() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDiscordRelay_SplitsLines(t *testing.T) {
+	got := formatDiscordRelay("Bob", strings.Repeat("あ", 60), 183)
+	if len(got) < 2 {
+		t.Fatalf("expected message to be split, got %d lines", len(got))
+	}
+	for _, line := range got {
+		if n := len(stringsupport.UTF8ToSJIS(line)); n > discordRelayLineLength {
+			t.Errorf("line is %d bytes, want <= %d", n, discordRelayLineLength)
+		}
+	}
+	if strings.Join(got, "") != "[D] Bob      > "+strings.Repeat("あ", 60) {
+		t.Errorf("split lines do not rejoin to the original message: %q", got)
+	}
+}
+
+func createDiscordRelayServer() (*Server, *Session) {
+	server := createTestServer()
+	server.erupeConfig.Discord = cfg.Discord{
+		Enabled: true,
+		RelayChannel: cfg.DiscordRelay{
+			Enabled:          true,
+			MaxMessageLength: 183,
+			RelayChannelID:   "relay",
+		},
+	}
+	server.discordBot = &discordbot.DiscordBot{}
+	server.discordRepo = &mockDiscordRepo{}
+	conn := &mockConn{}
+	session := createTestSessionForServer(server, conn, 1, "Player")
+	server.sessions[conn] = session
+	return server, session
+}
+
+func discordMessage(channelID, authorID string, bot bool, content string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{Message: &discordgo.Message{
+		ChannelID: channelID,
+		Content:   content,
+		Author:    &discordgo.User{ID: authorID, Username: "Bob", Bot: bot},
+	}}
+}
+
+func TestOnDiscordMessage_Relays(t *testing.T) {
+	server, session := createDiscordRelayServer()
+
+	server.onDiscordMessage(nil, discordMessage("relay", "1", false, "hello"))
+
+	if n := len(session.sendPackets); n != 1 {
+		t.Errorf("relayed packets = %d, want 1", n)
+	}
+}
+
+func TestOnDiscordMessage_Ignored(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *discordgo.MessageCreate
+		mute bool
+	}{
+		{"other channel", discordMessage("general", "1", false, "hello"), false},
+		{"bot author", discordMessage("relay", "1", true, "hello"), false},
+		{"muted author", discordMessage("relay", "1", false, "hello"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, session := createDiscordRelayServer()
+			if tt.mute {
+				_ = server.discordRepo.Mute("1", "mod")
+			}
+
+			server.onDiscordMessage(nil, tt.msg)
+
+			if n := len(session.sendPackets); n != 0 {
+				t.Errorf("relayed packets = %d, want 0", n)
+			}
+		})
+	}
+}
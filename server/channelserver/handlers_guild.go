@@ -1,11 +1,13 @@
 package channelserver
 
 import (
+	"errors"
 	"sort"
 	"time"
 
 	"erupe-ce/common/byteframe"
 	"erupe-ce/common/mhfitem"
+	"erupe-ce/common/token"
 	cfg "erupe-ce/config"
 
 	ps "erupe-ce/common/pascalstring"
@@ -278,13 +280,67 @@ func handleMsgMhfEnumerateGuildItem(s *Session, p mhfpacket.MHFPacket) {
 
 func handleMsgMhfUpdateGuildItem(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfUpdateGuildItem)
-	newStacks := mhfitem.DiffItemStacks(guildGetItems(s, pkt.GuildID), pkt.UpdatedItems)
-	if err := s.server.guildRepo.SaveItemBox(pkt.GuildID, mhfitem.SerializeWarehouseItems(newStacks)); err != nil {
+	member, err := s.server.guildRepo.GetCharacterMembership(s.charID)
+	if err != nil || member == nil || member.GuildID != pkt.GuildID || member.IsApplicant {
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+
+	limit := s.server.guildWithdrawalLimit(member)
+	changes, err := s.server.guildRepo.UpdateItemBox(pkt.GuildID, s.charID, pkt.UpdatedItems, limit, TimeMidnight())
+	if errors.Is(err, ErrWithdrawalLimit) {
+		s.logger.Info("Guild item withdrawal over the daily limit",
+			zap.Uint32("guildID", pkt.GuildID), zap.Uint32("charID", s.charID), zap.Int("limit", limit))
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+	if err != nil {
 		s.logger.Error("Failed to update guild item box", zap.Error(err))
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+	if len(changes) > 0 {
+		s.server.guildService.LogEvent(GuildEvent{GuildID: pkt.GuildID, ActorID: s.charID, Action: GuildEventItemBox, Detail: formatItemChanges(changes)})
 	}
 	doAckSimpleSucceed(s, pkt.AckHandle, make([]byte, 4))
 }
 
+// guildWithdrawalLimit returns the member's daily guild item withdrawal cap
+// for their role, or 0 if they may withdraw without limit.
+func (s *Server) guildWithdrawalLimit(member *GuildMember) int {
+	limits := s.erupeConfig.Guild.ItemWithdrawalLimits
+	switch {
+	case member.IsLeader:
+		return limits.Leader
+	case member.IsSubLeader():
+		return limits.SubLeader
+	case member.Recruiter:
+		return limits.Recruiter
+	default:
+		return limits.Member
+	}
+}
+
+// diffGuildItems returns the net change of each item between two guild item
+// box states, ordered by item ID. Unchanged items are omitted.
+func diffGuildItems(before, after []mhfitem.MHFItemStack) []GuildItemChange {
+	delta := make(map[uint16]int)
+	for _, stack := range before {
+		delta[stack.Item.ItemID] -= int(stack.Quantity)
+	}
+	for _, stack := range after {
+		delta[stack.Item.ItemID] += int(stack.Quantity)
+	}
+	var changes []GuildItemChange
+	for id, d := range delta {
+		if d != 0 {
+			changes = append(changes, GuildItemChange{ItemID: id, Quantity: d})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ItemID < changes[j].ItemID })
+	return changes
+}
+
 func handleMsgMhfUpdateGuildIcon(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfUpdateGuildIcon)
 
@@ -342,6 +398,7 @@ func handleMsgMhfUpdateGuildIcon(s *Session, p mhfpacket.MHFPacket) {
 		return
 	}
 
+	s.server.guildService.LogEvent(GuildEvent{GuildID: guild.ID, ActorID: s.charID, Action: GuildEventIcon})
 	doAckSimpleSucceed(s, pkt.AckHandle, make([]byte, 4))
 }
 
@@ -379,6 +436,12 @@ func handleMsgMhfSetGuildManageRight(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfSetGuildManageRight)
 	if err := s.server.guildRepo.SetRecruiter(pkt.CharID, pkt.Allowed); err != nil {
 		s.logger.Error("Failed to update guild manage right", zap.Error(err))
+	} else if member, err := s.server.guildRepo.GetCharacterMembership(pkt.CharID); err == nil && member != nil {
+		detail := "revoked"
+		if pkt.Allowed {
+			detail = "granted"
+		}
+		s.server.guildService.LogEvent(GuildEvent{GuildID: member.GuildID, ActorID: s.charID, TargetCharID: pkt.CharID, Action: GuildEventRecruiter, Detail: detail})
 	}
 	doAckBufSucceed(s, pkt.AckHandle, make([]byte, 4))
 }
@@ -447,6 +510,11 @@ func guildGetItems(s *Session, guildID uint32) []mhfitem.MHFItemStack {
 		s.logger.Error("Failed to get guild item box", zap.Error(err))
 		return nil
 	}
+	return readItemBox(data)
+}
+
+// readItemBox parses a serialized item box.
+func readItemBox(data []byte) []mhfitem.MHFItemStack {
 	var items []mhfitem.MHFItemStack
 	if len(data) > 0 {
 		box := byteframe.NewByteFrameFromBytes(data)
@@ -458,3 +526,27 @@ func guildGetItems(s *Session, guildID uint32) []mhfitem.MHFItemStack {
 	}
 	return items
 }
+
+// addItemStacks adds each reward to the stack of the same item in items, or
+// as a new stack if there is none.
+func addItemStacks(items []mhfitem.MHFItemStack, rewards []mhfitem.MHFItemStack) []mhfitem.MHFItemStack {
+	for _, reward := range rewards {
+		merged := false
+		for i := range items {
+			if items[i].Item.ItemID == reward.Item.ItemID {
+				if sum := uint32(items[i].Quantity) + uint32(reward.Quantity); sum > 0xFFFF {
+					items[i].Quantity = 0xFFFF
+				} else {
+					items[i].Quantity = uint16(sum)
+				}
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			reward.WarehouseID = token.RNG.Uint32()
+			items = append(items, reward)
+		}
+	}
+	return items
+}
@@ -14,6 +14,7 @@ type GuildAlliance struct {
 	ID           uint32    `db:"id"`
 	Name         string    `db:"name"`
 	CreatedAt    time.Time `db:"created_at"`
+	Recruiting   bool      `db:"recruiting"`
 	TotalMembers uint16
 
 	ParentGuildID uint32 `db:"parent_id"`
@@ -25,6 +26,19 @@ type GuildAlliance struct {
 	SubGuild2   Guild
 }
 
+// AllianceApplication is a guild's pending request to join an alliance.
+type AllianceApplication struct {
+	AllianceID uint32    `db:"alliance_id"`
+	GuildID    uint32    `db:"guild_id"`
+	AppliedBy  uint32    `db:"applied_by"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// hasGuild reports whether guildID is the alliance's parent or one of its sub guilds.
+func (a *GuildAlliance) hasGuild(guildID uint32) bool {
+	return guildID == a.ParentGuildID || guildID == a.SubGuild1ID || guildID == a.SubGuild2ID
+}
+
 func handleMsgMhfCreateJoint(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfCreateJoint)
 	if err := s.server.guildRepo.CreateAlliance(pkt.Name, pkt.GuildID); err != nil {
@@ -47,7 +61,7 @@ func handleMsgMhfOperateJoint(s *Session, p mhfpacket.MHFPacket) {
 
 	switch pkt.Action {
 	case mhfpacket.OPERATE_JOINT_DISBAND:
-		if guild.LeaderCharID == s.charID && alliance.ParentGuildID == guild.ID {
+		if guild != nil && alliance != nil && guild.LeaderCharID == s.charID && alliance.ParentGuildID == guild.ID {
 			if err := s.server.guildRepo.DeleteAlliance(alliance.ID); err != nil {
 				s.logger.Error("Failed to disband alliance", zap.Error(err))
 			}
@@ -56,30 +70,44 @@ func handleMsgMhfOperateJoint(s *Session, p mhfpacket.MHFPacket) {
 			s.logger.Warn(
 				"Non-owner of alliance attempted disband",
 				zap.Uint32("CharID", s.charID),
-				zap.Uint32("AllyID", alliance.ID),
+				zap.Uint32("AllyID", pkt.AllianceID),
 			)
 			doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
 		}
 	case mhfpacket.OPERATE_JOINT_LEAVE:
-		if guild.LeaderCharID == s.charID {
-			if err := s.server.guildRepo.RemoveGuildFromAlliance(alliance.ID, guild.ID, alliance.SubGuild1ID, alliance.SubGuild2ID); err != nil {
-				s.logger.Error("Failed to remove guild from alliance", zap.Error(err))
-			}
-			// NOTE: Alliance join requests are not yet implemented (no DB table exists),
-			// so there are no pending applications to clean up on leave.
-			doAckSimpleSucceed(s, pkt.AckHandle, make([]byte, 4))
-		} else {
+		if guild == nil {
+			s.logger.Warn("Alliance leave for unknown guild",
+				zap.Uint32("CharID", s.charID), zap.Uint32("GuildID", pkt.GuildID))
+			doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		} else if guild.LeaderCharID != s.charID {
 			s.logger.Warn(
 				"Non-owner of guild attempted alliance leave",
 				zap.Uint32("CharID", s.charID),
 			)
 			doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		} else if alliance == nil || !alliance.hasGuild(guild.ID) {
+			s.logger.Warn("Alliance leave for guild outside the alliance",
+				zap.Uint32("CharID", s.charID), zap.Uint32("GuildID", guild.ID), zap.Uint32("AllyID", pkt.AllianceID))
+			doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		} else {
+			if err := s.server.guildRepo.RemoveGuildFromAlliance(alliance.ID, guild.ID, alliance.SubGuild1ID, alliance.SubGuild2ID); err != nil {
+				s.logger.Error("Failed to remove guild from alliance", zap.Error(err))
+			} else {
+				for _, id := range []uint32{guild.ID, alliance.ParentGuildID} {
+					s.server.guildService.LogEvent(GuildEvent{GuildID: id, ActorID: s.charID, TargetGuildID: guild.ID, Action: GuildEventAllianceLeave, Detail: alliance.Name})
+				}
+			}
+			doAckSimpleSucceed(s, pkt.AckHandle, make([]byte, 4))
 		}
 	case mhfpacket.OPERATE_JOINT_KICK:
 		if alliance.ParentGuild.LeaderCharID == s.charID {
 			kickedGuildID := pkt.Data1.ReadUint32()
 			if err := s.server.guildRepo.RemoveGuildFromAlliance(alliance.ID, kickedGuildID, alliance.SubGuild1ID, alliance.SubGuild2ID); err != nil {
 				s.logger.Error("Failed to kick guild from alliance", zap.Error(err))
+			} else {
+				for _, id := range []uint32{kickedGuildID, alliance.ParentGuildID} {
+					s.server.guildService.LogEvent(GuildEvent{GuildID: id, ActorID: s.charID, TargetGuildID: kickedGuildID, Action: GuildEventAllianceKick, Detail: alliance.Name})
+				}
 			}
 			doAckSimpleSucceed(s, pkt.AckHandle, make([]byte, 4))
 		} else {
@@ -119,14 +147,14 @@ func handleMsgMhfInfoJoint(s *Session, p mhfpacket.MHFPacket) {
 		}
 		bf.WriteUint32(alliance.ParentGuildID)
 		bf.WriteUint32(alliance.ParentGuild.LeaderCharID)
-		bf.WriteUint16(alliance.ParentGuild.Rank(s.server.erupeConfig.RealClientMode))
+		bf.WriteUint16(s.server.guildRank(&alliance.ParentGuild))
 		bf.WriteUint16(alliance.ParentGuild.MemberCount)
 		ps.Uint16(bf, alliance.ParentGuild.Name, true)
 		ps.Uint16(bf, alliance.ParentGuild.LeaderName, true)
 		if alliance.SubGuild1ID > 0 {
 			bf.WriteUint32(alliance.SubGuild1ID)
 			bf.WriteUint32(alliance.SubGuild1.LeaderCharID)
-			bf.WriteUint16(alliance.SubGuild1.Rank(s.server.erupeConfig.RealClientMode))
+			bf.WriteUint16(s.server.guildRank(&alliance.SubGuild1))
 			bf.WriteUint16(alliance.SubGuild1.MemberCount)
 			ps.Uint16(bf, alliance.SubGuild1.Name, true)
 			ps.Uint16(bf, alliance.SubGuild1.LeaderName, true)
@@ -134,7 +162,7 @@ func handleMsgMhfInfoJoint(s *Session, p mhfpacket.MHFPacket) {
 		if alliance.SubGuild2ID > 0 {
 			bf.WriteUint32(alliance.SubGuild2ID)
 			bf.WriteUint32(alliance.SubGuild2.LeaderCharID)
-			bf.WriteUint16(alliance.SubGuild2.Rank(s.server.erupeConfig.RealClientMode))
+			bf.WriteUint16(s.server.guildRank(&alliance.SubGuild2))
 			bf.WriteUint16(alliance.SubGuild2.MemberCount)
 			ps.Uint16(bf, alliance.SubGuild2.Name, true)
 			ps.Uint16(bf, alliance.SubGuild2.LeaderName, true)
@@ -142,3 +170,29 @@ func handleMsgMhfInfoJoint(s *Session, p mhfpacket.MHFPacket) {
 		doAckBufSucceed(s, pkt.AckHandle, bf.Data())
 	}
 }
+
+// pendingAllianceApplicants returns the guilds waiting to join guild's
+// alliance, or nil unless guild is the alliance's parent.
+func pendingAllianceApplicants(s *Session, guild *Guild) []*Guild {
+	if guild.AllianceID == 0 {
+		return nil
+	}
+	alliance, err := s.server.guildRepo.GetAllianceByID(guild.AllianceID)
+	if err != nil || alliance == nil || alliance.ParentGuildID != guild.ID {
+		return nil
+	}
+	apps, err := s.server.guildRepo.ListAllianceApplications(alliance.ID)
+	if err != nil {
+		s.logger.Error("Failed to list alliance applications", zap.Error(err))
+		return nil
+	}
+	var applicants []*Guild
+	for _, app := range apps {
+		applicant, err := s.server.guildRepo.GetByID(app.GuildID)
+		if err != nil || applicant == nil {
+			continue
+		}
+		applicants = append(applicants, applicant)
+	}
+	return applicants
+}
@@ -127,6 +127,7 @@ func TestOperateJoint_Leave_AsLeader(t *testing.T) {
 	guildMock.guild = &Guild{ID: 10}
 	guildMock.guild.LeaderCharID = 1
 	server.guildRepo = guildMock
+	ensureGuildService(server)
 	session := createMockSession(1, server)
 
 	pkt := &mhfpacket.MsgMhfOperateJoint{
@@ -189,6 +190,7 @@ func TestOperateJoint_Kick_AsAllianceOwner(t *testing.T) {
 	_, _ = data1.Seek(0, 0)
 
 	server.guildRepo = guildMock
+	ensureGuildService(server)
 	session := createMockSession(1, server)
 
 	pkt := &mhfpacket.MsgMhfOperateJoint{
@@ -318,3 +320,47 @@ func TestInfoJoint_NotFound(t *testing.T) {
 		t.Error("No response packet queued")
 	}
 }
+
+func TestOperateJoint_Leave_UnknownGuild(t *testing.T) {
+	server := createMockServer()
+	guildMock := &mockGuildRepo{
+		guild:    &Guild{ID: 20},
+		alliance: &GuildAlliance{ID: 5, ParentGuildID: 10, SubGuild1ID: 20},
+	}
+	guildMock.guild.LeaderCharID = 2
+	server.guildRepo = guildMock
+	ensureGuildService(server)
+	session := createMockSession(2, server)
+
+	handleMsgMhfOperateJoint(session, &mhfpacket.MsgMhfOperateJoint{
+		AckHandle: 1, AllianceID: 5, GuildID: 99, Action: mhfpacket.OPERATE_JOINT_LEAVE,
+	})
+	if ack := readAck(t, session); ack.ErrorCode == 0 {
+		t.Error("leaving for an unknown guild should fail")
+	}
+	if guildMock.removedAllyArgs != nil {
+		t.Error("no guild should be removed from the alliance")
+	}
+}
+
+func TestOperateJoint_Leave_NotMember(t *testing.T) {
+	server := createMockServer()
+	guildMock := &mockGuildRepo{
+		guild:    &Guild{ID: 20},
+		alliance: &GuildAlliance{ID: 5, ParentGuildID: 10},
+	}
+	guildMock.guild.LeaderCharID = 2
+	server.guildRepo = guildMock
+	ensureGuildService(server)
+	session := createMockSession(2, server)
+
+	handleMsgMhfOperateJoint(session, &mhfpacket.MsgMhfOperateJoint{
+		AckHandle: 1, AllianceID: 5, GuildID: 20, Action: mhfpacket.OPERATE_JOINT_LEAVE,
+	})
+	if ack := readAck(t, session); ack.ErrorCode == 0 {
+		t.Error("leaving an alliance the guild is not in should fail")
+	}
+	if guildMock.removedAllyArgs != nil {
+		t.Error("no guild should be removed from the alliance")
+	}
+}
@@ -68,18 +68,60 @@ func handleMsgMhfRegistGuildCooking(s *Session, p mhfpacket.MHFPacket) {
 	doAckBufSucceed(s, pkt.AckHandle, bf.Data())
 }
 
+// guildWeeklyActiveCount returns how many members of the guild were active
+// this week and how many exceptional users were registered on top of them.
+func (s *Server) guildWeeklyActiveCount(guildID uint32) (int, int, error) {
+	week := TimeWeekStart()
+	opts := s.erupeConfig.Guild.WeeklyBonus
+	active, err := s.guildRepo.CountActiveMembers(guildID, week, opts.MinQuests, opts.MinKills)
+	if err != nil {
+		return 0, 0, err
+	}
+	exceptional, err := s.guildRepo.GetWeeklyBonusUsers(guildID, week)
+	if err != nil {
+		return 0, 0, err
+	}
+	return active, exceptional, nil
+}
+
+// recordGuildWeeklyActivity credits a quest and its large monster kills to
+// the character's guild for this week's bonus. It is driven by
+// MSG_SYS_RECORD_LOG, which the client sends whenever it returns from a
+// quest. The packet carries no quest result and no other packet reliably
+// marks a clear, so failed and abandoned quests count as well; MinQuests is
+// a count of quests played, and MinKills is the stricter measure.
+func recordGuildWeeklyActivity(s *Session, kills int) {
+	if s.server.guildRepo == nil {
+		return
+	}
+	if err := s.server.guildRepo.RecordWeeklyActivity(s.charID, TimeWeekStart(), 1, kills); err != nil {
+		s.logger.Error("Failed to record guild weekly activity", zap.Error(err))
+	}
+}
+
 func handleMsgMhfGetGuildWeeklyBonusMaster(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfGetGuildWeeklyBonusMaster)
 
 	// Values taken from brand new guild capture
 	doAckBufSucceed(s, pkt.AckHandle, make([]byte, 40))
 }
+
 func handleMsgMhfGetGuildWeeklyBonusActiveCount(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfGetGuildWeeklyBonusActiveCount)
+	var active, exceptional int
+	if s.server.guildRepo != nil {
+		guild, err := s.server.guildRepo.GetByCharID(s.charID)
+		if err == nil && guild != nil {
+			active, exceptional, err = s.server.guildWeeklyActiveCount(guild.ID)
+			if err != nil {
+				s.logger.Error("Failed to count guild weekly activity", zap.Error(err))
+			}
+		}
+	}
 	bf := byteframe.NewByteFrame()
-	bf.WriteUint8(60) // Active count
-	bf.WriteUint8(60) // Current active count
-	bf.WriteUint8(0)  // New active count
+	bf.WriteUint8(uint8(min(active+exceptional, 255))) // Active count
+	bf.WriteUint8(uint8(min(active, 255)))             // Current active count
+	bf.WriteUint8(uint8(min(exceptional, 255)))        // New active count
 	doAckBufSucceed(s, pkt.AckHandle, bf.Data())
 }
 
@@ -93,7 +135,7 @@ func handleMsgMhfGuildHuntdata(s *Session, p mhfpacket.MHFPacket) {
 		}
 	case 1: // Enumerate
 		bf.WriteUint8(0) // Entries
-		kills, err := s.server.guildRepo.ListGuildKills(pkt.GuildID, s.charID)
+		kills, err := s.server.guildRepo.ListGuildKills(pkt.GuildID, s.charID, TimeWeekStart())
 		if err == nil {
 			var count uint8
 			for _, kill := range kills {
@@ -110,7 +152,7 @@ func handleMsgMhfGuildHuntdata(s *Session, p mhfpacket.MHFPacket) {
 	case 2: // Check
 		guild, err := s.server.guildRepo.GetByCharID(s.charID)
 		if err == nil {
-			count, err := s.server.guildRepo.CountGuildKills(guild.ID, s.charID)
+			count, err := s.server.guildRepo.CountGuildKills(guild.ID, s.charID, TimeWeekStart())
 			if err == nil && count > 0 {
 				bf.WriteBool(true)
 			} else {
@@ -128,7 +170,7 @@ func handleMsgMhfAddGuildWeeklyBonusExceptionalUser(s *Session, p mhfpacket.MHFP
 	if s.server.guildRepo != nil {
 		guild, err := s.server.guildRepo.GetByCharID(s.charID)
 		if err == nil && guild != nil {
-			if err := s.server.guildRepo.AddWeeklyBonusUsers(guild.ID, pkt.NumUsers); err != nil {
+			if err := s.server.guildRepo.AddWeeklyBonusUsers(guild.ID, TimeWeekStart(), pkt.NumUsers); err != nil {
 				s.logger.Error("Failed to add weekly bonus users", zap.Error(err))
 			}
 		}
@@ -1,9 +1,11 @@
 package channelserver
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
+	cfg "erupe-ce/config"
 	"erupe-ce/network/mhfpacket"
 )
 
@@ -303,4 +305,73 @@ func TestAddGuildWeeklyBonusExceptionalUser_NoGuild(t *testing.T) {
 	default:
 		t.Error("No response packet queued")
 	}
+	if guildMock.weeklyBonusUsers != 0 {
+		t.Errorf("weeklyBonusUsers = %d, want 0", guildMock.weeklyBonusUsers)
+	}
+}
+
+// --- Guild weekly bonus tests ---
+
+func TestGetGuildWeeklyBonusMaster(t *testing.T) {
+	server := createMockServer()
+	session := createMockSession(1, server)
+
+	handleMsgMhfGetGuildWeeklyBonusMaster(session, &mhfpacket.MsgMhfGetGuildWeeklyBonusMaster{AckHandle: 1})
+	ack := readAck(t, session)
+	if len(ack.Payload) != 40 {
+		t.Fatalf("master size = %d, want 40", len(ack.Payload))
+	}
+	if !bytes.Equal(ack.Payload, make([]byte, 40)) {
+		t.Errorf("master = %x, want 40 zero bytes", ack.Payload)
+	}
+}
+
+func TestGetGuildWeeklyBonusActiveCount(t *testing.T) {
+	server := createMockServer()
+	guildMock := &mockGuildRepo{activeMembers: 12, weeklyBonusUsers: 3}
+	guildMock.guild = &Guild{ID: 10}
+	server.guildRepo = guildMock
+	session := createMockSession(1, server)
+
+	handleMsgMhfGetGuildWeeklyBonusActiveCount(session, &mhfpacket.MsgMhfGetGuildWeeklyBonusActiveCount{AckHandle: 1})
+	ack := readAck(t, session)
+	if len(ack.Payload) != 3 || ack.Payload[0] != 15 || ack.Payload[1] != 12 || ack.Payload[2] != 3 {
+		t.Errorf("active count = %v, want [15 12 3]", ack.Payload)
+	}
+}
+
+func TestAddGuildWeeklyBonusExceptionalUser_Accumulates(t *testing.T) {
+	server := createMockServer()
+	guildMock := &mockGuildRepo{}
+	guildMock.guild = &Guild{ID: 10}
+	server.guildRepo = guildMock
+	session := createMockSession(1, server)
+
+	handleMsgMhfAddGuildWeeklyBonusExceptionalUser(session, &mhfpacket.MsgMhfAddGuildWeeklyBonusExceptionalUser{AckHandle: 1, NumUsers: 3})
+	handleMsgMhfAddGuildWeeklyBonusExceptionalUser(session, &mhfpacket.MsgMhfAddGuildWeeklyBonusExceptionalUser{AckHandle: 2, NumUsers: 2})
+	if guildMock.weeklyBonusUsers != 5 {
+		t.Errorf("weeklyBonusUsers = %d, want 5", guildMock.weeklyBonusUsers)
+	}
+}
+
+func TestRecordLogRecordsGuildWeeklyActivity(t *testing.T) {
+	server := createMockServer()
+	server.erupeConfig.RealClientMode = cfg.ZZ
+	guildMock := &mockGuildRepo{membership: &GuildMember{GuildID: 10, CharID: 1}}
+	server.guildRepo = guildMock
+	session := createMockSession(1, server)
+	session.stage = NewStage("test_stage")
+
+	data := make([]byte, killLogHeaderSize+killLogMonsterCount)
+	data[killLogHeaderSize+1] = 2 // Rathian
+	handleMsgSysRecordLog(session, &mhfpacket.MsgSysRecordLog{AckHandle: 1, Data: data})
+	if guildMock.weeklyQuests != 1 || guildMock.weeklyKills != 2 {
+		t.Errorf("recorded %d quests and %d kills, want 1 and 2", guildMock.weeklyQuests, guildMock.weeklyKills)
+	}
+
+	guildMock.membership.IsApplicant = true
+	handleMsgSysRecordLog(session, &mhfpacket.MsgSysRecordLog{AckHandle: 2, Data: data})
+	if guildMock.weeklyQuests != 1 {
+		t.Error("applicants should not earn weekly activity")
+	}
 }
@@ -56,7 +56,7 @@ func handleMsgMhfInfoGuild(s *Session, p mhfpacket.MHFPacket) {
 
 		bf.WriteUint32(guild.ID)
 		bf.WriteUint32(guild.LeaderCharID)
-		bf.WriteUint16(guild.Rank(s.server.erupeConfig.RealClientMode))
+		bf.WriteUint16(s.server.guildRank(guild))
 		bf.WriteUint16(guild.MemberCount)
 
 		bf.WriteUint8(guild.MainMotto)
@@ -117,16 +117,7 @@ func handleMsgMhfInfoGuild(s *Session, p mhfpacket.MHFPacket) {
 		}
 		bf.WriteUint32(guild.PugiOutfits)
 
-		limit := s.server.erupeConfig.GameplayOptions.ClanMemberLimits[0][1]
-		for _, j := range s.server.erupeConfig.GameplayOptions.ClanMemberLimits {
-			if guild.Rank(s.server.erupeConfig.RealClientMode) >= uint16(j[0]) {
-				limit = j[1]
-			}
-		}
-		if limit > 100 {
-			limit = 100
-		}
-		bf.WriteUint8(limit)
+		bf.WriteUint8(s.server.guildRankTable().MemberLimit(s.server.guildRank(guild)))
 
 		bf.WriteUint32(guildRoomMaxRP)
 		bf.WriteUint32(uint32(guild.RoomExpiry.Unix()))
@@ -160,7 +151,7 @@ func handleMsgMhfInfoGuild(s *Session, p mhfpacket.MHFPacket) {
 				} else {
 					bf.WriteUint16(0)
 				}
-				bf.WriteUint16(alliance.ParentGuild.Rank(s.server.erupeConfig.RealClientMode))
+				bf.WriteUint16(s.server.guildRank(&alliance.ParentGuild))
 				bf.WriteUint16(alliance.ParentGuild.MemberCount)
 				ps.Uint16(bf, alliance.ParentGuild.Name, true)
 				ps.Uint16(bf, alliance.ParentGuild.LeaderName, true)
@@ -172,7 +163,7 @@ func handleMsgMhfInfoGuild(s *Session, p mhfpacket.MHFPacket) {
 					} else {
 						bf.WriteUint16(0)
 					}
-					bf.WriteUint16(alliance.SubGuild1.Rank(s.server.erupeConfig.RealClientMode))
+					bf.WriteUint16(s.server.guildRank(&alliance.SubGuild1))
 					bf.WriteUint16(alliance.SubGuild1.MemberCount)
 					ps.Uint16(bf, alliance.SubGuild1.Name, true)
 					ps.Uint16(bf, alliance.SubGuild1.LeaderName, true)
@@ -185,7 +176,7 @@ func handleMsgMhfInfoGuild(s *Session, p mhfpacket.MHFPacket) {
 					} else {
 						bf.WriteUint16(0)
 					}
-					bf.WriteUint16(alliance.SubGuild2.Rank(s.server.erupeConfig.RealClientMode))
+					bf.WriteUint16(s.server.guildRank(&alliance.SubGuild2))
 					bf.WriteUint16(alliance.SubGuild2.MemberCount)
 					ps.Uint16(bf, alliance.SubGuild2.Name, true)
 					ps.Uint16(bf, alliance.SubGuild2.LeaderName, true)
@@ -237,6 +228,17 @@ func handleMsgMhfInfoGuild(s *Session, p mhfpacket.MHFPacket) {
 			LeaderName string
 		}
 		allianceInvites := []AllianceInvite{}
+		if guild.LeaderCharID == s.charID {
+			for _, applicant := range pendingAllianceApplicants(s, guild) {
+				allianceInvites = append(allianceInvites, AllianceInvite{
+					GuildID:    applicant.ID,
+					LeaderID:   applicant.LeaderCharID,
+					Members:    applicant.MemberCount,
+					GuildName:  applicant.Name,
+					LeaderName: applicant.LeaderName,
+				})
+			}
+		}
 		bf.WriteUint8(uint8(len(allianceInvites)))
 		for _, invite := range allianceInvites {
 			bf.WriteUint32(invite.GuildID)
@@ -440,7 +442,7 @@ func handleMsgMhfEnumerateGuild(s *Session, p mhfpacket.MHFPacket) {
 			ps.Uint8(bf, alliance.Name, true)
 			ps.Uint8(bf, alliance.ParentGuild.LeaderName, true)
 			bf.WriteUint8(0x01) // Unk
-			bf.WriteBool(true)  // TODO: Enable GuildAlliance applications
+			bf.WriteBool(alliance.Recruiting)
 		}
 	} else {
 		hasNextPage := false
@@ -455,7 +457,7 @@ func handleMsgMhfEnumerateGuild(s *Session, p mhfpacket.MHFPacket) {
 			bf.WriteUint32(guild.LeaderCharID)
 			bf.WriteUint16(guild.MemberCount)
 			bf.WriteUint16(0x0000) // Unk
-			bf.WriteUint16(guild.Rank(s.server.erupeConfig.RealClientMode))
+			bf.WriteUint16(s.server.guildRank(guild))
 			bf.WriteUint32(uint32(guild.CreatedAt.Unix()))
 			ps.Uint8(bf, guild.Name, true)
 			ps.Uint8(bf, guild.LeaderName, true)
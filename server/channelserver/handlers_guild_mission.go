@@ -1,8 +1,14 @@
 package channelserver
 
 import (
+	"math/rand"
+	"time"
+
 	"erupe-ce/common/byteframe"
+	"erupe-ce/common/mhfitem"
 	"erupe-ce/network/mhfpacket"
+
+	"go.uber.org/zap"
 )
 
 // GuildMission represents a guild mission entry.
@@ -16,28 +22,118 @@ type GuildMission struct {
 	GR          bool
 	RewardType  uint16
 	RewardLevel uint16
+	Rewards     []mhfitem.MHFItemStack // Paid into the guild item box on completion
+}
+
+// GuildMissionTarget is the mission a guild is working on.
+type GuildMissionTarget struct {
+	GuildID   uint32    `db:"guild_id"`
+	MissionID uint32    `db:"mission_id"`
+	Rotation  uint32    `db:"rotation"`
+	SetBy     uint32    `db:"set_by"`
+	SetAt     time.Time `db:"set_at"`
+}
+
+// guildMissionsOffered is the number of missions listed per rotation.
+const guildMissionsOffered = 15
+
+// defaultGuildMissions is the catalogue used when Guild.Missions.Catalogue
+// is empty. Its missions pay no rewards; those are only set in config.
+var defaultGuildMissions = []GuildMission{
+	{ID: 431201, Unk: 574, Type: 1, Goal: 4761, Quantity: 35, SkipTickets: 1, RewardType: 2, RewardLevel: 1},
+	{ID: 431202, Unk: 755, Type: 0, Goal: 95, Quantity: 12, SkipTickets: 2, RewardType: 3, RewardLevel: 2},
+	{ID: 431203, Unk: 746, Type: 0, Goal: 95, Quantity: 6, SkipTickets: 1, RewardType: 1, RewardLevel: 1},
+	{ID: 431204, Unk: 581, Type: 0, Goal: 83, Quantity: 16, SkipTickets: 2, RewardType: 4, RewardLevel: 2},
+	{ID: 431205, Unk: 694, Type: 1, Goal: 4763, Quantity: 25, SkipTickets: 1, RewardType: 2, RewardLevel: 1},
+	{ID: 431206, Unk: 988, Type: 0, Goal: 27, Quantity: 16, SkipTickets: 1, RewardType: 6, RewardLevel: 1},
+	{ID: 431207, Unk: 730, Type: 1, Goal: 4768, Quantity: 25, SkipTickets: 1, RewardType: 4, RewardLevel: 1},
+	{ID: 431208, Unk: 680, Type: 1, Goal: 3567, Quantity: 50, SkipTickets: 2, RewardType: 2, RewardLevel: 2},
+	{ID: 431209, Unk: 1109, Type: 0, Goal: 34, Quantity: 60, SkipTickets: 2, RewardType: 6, RewardLevel: 2},
+	{ID: 431210, Unk: 128, Type: 1, Goal: 8921, Quantity: 70, SkipTickets: 2, RewardType: 3, RewardLevel: 2},
+	{ID: 431211, Unk: 406, Type: 0, Goal: 59, Quantity: 10, SkipTickets: 1, RewardType: 1, RewardLevel: 1},
+	{ID: 431212, Unk: 1170, Type: 0, Goal: 70, Quantity: 90, SkipTickets: 3, RewardType: 6, RewardLevel: 3},
+	{ID: 431213, Unk: 164, Type: 0, Goal: 38, Quantity: 24, SkipTickets: 2, RewardType: 6, RewardLevel: 2},
+	{ID: 431214, Unk: 378, Type: 1, Goal: 3556, Quantity: 150, SkipTickets: 3, RewardType: 1, RewardLevel: 3},
+	{ID: 431215, Unk: 446, Type: 0, Goal: 94, Quantity: 20, SkipTickets: 2, RewardType: 4, RewardLevel: 2},
+}
+
+// guildMissionCatalogue returns the configured missions, or the built-in
+// ones if none are configured.
+func (s *Server) guildMissionCatalogue() []GuildMission {
+	entries := s.erupeConfig.Guild.Missions.Catalogue
+	if len(entries) == 0 {
+		return defaultGuildMissions
+	}
+	missions := make([]GuildMission, len(entries))
+	for i, e := range entries {
+		missions[i] = GuildMission{
+			ID: e.ID, Unk: e.Unk, Type: e.Type, Goal: e.Goal, Quantity: e.Quantity,
+			SkipTickets: e.SkipTickets, GR: e.GR, RewardType: e.RewardType, RewardLevel: e.RewardLevel,
+		}
+		for _, r := range e.Rewards {
+			missions[i].Rewards = append(missions[i].Rewards,
+				mhfitem.MHFItemStack{Item: mhfitem.MHFItem{ItemID: r.ItemID}, Quantity: r.Quantity})
+		}
+	}
+	return missions
+}
+
+// guildMissionRotation returns the number of the rotation containing now and
+// when it started. Rotations last Guild.Missions.RotationDays, counted from
+// the Unix epoch.
+func (s *Server) guildMissionRotation(now time.Time) (uint32, time.Time) {
+	days := s.erupeConfig.Guild.Missions.RotationDays
+	if days <= 0 {
+		days = 7
+	}
+	period := int64(days) * 86400
+	rotation := now.Unix() / period
+	return uint32(rotation), time.Unix(rotation*period, 0)
+}
+
+// offeredGuildMissions picks the rotation's missions from the catalogue.
+// The choice depends only on the rotation, so every channel agrees on it.
+func offeredGuildMissions(catalogue []GuildMission, rotation uint32) []GuildMission {
+	if len(catalogue) <= guildMissionsOffered {
+		return catalogue
+	}
+	rng := rand.New(rand.NewSource(int64(rotation)))
+	offered := make([]GuildMission, 0, guildMissionsOffered)
+	for _, i := range rng.Perm(len(catalogue))[:guildMissionsOffered] {
+		offered = append(offered, catalogue[i])
+	}
+	return offered
+}
+
+// currentGuildMissions returns the rotation number and its offered missions.
+func (s *Server) currentGuildMissions() (uint32, time.Time, []GuildMission) {
+	rotation, start := s.guildMissionRotation(TimeAdjusted())
+	return rotation, start, offeredGuildMissions(s.guildMissionCatalogue(), rotation)
+}
+
+func findGuildMission(missions []GuildMission, id uint32) (GuildMission, bool) {
+	for _, m := range missions {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return GuildMission{}, false
+}
+
+// currentGuildMissionTarget returns the guild's target if it was set in the
+// current rotation.
+func currentGuildMissionTarget(s *Session, guildID, rotation uint32) (*GuildMissionTarget, error) {
+	target, err := s.server.guildRepo.GetMissionTarget(guildID)
+	if err != nil || target == nil || target.Rotation != rotation {
+		return nil, err
+	}
+	return target, nil
 }
 
 func handleMsgMhfGetGuildMissionList(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfGetGuildMissionList)
 	bf := byteframe.NewByteFrame()
-	missions := []GuildMission{
-		{431201, 574, 1, 4761, 35, 1, false, 2, 1},
-		{431202, 755, 0, 95, 12, 2, false, 3, 2},
-		{431203, 746, 0, 95, 6, 1, false, 1, 1},
-		{431204, 581, 0, 83, 16, 2, false, 4, 2},
-		{431205, 694, 1, 4763, 25, 1, false, 2, 1},
-		{431206, 988, 0, 27, 16, 1, false, 6, 1},
-		{431207, 730, 1, 4768, 25, 1, false, 4, 1},
-		{431208, 680, 1, 3567, 50, 2, false, 2, 2},
-		{431209, 1109, 0, 34, 60, 2, false, 6, 2},
-		{431210, 128, 1, 8921, 70, 2, false, 3, 2},
-		{431211, 406, 0, 59, 10, 1, false, 1, 1},
-		{431212, 1170, 0, 70, 90, 3, false, 6, 3},
-		{431213, 164, 0, 38, 24, 2, false, 6, 2},
-		{431214, 378, 1, 3556, 150, 3, false, 1, 3},
-		{431215, 446, 0, 94, 20, 2, false, 4, 2},
-	}
+	_, start, missions := s.server.currentGuildMissions()
 	for _, mission := range missions {
 		bf.WriteUint32(mission.ID)
 		bf.WriteUint32(mission.Unk)
@@ -48,7 +144,7 @@ func handleMsgMhfGetGuildMissionList(s *Session, p mhfpacket.MHFPacket) {
 		bf.WriteBool(mission.GR)
 		bf.WriteUint16(mission.RewardType)
 		bf.WriteUint16(mission.RewardLevel)
-		bf.WriteUint32(uint32(TimeAdjusted().Unix()))
+		bf.WriteUint32(uint32(start.Unix()))
 	}
 	doAckBufSucceed(s, pkt.AckHandle, bf.Data())
 }
@@ -63,15 +159,101 @@ func handleMsgMhfGetGuildMissionRecord(s *Session, p mhfpacket.MHFPacket) {
 
 func handleMsgMhfAddGuildMissionCount(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfAddGuildMissionCount)
+	guild, err := s.server.guildRepo.GetByCharID(s.charID)
+	if err != nil || guild == nil {
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+	member, err := s.server.guildRepo.GetCharacterMembership(s.charID)
+	if err != nil || member == nil || member.GuildID != guild.ID || member.IsApplicant {
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+	rotation, _, missions := s.server.currentGuildMissions()
+	target, err := currentGuildMissionTarget(s, guild.ID, rotation)
+	if err != nil || target == nil || target.MissionID != pkt.MissionID {
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+	mission, ok := findGuildMission(missions, pkt.MissionID)
+	if !ok {
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+
+	total, err := s.server.guildRepo.AddMissionCount(guild.ID, mission.ID, rotation, s.charID, pkt.Count, uint32(mission.Quantity))
+	if err != nil {
+		s.logger.Error("Failed to add guild mission count", zap.Error(err))
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+	if total >= uint32(mission.Quantity) {
+		if _, err := s.server.guildService.CompleteMission(guild.ID, mission, rotation); err != nil {
+			s.logger.Error("Failed to complete guild mission", zap.Error(err),
+				zap.Uint32("guildID", guild.ID), zap.Uint32("missionID", mission.ID))
+		}
+	}
 	doAckSimpleSucceed(s, pkt.AckHandle, make([]byte, 4))
 }
 
+// canManageGuildMissions reports whether the session's character is the
+// guild's leader or a sub-leader.
+func canManageGuildMissions(s *Session, guild *Guild) bool {
+	if guild.LeaderCharID == s.charID {
+		return true
+	}
+	member, err := s.server.guildRepo.GetCharacterMembership(s.charID)
+	return err == nil && member != nil && member.GuildID == guild.ID && member.IsSubLeader()
+}
+
 func handleMsgMhfSetGuildMissionTarget(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfSetGuildMissionTarget)
+	guild, err := s.server.guildRepo.GetByCharID(s.charID)
+	if err != nil || guild == nil || !canManageGuildMissions(s, guild) {
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+	rotation, _, missions := s.server.currentGuildMissions()
+	if _, ok := findGuildMission(missions, pkt.MissionID); !ok {
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+	completed, err := s.server.guildRepo.ListCompletedMissions(guild.ID, rotation)
+	if err != nil {
+		s.logger.Error("Failed to get completed guild missions", zap.Error(err))
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+	for _, id := range completed {
+		if id == pkt.MissionID {
+			doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+			return
+		}
+	}
+	if err := s.server.guildRepo.SetMissionTarget(guild.ID, pkt.MissionID, rotation, s.charID); err != nil {
+		s.logger.Error("Failed to set guild mission target", zap.Error(err))
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
 	doAckSimpleSucceed(s, pkt.AckHandle, make([]byte, 4))
 }
 
 func handleMsgMhfCancelGuildMissionTarget(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfCancelGuildMissionTarget)
+	guild, err := s.server.guildRepo.GetByCharID(s.charID)
+	if err != nil || guild == nil || !canManageGuildMissions(s, guild) {
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+	target, err := s.server.guildRepo.GetMissionTarget(guild.ID)
+	if err != nil || target == nil || target.MissionID != pkt.MissionID {
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
+	if err := s.server.guildRepo.ClearMissionTarget(guild.ID); err != nil {
+		s.logger.Error("Failed to cancel guild mission target", zap.Error(err))
+		doAckSimpleFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
 	doAckSimpleSucceed(s, pkt.AckHandle, make([]byte, 4))
 }
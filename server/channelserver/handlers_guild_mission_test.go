@@ -0,0 +1,248 @@
+package channelserver
+
+import (
+	"testing"
+	"time"
+
+	"erupe-ce/common/mhfitem"
+	cfg "erupe-ce/config"
+	"erupe-ce/network/mhfpacket"
+)
+
+// setupGuildMissionTest returns a session whose character leads guild 10.
+func setupGuildMissionTest(t *testing.T) (*Session, *mockGuildRepo) {
+	t.Helper()
+	server := createMockServer()
+	guildMock := &mockGuildRepo{
+		guild:      &Guild{ID: 10, Name: "Missions"},
+		membership: &GuildMember{GuildID: 10, CharID: 1, OrderIndex: 1},
+	}
+	guildMock.guild.LeaderCharID = 1
+	server.guildRepo = guildMock
+	ensureGuildService(server)
+	return createMockSession(1, server), guildMock
+}
+
+func TestGuildMissionRotation(t *testing.T) {
+	server := createMockServer()
+	server.erupeConfig.Guild.Missions.RotationDays = 7
+	week := int64(7 * 86400)
+
+	rotation, start := server.guildMissionRotation(time.Unix(3*week+100, 0))
+	if rotation != 3 || start.Unix() != 3*week {
+		t.Errorf("rotation = %d starting %d, want 3 starting %d", rotation, start.Unix(), 3*week)
+	}
+}
+
+func TestOfferedGuildMissions(t *testing.T) {
+	small := defaultGuildMissions[:5]
+	if got := offeredGuildMissions(small, 1); len(got) != 5 {
+		t.Errorf("small catalogue offered %d missions, want all 5", len(got))
+	}
+
+	var catalogue []GuildMission
+	for i := uint32(0); i < 40; i++ {
+		catalogue = append(catalogue, GuildMission{ID: 1000 + i})
+	}
+	a := offeredGuildMissions(catalogue, 7)
+	b := offeredGuildMissions(catalogue, 7)
+	if len(a) != guildMissionsOffered {
+		t.Fatalf("offered %d missions, want %d", len(a), guildMissionsOffered)
+	}
+	for i := range a {
+		if a[i].ID != b[i].ID {
+			t.Fatal("the same rotation offered different missions")
+		}
+	}
+	c := offeredGuildMissions(catalogue, 8)
+	same := true
+	for i := range a {
+		if a[i].ID != c[i].ID {
+			same = false
+		}
+	}
+	if same {
+		t.Error("consecutive rotations offered identical missions")
+	}
+}
+
+func TestGuildMissionCatalogueFromConfig(t *testing.T) {
+	server := createMockServer()
+	if got := server.guildMissionCatalogue(); len(got) != len(defaultGuildMissions) {
+		t.Errorf("empty config gave %d missions, want the %d built-in", len(got), len(defaultGuildMissions))
+	}
+	server.erupeConfig.Guild.Missions.Catalogue = []cfg.GuildMissionEntry{
+		{ID: 9, Quantity: 3, Rewards: []cfg.GuildMissionReward{{ItemID: 7, Quantity: 2}}},
+	}
+	got := server.guildMissionCatalogue()
+	if len(got) != 1 || got[0].ID != 9 || len(got[0].Rewards) != 1 || got[0].Rewards[0].Item.ItemID != 7 {
+		t.Errorf("configured catalogue = %+v", got)
+	}
+}
+
+func TestSetGuildMissionTarget(t *testing.T) {
+	session, guildMock := setupGuildMissionTest(t)
+	handleMsgMhfSetGuildMissionTarget(session, &mhfpacket.MsgMhfSetGuildMissionTarget{AckHandle: 1, MissionID: 431203})
+	if ack := readAck(t, session); ack.ErrorCode != 0 {
+		t.Fatalf("ErrorCode = %d, want 0", ack.ErrorCode)
+	}
+	if guildMock.missionTarget == nil || guildMock.missionTarget.MissionID != 431203 {
+		t.Errorf("target = %+v, want mission 431203", guildMock.missionTarget)
+	}
+}
+
+func TestSetGuildMissionTarget_Rejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		missionID uint32
+		setup     func(*mockGuildRepo)
+	}{
+		{"unknown mission", 1, nil},
+		{"already completed", 431203, func(m *mockGuildRepo) { m.completedMissions = []uint32{431203} }},
+		{"regular member", 431203, func(m *mockGuildRepo) {
+			m.guild.LeaderCharID = 2
+			m.membership.OrderIndex = 8
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, guildMock := setupGuildMissionTest(t)
+			if tt.setup != nil {
+				tt.setup(guildMock)
+			}
+			handleMsgMhfSetGuildMissionTarget(session, &mhfpacket.MsgMhfSetGuildMissionTarget{AckHandle: 1, MissionID: tt.missionID})
+			if ack := readAck(t, session); ack.ErrorCode == 0 {
+				t.Error("expected a fail ACK")
+			}
+			if guildMock.missionTarget != nil {
+				t.Error("target should not be set")
+			}
+		})
+	}
+}
+
+func TestCancelGuildMissionTarget(t *testing.T) {
+	session, guildMock := setupGuildMissionTest(t)
+	guildMock.missionTarget = &GuildMissionTarget{GuildID: 10, MissionID: 431203}
+
+	handleMsgMhfCancelGuildMissionTarget(session, &mhfpacket.MsgMhfCancelGuildMissionTarget{AckHandle: 1, MissionID: 431204})
+	if ack := readAck(t, session); ack.ErrorCode == 0 {
+		t.Error("cancelling another mission should fail")
+	}
+	handleMsgMhfCancelGuildMissionTarget(session, &mhfpacket.MsgMhfCancelGuildMissionTarget{AckHandle: 2, MissionID: 431203})
+	if ack := readAck(t, session); ack.ErrorCode != 0 {
+		t.Errorf("ErrorCode = %d, want 0", ack.ErrorCode)
+	}
+	if guildMock.missionTarget != nil {
+		t.Error("target should be cleared")
+	}
+}
+
+func TestAddGuildMissionCount_CompletesAndPaysOut(t *testing.T) {
+	session, guildMock := setupGuildMissionTest(t)
+	session.server.erupeConfig.Guild.Missions.Catalogue = []cfg.GuildMissionEntry{
+		{ID: 431203, Quantity: 6, Rewards: []cfg.GuildMissionReward{{ItemID: 7, Quantity: 2}}},
+	}
+	rotation, _, missions := session.server.currentGuildMissions()
+	mission, _ := findGuildMission(missions, 431203)
+	guildMock.missionTarget = &GuildMissionTarget{GuildID: 10, MissionID: mission.ID, Rotation: rotation}
+	guildMock.missionCounts = map[uint32]uint32{2: 4}
+
+	handleMsgMhfAddGuildMissionCount(session, &mhfpacket.MsgMhfAddGuildMissionCount{AckHandle: 1, MissionID: mission.ID, Count: 1})
+	if ack := readAck(t, session); ack.ErrorCode != 0 {
+		t.Fatalf("ErrorCode = %d, want 0", ack.ErrorCode)
+	}
+	if len(guildMock.completedMissions) != 0 {
+		t.Fatal("mission completed before reaching its quantity")
+	}
+
+	handleMsgMhfAddGuildMissionCount(session, &mhfpacket.MsgMhfAddGuildMissionCount{AckHandle: 2, MissionID: mission.ID, Count: 1})
+	_ = readAck(t, session)
+	if len(guildMock.completedMissions) != 1 || guildMock.completedMissions[0] != mission.ID {
+		t.Fatalf("completed = %v, want [%d]", guildMock.completedMissions, mission.ID)
+	}
+	if guildMock.missionTarget != nil {
+		t.Error("target should be cleared on completion")
+	}
+	items := readItemBox(guildMock.itemBox)
+	if len(items) != 1 || items[0].Item.ItemID != 7 || items[0].Quantity != 2 {
+		t.Errorf("item box = %+v, want 2 of item 7", items)
+	}
+}
+
+func TestDefaultGuildMissionsPayNoRewards(t *testing.T) {
+	for _, m := range defaultGuildMissions {
+		if len(m.Rewards) != 0 {
+			t.Errorf("mission %d has built-in rewards %+v", m.ID, m.Rewards)
+		}
+	}
+}
+
+func TestAddGuildMissionCount_ClampsToRemaining(t *testing.T) {
+	session, guildMock := setupGuildMissionTest(t)
+	rotation, _, _ := session.server.currentGuildMissions()
+	mission, _ := findGuildMission(defaultGuildMissions, 431203) // Quantity 6
+	guildMock.missionTarget = &GuildMissionTarget{GuildID: 10, MissionID: mission.ID, Rotation: rotation}
+	guildMock.missionCounts = map[uint32]uint32{3: 4}
+
+	handleMsgMhfAddGuildMissionCount(session, &mhfpacket.MsgMhfAddGuildMissionCount{AckHandle: 1, MissionID: mission.ID, Count: 0xFFFFFFFF})
+	if ack := readAck(t, session); ack.ErrorCode != 0 {
+		t.Fatalf("ErrorCode = %d, want 0", ack.ErrorCode)
+	}
+	if got := guildMock.missionCounts[session.charID]; got != 2 {
+		t.Errorf("own count = %d, want the 2 remaining", got)
+	}
+	if len(guildMock.completedMissions) != 1 {
+		t.Errorf("completed = %v, want the mission completed", guildMock.completedMissions)
+	}
+}
+
+func TestAddGuildMissionCount_Rejected(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*mockGuildRepo, uint32)
+	}{
+		{"no target", func(m *mockGuildRepo, _ uint32) {}},
+		{"other target", func(m *mockGuildRepo, rotation uint32) {
+			m.missionTarget = &GuildMissionTarget{MissionID: 431204, Rotation: rotation}
+		}},
+		{"stale rotation", func(m *mockGuildRepo, rotation uint32) {
+			m.missionTarget = &GuildMissionTarget{MissionID: 431203, Rotation: rotation - 1}
+		}},
+		{"applicant", func(m *mockGuildRepo, rotation uint32) {
+			m.missionTarget = &GuildMissionTarget{MissionID: 431203, Rotation: rotation}
+			m.membership.IsApplicant = true
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, guildMock := setupGuildMissionTest(t)
+			rotation, _, _ := session.server.currentGuildMissions()
+			tt.setup(guildMock, rotation)
+			handleMsgMhfAddGuildMissionCount(session, &mhfpacket.MsgMhfAddGuildMissionCount{AckHandle: 1, MissionID: 431203, Count: 1})
+			if ack := readAck(t, session); ack.ErrorCode == 0 {
+				t.Error("expected a fail ACK")
+			}
+			if len(guildMock.missionCounts) != 0 {
+				t.Error("count should not be recorded")
+			}
+		})
+	}
+}
+
+func TestAddItemStacks(t *testing.T) {
+	items := []mhfitem.MHFItemStack{{WarehouseID: 5, Item: mhfitem.MHFItem{ItemID: 7}, Quantity: 65000}}
+	items = addItemStacks(items, []mhfitem.MHFItemStack{
+		{Item: mhfitem.MHFItem{ItemID: 7}, Quantity: 1000},
+		{Item: mhfitem.MHFItem{ItemID: 8}, Quantity: 3},
+	})
+	if len(items) != 2 {
+		t.Fatalf("got %d stacks, want 2", len(items))
+	}
+	if items[0].Quantity != 0xFFFF {
+		t.Errorf("merged quantity = %d, want capped at 65535", items[0].Quantity)
+	}
+	if items[1].Item.ItemID != 8 || items[1].Quantity != 3 || items[1].WarehouseID == 0 {
+		t.Errorf("new stack = %+v", items[1])
+	}
+}
@@ -1,6 +1,7 @@
 package channelserver
 
 import (
+	"fmt"
 	"time"
 
 	"erupe-ce/common/byteframe"
@@ -42,10 +43,12 @@ func handleMsgMhfOperateGuild(s *Session, p mhfpacket.MHFPacket) {
 			bf.WriteUint32(result.NewLeaderCharID)
 		}
 	case mhfpacket.OperateGuildApply:
-		err = s.server.guildRepo.CreateApplication(guild.ID, s.charID, s.charID, GuildApplicationTypeApplied)
+		err = s.server.guildService.ApplyToGuild(s.charID, guild.ID, s.server.guildRankTable(), s.server.erupeConfig.RealClientMode)
 		if err == nil {
 			bf.WriteUint32(guild.LeaderCharID)
 		} else {
+			s.logger.Warn("Failed to apply to guild",
+				zap.Uint32("CharID", s.charID), zap.Uint32("GuildID", guild.ID), zap.Error(err))
 			bf.WriteUint32(0)
 		}
 	case mhfpacket.OperateGuildLeave:
@@ -186,8 +189,12 @@ func handleDonateRP(s *Session, amount uint16, guild *Guild, _type int) []byte {
 	saveData.Save(s)
 	switch _type {
 	case 0:
+		oldRank := s.server.guildRank(guild)
 		if err := s.server.guildRepo.AddRankRP(guild.ID, amount); err != nil {
 			s.logger.Error("Failed to update guild rank RP", zap.Error(err))
+		} else {
+			guild.RankRP += uint32(amount)
+			s.server.notifyGuildRankChange(guild, oldRank, s.server.guildRank(guild), s.charID)
 		}
 	case 1:
 		if err := s.server.guildRepo.AddEventRP(guild.ID, amount); err != nil {
@@ -207,6 +214,12 @@ func handleDonateRP(s *Session, amount uint16, guild *Guild, _type int) []byte {
 			}
 		}
 	}
+	s.server.guildService.LogEvent(GuildEvent{
+		GuildID: guild.ID,
+		ActorID: s.charID,
+		Action:  GuildEventDonateRP,
+		Detail:  fmt.Sprintf("%s %d", [...]string{"rank", "event", "room"}[_type], amount),
+	})
 	_, _ = bf.Seek(0, 0)
 	bf.WriteUint32(uint32(saveData.RP))
 	return bf.Data()
@@ -177,6 +177,7 @@ func TestOperateGuild_Apply_Success(t *testing.T) {
 	guildMock.guild = &Guild{ID: 10}
 	guildMock.guild.LeaderCharID = 999
 	server.guildRepo = guildMock
+	ensureGuildService(server)
 	session := createMockSession(1, server)
 
 	pkt := &mhfpacket.MsgMhfOperateGuild{
@@ -187,8 +188,8 @@ func TestOperateGuild_Apply_Success(t *testing.T) {
 
 	handleMsgMhfOperateGuild(session, pkt)
 
-	if guildMock.createdAppArgs == nil {
-		t.Fatal("CreateApplication should be called")
+	if guildMock.appliedArgs == nil {
+		t.Fatal("Apply should be called")
 	}
 
 	select {
@@ -201,12 +202,13 @@ func TestOperateGuild_Apply_Success(t *testing.T) {
 func TestOperateGuild_Apply_RepoError(t *testing.T) {
 	server := createMockServer()
 	guildMock := &mockGuildRepo{
-		membership:   &GuildMember{GuildID: 10, CharID: 1, OrderIndex: 5},
-		createAppErr: errNotFound,
+		membership: &GuildMember{GuildID: 10, CharID: 1, OrderIndex: 5},
+		applyErr:   ErrGuildFull,
 	}
 	guildMock.guild = &Guild{ID: 10}
 	guildMock.guild.LeaderCharID = 999
 	server.guildRepo = guildMock
+	ensureGuildService(server)
 	session := createMockSession(1, server)
 
 	pkt := &mhfpacket.MsgMhfOperateGuild{
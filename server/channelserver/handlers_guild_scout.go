@@ -12,15 +12,27 @@ import (
 func handleMsgMhfPostGuildScout(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfPostGuildScout)
 
-	err := s.server.guildService.PostScout(s.charID, pkt.CharID, ScoutInviteStrings{
-		Title: s.server.i18n.guild.invite.title,
-		Body:  s.server.i18n.guild.invite.body,
+	// The invitation mail is read by the target, so use their language.
+	target := s.server.langForChar(pkt.CharID)
+	scouts := s.server.erupeConfig.Guild.Scouts
+	err := s.server.guildService.PostScout(s.charID, pkt.CharID, ScoutLimits{
+		MaxPerGuild:  scouts.MaxPerGuild,
+		MaxPerTarget: scouts.MaxPerTarget,
+	}, ScoutInviteStrings{
+		Title: target.guild.invite.title,
+		Body:  target.guild.invite.body,
 	})
 
 	if errors.Is(err, ErrAlreadyInvited) {
 		doAckBufSucceed(s, pkt.AckHandle, []byte{0x00, 0x00, 0x00, 0x04})
 		return
 	}
+	if errors.Is(err, ErrScoutsRejected) || errors.Is(err, ErrGuildScoutLimit) || errors.Is(err, ErrTargetScoutLimit) {
+		s.logger.Info("Guild scout refused", zap.Error(err),
+			zap.Uint32("charID", s.charID), zap.Uint32("targetID", pkt.CharID))
+		doAckBufFail(s, pkt.AckHandle, make([]byte, 4))
+		return
+	}
 	if err != nil {
 		s.logger.Error("Failed to post guild scout", zap.Error(err))
 		doAckBufFail(s, pkt.AckHandle, make([]byte, 4))
@@ -66,16 +78,19 @@ func handleMsgMhfCancelGuildScout(s *Session, p mhfpacket.MHFPacket) {
 func handleMsgMhfAnswerGuildScout(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfAnswerGuildScout)
 
-	i := s.server.i18n.guild.invite
+	// Success and rejected mails go to the answering character, accepted and
+	// declined mails to the leader who sent the invitation.
+	i := s.lang().guild.invite
+	leader := s.server.langForChar(pkt.LeaderID).guild.invite
 	result, err := s.server.guildService.AnswerScout(s.charID, pkt.LeaderID, pkt.Answer, AnswerScoutStrings{
 		SuccessTitle:  i.success.title,
 		SuccessBody:   i.success.body,
-		AcceptedTitle: i.accepted.title,
-		AcceptedBody:  i.accepted.body,
+		AcceptedTitle: leader.accepted.title,
+		AcceptedBody:  leader.accepted.body,
 		RejectedTitle: i.rejected.title,
 		RejectedBody:  i.rejected.body,
-		DeclinedTitle: i.declined.title,
-		DeclinedBody:  i.declined.body,
+		DeclinedTitle: leader.declined.title,
+		DeclinedBody:  leader.declined.body,
 	})
 
 	if err != nil && !errors.Is(err, ErrApplicationMissing) {
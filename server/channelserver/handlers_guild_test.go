@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"erupe-ce/common/mhfguild"
+	"erupe-ce/common/mhfitem"
 	cfg "erupe-ce/config"
 	"erupe-ce/network/mhfpacket"
 )
@@ -122,7 +124,7 @@ func TestGuildRankCalculation(t *testing.T) {
 				RankRP: tt.rankRP,
 			}
 
-			rank := guild.Rank(tt.config)
+			rank := guild.Rank(mhfguild.DefaultTable(tt.config, nil), tt.config)
 			if rank != tt.wantRank {
 				t.Errorf("guild rank calculation: got %d, want %d for RP %d", rank, tt.wantRank, tt.rankRP)
 			}
@@ -924,3 +926,122 @@ func TestAcquireMonthlyItem_MarksAsClaimed(t *testing.T) {
 		t.Error("No response packet queued")
 	}
 }
+
+// setupGuildItemTest returns a session for char 3 in guild 10 whose item box
+// holds 10 of item 1630.
+func setupGuildItemTest(t *testing.T, member *GuildMember) (*Session, *mockGuildRepo) {
+	t.Helper()
+	server := createMockServer()
+	guildMock := &mockGuildRepo{membership: member}
+	guildMock.itemBox = mhfitem.SerializeWarehouseItems([]mhfitem.MHFItemStack{
+		{WarehouseID: 1, Item: mhfitem.MHFItem{ItemID: 1630}, Quantity: 10},
+	})
+	server.guildRepo = guildMock
+	ensureGuildService(server)
+	return createMockSession(3, server), guildMock
+}
+
+func updateGuildItemQuantity(session *Session, quantity uint16) {
+	handleMsgMhfUpdateGuildItem(session, &mhfpacket.MsgMhfUpdateGuildItem{
+		AckHandle: 1,
+		GuildID:   10,
+		UpdatedItems: []mhfitem.MHFItemStack{
+			{WarehouseID: 1, Item: mhfitem.MHFItem{ItemID: 1630}, Quantity: quantity},
+		},
+	})
+}
+
+func TestUpdateGuildItem_RecordsTransactions(t *testing.T) {
+	session, guildMock := setupGuildItemTest(t, &GuildMember{GuildID: 10, CharID: 3, OrderIndex: 5})
+
+	updateGuildItemQuantity(session, 7)
+	if ack := readAck(t, session); ack.ErrorCode != 0 {
+		t.Fatalf("ErrorCode = %d, want 0", ack.ErrorCode)
+	}
+	if len(guildMock.itemChanges) != 1 || guildMock.itemChanges[0] != (GuildItemChange{ItemID: 1630, Quantity: -3}) {
+		t.Errorf("changes = %+v, want a withdrawal of 3", guildMock.itemChanges)
+	}
+	if items := readItemBox(guildMock.itemBox); len(items) != 1 || items[0].Quantity != 7 {
+		t.Errorf("item box = %+v, want 7 left", items)
+	}
+}
+
+func TestUpdateGuildItem_RejectsNonMembers(t *testing.T) {
+	for name, member := range map[string]*GuildMember{
+		"other guild": {GuildID: 11, CharID: 3, OrderIndex: 5},
+		"applicant":   {GuildID: 10, CharID: 3, OrderIndex: 5, IsApplicant: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			session, guildMock := setupGuildItemTest(t, member)
+			updateGuildItemQuantity(session, 0)
+			if ack := readAck(t, session); ack.ErrorCode == 0 {
+				t.Error("expected a fail ACK")
+			}
+			if len(guildMock.itemChanges) != 0 {
+				t.Error("item box should not change")
+			}
+		})
+	}
+}
+
+func TestUpdateGuildItem_WithdrawalLimits(t *testing.T) {
+	limits := cfg.GuildWithdrawalLimits{Leader: 0, SubLeader: 8, Recruiter: 5, Member: 3}
+	tests := []struct {
+		name      string
+		member    *GuildMember
+		withdrawn int
+		quantity  uint16
+		wantOK    bool
+	}{
+		{"member within limit", &GuildMember{OrderIndex: 5}, 0, 7, true},
+		{"member over limit", &GuildMember{OrderIndex: 5}, 0, 6, false},
+		{"member over limit with earlier withdrawals", &GuildMember{OrderIndex: 5}, 2, 8, false},
+		{"recruiter", &GuildMember{OrderIndex: 5, Recruiter: true}, 0, 5, true},
+		{"sub-leader", &GuildMember{OrderIndex: 2}, 0, 2, true},
+		{"leader is unlimited", &GuildMember{OrderIndex: 1, IsLeader: true}, 100, 0, true},
+		{"deposits are not capped", &GuildMember{OrderIndex: 5}, 3, 20, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.member.GuildID, tt.member.CharID = 10, 3
+			session, guildMock := setupGuildItemTest(t, tt.member)
+			session.server.erupeConfig.Guild.ItemWithdrawalLimits = limits
+			guildMock.withdrawn = tt.withdrawn
+
+			updateGuildItemQuantity(session, tt.quantity)
+			ack := readAck(t, session)
+			if ok := ack.ErrorCode == 0; ok != tt.wantOK {
+				t.Fatalf("succeeded = %v, want %v", ok, tt.wantOK)
+			}
+			if !tt.wantOK && len(guildMock.itemChanges) != 0 {
+				t.Error("rejected update should not change the item box")
+			}
+		})
+	}
+}
+
+func TestGuildRank_ForcedRankOverridesRP(t *testing.T) {
+	table := mhfguild.DefaultTable(cfg.ZZ, nil)
+	forced := uint16(2)
+	guild := &Guild{RankRP: 120001, ForcedRank: &forced}
+	if got := guild.Rank(table, cfg.ZZ); got != 2 {
+		t.Errorf("Rank() = %d, want forced rank 2", got)
+	}
+	forced = 200
+	if got, want := guild.Rank(table, cfg.ZZ), mhfguild.MaxRank(cfg.ZZ); got != want {
+		t.Errorf("Rank() = %d, want clamped rank %d", got, want)
+	}
+}
+
+func TestRankChangeStrings(t *testing.T) {
+	server := createMockServer()
+	strings := rankChangeStrings(server.locales, &server.i18n)
+	if strings.Fallback.Subject != server.i18n.guild.rank.title {
+		t.Errorf("fallback = %+v, want the server language", strings.Fallback)
+	}
+	for _, code := range []string{"en", "jp"} {
+		if text, ok := strings.Localized[code]; !ok || text.Subject == "" || text.Body == "" {
+			t.Errorf("locale %s = %+v, want a rank change mail", code, text)
+		}
+	}
+}
@@ -133,15 +133,18 @@ func handleMsgMhfOperateGuildTresureReport(s *Session, p mhfpacket.MHFPacket) {
 
 // TreasureSouvenir represents a guild treasure souvenir entry.
 type TreasureSouvenir struct {
-	Destination uint32
-	Quantity    uint32
+	Destination uint32 `db:"destination"`
+	Quantity    uint32 `db:"quantity"`
 }
 
 func handleMsgMhfGetGuildTresureSouvenir(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfGetGuildTresureSouvenir)
 	bf := byteframe.NewByteFrame()
 	bf.WriteUint32(0)
-	souvenirs := []TreasureSouvenir{}
+	souvenirs, err := s.server.guildRepo.ListSouvenirs(s.charID)
+	if err != nil {
+		s.logger.Error("Failed to get treasure hunt souvenirs", zap.Error(err))
+	}
 	bf.WriteUint16(uint16(len(souvenirs)))
 	for _, souvenir := range souvenirs {
 		bf.WriteUint32(souvenir.Destination)
@@ -152,5 +155,8 @@ func handleMsgMhfGetGuildTresureSouvenir(s *Session, p mhfpacket.MHFPacket) {
 
 func handleMsgMhfAcquireGuildTresureSouvenir(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgMhfAcquireGuildTresureSouvenir)
+	if err := s.server.guildRepo.ClaimSouvenirs(s.charID); err != nil {
+		s.logger.Error("Failed to claim treasure hunt souvenirs", zap.Error(err))
+	}
 	doAckSimpleSucceed(s, pkt.AckHandle, make([]byte, 4))
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"erupe-ce/common/byteframe"
 	"erupe-ce/network/mhfpacket"
 )
 
@@ -190,6 +191,7 @@ func TestOperateGuildTresureReport_Claim(t *testing.T) {
 
 func TestGetGuildTresureSouvenir_Empty(t *testing.T) {
 	server := createMockServer()
+	server.guildRepo = &mockGuildRepo{}
 	session := createMockSession(1, server)
 
 	pkt := &mhfpacket.MsgMhfGetGuildTresureSouvenir{AckHandle: 100}
@@ -202,3 +204,29 @@ func TestGetGuildTresureSouvenir_Empty(t *testing.T) {
 		t.Error("No response packet queued")
 	}
 }
+
+func TestGetGuildTresureSouvenir_ListsSouvenirs(t *testing.T) {
+	server := createMockServer()
+	guildMock := &mockGuildRepo{souvenirs: []TreasureSouvenir{{Destination: 3, Quantity: 2}, {Destination: 5, Quantity: 1}}}
+	server.guildRepo = guildMock
+	session := createMockSession(1, server)
+
+	handleMsgMhfGetGuildTresureSouvenir(session, &mhfpacket.MsgMhfGetGuildTresureSouvenir{AckHandle: 100})
+	ack := readAck(t, session)
+	bf := byteframe.NewByteFrameFromBytes(ack.Payload)
+	_ = bf.ReadUint32()
+	if n := bf.ReadUint16(); n != 2 {
+		t.Fatalf("souvenir count = %d, want 2", n)
+	}
+	if dest, qty := bf.ReadUint32(), bf.ReadUint32(); dest != 3 || qty != 2 {
+		t.Errorf("first souvenir = %d x%d, want 3 x2", dest, qty)
+	}
+
+	handleMsgMhfAcquireGuildTresureSouvenir(session, &mhfpacket.MsgMhfAcquireGuildTresureSouvenir{AckHandle: 101})
+	if ack := readAck(t, session); ack.ErrorCode != 0 {
+		t.Fatalf("ErrorCode = %d, want 0", ack.ErrorCode)
+	}
+	if guildMock.souvenirs != nil {
+		t.Error("acquiring should claim all souvenirs")
+	}
+}
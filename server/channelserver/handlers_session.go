@@ -76,9 +76,18 @@ func handleMsgSysLogin(s *Session, p mhfpacket.MHFPacket) {
 	}
 	s.userID = userID
 
+	language, err := s.server.charRepo.ReadString(s.charID, "language")
+	if err != nil {
+		s.logger.Warn("Failed to read language preference", zap.Error(err))
+	}
+	s.setLanguage(language)
+
 	if s.captureConn != nil {
 		s.captureConn.SetSessionInfo(s.charID, s.userID)
 	}
+	if s.tapConn != nil {
+		s.tapConn.SetCharID(s.charID)
+	}
 
 	bf := byteframe.NewByteFrame()
 	bf.WriteUint32(uint32(TimeAdjusted().Unix())) // Unix timestamp
@@ -278,13 +287,11 @@ func logoutPlayer(s *Session) {
 			// Continue with logout even if save fails
 		}
 
-		// Update time_played and guild treasure hunt
+		// Update time_played. Treasure hunt reports are kept until the hunt
+		// ends, even while the character is offline.
 		if err := s.server.charRepo.UpdateTimePlayed(s.charID, timePlayed); err != nil {
 			s.logger.Error("Failed to update time played", zap.Error(err))
 		}
-		if err := s.server.guildRepo.ClearTreasureHunt(s.charID); err != nil {
-			s.logger.Error("Failed to clear treasure hunt", zap.Error(err))
-		}
 	}
 
 	// Flush and close capture file before closing the connection.
@@ -297,6 +304,7 @@ func logoutPlayer(s *Session) {
 	delete(s.server.sessions, s.rawConn)
 	_ = s.rawConn.Close()
 	s.server.Unlock()
+	s.markClosed()
 
 	// Stage cleanup — snapshot sessions first under server mutex, then iterate stages
 	s.server.Lock()
@@ -428,6 +436,7 @@ const (
 
 func handleMsgSysRecordLog(s *Session, p mhfpacket.MHFPacket) {
 	pkt := p.(*mhfpacket.MsgSysRecordLog)
+	var kills int
 	if s.server.erupeConfig.RealClientMode == cfg.ZZ {
 		bf := byteframe.NewByteFrameFromBytes(pkt.Data)
 		_, _ = bf.Seek(killLogHeaderSize, 0)
@@ -435,12 +444,14 @@ func handleMsgSysRecordLog(s *Session, p mhfpacket.MHFPacket) {
 		for i := 0; i < killLogMonsterCount; i++ {
 			val = bf.ReadUint8()
 			if val > 0 && mhfmon.Monsters[i].Large {
+				kills += int(val)
 				if err := s.server.guildRepo.InsertKillLog(s.charID, i, val, TimeAdjusted()); err != nil {
 					s.logger.Error("Failed to insert kill log", zap.Error(err))
 				}
 			}
 		}
 	}
+	recordGuildWeeklyActivity(s, kills)
 	// remove a client returning to town from reserved slots to make sure the stage is hidden from board
 	delete(s.stage.reservedClientSlots, s.charID)
 	doAckSimpleSucceed(s, pkt.AckHandle, make([]byte, 4))
@@ -9,9 +9,10 @@ import (
 	"erupe-ce/network/mhfpacket"
 )
 
-func TestHandleMsgMhfEnumerateShop_Case1_G7EarlyReturn(t *testing.T) {
+func TestHandleMsgMhfEnumerateShop_Case1_PreG1EarlyReturn(t *testing.T) {
 	server := createMockServer()
-	server.erupeConfig.RealClientMode = cfg.G7
+	server.erupeConfig.RealClientMode = cfg.F5
+	// No gachaRepo: clients before G1 must not reach the gacha list.
 
 	session := createMockSession(1, server)
 
@@ -22,13 +23,9 @@ func TestHandleMsgMhfEnumerateShop_Case1_G7EarlyReturn(t *testing.T) {
 	}
 	handleMsgMhfEnumerateShop(session, pkt)
 
-	select {
-	case p := <-session.sendPackets:
-		if len(p.data) == 0 {
-			t.Fatal("Empty response")
-		}
-	default:
-		t.Error("No response packet queued")
+	ack := readAck(t, session)
+	if ack.ErrorCode != 0 || len(ack.Payload) != 4 {
+		t.Errorf("ack = %+v, want an empty 4-byte success", ack)
 	}
 }
 
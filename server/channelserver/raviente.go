@@ -78,34 +78,37 @@ func (s *Server) UpdateRavi(semaID uint32, index uint8, value uint32, update boo
 }
 
 func (s *Server) BroadcastRaviente(ip uint32, port uint16, stage []byte, _type uint8) {
-	bf := byteframe.NewByteFrame()
-	bf.SetLE()
-	bf.WriteUint16(0)    // Unk
-	bf.WriteUint16(0x43) // Data len
-	bf.WriteUint16(3)    // Unk len
-	var text string
-	switch _type {
-	case 2:
-		text = s.i18n.raviente.berserk
-	case 3:
-		text = s.i18n.raviente.extreme
-	case 4:
-		text = s.i18n.raviente.extremeLimited
-	case 5:
-		text = s.i18n.raviente.berserkSmall
-	default:
+	if _type < 2 || _type > 5 {
 		s.logger.Error("Unk raviente type", zap.Uint8("_type", _type))
 	}
-	ps.Uint16(bf, text, true)
-	bf.WriteBytes([]byte{0x5F, 0x53, 0x00})
-	bf.WriteUint32(ip)   // IP address
-	bf.WriteUint16(port) // Port
-	bf.WriteUint16(0)    // Unk
-	bf.WriteBytes(stage)
-	s.WorldcastMHF(&mhfpacket.MsgSysCastedBinary{
-		BroadcastType:  BroadcastTypeServer,
-		MessageType:    BinaryMessageTypeChat,
-		RawDataPayload: bf.Data(),
+	s.WorldcastLocalizedMHF(func(lang *i18n) mhfpacket.MHFPacket {
+		bf := byteframe.NewByteFrame()
+		bf.SetLE()
+		bf.WriteUint16(0)    // Unk
+		bf.WriteUint16(0x43) // Data len
+		bf.WriteUint16(3)    // Unk len
+		var text string
+		switch _type {
+		case 2:
+			text = lang.raviente.berserk
+		case 3:
+			text = lang.raviente.extreme
+		case 4:
+			text = lang.raviente.extremeLimited
+		case 5:
+			text = lang.raviente.berserkSmall
+		}
+		ps.Uint16(bf, text, true)
+		bf.WriteBytes([]byte{0x5F, 0x53, 0x00})
+		bf.WriteUint32(ip)   // IP address
+		bf.WriteUint16(port) // Port
+		bf.WriteUint16(0)    // Unk
+		bf.WriteBytes(stage)
+		return &mhfpacket.MsgSysCastedBinary{
+			BroadcastType:  BroadcastTypeServer,
+			MessageType:    BinaryMessageTypeChat,
+			RawDataPayload: bf.Data(),
+		}
 	}, nil, s)
 }
 
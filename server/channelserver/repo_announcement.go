@@ -0,0 +1,88 @@
+package channelserver
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Announcement is a scheduled server-wide chat message.
+type Announcement struct {
+	ID         uint32        `db:"id"`
+	Kind       string        `db:"kind"`
+	Message    string        `db:"message"`
+	SendAt     sql.NullTime  `db:"send_at"`
+	Schedule   string        `db:"schedule"`
+	Countdown  pq.Int64Array `db:"countdown"` // Minutes before SendAt
+	Enabled    bool          `db:"enabled"`
+	LastSentAt sql.NullTime  `db:"last_sent_at"`
+	CreatedBy  string        `db:"created_by"`
+}
+
+// AnnouncementRepository centralizes all database access for the announcements table.
+type AnnouncementRepository struct {
+	db *sqlx.DB
+}
+
+// NewAnnouncementRepository creates a new AnnouncementRepository.
+func NewAnnouncementRepository(db *sqlx.DB) *AnnouncementRepository {
+	return &AnnouncementRepository{db: db}
+}
+
+const announcementColumns = `id, kind, message, send_at, COALESCE(schedule, '') AS schedule,
+	countdown, enabled, last_sent_at, created_by`
+
+// List returns every announcement ordered by ID.
+func (r *AnnouncementRepository) List() ([]Announcement, error) {
+	var announcements []Announcement
+	err := r.db.Select(&announcements, `SELECT `+announcementColumns+` FROM announcements ORDER BY id`)
+	return announcements, err
+}
+
+// ListEnabled returns the announcements the scheduler should consider.
+func (r *AnnouncementRepository) ListEnabled() ([]Announcement, error) {
+	var announcements []Announcement
+	err := r.db.Select(&announcements, `SELECT `+announcementColumns+` FROM announcements WHERE enabled ORDER BY id`)
+	return announcements, err
+}
+
+// Create inserts an announcement and returns its ID.
+func (r *AnnouncementRepository) Create(a *Announcement) (uint32, error) {
+	var id uint32
+	err := r.db.QueryRow(
+		`INSERT INTO announcements (kind, message, send_at, schedule, countdown, enabled, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7) RETURNING id`,
+		a.Kind, a.Message, a.SendAt, a.Schedule, a.Countdown, a.Enabled, a.CreatedBy,
+	).Scan(&id)
+	return id, err
+}
+
+// Delete removes an announcement. Returns sql.ErrNoRows if it does not exist.
+func (r *AnnouncementRepository) Delete(id uint32) error {
+	res, err := r.db.Exec(`DELETE FROM announcements WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ClaimSend records that the occurrence at the given time is being sent and
+// reports whether this caller won the claim. Every channel server runs the
+// scheduler, so only the claimant broadcasts.
+func (r *AnnouncementRepository) ClaimSend(id uint32, occurrence time.Time) (bool, error) {
+	res, err := r.db.Exec(
+		`UPDATE announcements SET last_sent_at=$2
+		WHERE id=$1 AND enabled AND (last_sent_at IS NULL OR last_sent_at < $2)`,
+		id, occurrence,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
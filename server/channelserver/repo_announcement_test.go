@@ -0,0 +1,91 @@
+package channelserver
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func setupAnnouncementRepo(t *testing.T) (*AnnouncementRepository, *sqlx.DB) {
+	t.Helper()
+	db := SetupTestDB(t)
+	repo := NewAnnouncementRepository(db)
+	t.Cleanup(func() { TeardownTestDB(t, db) })
+	return repo, db
+}
+
+func TestAnnouncementRepoCreateListDelete(t *testing.T) {
+	repo, _ := setupAnnouncementRepo(t)
+
+	sendAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	id, err := repo.Create(&Announcement{
+		Kind:      AnnouncementCountdown,
+		Message:   "Restart in {minutes} min",
+		SendAt:    sql.NullTime{Time: sendAt, Valid: true},
+		Countdown: pq.Int64Array{10, 5},
+		Enabled:   true,
+		CreatedBy: "test",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := repo.Create(&Announcement{Kind: AnnouncementRecurring, Message: "off", Schedule: "0 * * * *", CreatedBy: "test"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	all, err := repo.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("List returned %d, want 2", len(all))
+	}
+	if !all[0].SendAt.Time.Equal(sendAt) || len(all[0].Countdown) != 2 || all[0].Schedule != "" {
+		t.Errorf("countdown announcement = %+v", all[0])
+	}
+	if all[1].Schedule != "0 * * * *" || all[1].SendAt.Valid {
+		t.Errorf("recurring announcement = %+v", all[1])
+	}
+
+	enabled, err := repo.ListEnabled()
+	if err != nil {
+		t.Fatalf("ListEnabled failed: %v", err)
+	}
+	if len(enabled) != 1 || enabled[0].ID != id {
+		t.Errorf("ListEnabled = %+v, want only #%d", enabled, id)
+	}
+
+	if err := repo.Delete(id); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := repo.Delete(id); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Delete of missing announcement error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestAnnouncementRepoClaimSend(t *testing.T) {
+	repo, _ := setupAnnouncementRepo(t)
+
+	id, err := repo.Create(&Announcement{Kind: AnnouncementRecurring, Message: "hi", Schedule: "* * * * *", Enabled: true, CreatedBy: "test"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	occurrence := time.Now().Truncate(time.Minute)
+	claimed, err := repo.ClaimSend(id, occurrence)
+	if err != nil || !claimed {
+		t.Fatalf("first ClaimSend = %v, %v, want true", claimed, err)
+	}
+	claimed, err = repo.ClaimSend(id, occurrence)
+	if err != nil || claimed {
+		t.Errorf("second ClaimSend = %v, %v, want false", claimed, err)
+	}
+	claimed, err = repo.ClaimSend(id, occurrence.Add(time.Minute))
+	if err != nil || !claimed {
+		t.Errorf("next occurrence ClaimSend = %v, %v, want true", claimed, err)
+	}
+}
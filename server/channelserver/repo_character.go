@@ -211,6 +211,30 @@ func (r *CharacterRepository) FindByRastaID(rastaID int) (charID uint32, name st
 	return
 }
 
+// CharacterSummary holds the public profile fields of a character.
+type CharacterSummary struct {
+	ID         uint32 `db:"id"`
+	Name       string `db:"name"`
+	HR         uint16 `db:"hr"`
+	GR         uint16 `db:"gr"`
+	WeaponType uint16 `db:"weapon_type"`
+	WeaponID   uint16 `db:"weapon_id"`
+	LastLogin  int64  `db:"last_login"`
+}
+
+// FindByName looks up a non-deleted character by exact name.
+func (r *CharacterRepository) FindByName(name string) (*CharacterSummary, error) {
+	var c CharacterSummary
+	err := r.db.Get(&c, `SELECT id, COALESCE(name, '') AS name, COALESCE(hr, 0) AS hr, COALESCE(gr, 0) AS gr,
+		COALESCE(weapon_type, 0) AS weapon_type, weapon_id, COALESCE(last_login, 0) AS last_login
+		FROM characters WHERE name = $1 AND deleted = false AND is_new_character = false
+		ORDER BY last_login DESC LIMIT 1`, name)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
 // SaveCharacterData updates the core save fields on a character.
 func (r *CharacterRepository) SaveCharacterData(charID uint32, compSave []byte, hr, gr uint16, isFemale bool, weaponType uint8, weaponID uint16) error {
 	_, err := r.db.Exec(`UPDATE characters SET savedata=$1, is_new_character=false, hr=$2, gr=$3, is_female=$4, weapon_type=$5, weapon_id=$6 WHERE id=$7`,
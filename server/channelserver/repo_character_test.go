@@ -580,6 +580,26 @@ func TestFindByRastaID(t *testing.T) {
 	}
 }
 
+func TestFindByName(t *testing.T) {
+	repo, db, charID := setupCharRepo(t)
+
+	if _, err := db.Exec("UPDATE characters SET hr=7, gr=250, weapon_type=3, weapon_id=120 WHERE id=$1", charID); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	c, err := repo.FindByName("RepoChar")
+	if err != nil {
+		t.Fatalf("FindByName failed: %v", err)
+	}
+	if c.ID != charID || c.HR != 7 || c.GR != 250 || c.WeaponType != 3 || c.WeaponID != 120 {
+		t.Errorf("Unexpected summary: %+v", c)
+	}
+
+	if _, err := repo.FindByName("NoSuchChar"); err == nil {
+		t.Error("Expected error for unknown name")
+	}
+}
+
 func TestLoadSaveData(t *testing.T) {
 	repo, _, charID := setupCharRepo(t)
 
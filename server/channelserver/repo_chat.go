@@ -0,0 +1,42 @@
+package channelserver
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ChatLogEntry is a single chat message recorded for moderation.
+type ChatLogEntry struct {
+	CharID       uint32
+	SenderName   string
+	ChatType     string
+	ChannelID    uint16
+	StageID      string
+	Targets      []uint32 // Recipients of targeted (party, whisper, ...) messages
+	Message      string
+	FilterAction string
+}
+
+// ChatRepository centralizes all database access for the chat_logs table.
+type ChatRepository struct {
+	db *sqlx.DB
+}
+
+// NewChatRepository creates a new ChatRepository.
+func NewChatRepository(db *sqlx.DB) *ChatRepository {
+	return &ChatRepository{db: db}
+}
+
+// Log records a chat message.
+func (r *ChatRepository) Log(e ChatLogEntry) error {
+	var targets pq.Int64Array
+	for _, t := range e.Targets {
+		targets = append(targets, int64(t))
+	}
+	_, err := r.db.Exec(
+		`INSERT INTO chat_logs (char_id, sender_name, chat_type, channel_id, stage_id, targets, message, filter_action)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		e.CharID, e.SenderName, e.ChatType, e.ChannelID, e.StageID, targets, e.Message, e.FilterAction,
+	)
+	return err
+}
@@ -0,0 +1,41 @@
+package channelserver
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestChatRepoLog(t *testing.T) {
+	db := SetupTestDB(t)
+	defer TeardownTestDB(t, db)
+	repo := NewChatRepository(db)
+
+	err := repo.Log(ChatLogEntry{
+		CharID:       7,
+		SenderName:   "Hunter",
+		ChatType:     "party",
+		ChannelID:    4097,
+		StageID:      "sl1Ns200p0a0u0",
+		Targets:      []uint32{8, 9},
+		Message:      "hello",
+		FilterAction: chatLogMasked,
+	})
+	if err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+
+	var (
+		chatType, message, action string
+		channelID                 int
+		targets                   pq.Int64Array
+	)
+	err = db.QueryRow(`SELECT chat_type, message, filter_action, channel_id, targets FROM chat_logs WHERE char_id=7`).
+		Scan(&chatType, &message, &action, &channelID, &targets)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if chatType != "party" || message != "hello" || action != chatLogMasked || channelID != 4097 || len(targets) != 2 {
+		t.Errorf("row = %q %q %q %d %v", chatType, message, action, channelID, targets)
+	}
+}
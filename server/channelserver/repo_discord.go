@@ -0,0 +1,35 @@
+package channelserver
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+// DiscordRepository centralizes all database access for Discord relay state.
+type DiscordRepository struct {
+	db *sqlx.DB
+}
+
+// NewDiscordRepository creates a new DiscordRepository.
+func NewDiscordRepository(db *sqlx.DB) *DiscordRepository {
+	return &DiscordRepository{db: db}
+}
+
+// IsMuted returns whether the Discord user is muted from the chat relay.
+func (r *DiscordRepository) IsMuted(discordID string) (bool, error) {
+	var muted bool
+	err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM discord_relay_mutes WHERE discord_id=$1)`, discordID).Scan(&muted)
+	return muted, err
+}
+
+// Mute stops the Discord user's messages from being relayed into the game.
+func (r *DiscordRepository) Mute(discordID, mutedBy string) error {
+	_, err := r.db.Exec(`INSERT INTO discord_relay_mutes (discord_id, muted_by) VALUES ($1, $2)
+		ON CONFLICT (discord_id) DO UPDATE SET muted_by=$2, created_at=now()`, discordID, mutedBy)
+	return err
+}
+
+// Unmute resumes relaying the Discord user's messages into the game.
+func (r *DiscordRepository) Unmute(discordID string) error {
+	_, err := r.db.Exec(`DELETE FROM discord_relay_mutes WHERE discord_id=$1`, discordID)
+	return err
+}
@@ -0,0 +1,53 @@
+package channelserver
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func setupDiscordRepo(t *testing.T) (*DiscordRepository, *sqlx.DB) {
+	t.Helper()
+	db := SetupTestDB(t)
+	repo := NewDiscordRepository(db)
+	t.Cleanup(func() { TeardownTestDB(t, db) })
+	return repo, db
+}
+
+func TestDiscordRepoMuteUnmute(t *testing.T) {
+	repo, _ := setupDiscordRepo(t)
+
+	muted, err := repo.IsMuted("123")
+	if err != nil {
+		t.Fatalf("IsMuted failed: %v", err)
+	}
+	if muted {
+		t.Error("Expected user to not be muted initially")
+	}
+
+	if err := repo.Mute("123", "456"); err != nil {
+		t.Fatalf("Mute failed: %v", err)
+	}
+	// Muting twice upserts.
+	if err := repo.Mute("123", "789"); err != nil {
+		t.Fatalf("Mute (upsert) failed: %v", err)
+	}
+	muted, err = repo.IsMuted("123")
+	if err != nil {
+		t.Fatalf("IsMuted failed: %v", err)
+	}
+	if !muted {
+		t.Error("Expected user to be muted")
+	}
+
+	if err := repo.Unmute("123"); err != nil {
+		t.Fatalf("Unmute failed: %v", err)
+	}
+	muted, err = repo.IsMuted("123")
+	if err != nil {
+		t.Fatalf("IsMuted failed: %v", err)
+	}
+	if muted {
+		t.Error("Expected user to be unmuted")
+	}
+}
@@ -9,6 +9,21 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// ErrGuildNotRecruiting is returned when applying to a guild that is not
+// accepting applications.
+var ErrGuildNotRecruiting = errors.New("guild not accepting applications")
+
+// ErrGuildFull is returned when applying to a guild at its member limit.
+var ErrGuildFull = errors.New("guild is full")
+
+// ErrAlreadyInGuild is returned when a character that belongs to a guild
+// applies to one.
+var ErrAlreadyInGuild = errors.New("character already in a guild")
+
+// ErrAlreadyApplied is returned when a character with a pending application
+// applies again, or applies to a guild that has already invited it.
+var ErrAlreadyApplied = errors.New("character already applied")
+
 // GuildRepository centralizes all database access for guild-related tables
 // (guilds, guild_characters, guild_applications).
 type GuildRepository struct {
@@ -52,6 +67,7 @@ SELECT
 	), 0) AS alliance_id,
 	icon,
 	COALESCE(rp_reset_at, '2000-01-01'::timestamptz) AS rp_reset_at,
+	forced_rank,
 	(SELECT count(1) FROM guild_characters gc WHERE gc.guild_id = g.id) AS member_count
 	FROM guilds g
 	JOIN guild_characters gc ON gc.character_id = leader_id
@@ -216,7 +232,9 @@ func (r *GuildRepository) Disband(guildID uint32) error {
 	stmts := []string{
 		"DELETE FROM guild_characters WHERE guild_id = $1",
 		"DELETE FROM guilds WHERE id = $1",
+		"DELETE FROM guild_alliance_applications WHERE guild_id=$1 OR alliance_id IN (SELECT id FROM guild_alliances WHERE parent_id=$1)",
 		"DELETE FROM guild_alliances WHERE parent_id=$1",
+		"DELETE FROM guild_mission_targets WHERE guild_id = $1",
 	}
 	for _, stmt := range stmts {
 		if _, err := tx.Exec(stmt, guildID); err != nil {
@@ -289,6 +307,57 @@ func (r *GuildRepository) CreateApplicationWithMail(guildID, charID, actorID uin
 	return tx.Commit()
 }
 
+// Apply files a character's application to a guild. The guild must be
+// recruiting and below memberLimit, and the character may neither belong to a
+// guild nor hold another application. The checks and the insert share one
+// transaction holding the guild and character rows, so concurrent
+// applications cannot slip past them.
+func (r *GuildRepository) Apply(guildID, charID uint32, memberLimit uint8) error {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var recruiting bool
+	if err := tx.QueryRow(`SELECT recruiting FROM guilds WHERE id = $1 FOR UPDATE`, guildID).Scan(&recruiting); err != nil {
+		return err
+	}
+	if !recruiting {
+		return ErrGuildNotRecruiting
+	}
+	var members int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM guild_characters WHERE guild_id = $1`, guildID).Scan(&members); err != nil {
+		return err
+	}
+	if members >= int(memberLimit) {
+		return ErrGuildFull
+	}
+	if _, err := tx.Exec(`SELECT 1 FROM characters WHERE id = $1 FOR UPDATE`, charID); err != nil {
+		return err
+	}
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM guild_characters WHERE character_id = $1)`, charID).Scan(&exists); err != nil {
+		return err
+	} else if exists {
+		return ErrAlreadyInGuild
+	}
+	if err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM guild_applications
+		WHERE character_id = $1 AND (application_type = 'applied' OR guild_id = $2))`,
+		charID, guildID).Scan(&exists); err != nil {
+		return err
+	} else if exists {
+		return ErrAlreadyApplied
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO guild_applications (guild_id, character_id, actor_id, application_type) VALUES ($1, $2, $2, $3)`,
+		guildID, charID, GuildApplicationTypeApplied); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // CancelInvitation removes an invitation for a character.
 func (r *GuildRepository) CancelInvitation(guildID, charID uint32) error {
 	_, err := r.db.Exec(
@@ -421,6 +490,13 @@ func (r *GuildRepository) SetRecruiting(guildID uint32, recruiting bool) error {
 	return err
 }
 
+// SetForcedRank pins a guild to a rank regardless of its rank RP, or clears
+// the override when rank is nil.
+func (r *GuildRepository) SetForcedRank(guildID uint32, rank *uint16) error {
+	_, err := r.db.Exec("UPDATE guilds SET forced_rank=$1 WHERE id=$2", rank, guildID)
+	return err
+}
+
 // SetPugiOutfits updates the unlocked pugi outfit bitmask.
 func (r *GuildRepository) SetPugiOutfits(guildID uint32, outfits uint32) error {
 	_, err := r.db.Exec(`UPDATE guilds SET pugi_outfits=$1 WHERE id=$2`, outfits, guildID)
@@ -1,16 +1,28 @@
 package channelserver
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// ErrAllianceFull is returned when a guild joins an alliance whose parent
+// and both sub guild slots are already taken.
+var ErrAllianceFull = errors.New("alliance is full")
+
+// ErrAlreadyAllied is returned when a guild that already belongs to an
+// alliance applies to or is accepted into another.
+var ErrAlreadyAllied = errors.New("guild already in an alliance")
+
 const allianceInfoSelectSQL = `
 SELECT
 ga.id,
 ga.name,
 created_at,
+recruiting,
 parent_id,
 CASE
 	WHEN sub1_id IS NULL THEN 0
@@ -60,12 +72,127 @@ func (r *GuildRepository) CreateAlliance(name string, parentGuildID uint32) erro
 	return err
 }
 
-// DeleteAlliance removes an alliance by ID.
+// DeleteAlliance removes an alliance and its pending applications.
 func (r *GuildRepository) DeleteAlliance(allianceID uint32) error {
-	_, err := r.db.Exec("DELETE FROM guild_alliances WHERE id=$1", allianceID)
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("DELETE FROM guild_alliance_applications WHERE alliance_id=$1", allianceID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM guild_alliances WHERE id=$1", allianceID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetAllianceRecruiting sets whether an alliance accepts new applications.
+func (r *GuildRepository) SetAllianceRecruiting(allianceID uint32, recruiting bool) error {
+	_, err := r.db.Exec("UPDATE guild_alliances SET recruiting=$2 WHERE id=$1", allianceID, recruiting)
+	return err
+}
+
+// CreateAllianceApplication queues a guild's application to join an alliance.
+// Applying again to the same alliance is a no-op.
+func (r *GuildRepository) CreateAllianceApplication(allianceID, guildID, charID uint32) error {
+	_, err := r.db.Exec(`
+		INSERT INTO guild_alliance_applications (alliance_id, guild_id, applied_by) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+		allianceID, guildID, charID)
+	return err
+}
+
+// GetAllianceApplication returns a guild's pending application to an
+// alliance, or nil if there is none.
+func (r *GuildRepository) GetAllianceApplication(allianceID, guildID uint32) (*AllianceApplication, error) {
+	app := &AllianceApplication{}
+	err := r.db.QueryRowx(`
+		SELECT alliance_id, guild_id, applied_by, created_at FROM guild_alliance_applications
+		WHERE alliance_id=$1 AND guild_id=$2`,
+		allianceID, guildID).StructScan(app)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// ListAllianceApplications returns an alliance's pending applications, oldest first.
+func (r *GuildRepository) ListAllianceApplications(allianceID uint32) ([]*AllianceApplication, error) {
+	rows, err := r.db.Queryx(`
+		SELECT alliance_id, guild_id, applied_by, created_at FROM guild_alliance_applications
+		WHERE alliance_id=$1 ORDER BY created_at, guild_id`,
+		allianceID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var apps []*AllianceApplication
+	for rows.Next() {
+		app := &AllianceApplication{}
+		if err := rows.StructScan(app); err != nil {
+			return nil, err
+		}
+		apps = append(apps, app)
+	}
+	return apps, rows.Err()
+}
+
+// DeleteAllianceApplication removes a guild's pending application to an alliance.
+func (r *GuildRepository) DeleteAllianceApplication(allianceID, guildID uint32) error {
+	_, err := r.db.Exec("DELETE FROM guild_alliance_applications WHERE alliance_id=$1 AND guild_id=$2", allianceID, guildID)
 	return err
 }
 
+// AcceptAllianceApplication moves an applicant guild into the alliance's
+// first free sub guild slot and drops all of that guild's applications. It
+// returns ErrAllianceFull if both slots are taken and ErrAlreadyAllied if the
+// guild joined another alliance in the meantime.
+func (r *GuildRepository) AcceptAllianceApplication(allianceID, guildID uint32) error {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var sub1, sub2 sql.NullInt64
+	if err := tx.QueryRow(
+		"SELECT sub1_id, sub2_id FROM guild_alliances WHERE id=$1 FOR UPDATE", allianceID,
+	).Scan(&sub1, &sub2); err != nil {
+		return err
+	}
+	if sub1.Valid && sub2.Valid {
+		return ErrAllianceFull
+	}
+
+	var allied bool
+	if err := tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM guild_alliances WHERE parent_id=$1 OR sub1_id=$1 OR sub2_id=$1)", guildID,
+	).Scan(&allied); err != nil {
+		return err
+	}
+	if allied {
+		return ErrAlreadyAllied
+	}
+
+	slot := "sub1_id"
+	if sub1.Valid {
+		slot = "sub2_id"
+	}
+	if _, err := tx.Exec(fmt.Sprintf("UPDATE guild_alliances SET %s=$2 WHERE id=$1", slot), allianceID, guildID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM guild_alliance_applications WHERE guild_id=$1", guildID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // RemoveGuildFromAlliance removes a guild from its alliance, shifting sub2 into sub1's slot if needed.
 func (r *GuildRepository) RemoveGuildFromAlliance(allianceID, guildID, subGuild1ID, subGuild2ID uint32) error {
 	if guildID == subGuild1ID && subGuild2ID > 0 {
@@ -0,0 +1,55 @@
+package channelserver
+
+import "time"
+
+// GuildEventAction names a recorded guild state change.
+type GuildEventAction string
+
+// Guild audit log actions.
+const (
+	GuildEventAccept        GuildEventAction = "accept"
+	GuildEventReject        GuildEventAction = "reject"
+	GuildEventKick          GuildEventAction = "kick"
+	GuildEventLeave         GuildEventAction = "leave"
+	GuildEventDisband       GuildEventAction = "disband"
+	GuildEventResign        GuildEventAction = "resign"
+	GuildEventDonateRP      GuildEventAction = "donate_rp"
+	GuildEventItemBox       GuildEventAction = "item_box"
+	GuildEventIcon          GuildEventAction = "icon"
+	GuildEventRank          GuildEventAction = "rank"
+	GuildEventRecruiter     GuildEventAction = "recruiter"
+	GuildEventAllianceJoin  GuildEventAction = "alliance_join"
+	GuildEventAllianceLeave GuildEventAction = "alliance_leave"
+	GuildEventAllianceKick  GuildEventAction = "alliance_kick"
+)
+
+// GuildEvent is one guild audit log entry. TargetCharID is the character
+// the action was applied to, if any, and TargetGuildID the guild, for
+// alliance actions.
+type GuildEvent struct {
+	GuildID       uint32
+	ActorID       uint32
+	TargetCharID  uint32
+	TargetGuildID uint32
+	Action        GuildEventAction
+	Detail        string
+}
+
+// LogEvent appends an entry to the guild audit log.
+func (r *GuildRepository) LogEvent(e GuildEvent) error {
+	_, err := r.db.Exec(
+		`INSERT INTO guild_audit_log (guild_id, actor_id, target_char_id, target_guild_id, action, detail)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		e.GuildID, e.ActorID, e.TargetCharID, e.TargetGuildID, string(e.Action), e.Detail)
+	return err
+}
+
+// PruneEvents deletes guild audit log entries older than before and returns
+// how many were removed.
+func (r *GuildRepository) PruneEvents(before time.Time) (int64, error) {
+	res, err := r.db.Exec(`DELETE FROM guild_audit_log WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
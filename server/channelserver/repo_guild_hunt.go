@@ -3,6 +3,7 @@ package channelserver
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -74,12 +75,72 @@ func (r *GuildRepository) RegisterHuntReport(huntID, charID uint32) error {
 	return err
 }
 
-// CollectHunt marks a hunt as collected and clears all characters' treasure_hunt references.
+// endHuntsSQL marks the hunts matched by its WHERE fragment as collected,
+// clears their reports and gives each reporter a souvenir of the destination,
+// all in one statement so concurrent callers never end a hunt twice. It
+// returns how many hunts were ended.
+const endHuntsSQL = `
+	WITH ended AS (
+		UPDATE guild_hunts SET collected=true
+		WHERE NOT collected AND %s
+		RETURNING id, destination
+	), reporters AS (
+		UPDATE guild_characters gc SET treasure_hunt=NULL
+		FROM ended WHERE gc.treasure_hunt = ended.id
+		RETURNING gc.character_id, ended.destination
+	), souvenirs AS (
+		INSERT INTO guild_hunt_souvenirs (character_id, destination, quantity)
+		SELECT character_id, destination, COUNT(*) FROM reporters GROUP BY character_id, destination
+		ON CONFLICT (character_id, destination) DO UPDATE
+		SET quantity = guild_hunt_souvenirs.quantity + EXCLUDED.quantity
+	)
+	SELECT COUNT(*) FROM ended`
+
+// CollectHunt marks a hunt as collected, clears all characters' treasure_hunt
+// references and gives each reporter a souvenir.
 func (r *GuildRepository) CollectHunt(huntID uint32) error {
-	if _, err := r.db.Exec(`UPDATE guild_hunts SET collected=true WHERE id=$1`, huntID); err != nil {
-		return err
-	}
-	_, err := r.db.Exec(`UPDATE guild_characters SET treasure_hunt=NULL WHERE treasure_hunt=$1`, huntID)
+	var n int
+	return r.db.QueryRow(fmt.Sprintf(endHuntsSQL, "id=$1"), huntID).Scan(&n)
+}
+
+// EndExpiredHunts ends the acquired hunts started before the given time as
+// if they had been collected, so members who reported on them while offline
+// still get their souvenirs. It returns how many hunts were ended.
+func (r *GuildRepository) EndExpiredHunts(before time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(fmt.Sprintf(endHuntsSQL, "acquired AND start < $1"), before).Scan(&n)
+	return n, err
+}
+
+// PurgeHunts deletes the hunts started before the given time along with
+// their claims and any reports still pointing at them, and returns how many
+// hunts were deleted.
+func (r *GuildRepository) PurgeHunts(before time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(`
+		WITH purged AS (
+			DELETE FROM guild_hunts WHERE start < $1 RETURNING id
+		), claims AS (
+			DELETE FROM guild_hunts_claimed WHERE hunt_id IN (SELECT id FROM purged)
+		), reports AS (
+			UPDATE guild_characters SET treasure_hunt=NULL WHERE treasure_hunt IN (SELECT id FROM purged)
+		)
+		SELECT COUNT(*) FROM purged`, before).Scan(&n)
+	return n, err
+}
+
+// ListSouvenirs returns the treasure hunt souvenirs a character has yet to
+// collect.
+func (r *GuildRepository) ListSouvenirs(charID uint32) ([]TreasureSouvenir, error) {
+	var souvenirs []TreasureSouvenir
+	err := r.db.Select(&souvenirs,
+		`SELECT destination, quantity FROM guild_hunt_souvenirs WHERE character_id=$1 ORDER BY destination`, charID)
+	return souvenirs, err
+}
+
+// ClaimSouvenirs removes all of a character's uncollected souvenirs.
+func (r *GuildRepository) ClaimSouvenirs(charID uint32) error {
+	_, err := r.db.Exec(`DELETE FROM guild_hunt_souvenirs WHERE character_id=$1`, charID)
 	return err
 }
 
@@ -89,13 +150,14 @@ func (r *GuildRepository) ClaimHuntReward(huntID, charID uint32) error {
 	return err
 }
 
-// ListGuildKills returns kill log entries for guild members since the character's last box claim.
-func (r *GuildRepository) ListGuildKills(guildID, charID uint32) ([]*GuildKill, error) {
+// ListGuildKills returns kill log entries for guild members since the character's
+// last box claim or since, whichever is later.
+func (r *GuildRepository) ListGuildKills(guildID, charID uint32, since time.Time) ([]*GuildKill, error) {
 	rows, err := r.db.Queryx(`SELECT kl.id, kl.monster FROM kill_logs kl
 		INNER JOIN guild_characters gc ON kl.character_id = gc.character_id
 		WHERE gc.guild_id=$1
-		AND kl.timestamp >= (SELECT box_claimed FROM guild_characters WHERE character_id=$2)
-	`, guildID, charID)
+		AND kl.timestamp >= GREATEST((SELECT box_claimed FROM guild_characters WHERE character_id=$2), $3)
+	`, guildID, charID, since)
 	if err != nil {
 		return nil, err
 	}
@@ -111,23 +173,18 @@ func (r *GuildRepository) ListGuildKills(guildID, charID uint32) ([]*GuildKill,
 	return kills, nil
 }
 
-// CountGuildKills returns the count of kill log entries for guild members since the character's last box claim.
-func (r *GuildRepository) CountGuildKills(guildID, charID uint32) (int, error) {
+// CountGuildKills returns the count of kill log entries for guild members since
+// the character's last box claim or since, whichever is later.
+func (r *GuildRepository) CountGuildKills(guildID, charID uint32, since time.Time) (int, error) {
 	var count int
 	err := r.db.QueryRow(`SELECT COUNT(*) FROM kill_logs kl
 		INNER JOIN guild_characters gc ON kl.character_id = gc.character_id
 		WHERE gc.guild_id=$1
-		AND kl.timestamp >= (SELECT box_claimed FROM guild_characters WHERE character_id=$2)
-	`, guildID, charID).Scan(&count)
+		AND kl.timestamp >= GREATEST((SELECT box_claimed FROM guild_characters WHERE character_id=$2), $3)
+	`, guildID, charID, since).Scan(&count)
 	return count, err
 }
 
-// ClearTreasureHunt clears the treasure_hunt field for a character on logout.
-func (r *GuildRepository) ClearTreasureHunt(charID uint32) error {
-	_, err := r.db.Exec(`UPDATE guild_characters SET treasure_hunt=NULL WHERE character_id=$1`, charID)
-	return err
-}
-
 // InsertKillLog records a monster kill log entry for a character.
 func (r *GuildRepository) InsertKillLog(charID uint32, monster int, quantity uint8, timestamp time.Time) error {
 	_, err := r.db.Exec(`INSERT INTO kill_logs (character_id, monster, quantity, timestamp) VALUES ($1, $2, $3, $4)`, charID, monster, quantity, timestamp)
@@ -0,0 +1,79 @@
+package channelserver
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"erupe-ce/common/mhfitem"
+)
+
+// ErrWithdrawalLimit is returned when a guild item box update would take a
+// member past their daily withdrawal limit.
+var ErrWithdrawalLimit = errors.New("guild item withdrawal limit reached")
+
+// GuildItemChange is the net change of one item in a guild item box.
+// Quantity is positive for deposits and negative for withdrawals.
+type GuildItemChange struct {
+	ItemID   uint16 `db:"item_id"`
+	Quantity int    `db:"quantity"`
+}
+
+// UpdateItemBox applies a member's item box update to the stored box and
+// records the net changes, returning them. The box row stays locked from the
+// read to the write, so concurrent updates apply one after another. If limit
+// is positive, withdrawals since the given time plus those in this update
+// may not exceed it; the update then fails with ErrWithdrawalLimit.
+func (r *GuildRepository) UpdateItemBox(guildID, charID uint32, updates []mhfitem.MHFItemStack, limit int, since time.Time) ([]GuildItemChange, error) {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var data []byte
+	if err := tx.QueryRow(`SELECT item_box FROM guilds WHERE id=$1 FOR UPDATE`, guildID).Scan(&data); err != nil {
+		return nil, err
+	}
+	oldStacks := readItemBox(data)
+	// DiffItemStacks updates quantities in place, so diff against a copy.
+	newStacks := mhfitem.DiffItemStacks(slices.Clone(oldStacks), updates)
+	changes := diffGuildItems(oldStacks, newStacks)
+
+	if limit > 0 {
+		var withdrawing int
+		for _, c := range changes {
+			if c.Quantity < 0 {
+				withdrawing -= c.Quantity
+			}
+		}
+		if withdrawing > 0 {
+			var withdrawn int
+			if err := tx.QueryRow(`
+				SELECT COALESCE(-SUM(quantity), 0) FROM guild_item_transactions
+				WHERE guild_id=$1 AND character_id=$2 AND quantity < 0 AND created_at >= $3`,
+				guildID, charID, since).Scan(&withdrawn); err != nil {
+				return nil, err
+			}
+			if withdrawn+withdrawing > limit {
+				return nil, ErrWithdrawalLimit
+			}
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE guilds SET item_box=$1 WHERE id=$2`, mhfitem.SerializeWarehouseItems(newStacks), guildID); err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		if _, err := tx.Exec(
+			`INSERT INTO guild_item_transactions (guild_id, character_id, item_id, quantity) VALUES ($1, $2, $3, $4)`,
+			guildID, charID, c.ItemID, c.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,129 @@
+package channelserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"erupe-ce/common/mhfitem"
+)
+
+// GetMissionTarget returns the guild's current mission target, or nil if it
+// has none.
+func (r *GuildRepository) GetMissionTarget(guildID uint32) (*GuildMissionTarget, error) {
+	target := &GuildMissionTarget{}
+	err := r.db.QueryRowx(
+		`SELECT guild_id, mission_id, rotation, set_by, set_at FROM guild_mission_targets WHERE guild_id=$1`,
+		guildID).StructScan(target)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// SetMissionTarget makes missionID the guild's target, replacing any other.
+func (r *GuildRepository) SetMissionTarget(guildID, missionID, rotation, charID uint32) error {
+	_, err := r.db.Exec(`
+		INSERT INTO guild_mission_targets (guild_id, mission_id, rotation, set_by, set_at)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (guild_id) DO UPDATE
+		SET mission_id=EXCLUDED.mission_id, rotation=EXCLUDED.rotation, set_by=EXCLUDED.set_by, set_at=EXCLUDED.set_at`,
+		guildID, missionID, rotation, charID)
+	return err
+}
+
+// ClearMissionTarget removes the guild's mission target.
+func (r *GuildRepository) ClearMissionTarget(guildID uint32) error {
+	_, err := r.db.Exec(`DELETE FROM guild_mission_targets WHERE guild_id=$1`, guildID)
+	return err
+}
+
+// AddMissionCount adds up to count to a member's count for a mission and
+// returns the guild's total for it. The total is never taken past quantity,
+// so a forged count cannot inflate a member's contribution. The guild row is
+// locked while the total is read, so concurrent additions are clamped in turn.
+func (r *GuildRepository) AddMissionCount(guildID, missionID, rotation, charID, count, quantity uint32) (uint32, error) {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`SELECT id FROM guilds WHERE id=$1 FOR UPDATE`, guildID); err != nil {
+		return 0, err
+	}
+	var total uint32
+	if err := tx.QueryRow(
+		`SELECT COALESCE(SUM(count), 0) FROM guild_mission_counts WHERE guild_id=$1 AND mission_id=$2 AND rotation=$3`,
+		guildID, missionID, rotation).Scan(&total); err != nil {
+		return 0, err
+	}
+	if total >= quantity || count == 0 {
+		return total, nil
+	}
+	count = min(count, quantity-total)
+	if _, err := tx.Exec(`
+		INSERT INTO guild_mission_counts (guild_id, mission_id, rotation, character_id, count)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (guild_id, mission_id, rotation, character_id) DO UPDATE
+		SET count=guild_mission_counts.count+EXCLUDED.count`,
+		guildID, missionID, rotation, charID, count); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return total + count, nil
+}
+
+// ListCompletedMissions returns the IDs of the missions the guild completed
+// in a rotation.
+func (r *GuildRepository) ListCompletedMissions(guildID, rotation uint32) ([]uint32, error) {
+	var ids []uint32
+	err := r.db.Select(&ids,
+		`SELECT mission_id FROM guild_mission_completions WHERE guild_id=$1 AND rotation=$2 ORDER BY completed_at`,
+		guildID, rotation)
+	return ids, err
+}
+
+// CompleteMission records a completed mission, clears it as the guild's
+// target and pays rewards into the guild item box, atomically. The item box
+// row is locked with SELECT FOR UPDATE so concurrent deposits cannot be lost.
+// It reports false if the mission was already completed in this rotation, so
+// rewards are paid out only once.
+func (r *GuildRepository) CompleteMission(guildID, missionID, rotation uint32, rewards []mhfitem.MHFItemStack) (bool, error) {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(`
+		INSERT INTO guild_mission_completions (guild_id, mission_id, rotation) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+		guildID, missionID, rotation)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM guild_mission_targets WHERE guild_id=$1 AND mission_id=$2`, guildID, missionID); err != nil {
+		return false, err
+	}
+	if len(rewards) > 0 {
+		var data []byte
+		if err := tx.QueryRow(`SELECT item_box FROM guilds WHERE id=$1 FOR UPDATE`, guildID).Scan(&data); err != nil {
+			return false, err
+		}
+		items := addItemStacks(readItemBox(data), rewards)
+		if _, err := tx.Exec(`UPDATE guilds SET item_box=$1 WHERE id=$2`,
+			mhfitem.SerializeWarehouseItems(items), guildID); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
@@ -91,12 +91,3 @@ func (r *GuildRepository) RolloverDailyRP(guildID uint32, noon time.Time) error
 	}
 	return tx.Commit()
 }
-
-// AddWeeklyBonusUsers atomically adds numUsers to the guild's weekly bonus exceptional user count.
-func (r *GuildRepository) AddWeeklyBonusUsers(guildID uint32, numUsers uint8) error {
-	_, err := r.db.Exec(
-		"UPDATE guilds SET weekly_bonus_users = weekly_bonus_users + $1 WHERE id = $2",
-		numUsers, guildID,
-	)
-	return err
-}
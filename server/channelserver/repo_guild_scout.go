@@ -0,0 +1,48 @@
+package channelserver
+
+import "time"
+
+// ExpiredScout is a guild scout invitation withdrawn because it went
+// unanswered for too long.
+type ExpiredScout struct {
+	GuildID   uint32 `db:"guild_id"`
+	GuildName string `db:"guild_name"`
+	CharID    uint32 `db:"character_id"`
+	CharName  string `db:"character_name"`
+	ActorID   uint32 `db:"actor_id"`
+}
+
+// CountInvitations returns how many scout invitations the guild has
+// outstanding.
+func (r *GuildRepository) CountInvitations(guildID uint32) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM guild_applications WHERE guild_id = $1 AND application_type = 'invited'`,
+		guildID).Scan(&count)
+	return count, err
+}
+
+// CountCharacterInvitations returns how many scout invitations the
+// character holds from all guilds.
+func (r *GuildRepository) CountCharacterInvitations(charID uint32) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM guild_applications WHERE character_id = $1 AND application_type = 'invited'`,
+		charID).Scan(&count)
+	return count, err
+}
+
+// ExpireInvitations deletes the scout invitations sent before the given time
+// and returns them. Each invitation is returned to exactly one caller, so
+// channels sweeping concurrently never report the same one twice.
+func (r *GuildRepository) ExpireInvitations(before time.Time) ([]*ExpiredScout, error) {
+	var scouts []*ExpiredScout
+	err := r.db.Select(&scouts, `
+		DELETE FROM guild_applications ga
+		WHERE ga.application_type = 'invited' AND ga.created_at < $1
+		RETURNING ga.guild_id, ga.character_id, ga.actor_id,
+			COALESCE((SELECT g.name FROM guilds g WHERE g.id = ga.guild_id), '') AS guild_name,
+			COALESCE((SELECT c.name FROM characters c WHERE c.id = ga.character_id), '') AS character_name`,
+		before)
+	return scouts, err
+}
@@ -1,10 +1,13 @@
 package channelserver
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
+	"erupe-ce/common/mhfitem"
+
 	"github.com/jmoiron/sqlx"
 )
 
@@ -359,6 +362,29 @@ func TestSetRecruiting(t *testing.T) {
 	}
 }
 
+func TestSetForcedRank(t *testing.T) {
+	repo, _, guildID, _ := setupGuildRepo(t)
+
+	rank := uint16(7)
+	if err := repo.SetForcedRank(guildID, &rank); err != nil {
+		t.Fatalf("SetForcedRank failed: %v", err)
+	}
+	guild, err := repo.GetByID(guildID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if guild.ForcedRank == nil || *guild.ForcedRank != 7 {
+		t.Errorf("ForcedRank = %v, want 7", guild.ForcedRank)
+	}
+
+	if err := repo.SetForcedRank(guildID, nil); err != nil {
+		t.Fatalf("SetForcedRank(nil) failed: %v", err)
+	}
+	if guild, _ = repo.GetByID(guildID); guild.ForcedRank != nil {
+		t.Errorf("ForcedRank = %d, want cleared", *guild.ForcedRank)
+	}
+}
+
 func TestRPOperations(t *testing.T) {
 	repo, db, guildID, _ := setupGuildRepo(t)
 
@@ -556,6 +582,43 @@ func TestCancelInvitation(t *testing.T) {
 	}
 }
 
+func TestExpireInvitations(t *testing.T) {
+	repo, db, guildID, leaderID := setupGuildRepo(t)
+
+	user2 := CreateTestUser(t, db, "expire_user")
+	oldChar := CreateTestCharacter(t, db, user2, "Stale")
+	newChar := CreateTestCharacter(t, db, user2, "Fresh")
+	for _, charID := range []uint32{oldChar, newChar} {
+		if err := repo.CreateApplication(guildID, charID, leaderID, GuildApplicationTypeInvited); err != nil {
+			t.Fatalf("CreateApplication failed: %v", err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE guild_applications SET created_at = now() - interval '4 days' WHERE character_id = $1`, oldChar); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	if n, err := repo.CountInvitations(guildID); err != nil || n != 2 {
+		t.Errorf("CountInvitations = %d, %v; want 2", n, err)
+	}
+	if n, err := repo.CountCharacterInvitations(oldChar); err != nil || n != 1 {
+		t.Errorf("CountCharacterInvitations = %d, %v; want 1", n, err)
+	}
+
+	scouts, err := repo.ExpireInvitations(time.Now().Add(-72 * time.Hour))
+	if err != nil {
+		t.Fatalf("ExpireInvitations failed: %v", err)
+	}
+	if len(scouts) != 1 {
+		t.Fatalf("Expected 1 expired invitation, got %d", len(scouts))
+	}
+	if sc := scouts[0]; sc.CharID != oldChar || sc.CharName != "Stale" || sc.GuildName != "TestGuild" || sc.ActorID != leaderID {
+		t.Errorf("Unexpected expired invitation: %+v", sc)
+	}
+	if n, _ := repo.CountInvitations(guildID); n != 1 {
+		t.Errorf("Expected 1 invitation left, got %d", n)
+	}
+}
+
 func TestListInvitedCharacters(t *testing.T) {
 	repo, db, guildID, leaderID := setupGuildRepo(t)
 
@@ -1300,6 +1363,83 @@ func TestCollectHunt(t *testing.T) {
 	}
 }
 
+func TestCollectHuntGivesSouvenirs(t *testing.T) {
+	repo, _, guildID, charID := setupGuildRepo(t)
+
+	if err := repo.CreateHunt(guildID, charID, 10, 2, nil, ""); err != nil {
+		t.Fatalf("CreateHunt failed: %v", err)
+	}
+	hunt, _ := repo.GetPendingHunt(charID)
+	if err := repo.RegisterHuntReport(hunt.HuntID, charID); err != nil {
+		t.Fatalf("RegisterHuntReport failed: %v", err)
+	}
+	if err := repo.CollectHunt(hunt.HuntID); err != nil {
+		t.Fatalf("CollectHunt failed: %v", err)
+	}
+	// Collecting again must not pay out twice.
+	if err := repo.CollectHunt(hunt.HuntID); err != nil {
+		t.Fatalf("second CollectHunt failed: %v", err)
+	}
+
+	souvenirs, err := repo.ListSouvenirs(charID)
+	if err != nil {
+		t.Fatalf("ListSouvenirs failed: %v", err)
+	}
+	if len(souvenirs) != 1 || souvenirs[0] != (TreasureSouvenir{Destination: 10, Quantity: 1}) {
+		t.Errorf("Expected one souvenir for destination 10, got %+v", souvenirs)
+	}
+
+	if err := repo.ClaimSouvenirs(charID); err != nil {
+		t.Fatalf("ClaimSouvenirs failed: %v", err)
+	}
+	if souvenirs, _ = repo.ListSouvenirs(charID); len(souvenirs) != 0 {
+		t.Errorf("Expected no souvenirs after claiming, got %+v", souvenirs)
+	}
+}
+
+func TestEndExpiredHunts(t *testing.T) {
+	repo, db, guildID, charID := setupGuildRepo(t)
+
+	if err := repo.CreateHunt(guildID, charID, 10, 2, nil, ""); err != nil {
+		t.Fatalf("CreateHunt failed: %v", err)
+	}
+	hunt, _ := repo.GetPendingHunt(charID)
+	if err := repo.AcquireHunt(hunt.HuntID); err != nil {
+		t.Fatalf("AcquireHunt failed: %v", err)
+	}
+	if err := repo.RegisterHuntReport(hunt.HuntID, charID); err != nil {
+		t.Fatalf("RegisterHuntReport failed: %v", err)
+	}
+
+	if n, err := repo.EndExpiredHunts(time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("EndExpiredHunts on a fresh hunt = %d, %v; want 0", n, err)
+	}
+	if _, err := db.Exec("UPDATE guild_hunts SET start = now() - interval '2 hours' WHERE id=$1", hunt.HuntID); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if n, err := repo.EndExpiredHunts(time.Now().Add(-time.Hour)); err != nil || n != 1 {
+		t.Fatalf("EndExpiredHunts = %d, %v; want 1", n, err)
+	}
+
+	var treasureHunt *uint32
+	if err := db.QueryRow("SELECT treasure_hunt FROM guild_characters WHERE character_id=$1", charID).Scan(&treasureHunt); err != nil {
+		t.Fatalf("Failed to get treasure_hunt: %v", err)
+	}
+	if treasureHunt != nil {
+		t.Errorf("Expected treasure_hunt=NULL, got %v", *treasureHunt)
+	}
+	if souvenirs, _ := repo.ListSouvenirs(charID); len(souvenirs) != 1 {
+		t.Errorf("Expected the offline reporter to get a souvenir, got %+v", souvenirs)
+	}
+
+	if n, err := repo.PurgeHunts(time.Now().Add(-time.Hour)); err != nil || n != 1 {
+		t.Fatalf("PurgeHunts = %d, %v; want 1", n, err)
+	}
+	if souvenirs, _ := repo.ListSouvenirs(charID); len(souvenirs) != 1 {
+		t.Error("Purging hunts should keep uncollected souvenirs")
+	}
+}
+
 func TestClaimHuntReward(t *testing.T) {
 	repo, db, guildID, charID := setupGuildRepo(t)
 
@@ -1418,7 +1558,7 @@ func TestListAndCountGuildKills(t *testing.T) {
 		t.Fatalf("Failed to insert kill log: %v", err)
 	}
 
-	kills, err := repo.ListGuildKills(guildID, charID)
+	kills, err := repo.ListGuildKills(guildID, charID, time.Time{})
 	if err != nil {
 		t.Fatalf("ListGuildKills failed: %v", err)
 	}
@@ -1426,7 +1566,7 @@ func TestListAndCountGuildKills(t *testing.T) {
 		t.Fatalf("Expected 2 kills, got %d", len(kills))
 	}
 
-	count, err := repo.CountGuildKills(guildID, charID)
+	count, err := repo.CountGuildKills(guildID, charID, time.Time{})
 	if err != nil {
 		t.Fatalf("CountGuildKills failed: %v", err)
 	}
@@ -1443,7 +1583,7 @@ func TestListGuildKillsEmpty(t *testing.T) {
 		t.Fatalf("ClaimHuntBox failed: %v", err)
 	}
 
-	kills, err := repo.ListGuildKills(guildID, charID)
+	kills, err := repo.ListGuildKills(guildID, charID, time.Time{})
 	if err != nil {
 		t.Fatalf("ListGuildKills failed: %v", err)
 	}
@@ -1451,7 +1591,7 @@ func TestListGuildKillsEmpty(t *testing.T) {
 		t.Errorf("Expected 0 kills, got %d", len(kills))
 	}
 
-	count, err := repo.CountGuildKills(guildID, charID)
+	count, err := repo.CountGuildKills(guildID, charID, time.Time{})
 	if err != nil {
 		t.Fatalf("CountGuildKills failed: %v", err)
 	}
@@ -1521,3 +1661,312 @@ func TestCreateApplicationWithMail(t *testing.T) {
 		t.Errorf("Expected 1 mail row, got %d", mailCount)
 	}
 }
+
+func TestApply(t *testing.T) {
+	repo, db, guildID, leaderID := setupGuildRepo(t)
+	user2 := CreateTestUser(t, db, "apply_user")
+	char2 := CreateTestCharacter(t, db, user2, "Applicant")
+	if _, err := db.Exec("UPDATE guilds SET recruiting = true WHERE id = $1", guildID); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	if err := repo.Apply(guildID, char2, 1); !errors.Is(err, ErrGuildFull) {
+		t.Errorf("Apply to a full guild = %v, want ErrGuildFull", err)
+	}
+	if err := repo.Apply(guildID, leaderID, 50); !errors.Is(err, ErrAlreadyInGuild) {
+		t.Errorf("Apply by a member = %v, want ErrAlreadyInGuild", err)
+	}
+	if err := repo.Apply(guildID, char2, 50); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if has, err := repo.HasApplication(guildID, char2); err != nil || !has {
+		t.Errorf("HasApplication = %v, %v; want true", has, err)
+	}
+	if err := repo.Apply(guildID, char2, 50); !errors.Is(err, ErrAlreadyApplied) {
+		t.Errorf("second Apply = %v, want ErrAlreadyApplied", err)
+	}
+
+	if _, err := db.Exec("UPDATE guilds SET recruiting = false WHERE id = $1", guildID); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	user3 := CreateTestUser(t, db, "apply_closed_user")
+	char3 := CreateTestCharacter(t, db, user3, "LateApplicant")
+	if err := repo.Apply(guildID, char3, 50); !errors.Is(err, ErrGuildNotRecruiting) {
+		t.Errorf("Apply to a closed guild = %v, want ErrGuildNotRecruiting", err)
+	}
+}
+
+func TestLogEventTargets(t *testing.T) {
+	repo, db, guildID, leaderID := setupGuildRepo(t)
+
+	if err := repo.LogEvent(GuildEvent{GuildID: guildID, ActorID: leaderID, TargetCharID: 42, Action: GuildEventKick}); err != nil {
+		t.Fatalf("LogEvent failed: %v", err)
+	}
+	if err := repo.LogEvent(GuildEvent{GuildID: guildID, ActorID: leaderID, TargetGuildID: 7, Action: GuildEventAllianceJoin, Detail: "Allies"}); err != nil {
+		t.Fatalf("LogEvent failed: %v", err)
+	}
+
+	var targets []struct {
+		Action  string `db:"action"`
+		CharID  uint32 `db:"target_char_id"`
+		GuildID uint32 `db:"target_guild_id"`
+	}
+	if err := db.Select(&targets,
+		"SELECT action, target_char_id, target_guild_id FROM guild_audit_log WHERE guild_id = $1 ORDER BY id",
+		guildID); err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if len(targets) != 2 ||
+		targets[0].CharID != 42 || targets[0].GuildID != 0 ||
+		targets[1].CharID != 0 || targets[1].GuildID != 7 {
+		t.Errorf("targets = %+v, want the kick on char 42 and the alliance join on guild 7", targets)
+	}
+}
+
+// --- Guild missions ---
+
+func TestGuildMissionTargetAndCounts(t *testing.T) {
+	repo, db, guildID, leaderID := setupGuildRepo(t)
+	user2 := CreateTestUser(t, db, "mission_user")
+	char2 := CreateTestCharacter(t, db, user2, "MissionMember")
+
+	target, err := repo.GetMissionTarget(guildID)
+	if err != nil || target != nil {
+		t.Fatalf("GetMissionTarget before set = %+v, %v; want nil, nil", target, err)
+	}
+	if err := repo.SetMissionTarget(guildID, 431201, 5, leaderID); err != nil {
+		t.Fatalf("SetMissionTarget failed: %v", err)
+	}
+	if err := repo.SetMissionTarget(guildID, 431202, 5, leaderID); err != nil {
+		t.Fatalf("SetMissionTarget (replace) failed: %v", err)
+	}
+	target, err = repo.GetMissionTarget(guildID)
+	if err != nil || target == nil || target.MissionID != 431202 || target.Rotation != 5 {
+		t.Fatalf("GetMissionTarget = %+v, %v; want mission 431202 in rotation 5", target, err)
+	}
+
+	if _, err := repo.AddMissionCount(guildID, 431202, 5, leaderID, 3, 12); err != nil {
+		t.Fatalf("AddMissionCount failed: %v", err)
+	}
+	if _, err := repo.AddMissionCount(guildID, 431202, 5, char2, 2, 12); err != nil {
+		t.Fatalf("AddMissionCount failed: %v", err)
+	}
+	total, err := repo.AddMissionCount(guildID, 431202, 5, leaderID, 4, 12)
+	if err != nil {
+		t.Fatalf("AddMissionCount failed: %v", err)
+	}
+	if total != 9 {
+		t.Errorf("total = %d, want 9", total)
+	}
+	if total, _ := repo.AddMissionCount(guildID, 431202, 5, char2, 100, 12); total != 12 {
+		t.Errorf("total after an oversized count = %d, want clamped to 12", total)
+	}
+	if total, _ := repo.AddMissionCount(guildID, 431202, 5, leaderID, 1, 12); total != 12 {
+		t.Errorf("total after completion = %d, want 12", total)
+	}
+
+	if err := repo.ClearMissionTarget(guildID); err != nil {
+		t.Fatalf("ClearMissionTarget failed: %v", err)
+	}
+	if target, _ := repo.GetMissionTarget(guildID); target != nil {
+		t.Error("target should be cleared")
+	}
+}
+
+func TestCompleteGuildMissionOnce(t *testing.T) {
+	repo, _, guildID, leaderID := setupGuildRepo(t)
+	if err := repo.SetMissionTarget(guildID, 431203, 2, leaderID); err != nil {
+		t.Fatalf("SetMissionTarget failed: %v", err)
+	}
+
+	rewards := []mhfitem.MHFItemStack{{Item: mhfitem.MHFItem{ItemID: 7}, Quantity: 2}}
+	completed, err := repo.CompleteMission(guildID, 431203, 2, rewards)
+	if err != nil || !completed {
+		t.Fatalf("CompleteMission = %v, %v; want true, nil", completed, err)
+	}
+	completed, err = repo.CompleteMission(guildID, 431203, 2, rewards)
+	if err != nil || completed {
+		t.Errorf("second CompleteMission = %v, %v; want false, nil", completed, err)
+	}
+	data, err := repo.GetItemBox(guildID)
+	if err != nil {
+		t.Fatalf("GetItemBox failed: %v", err)
+	}
+	if items := readItemBox(data); len(items) != 1 || items[0].Quantity != 2 {
+		t.Errorf("item box = %+v, want the rewards paid once", items)
+	}
+	if target, _ := repo.GetMissionTarget(guildID); target != nil {
+		t.Error("completing the target should clear it")
+	}
+	ids, err := repo.ListCompletedMissions(guildID, 2)
+	if err != nil || len(ids) != 1 || ids[0] != 431203 {
+		t.Errorf("ListCompletedMissions = %v, %v; want [431203]", ids, err)
+	}
+	if ids, _ := repo.ListCompletedMissions(guildID, 3); len(ids) != 0 {
+		t.Errorf("next rotation has completions %v", ids)
+	}
+}
+
+// --- Alliance applications ---
+
+func TestAllianceApplicationsAndSizeCap(t *testing.T) {
+	repo, db, parentID, _ := setupGuildRepo(t)
+	if err := repo.CreateAlliance("Allies", parentID); err != nil {
+		t.Fatalf("CreateAlliance failed: %v", err)
+	}
+	var allianceID uint32
+	if err := db.QueryRow("SELECT id FROM guild_alliances WHERE parent_id=$1", parentID).Scan(&allianceID); err != nil {
+		t.Fatalf("Alliance not found in DB: %v", err)
+	}
+
+	var applicants []uint32
+	for _, name := range []string{"ApplicantA", "ApplicantB", "ApplicantC"} {
+		user := CreateTestUser(t, db, name+"_user")
+		leader := CreateTestCharacter(t, db, user, name)
+		guildID := CreateTestGuild(t, db, leader, name)
+		if err := repo.CreateAllianceApplication(allianceID, guildID, leader); err != nil {
+			t.Fatalf("CreateAllianceApplication failed: %v", err)
+		}
+		applicants = append(applicants, guildID)
+	}
+	apps, err := repo.ListAllianceApplications(allianceID)
+	if err != nil || len(apps) != 3 {
+		t.Fatalf("ListAllianceApplications = %d, %v; want 3", len(apps), err)
+	}
+
+	if err := repo.AcceptAllianceApplication(allianceID, applicants[0]); err != nil {
+		t.Fatalf("first AcceptAllianceApplication failed: %v", err)
+	}
+	if err := repo.AcceptAllianceApplication(allianceID, applicants[1]); err != nil {
+		t.Fatalf("second AcceptAllianceApplication failed: %v", err)
+	}
+	if err := repo.AcceptAllianceApplication(allianceID, applicants[2]); !errors.Is(err, ErrAllianceFull) {
+		t.Errorf("third AcceptAllianceApplication err = %v, want ErrAllianceFull", err)
+	}
+
+	alliance, err := repo.GetAllianceByID(allianceID)
+	if err != nil {
+		t.Fatalf("GetAllianceByID failed: %v", err)
+	}
+	if alliance.SubGuild1ID != applicants[0] || alliance.SubGuild2ID != applicants[1] {
+		t.Errorf("subs = %d, %d; want %d, %d", alliance.SubGuild1ID, alliance.SubGuild2ID, applicants[0], applicants[1])
+	}
+	if app, _ := repo.GetAllianceApplication(allianceID, applicants[2]); app == nil {
+		t.Error("rejected-for-size application should remain pending")
+	}
+
+	if err := repo.SetAllianceRecruiting(allianceID, false); err != nil {
+		t.Fatalf("SetAllianceRecruiting failed: %v", err)
+	}
+	if alliance, _ := repo.GetAllianceByID(allianceID); alliance.Recruiting {
+		t.Error("alliance should not be recruiting")
+	}
+
+	if err := repo.DeleteAlliance(allianceID); err != nil {
+		t.Fatalf("DeleteAlliance failed: %v", err)
+	}
+	if apps, _ := repo.ListAllianceApplications(allianceID); len(apps) != 0 {
+		t.Errorf("disbanding left %d applications", len(apps))
+	}
+}
+
+// --- Guild item box history ---
+
+func TestUpdateItemBoxRecordsWithdrawals(t *testing.T) {
+	repo, _, guildID, charID := setupGuildRepo(t)
+	today := time.Now().Add(-time.Minute)
+	stack := func(quantity uint16) []mhfitem.MHFItemStack {
+		return []mhfitem.MHFItemStack{{WarehouseID: 1, Item: mhfitem.MHFItem{ItemID: 7}, Quantity: quantity}}
+	}
+	if err := repo.SaveItemBox(guildID, mhfitem.SerializeWarehouseItems(stack(10))); err != nil {
+		t.Fatalf("SaveItemBox failed: %v", err)
+	}
+
+	changes, err := repo.UpdateItemBox(guildID, charID, stack(8), 5, today)
+	if err != nil {
+		t.Fatalf("UpdateItemBox failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0] != (GuildItemChange{ItemID: 7, Quantity: -2}) {
+		t.Errorf("changes = %+v, want a withdrawal of 2", changes)
+	}
+	if _, err := repo.UpdateItemBox(guildID, charID, stack(4), 5, today); !errors.Is(err, ErrWithdrawalLimit) {
+		t.Errorf("UpdateItemBox over the limit = %v, want ErrWithdrawalLimit", err)
+	}
+	if _, err := repo.UpdateItemBox(guildID, charID, stack(4), 5, time.Now().Add(time.Minute)); err != nil {
+		t.Errorf("UpdateItemBox with no withdrawals since = %v, want success", err)
+	}
+
+	data, err := repo.GetItemBox(guildID)
+	if items := readItemBox(data); err != nil || len(items) != 1 || items[0].Quantity != 4 {
+		t.Errorf("GetItemBox = %+v, %v; want 4 of item 7", items, err)
+	}
+}
+
+// --- Guild weekly bonus ---
+
+func TestGuildWeeklyActivityAndBonusUsers(t *testing.T) {
+	repo, _, guildID, charID := setupGuildRepo(t)
+	week := time.Now().Truncate(time.Hour).Add(-time.Hour)
+	lastWeek := week.Add(-7 * 24 * time.Hour)
+
+	if err := repo.RecordWeeklyActivity(charID, lastWeek, 5, 5); err != nil {
+		t.Fatalf("RecordWeeklyActivity failed: %v", err)
+	}
+	if active, err := repo.CountActiveMembers(guildID, week, 1, 0); err != nil || active != 0 {
+		t.Errorf("CountActiveMembers = %d, %v; last week's activity should not count", active, err)
+	}
+
+	if err := repo.RecordWeeklyActivity(charID, week, 1, 2); err != nil {
+		t.Fatalf("RecordWeeklyActivity failed: %v", err)
+	}
+	if err := repo.RecordWeeklyActivity(charID, week, 1, 1); err != nil {
+		t.Fatalf("RecordWeeklyActivity failed: %v", err)
+	}
+	if active, _ := repo.CountActiveMembers(guildID, week, 2, 0); active != 1 {
+		t.Errorf("CountActiveMembers(minQuests=2) = %d, want 1", active)
+	}
+	if active, _ := repo.CountActiveMembers(guildID, week, 3, 0); active != 0 {
+		t.Errorf("CountActiveMembers(minQuests=3) = %d, want 0", active)
+	}
+	if active, _ := repo.CountActiveMembers(guildID, week, 3, 3); active != 1 {
+		t.Errorf("CountActiveMembers(minKills=3) = %d, want 1", active)
+	}
+
+	if users, err := repo.GetWeeklyBonusUsers(guildID, week); err != nil || users != 0 {
+		t.Errorf("GetWeeklyBonusUsers = %d, %v; want 0", users, err)
+	}
+	if err := repo.AddWeeklyBonusUsers(guildID, week, 3); err != nil {
+		t.Fatalf("AddWeeklyBonusUsers failed: %v", err)
+	}
+	if err := repo.AddWeeklyBonusUsers(guildID, week, 2); err != nil {
+		t.Fatalf("AddWeeklyBonusUsers failed: %v", err)
+	}
+	if users, _ := repo.GetWeeklyBonusUsers(guildID, week); users != 5 {
+		t.Errorf("GetWeeklyBonusUsers = %d, want 5", users)
+	}
+	if users, _ := repo.GetWeeklyBonusUsers(guildID, lastWeek); users != 0 {
+		t.Errorf("GetWeeklyBonusUsers(last week) = %d, want 0", users)
+	}
+}
+
+func TestGuildKillsResetWeekly(t *testing.T) {
+	repo, db, guildID, charID := setupGuildRepo(t)
+	if err := repo.ClaimHuntBox(charID, time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatalf("ClaimHuntBox failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO kill_logs (character_id, monster, quantity, timestamp) VALUES ($1, 100, 1, NOW() - interval '24 hours')", charID); err != nil {
+		t.Fatalf("Failed to insert kill log: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO kill_logs (character_id, monster, quantity, timestamp) VALUES ($1, 200, 1, NOW())", charID); err != nil {
+		t.Fatalf("Failed to insert kill log: %v", err)
+	}
+
+	week := time.Now().Add(-time.Hour)
+	kills, err := repo.ListGuildKills(guildID, charID, week)
+	if err != nil || len(kills) != 1 || kills[0].Monster != 200 {
+		t.Errorf("ListGuildKills = %+v, %v; want only this week's kill", kills, err)
+	}
+	if count, _ := repo.CountGuildKills(guildID, charID, week); count != 1 {
+		t.Errorf("CountGuildKills = %d, want 1", count)
+	}
+}
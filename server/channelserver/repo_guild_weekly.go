@@ -0,0 +1,51 @@
+package channelserver
+
+import "time"
+
+// RecordWeeklyActivity adds quests and kills to a character's activity in
+// their guild for the week starting at week. Characters outside a guild,
+// including applicants, are ignored.
+func (r *GuildRepository) RecordWeeklyActivity(charID uint32, week time.Time, quests, kills int) error {
+	_, err := r.db.Exec(`
+		INSERT INTO guild_weekly_activity (guild_id, week_start, character_id, quests, kills)
+		SELECT guild_id, $2, character_id, $3, $4 FROM guild_characters WHERE character_id=$1
+		ON CONFLICT (guild_id, week_start, character_id) DO UPDATE
+		SET quests=guild_weekly_activity.quests+EXCLUDED.quests, kills=guild_weekly_activity.kills+EXCLUDED.kills`,
+		charID, week, quests, kills)
+	return err
+}
+
+// CountActiveMembers returns how many current members of the guild played
+// at least minQuests quests, or hunted at least minKills large monsters when
+// minKills is above zero, in the week starting at week.
+func (r *GuildRepository) CountActiveMembers(guildID uint32, week time.Time, minQuests, minKills int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM guild_weekly_activity a
+		INNER JOIN guild_characters gc ON gc.character_id = a.character_id AND gc.guild_id = a.guild_id
+		WHERE a.guild_id=$1 AND a.week_start=$2
+		AND (a.quests >= $3 OR ($4 > 0 AND a.kills >= $4))`,
+		guildID, week, minQuests, minKills).Scan(&count)
+	return count, err
+}
+
+// AddWeeklyBonusUsers atomically adds numUsers to the guild's exceptional
+// users for the week starting at week.
+func (r *GuildRepository) AddWeeklyBonusUsers(guildID uint32, week time.Time, numUsers uint8) error {
+	_, err := r.db.Exec(`
+		INSERT INTO guild_weekly_bonus (guild_id, week_start, exceptional_users) VALUES ($1, $2, $3)
+		ON CONFLICT (guild_id, week_start) DO UPDATE
+		SET exceptional_users=guild_weekly_bonus.exceptional_users+EXCLUDED.exceptional_users`,
+		guildID, week, numUsers)
+	return err
+}
+
+// GetWeeklyBonusUsers returns the guild's exceptional users for the week
+// starting at week.
+func (r *GuildRepository) GetWeeklyBonusUsers(guildID uint32, week time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COALESCE(SUM(exceptional_users), 0) FROM guild_weekly_bonus WHERE guild_id=$1 AND week_start=$2`,
+		guildID, week).Scan(&count)
+	return count, err
+}
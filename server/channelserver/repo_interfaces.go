@@ -2,6 +2,8 @@ package channelserver
 
 import (
 	"time"
+
+	"erupe-ce/common/mhfitem"
 )
 
 // Repository interfaces decouple handlers from concrete PostgreSQL implementations,
@@ -36,6 +38,7 @@ type CharacterRepo interface {
 	SaveMercenary(charID uint32, data []byte, rastaID uint32) error
 	UpdateGCPAndPact(charID uint32, gcp uint32, pactID uint32) error
 	FindByRastaID(rastaID int) (charID uint32, name string, err error)
+	FindByName(name string) (*CharacterSummary, error)
 	SaveCharacterData(charID uint32, compSave []byte, hr, gr uint16, isFemale bool, weaponType uint8, weaponID uint16) error
 	SaveHouseData(charID uint32, houseTier []byte, houseData, bookshelf, gallery, tore, garden []byte) error
 	LoadSaveData(charID uint32) (uint32, []byte, bool, string, error)
@@ -52,8 +55,12 @@ type GuildRepo interface {
 	RemoveCharacter(charID uint32) error
 	AcceptApplication(guildID, charID uint32) error
 	CreateApplication(guildID, charID, actorID uint32, appType GuildApplicationType) error
+	Apply(guildID, charID uint32, memberLimit uint8) error
 	CreateApplicationWithMail(guildID, charID, actorID uint32, appType GuildApplicationType, mailSenderID, mailRecipientID uint32, mailSubject, mailBody string) error
 	CancelInvitation(guildID, charID uint32) error
+	CountInvitations(guildID uint32) (int, error)
+	CountCharacterInvitations(charID uint32) (int, error)
+	ExpireInvitations(before time.Time) ([]*ExpiredScout, error)
 	RejectApplication(guildID, charID uint32) error
 	ArrangeCharacters(charIDs []uint32) error
 	GetApplication(guildID, charID uint32, appType GuildApplicationType) (*GuildApplication, error)
@@ -64,6 +71,7 @@ type GuildRepo interface {
 	GetCharacterMembership(charID uint32) (*GuildMember, error)
 	SaveMember(member *GuildMember) error
 	SetRecruiting(guildID uint32, recruiting bool) error
+	SetForcedRank(guildID uint32, rank *uint16) error
 	SetPugiOutfits(guildID uint32, outfits uint32) error
 	SetRecruiter(charID uint32, allowed bool) error
 	AddMemberDailyRP(charID uint32, amount uint16) error
@@ -87,6 +95,12 @@ type GuildRepo interface {
 	CreateAlliance(name string, parentGuildID uint32) error
 	DeleteAlliance(allianceID uint32) error
 	RemoveGuildFromAlliance(allianceID, guildID, subGuild1ID, subGuild2ID uint32) error
+	SetAllianceRecruiting(allianceID uint32, recruiting bool) error
+	CreateAllianceApplication(allianceID, guildID, charID uint32) error
+	GetAllianceApplication(allianceID, guildID uint32) (*AllianceApplication, error)
+	ListAllianceApplications(allianceID uint32) ([]*AllianceApplication, error)
+	DeleteAllianceApplication(allianceID, guildID uint32) error
+	AcceptAllianceApplication(allianceID, guildID uint32) error
 	ListAdventures(guildID uint32) ([]*GuildAdventure, error)
 	CreateAdventure(guildID, destination uint32, depart, returnTime int64) error
 	CreateAdventureWithCharge(guildID, destination, charge uint32, depart, returnTime int64) error
@@ -98,18 +112,33 @@ type GuildRepo interface {
 	AcquireHunt(huntID uint32) error
 	RegisterHuntReport(huntID, charID uint32) error
 	CollectHunt(huntID uint32) error
+	EndExpiredHunts(before time.Time) (int, error)
+	PurgeHunts(before time.Time) (int, error)
+	ListSouvenirs(charID uint32) ([]TreasureSouvenir, error)
+	ClaimSouvenirs(charID uint32) error
 	ClaimHuntReward(huntID, charID uint32) error
 	ListMeals(guildID uint32) ([]*GuildMeal, error)
 	CreateMeal(guildID, mealID, level uint32, createdAt time.Time) (uint32, error)
 	UpdateMeal(mealID, newMealID, level uint32, createdAt time.Time) error
 	ClaimHuntBox(charID uint32, claimedAt time.Time) error
-	ListGuildKills(guildID, charID uint32) ([]*GuildKill, error)
-	CountGuildKills(guildID, charID uint32) (int, error)
-	ClearTreasureHunt(charID uint32) error
+	ListGuildKills(guildID, charID uint32, since time.Time) ([]*GuildKill, error)
+	CountGuildKills(guildID, charID uint32, since time.Time) (int, error)
 	InsertKillLog(charID uint32, monster int, quantity uint8, timestamp time.Time) error
 	ListInvitedCharacters(guildID uint32) ([]*ScoutedCharacter, error)
 	RolloverDailyRP(guildID uint32, noon time.Time) error
-	AddWeeklyBonusUsers(guildID uint32, numUsers uint8) error
+	AddWeeklyBonusUsers(guildID uint32, week time.Time, numUsers uint8) error
+	GetWeeklyBonusUsers(guildID uint32, week time.Time) (int, error)
+	RecordWeeklyActivity(charID uint32, week time.Time, quests, kills int) error
+	CountActiveMembers(guildID uint32, week time.Time, minQuests, minKills int) (int, error)
+	GetMissionTarget(guildID uint32) (*GuildMissionTarget, error)
+	SetMissionTarget(guildID, missionID, rotation, charID uint32) error
+	ClearMissionTarget(guildID uint32) error
+	AddMissionCount(guildID, missionID, rotation, charID, count, quantity uint32) (uint32, error)
+	ListCompletedMissions(guildID, rotation uint32) ([]uint32, error)
+	CompleteMission(guildID, missionID, rotation uint32, rewards []mhfitem.MHFItemStack) (bool, error)
+	LogEvent(e GuildEvent) error
+	UpdateItemBox(guildID, charID uint32, updates []mhfitem.MHFItemStack, limit int, since time.Time) ([]GuildItemChange, error)
+	PruneEvents(before time.Time) (int64, error)
 }
 
 // UserRepo defines the contract for user account data access.
@@ -139,6 +168,7 @@ type UserRepo interface {
 	SetItemBox(userID uint32, data []byte) error
 	LinkDiscord(discordID string, token string) (string, error)
 	SetPasswordByDiscordID(discordID string, hash []byte) error
+	GetByDiscordID(discordID string) (uint32, error)
 	GetByIDAndUsername(charID uint32) (userID uint32, username string, err error)
 	BanUser(userID uint32, expires *time.Time) error
 }
@@ -227,6 +257,7 @@ type RengokuRepo interface {
 // MailRepo defines the contract for in-game mail data access.
 type MailRepo interface {
 	SendMail(senderID, recipientID uint32, subject, body string, itemID, itemAmount uint16, isGuildInvite, isSystemMessage bool) error
+	SendSystemToGuild(guildID uint32, fallback MailText, localized map[string]MailText) error
 	GetListForCharacter(charID uint32) ([]Mail, error)
 	GetByID(id int) (*Mail, error)
 	MarkRead(id int) error
@@ -325,6 +356,27 @@ type ScenarioRepo interface {
 	GetCounters() ([]Scenario, error)
 }
 
+// DiscordRepo defines the contract for Discord relay data access.
+type DiscordRepo interface {
+	IsMuted(discordID string) (bool, error)
+	Mute(discordID, mutedBy string) error
+	Unmute(discordID string) error
+}
+
+// AnnouncementRepo defines the contract for scheduled announcement data access.
+type AnnouncementRepo interface {
+	List() ([]Announcement, error)
+	ListEnabled() ([]Announcement, error)
+	Create(a *Announcement) (uint32, error)
+	Delete(id uint32) error
+	ClaimSend(id uint32, occurrence time.Time) (bool, error)
+}
+
+// ChatRepo defines the contract for chat log data access.
+type ChatRepo interface {
+	Log(e ChatLogEntry) error
+}
+
 // MercenaryRepo defines the contract for mercenary/rasta data access.
 type MercenaryRepo interface {
 	NextRastaID() (uint32, error)
@@ -2,6 +2,7 @@ package channelserver
 
 import (
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // MailRepository centralizes all database access for the mail table.
@@ -25,6 +26,33 @@ func (r *MailRepository) SendMail(senderID, recipientID uint32, subject, body st
 	return err
 }
 
+// MailText is the subject and body of a mail in one language.
+type MailText struct {
+	Subject string
+	Body    string
+}
+
+// SendSystemToGuild inserts a system mail for every member of a guild in a
+// single statement. Each member gets the text for their language from
+// localized, or fallback if they have no language or it is not listed.
+func (r *MailRepository) SendSystemToGuild(guildID uint32, fallback MailText, localized map[string]MailText) error {
+	var codes, subjects, bodies []string
+	for code, text := range localized {
+		codes = append(codes, code)
+		subjects = append(subjects, text.Subject)
+		bodies = append(bodies, text.Body)
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO mail (sender_id, recipient_id, subject, body, attached_item, attached_item_amount, is_guild_invite, is_sys_message)
+		SELECT 0, gc.character_id, COALESCE(l.subject, $2), COALESCE(l.body, $3), 0, 0, false, true
+		FROM guild_characters gc
+		JOIN characters c ON c.id = gc.character_id
+		LEFT JOIN unnest($4::text[], $5::text[], $6::text[]) AS l(code, subject, body) ON l.code = c.language
+		WHERE gc.guild_id = $1`,
+		guildID, fallback.Subject, fallback.Body, pq.Array(codes), pq.Array(subjects), pq.Array(bodies))
+	return err
+}
+
 // GetListForCharacter loads all non-deleted mail for a character (max 32).
 func (r *MailRepository) GetListForCharacter(charID uint32) ([]Mail, error) {
 	rows, err := r.db.Queryx(`
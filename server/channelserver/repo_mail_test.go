@@ -229,3 +229,29 @@ func TestRepoMailSystemMessage(t *testing.T) {
 		t.Error("Expected is_sys_message=true")
 	}
 }
+
+func TestRepoMailSendSystemToGuild(t *testing.T) {
+	repo, db, leaderID, outsiderID := setupMailRepo(t)
+	guildID := CreateTestGuild(t, db, leaderID, "MailGuild")
+
+	if _, err := db.Exec("UPDATE characters SET language='jp' WHERE id=$1", leaderID); err != nil {
+		t.Fatalf("Failed to set language: %v", err)
+	}
+
+	fallback := MailText{Subject: "Rank Up", Body: "Congratulations"}
+	localized := map[string]MailText{"jp": {Subject: "ランクアップ", Body: "おめでとうございます"}}
+	if err := repo.SendSystemToGuild(guildID, fallback, localized); err != nil {
+		t.Fatalf("SendSystemToGuild failed: %v", err)
+	}
+
+	var members, outsiders int
+	if err := db.QueryRow("SELECT COUNT(*) FROM mail WHERE recipient_id=$1 AND is_sys_message AND subject='ランクアップ'", leaderID).Scan(&members); err != nil {
+		t.Fatalf("Verification query failed: %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM mail WHERE recipient_id=$1", outsiderID).Scan(&outsiders); err != nil {
+		t.Fatalf("Verification query failed: %v", err)
+	}
+	if members != 1 || outsiders != 0 {
+		t.Errorf("member mails = %d, outsider mails = %d; want 1 and 0", members, outsiders)
+	}
+}
@@ -1,8 +1,12 @@
 package channelserver
 
 import (
+	"database/sql"
 	"errors"
+	"slices"
 	"time"
+
+	"erupe-ce/common/mhfitem"
 )
 
 // errNotFound is a sentinel for mock repos that simulate "not found".
@@ -46,9 +50,16 @@ type mockMailRepo struct {
 	lockValue      bool
 	itemReceivedID int
 	sentMails      []sentMailRecord
+	guildMails     []guildMailRecord
 	sendErr        error
 }
 
+type guildMailRecord struct {
+	guildID   uint32
+	fallback  MailText
+	localized map[string]MailText
+}
+
 type sentMailRecord struct {
 	senderID, recipientID          uint32
 	subject, body                  string
@@ -101,6 +112,11 @@ func (m *mockMailRepo) SendMail(senderID, recipientID uint32, subject, body stri
 	return m.sendErr
 }
 
+func (m *mockMailRepo) SendSystemToGuild(guildID uint32, fallback MailText, localized map[string]MailText) error {
+	m.guildMails = append(m.guildMails, guildMailRecord{guildID: guildID, fallback: fallback, localized: localized})
+	return m.sendErr
+}
+
 // --- mockCharacterRepo ---
 
 type mockCharacterRepo struct {
@@ -215,6 +231,9 @@ func (m *mockCharacterRepo) ReadGuildPostChecked(_ uint32) (time.Time, error) {
 func (m *mockCharacterRepo) SaveMercenary(_ uint32, _ []byte, _ uint32) error    { return nil }
 func (m *mockCharacterRepo) UpdateGCPAndPact(_ uint32, _ uint32, _ uint32) error { return nil }
 func (m *mockCharacterRepo) FindByRastaID(_ int) (uint32, string, error)         { return 0, "", nil }
+func (m *mockCharacterRepo) FindByName(_ string) (*CharacterSummary, error) {
+	return nil, errNotFound
+}
 func (m *mockCharacterRepo) SaveCharacterData(_ uint32, _ []byte, _, _ uint16, _ bool, _ uint8, _ uint16) error {
 	return nil
 }
@@ -279,6 +298,7 @@ type mockGuildRepo struct {
 	rejectErr     error
 	removeErr     error
 	createAppErr  error
+	applyErr      error
 	getMemberErr  error
 	hasAppResult  bool
 	hasAppErr     error
@@ -294,6 +314,7 @@ type mockGuildRepo struct {
 	savedGuild     *Guild
 	savedMembers   []*GuildMember
 	createdAppArgs []interface{}
+	appliedArgs    []interface{}
 	createdPost    []interface{}
 	deletedPostID  uint32
 
@@ -305,6 +326,8 @@ type mockGuildRepo struct {
 	removeAllyErr      error
 	deletedAllianceID  uint32
 	removedAllyArgs    []uint32
+	allianceApps       []*AllianceApplication
+	acceptAllyAppErr   error
 
 	// Cooking
 	meals         []*GuildMeal
@@ -330,6 +353,9 @@ type mockGuildRepo struct {
 	collectHuntID uint32
 	claimHuntID   uint32
 	createHuntErr error
+	endedBefore   time.Time
+	purgedBefore  time.Time
+	souvenirs     []TreasureSouvenir
 
 	// Hunt data
 	guildKills     []*GuildKill
@@ -338,6 +364,35 @@ type mockGuildRepo struct {
 	countKillsErr  error
 	claimBoxCalled bool
 
+	// Missions
+	missionTarget     *GuildMissionTarget
+	missionCounts     map[uint32]uint32 // charID -> count
+	completedMissions []uint32
+	itemBox           []byte
+
+	// Audit log
+	events []GuildEvent
+
+	// Item box history
+	itemChanges []GuildItemChange
+	withdrawn   int
+
+	// Weekly bonus
+	weeklyBonusUsers int
+	weeklyQuests     int
+	weeklyKills      int
+	activeMembers    int
+
+	// Scouts
+	guildInvites  int
+	targetInvites int
+	expiredScouts []*ExpiredScout
+	expireBefore  time.Time
+
+	// Forced rank
+	forcedRank    *uint16
+	forcedRankSet bool
+
 	// Data
 	membership  *GuildMember
 	application *GuildApplication
@@ -351,7 +406,7 @@ func (m *mockGuildRepo) GetByID(guildID uint32) (*Guild, error) {
 	if m.guild != nil && m.guild.ID == guildID {
 		return m.guild, nil
 	}
-	return nil, errNotFound
+	return nil, nil // Like GuildRepository.GetByID, an unknown ID is not an error
 }
 
 func (m *mockGuildRepo) GetByCharID(_ uint32) (*Guild, error) {
@@ -410,6 +465,11 @@ func (m *mockGuildRepo) CreateApplication(guildID, charID, actorID uint32, appTy
 	return m.createAppErr
 }
 
+func (m *mockGuildRepo) Apply(guildID, charID uint32, memberLimit uint8) error {
+	m.appliedArgs = []interface{}{guildID, charID, memberLimit}
+	return m.applyErr
+}
+
 func (m *mockGuildRepo) HasApplication(_, _ uint32) (bool, error) {
 	return m.hasAppResult, m.hasAppErr
 }
@@ -453,6 +513,66 @@ func (m *mockGuildRepo) RemoveGuildFromAlliance(allyID, guildID, sub1, sub2 uint
 	return m.removeAllyErr
 }
 
+func (m *mockGuildRepo) SetAllianceRecruiting(_ uint32, recruiting bool) error {
+	if m.alliance != nil {
+		m.alliance.Recruiting = recruiting
+	}
+	return nil
+}
+
+func (m *mockGuildRepo) CreateAllianceApplication(allianceID, guildID, charID uint32) error {
+	if app, _ := m.GetAllianceApplication(allianceID, guildID); app == nil {
+		m.allianceApps = append(m.allianceApps, &AllianceApplication{AllianceID: allianceID, GuildID: guildID, AppliedBy: charID})
+	}
+	return nil
+}
+
+func (m *mockGuildRepo) GetAllianceApplication(allianceID, guildID uint32) (*AllianceApplication, error) {
+	for _, app := range m.allianceApps {
+		if app.AllianceID == allianceID && app.GuildID == guildID {
+			return app, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockGuildRepo) ListAllianceApplications(allianceID uint32) ([]*AllianceApplication, error) {
+	var apps []*AllianceApplication
+	for _, app := range m.allianceApps {
+		if app.AllianceID == allianceID {
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
+func (m *mockGuildRepo) DeleteAllianceApplication(allianceID, guildID uint32) error {
+	for i, app := range m.allianceApps {
+		if app.AllianceID == allianceID && app.GuildID == guildID {
+			m.allianceApps = append(m.allianceApps[:i], m.allianceApps[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockGuildRepo) AcceptAllianceApplication(allianceID, guildID uint32) error {
+	if m.acceptAllyAppErr != nil {
+		return m.acceptAllyAppErr
+	}
+	if m.alliance != nil {
+		if m.alliance.SubGuild1ID > 0 && m.alliance.SubGuild2ID > 0 {
+			return ErrAllianceFull
+		}
+		if m.alliance.SubGuild1ID == 0 {
+			m.alliance.SubGuild1ID = guildID
+		} else {
+			m.alliance.SubGuild2ID = guildID
+		}
+	}
+	return m.DeleteAllianceApplication(allianceID, guildID)
+}
+
 func (m *mockGuildRepo) ListMeals(_ uint32) ([]*GuildMeal, error) {
 	return m.meals, m.listMealsErr
 }
@@ -515,6 +635,25 @@ func (m *mockGuildRepo) CollectHunt(id uint32) error {
 	return nil
 }
 
+func (m *mockGuildRepo) EndExpiredHunts(before time.Time) (int, error) {
+	m.endedBefore = before
+	return 0, nil
+}
+
+func (m *mockGuildRepo) PurgeHunts(before time.Time) (int, error) {
+	m.purgedBefore = before
+	return 0, nil
+}
+
+func (m *mockGuildRepo) ListSouvenirs(_ uint32) ([]TreasureSouvenir, error) {
+	return m.souvenirs, nil
+}
+
+func (m *mockGuildRepo) ClaimSouvenirs(_ uint32) error {
+	m.souvenirs = nil
+	return nil
+}
+
 func (m *mockGuildRepo) ClaimHuntReward(id, _ uint32) error {
 	m.claimHuntID = id
 	return nil
@@ -525,11 +664,11 @@ func (m *mockGuildRepo) ClaimHuntBox(_ uint32, _ time.Time) error {
 	return nil
 }
 
-func (m *mockGuildRepo) ListGuildKills(_, _ uint32) ([]*GuildKill, error) {
+func (m *mockGuildRepo) ListGuildKills(_, _ uint32, _ time.Time) ([]*GuildKill, error) {
 	return m.guildKills, m.listKillsErr
 }
 
-func (m *mockGuildRepo) CountGuildKills(_, _ uint32) (int, error) {
+func (m *mockGuildRepo) CountGuildKills(_, _ uint32, _ time.Time) (int, error) {
 	return m.countKills, m.countKillsErr
 }
 
@@ -541,8 +680,8 @@ func (m *mockGuildRepo) CreateApplicationWithMail(_, _, _ uint32, _ GuildApplica
 }
 func (m *mockGuildRepo) CancelInvitation(_, _ uint32) error           { return nil }
 func (m *mockGuildRepo) ArrangeCharacters(_ []uint32) error           { return nil }
-func (m *mockGuildRepo) GetItemBox(_ uint32) ([]byte, error)          { return nil, nil }
-func (m *mockGuildRepo) SaveItemBox(_ uint32, _ []byte) error         { return nil }
+func (m *mockGuildRepo) GetItemBox(_ uint32) ([]byte, error)          { return m.itemBox, nil }
+func (m *mockGuildRepo) SaveItemBox(_ uint32, data []byte) error      { m.itemBox = data; return nil }
 func (m *mockGuildRepo) SetRecruiting(_ uint32, _ bool) error         { return nil }
 func (m *mockGuildRepo) SetPugiOutfits(_ uint32, _ uint32) error      { return nil }
 func (m *mockGuildRepo) SetRecruiter(_ uint32, _ bool) error          { return nil }
@@ -560,13 +699,126 @@ func (m *mockGuildRepo) GetPostLikedBy(_ uint32) (string, error)      { return "
 func (m *mockGuildRepo) SetPostLikedBy(_ uint32, _ string) error      { return nil }
 func (m *mockGuildRepo) CountNewPosts(_ uint32, _ time.Time) (int, error)   { return 0, nil }
 func (m *mockGuildRepo) ListAlliances() ([]*GuildAlliance, error)     { return nil, nil }
-func (m *mockGuildRepo) ClearTreasureHunt(_ uint32) error             { return nil }
 func (m *mockGuildRepo) InsertKillLog(_ uint32, _ int, _ uint8, _ time.Time) error { return nil }
 func (m *mockGuildRepo) ListInvitedCharacters(_ uint32) ([]*ScoutedCharacter, error) {
 	return nil, nil
 }
 func (m *mockGuildRepo) RolloverDailyRP(_ uint32, _ time.Time) error { return nil }
-func (m *mockGuildRepo) AddWeeklyBonusUsers(_ uint32, _ uint8) error { return nil }
+
+func (m *mockGuildRepo) AddWeeklyBonusUsers(_ uint32, _ time.Time, numUsers uint8) error {
+	m.weeklyBonusUsers += int(numUsers)
+	return nil
+}
+
+func (m *mockGuildRepo) GetWeeklyBonusUsers(_ uint32, _ time.Time) (int, error) {
+	return m.weeklyBonusUsers, nil
+}
+
+func (m *mockGuildRepo) RecordWeeklyActivity(_ uint32, _ time.Time, quests, kills int) error {
+	if m.membership == nil || m.membership.IsApplicant {
+		return nil
+	}
+	m.weeklyQuests += quests
+	m.weeklyKills += kills
+	return nil
+}
+
+func (m *mockGuildRepo) CountActiveMembers(_ uint32, _ time.Time, _, _ int) (int, error) {
+	return m.activeMembers, nil
+}
+
+func (m *mockGuildRepo) GetMissionTarget(_ uint32) (*GuildMissionTarget, error) {
+	return m.missionTarget, nil
+}
+
+func (m *mockGuildRepo) SetMissionTarget(guildID, missionID, rotation, charID uint32) error {
+	m.missionTarget = &GuildMissionTarget{GuildID: guildID, MissionID: missionID, Rotation: rotation, SetBy: charID}
+	return nil
+}
+
+func (m *mockGuildRepo) ClearMissionTarget(_ uint32) error {
+	m.missionTarget = nil
+	return nil
+}
+
+func (m *mockGuildRepo) AddMissionCount(_, _, _, charID, count, quantity uint32) (uint32, error) {
+	if m.missionCounts == nil {
+		m.missionCounts = make(map[uint32]uint32)
+	}
+	var total uint32
+	for _, c := range m.missionCounts {
+		total += c
+	}
+	if total >= quantity || count == 0 {
+		return total, nil
+	}
+	count = min(count, quantity-total)
+	m.missionCounts[charID] += count
+	return total + count, nil
+}
+
+func (m *mockGuildRepo) ListCompletedMissions(_, _ uint32) ([]uint32, error) {
+	return m.completedMissions, nil
+}
+
+func (m *mockGuildRepo) CompleteMission(_, missionID, _ uint32, rewards []mhfitem.MHFItemStack) (bool, error) {
+	for _, id := range m.completedMissions {
+		if id == missionID {
+			return false, nil
+		}
+	}
+	m.completedMissions = append(m.completedMissions, missionID)
+	m.missionTarget = nil
+	if len(rewards) > 0 {
+		m.itemBox = mhfitem.SerializeWarehouseItems(addItemStacks(readItemBox(m.itemBox), rewards))
+	}
+	return true, nil
+}
+
+func (m *mockGuildRepo) CountInvitations(_ uint32) (int, error) { return m.guildInvites, nil }
+
+func (m *mockGuildRepo) CountCharacterInvitations(_ uint32) (int, error) {
+	return m.targetInvites, nil
+}
+
+func (m *mockGuildRepo) ExpireInvitations(before time.Time) ([]*ExpiredScout, error) {
+	m.expireBefore = before
+	scouts := m.expiredScouts
+	m.expiredScouts = nil
+	return scouts, nil
+}
+
+func (m *mockGuildRepo) SetForcedRank(_ uint32, rank *uint16) error {
+	m.forcedRank = rank
+	m.forcedRankSet = true
+	return nil
+}
+
+func (m *mockGuildRepo) LogEvent(e GuildEvent) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+func (m *mockGuildRepo) PruneEvents(_ time.Time) (int64, error) { return 0, nil }
+
+func (m *mockGuildRepo) UpdateItemBox(_, _ uint32, updates []mhfitem.MHFItemStack, limit int, _ time.Time) ([]GuildItemChange, error) {
+	oldStacks := readItemBox(m.itemBox)
+	newStacks := mhfitem.DiffItemStacks(slices.Clone(oldStacks), updates)
+	changes := diffGuildItems(oldStacks, newStacks)
+	withdrawing := 0
+	for _, c := range changes {
+		if c.Quantity < 0 {
+			withdrawing -= c.Quantity
+		}
+	}
+	if limit > 0 && withdrawing > 0 && m.withdrawn+withdrawing > limit {
+		return nil, ErrWithdrawalLimit
+	}
+	m.itemBox = mhfitem.SerializeWarehouseItems(newStacks)
+	m.itemChanges = append(m.itemChanges, changes...)
+	m.withdrawn += withdrawing
+	return changes, nil
+}
 
 // --- mockUserRepoForItems ---
 
@@ -617,6 +869,7 @@ func (m *mockUserRepoForItems) GetDiscordToken(_ uint32) (string, error)
 func (m *mockUserRepoForItems) SetDiscordToken(_ uint32, _ string) error        { return nil }
 func (m *mockUserRepoForItems) LinkDiscord(_ string, _ string) (string, error)  { return "", nil }
 func (m *mockUserRepoForItems) SetPasswordByDiscordID(_ string, _ []byte) error { return nil }
+func (m *mockUserRepoForItems) GetByDiscordID(_ string) (uint32, error)         { return 0, nil }
 func (m *mockUserRepoForItems) GetByIDAndUsername(_ uint32) (uint32, string, error) {
 	return 0, "", nil
 }
@@ -1108,6 +1361,81 @@ func (m *mockMiscRepo) GetTrendWeapons(_ uint8) ([]uint16, error) {
 }
 func (m *mockMiscRepo) UpsertTrendWeapon(_ uint16, _ uint8) error { return nil }
 
+// --- mockDiscordRepo ---
+
+type mockDiscordRepo struct {
+	muted    map[string]bool
+	mutedErr error
+}
+
+func (m *mockDiscordRepo) IsMuted(id string) (bool, error) { return m.muted[id], m.mutedErr }
+func (m *mockDiscordRepo) Mute(id, _ string) error {
+	if m.muted == nil {
+		m.muted = make(map[string]bool)
+	}
+	m.muted[id] = true
+	return nil
+}
+func (m *mockDiscordRepo) Unmute(id string) error {
+	delete(m.muted, id)
+	return nil
+}
+
+// --- mockAnnouncementRepo ---
+
+type mockAnnouncementRepo struct {
+	announcements []Announcement
+	claimed       map[uint32]time.Time
+	listErr       error
+}
+
+func (m *mockAnnouncementRepo) List() ([]Announcement, error) { return m.announcements, m.listErr }
+func (m *mockAnnouncementRepo) ListEnabled() ([]Announcement, error) {
+	var enabled []Announcement
+	for _, a := range m.announcements {
+		if a.Enabled {
+			enabled = append(enabled, a)
+		}
+	}
+	return enabled, m.listErr
+}
+func (m *mockAnnouncementRepo) Create(a *Announcement) (uint32, error) {
+	a.ID = uint32(len(m.announcements) + 1)
+	m.announcements = append(m.announcements, *a)
+	return a.ID, nil
+}
+func (m *mockAnnouncementRepo) Delete(id uint32) error {
+	for i, a := range m.announcements {
+		if a.ID == id {
+			m.announcements = append(m.announcements[:i], m.announcements[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+func (m *mockAnnouncementRepo) ClaimSend(id uint32, occurrence time.Time) (bool, error) {
+	if m.claimed == nil {
+		m.claimed = make(map[uint32]time.Time)
+	}
+	if last, ok := m.claimed[id]; ok && !last.Before(occurrence) {
+		return false, nil
+	}
+	m.claimed[id] = occurrence
+	return true, nil
+}
+
+// --- mockChatRepo ---
+
+type mockChatRepo struct {
+	entries []ChatLogEntry
+	logErr  error
+}
+
+func (m *mockChatRepo) Log(e ChatLogEntry) error {
+	m.entries = append(m.entries, e)
+	return m.logErr
+}
+
 // --- mockMercenaryRepo ---
 
 type mockMercenaryRepo struct {
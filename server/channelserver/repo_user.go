@@ -209,6 +209,13 @@ func (r *UserRepository) SetPasswordByDiscordID(discordID string, hash []byte) e
 	return err
 }
 
+// GetByDiscordID returns the ID of the user linked to the given Discord ID.
+func (r *UserRepository) GetByDiscordID(discordID string) (uint32, error) {
+	var userID uint32
+	err := r.db.QueryRow(`SELECT id FROM users WHERE discord_id = $1`, discordID).Scan(&userID)
+	return userID, err
+}
+
 // Auth methods
 
 // GetByIDAndUsername resolves a character ID to the owning user's ID and username.
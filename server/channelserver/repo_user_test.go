@@ -108,3 +108,23 @@ func TestBanUserUpsertTemporaryToPermanent(t *testing.T) {
 		t.Errorf("Expected NULL expires after upsert to permanent, got: %v", expires.Time)
 	}
 }
+
+func TestGetByDiscordID(t *testing.T) {
+	repo, db, userID := setupUserRepo(t)
+
+	if _, err := db.Exec("UPDATE users SET discord_id='123456789012345678' WHERE id=$1", userID); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	got, err := repo.GetByDiscordID("123456789012345678")
+	if err != nil {
+		t.Fatalf("GetByDiscordID failed: %v", err)
+	}
+	if got != userID {
+		t.Errorf("Expected userID %d, got: %d", userID, got)
+	}
+
+	if _, err := repo.GetByDiscordID("000000000000000000"); err == nil {
+		t.Error("Expected error for unlinked Discord ID")
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"sort"
 
+	"erupe-ce/common/mhfguild"
+	cfg "erupe-ce/config"
+
 	"go.uber.org/zap"
 )
 
@@ -29,12 +32,35 @@ var ErrNoEligibleLeader = errors.New("no eligible leader")
 // ErrAlreadyInvited is returned when a scout target already has a pending application.
 var ErrAlreadyInvited = errors.New("already invited")
 
+// ErrScoutsRejected is returned when the scout target has chosen to reject
+// guild scouts.
+var ErrScoutsRejected = errors.New("target rejects guild scouts")
+
+// ErrGuildScoutLimit is returned when the guild already has the most
+// outstanding scout invitations allowed.
+var ErrGuildScoutLimit = errors.New("guild scout limit reached")
+
+// ErrTargetScoutLimit is returned when the scout target already holds the
+// most invitations allowed.
+var ErrTargetScoutLimit = errors.New("target scout limit reached")
+
 // ErrCannotRecruit is returned when the actor lacks recruit permission.
 var ErrCannotRecruit = errors.New("cannot recruit")
 
 // ErrApplicationMissing is returned when the expected guild application is not found.
 var ErrApplicationMissing = errors.New("application missing")
 
+// ErrGuildNotFound is returned when the guild an operation refers to does not
+// exist.
+var ErrGuildNotFound = errors.New("guild not found")
+
+// ErrAllianceClosed is returned when applying to an alliance that is not accepting applications.
+var ErrAllianceClosed = errors.New("alliance not accepting applications")
+
+// ErrAllianceNotFound is returned when the alliance an operation refers to
+// does not exist.
+var ErrAllianceNotFound = errors.New("alliance not found")
+
 // OperateMemberResult holds the outcome of a guild member operation.
 type OperateMemberResult struct {
 	MailRecipientID uint32
@@ -62,6 +88,12 @@ type ScoutInviteStrings struct {
 	Body  string // must contain %s for guild name
 }
 
+// ScoutLimits caps outstanding guild scout invitations. 0 is unlimited.
+type ScoutLimits struct {
+	MaxPerGuild  int
+	MaxPerTarget int
+}
+
 // AnswerScoutStrings holds i18n strings needed for scout answer mails.
 type AnswerScoutStrings struct {
 	SuccessTitle  string
@@ -74,6 +106,14 @@ type AnswerScoutStrings struct {
 	DeclinedBody  string // %s for guild name
 }
 
+// RankChangeStrings holds i18n strings for guild rank change mails: one per
+// locale code, and Fallback for members whose language is not listed. Bodies
+// must contain %s for the guild name and %d for the new rank.
+type RankChangeStrings struct {
+	Fallback  MailText
+	Localized map[string]MailText
+}
+
 // AnswerScoutResult holds the outcome of answering a guild scout invitation.
 type AnswerScoutResult struct {
 	GuildID uint32
@@ -114,8 +154,10 @@ func (svc *GuildService) OperateMember(actorCharID, targetCharID uint32, action
 	}
 
 	var mail Mail
+	var event GuildEventAction
 	switch action {
 	case GuildMemberActionAccept:
+		event = GuildEventAccept
 		err = svc.guildRepo.AcceptApplication(guild.ID, targetCharID)
 		mail = Mail{
 			RecipientID:     targetCharID,
@@ -124,6 +166,7 @@ func (svc *GuildService) OperateMember(actorCharID, targetCharID uint32, action
 			IsSystemMessage: true,
 		}
 	case GuildMemberActionReject:
+		event = GuildEventReject
 		err = svc.guildRepo.RejectApplication(guild.ID, targetCharID)
 		mail = Mail{
 			RecipientID:     targetCharID,
@@ -132,6 +175,7 @@ func (svc *GuildService) OperateMember(actorCharID, targetCharID uint32, action
 			IsSystemMessage: true,
 		}
 	case GuildMemberActionKick:
+		event = GuildEventKick
 		err = svc.guildRepo.RemoveCharacter(targetCharID)
 		mail = Mail{
 			RecipientID:     targetCharID,
@@ -146,6 +190,7 @@ func (svc *GuildService) OperateMember(actorCharID, targetCharID uint32, action
 	if err != nil {
 		return nil, fmt.Errorf("guild member action %d: %w", action, err)
 	}
+	svc.LogEvent(GuildEvent{GuildID: guild.ID, ActorID: actorCharID, TargetCharID: targetCharID, Action: event})
 
 	// Send mail best-effort
 	if mailErr := svc.mailSvc.SendSystem(mail.RecipientID, mail.Subject, mail.Body); mailErr != nil {
@@ -174,6 +219,7 @@ func (svc *GuildService) Disband(actorCharID, guildID uint32) (*DisbandResult, e
 	if err := svc.guildRepo.Disband(guildID); err != nil {
 		return &DisbandResult{Success: false}, nil
 	}
+	svc.LogEvent(GuildEvent{GuildID: guildID, ActorID: actorCharID, Action: GuildEventDisband, Detail: guild.Name})
 
 	return &DisbandResult{Success: true}, nil
 }
@@ -236,6 +282,7 @@ func (svc *GuildService) ResignLeadership(actorCharID, guildID uint32) (*ResignR
 	if err := svc.guildRepo.Save(guild); err != nil {
 		svc.logger.Error("Failed to save guild after leadership resign", zap.Error(err))
 	}
+	svc.LogEvent(GuildEvent{GuildID: guildID, ActorID: actorCharID, TargetCharID: guild.LeaderCharID, Action: GuildEventResign})
 
 	return &ResignResult{NewLeaderCharID: members[newLeaderIdx].CharID}, nil
 }
@@ -252,6 +299,7 @@ func (svc *GuildService) Leave(charID, guildID uint32, isApplicant bool, guildNa
 		if err := svc.guildRepo.RemoveCharacter(charID); err != nil {
 			return &LeaveResult{Success: false}, nil
 		}
+		svc.LogEvent(GuildEvent{GuildID: guildID, ActorID: charID, TargetCharID: charID, Action: GuildEventLeave})
 	}
 
 	// Best-effort withdrawal notification
@@ -265,8 +313,10 @@ func (svc *GuildService) Leave(charID, guildID uint32, isApplicant bool, guildNa
 
 // PostScout sends a guild scout invitation to a target character.
 // The actor must have recruit permission. Returns ErrAlreadyInvited if the target
-// already has a pending application.
-func (svc *GuildService) PostScout(actorCharID, targetCharID uint32, strings ScoutInviteStrings) error {
+// already has a pending application, ErrScoutsRejected if the target rejects
+// guild scouts, and ErrGuildScoutLimit or ErrTargetScoutLimit once either side
+// holds as many invitations as limits allow.
+func (svc *GuildService) PostScout(actorCharID, targetCharID uint32, limits ScoutLimits, strings ScoutInviteStrings) error {
 	actorMember, err := svc.guildRepo.GetCharacterMembership(actorCharID)
 	if err != nil {
 		return fmt.Errorf("actor membership lookup: %w", err)
@@ -280,6 +330,14 @@ func (svc *GuildService) PostScout(actorCharID, targetCharID uint32, strings Sco
 		return fmt.Errorf("guild lookup: %w", err)
 	}
 
+	rejects, err := svc.charRepo.ReadBool(targetCharID, "restrict_guild_scout")
+	if err != nil {
+		return fmt.Errorf("read scout preference: %w", err)
+	}
+	if rejects {
+		return ErrScoutsRejected
+	}
+
 	hasApp, err := svc.guildRepo.HasApplication(guild.ID, targetCharID)
 	if err != nil {
 		return fmt.Errorf("check application: %w", err)
@@ -288,6 +346,25 @@ func (svc *GuildService) PostScout(actorCharID, targetCharID uint32, strings Sco
 		return ErrAlreadyInvited
 	}
 
+	if limits.MaxPerGuild > 0 {
+		n, err := svc.guildRepo.CountInvitations(guild.ID)
+		if err != nil {
+			return fmt.Errorf("count guild invitations: %w", err)
+		}
+		if n >= limits.MaxPerGuild {
+			return ErrGuildScoutLimit
+		}
+	}
+	if limits.MaxPerTarget > 0 {
+		n, err := svc.guildRepo.CountCharacterInvitations(targetCharID)
+		if err != nil {
+			return fmt.Errorf("count target invitations: %w", err)
+		}
+		if n >= limits.MaxPerTarget {
+			return ErrTargetScoutLimit
+		}
+	}
+
 	err = svc.guildRepo.CreateApplicationWithMail(
 		guild.ID, targetCharID, actorCharID, GuildApplicationTypeInvited,
 		actorCharID, targetCharID,
@@ -352,3 +429,211 @@ func (svc *GuildService) AnswerScout(charID, leaderID uint32, accept bool, strin
 		Mails:   mails,
 	}, nil
 }
+
+// LogEvent records a guild audit log entry, logging rather than returning
+// failures so that auditing never undoes a completed action.
+func (svc *GuildService) LogEvent(e GuildEvent) {
+	if err := svc.guildRepo.LogEvent(e); err != nil {
+		svc.logger.Warn("Failed to write guild audit log",
+			zap.Uint32("guildID", e.GuildID), zap.String("action", string(e.Action)), zap.Error(err))
+	}
+}
+
+// ForceRank pins a guild to a rank, or returns it to its RP rank when rank
+// is nil. If the rank shown to members changes, they are notified as by
+// NotifyRankChange.
+func (svc *GuildService) ForceRank(guild *Guild, rank *uint16, actorCharID uint32, table mhfguild.Table, mode cfg.Mode, strings RankChangeStrings) error {
+	oldRank := guild.Rank(table, mode)
+	if err := svc.guildRepo.SetForcedRank(guild.ID, rank); err != nil {
+		return err
+	}
+	guild.ForcedRank = rank
+	svc.NotifyRankChange(guild, oldRank, guild.Rank(table, mode), actorCharID, strings)
+	return nil
+}
+
+// NotifyRankChange records an audit log entry and mails every member of the
+// guild in their own language when its rank moved from oldRank to newRank.
+// The mail is a single INSERT ... SELECT, so a large guild does not hold up
+// the caller.
+func (svc *GuildService) NotifyRankChange(guild *Guild, oldRank, newRank uint16, actorCharID uint32, strings RankChangeStrings) {
+	if oldRank == newRank {
+		return
+	}
+	svc.LogEvent(GuildEvent{
+		GuildID: guild.ID,
+		ActorID: actorCharID,
+		Action:  GuildEventRank,
+		Detail:  fmt.Sprintf("%d -> %d", oldRank, newRank),
+	})
+	format := func(t MailText) MailText {
+		return MailText{Subject: t.Subject, Body: fmt.Sprintf(t.Body, guild.Name, newRank)}
+	}
+	localized := make(map[string]MailText, len(strings.Localized))
+	for code, t := range strings.Localized {
+		localized[code] = format(t)
+	}
+	if err := svc.mailSvc.SendSystemToGuild(guild.ID, format(strings.Fallback), localized); err != nil {
+		svc.logger.Error("Failed to send guild rank mail", zap.Uint32("guildID", guild.ID), zap.Error(err))
+	}
+}
+
+// CompleteMission records a guild mission as completed and pays its rewards
+// into the guild item box in one transaction. It returns false without paying
+// out if the guild already completed the mission this rotation.
+func (svc *GuildService) CompleteMission(guildID uint32, mission GuildMission, rotation uint32) (bool, error) {
+	completed, err := svc.guildRepo.CompleteMission(guildID, mission.ID, rotation, mission.Rewards)
+	if err != nil || !completed {
+		return false, err
+	}
+	svc.logger.Info("Guild mission completed",
+		zap.Uint32("guildID", guildID), zap.Uint32("missionID", mission.ID), zap.Int("rewards", len(mission.Rewards)))
+	return true, nil
+}
+
+// ApplyToGuild files a character's application to a guild, for the guild
+// leader to answer. The guild must be recruiting and below the member limit
+// of its rank in table; see GuildRepository.Apply for the other checks.
+func (svc *GuildService) ApplyToGuild(charID, guildID uint32, table mhfguild.Table, mode cfg.Mode) error {
+	guild, err := svc.guildRepo.GetByID(guildID)
+	if err != nil {
+		return fmt.Errorf("guild lookup: %w", err)
+	}
+	if guild == nil {
+		return ErrGuildNotFound
+	}
+	return svc.guildRepo.Apply(guild.ID, charID, table.MemberLimit(guild.Rank(table, mode)))
+}
+
+// ApplyToAlliance queues a guild's application to join an alliance and mails
+// the alliance's parent guild leader. Only the guild's leader may apply, and
+// only while the alliance is accepting applications and has a free slot.
+func (svc *GuildService) ApplyToAlliance(actorCharID, guildID, allianceID uint32) error {
+	guild, err := svc.guildRepo.GetByID(guildID)
+	if err != nil {
+		return fmt.Errorf("guild lookup: %w", err)
+	}
+	if guild == nil {
+		return ErrGuildNotFound
+	}
+	if guild.LeaderCharID != actorCharID {
+		return ErrUnauthorized
+	}
+	if guild.AllianceID != 0 {
+		return ErrAlreadyAllied
+	}
+
+	alliance, err := svc.guildRepo.GetAllianceByID(allianceID)
+	if err != nil {
+		return fmt.Errorf("alliance %d lookup: %w", allianceID, err)
+	}
+	if alliance == nil {
+		return ErrAllianceNotFound
+	}
+	if !alliance.Recruiting {
+		return ErrAllianceClosed
+	}
+	if alliance.SubGuild1ID > 0 && alliance.SubGuild2ID > 0 {
+		return ErrAllianceFull
+	}
+
+	if err := svc.guildRepo.CreateAllianceApplication(allianceID, guildID, actorCharID); err != nil {
+		return fmt.Errorf("create alliance application: %w", err)
+	}
+
+	if err := svc.mailSvc.SendSystem(alliance.ParentGuild.LeaderCharID, "Alliance Application",
+		fmt.Sprintf("「%s」 has applied to join 「%s」.", guild.Name, alliance.Name)); err != nil {
+		svc.logger.Warn("Failed to send alliance application mail", zap.Error(err))
+	}
+	return nil
+}
+
+// AnswerAllianceApplication accepts or declines a guild's application to an
+// alliance and mails the applicant guild's leader. Only the alliance's parent
+// guild leader may answer. Accepting fails with ErrAllianceFull once both sub
+// guild slots are taken; the application is kept so it can still be declined.
+func (svc *GuildService) AnswerAllianceApplication(actorCharID, allianceID, guildID uint32, accept bool) error {
+	alliance, err := svc.guildRepo.GetAllianceByID(allianceID)
+	if err != nil {
+		return fmt.Errorf("alliance %d lookup: %w", allianceID, err)
+	}
+	if alliance == nil {
+		return ErrAllianceNotFound
+	}
+	if alliance.ParentGuild.LeaderCharID != actorCharID {
+		return ErrUnauthorized
+	}
+
+	app, err := svc.guildRepo.GetAllianceApplication(allianceID, guildID)
+	if err != nil {
+		return fmt.Errorf("alliance application lookup: %w", err)
+	}
+	if app == nil {
+		return ErrApplicationMissing
+	}
+
+	var subject, body string
+	if accept {
+		if err := svc.guildRepo.AcceptAllianceApplication(allianceID, guildID); err != nil {
+			return fmt.Errorf("accept alliance application: %w", err)
+		}
+		for _, id := range []uint32{guildID, alliance.ParentGuildID} {
+			svc.LogEvent(GuildEvent{GuildID: id, ActorID: actorCharID, TargetGuildID: guildID, Action: GuildEventAllianceJoin, Detail: alliance.Name})
+		}
+		subject, body = "Accepted!", fmt.Sprintf("Your guild's application to join 「%s」 was accepted.", alliance.Name)
+	} else {
+		if err := svc.guildRepo.DeleteAllianceApplication(allianceID, guildID); err != nil {
+			return fmt.Errorf("decline alliance application: %w", err)
+		}
+		subject, body = "Rejected", fmt.Sprintf("Your guild's application to join 「%s」 was rejected.", alliance.Name)
+	}
+
+	// The applicant guild may have been disbanded since applying.
+	guild, err := svc.guildRepo.GetByID(guildID)
+	if err != nil || guild == nil {
+		return nil
+	}
+	if err := svc.mailSvc.SendSystem(guild.LeaderCharID, subject, body); err != nil {
+		svc.logger.Warn("Failed to send alliance application answer mail", zap.Error(err))
+	}
+	return nil
+}
+
+// WithdrawAllianceApplication removes a guild's pending application to an
+// alliance. Only the guild's leader may withdraw it.
+func (svc *GuildService) WithdrawAllianceApplication(actorCharID, guildID, allianceID uint32) error {
+	guild, err := svc.guildRepo.GetByID(guildID)
+	if err != nil {
+		return fmt.Errorf("guild lookup: %w", err)
+	}
+	if guild == nil {
+		return ErrGuildNotFound
+	}
+	if guild.LeaderCharID != actorCharID {
+		return ErrUnauthorized
+	}
+	app, err := svc.guildRepo.GetAllianceApplication(allianceID, guildID)
+	if err != nil {
+		return fmt.Errorf("alliance application lookup: %w", err)
+	}
+	if app == nil {
+		return ErrApplicationMissing
+	}
+	return svc.guildRepo.DeleteAllianceApplication(allianceID, guildID)
+}
+
+// SetAllianceRecruiting opens or closes an alliance to new applications.
+// Only the alliance's parent guild leader may change it.
+func (svc *GuildService) SetAllianceRecruiting(actorCharID, allianceID uint32, recruiting bool) error {
+	alliance, err := svc.guildRepo.GetAllianceByID(allianceID)
+	if err != nil {
+		return fmt.Errorf("alliance %d lookup: %w", allianceID, err)
+	}
+	if alliance == nil {
+		return ErrAllianceNotFound
+	}
+	if alliance.ParentGuild.LeaderCharID != actorCharID {
+		return ErrUnauthorized
+	}
+	return svc.guildRepo.SetAllianceRecruiting(allianceID, recruiting)
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"testing"
 
+	"erupe-ce/common/mhfguild"
+	cfg "erupe-ce/config"
+
 	"go.uber.org/zap"
 )
 
@@ -15,7 +18,7 @@ func newTestMailService(mr MailRepo, gr GuildRepo) *MailService {
 func newTestGuildService(gr GuildRepo, mr MailRepo) *GuildService {
 	logger, _ := zap.NewDevelopment()
 	ms := newTestMailService(mr, gr)
-	return NewGuildService(gr, ms, nil, logger)
+	return NewGuildService(gr, ms, newMockCharacterRepo(), logger)
 }
 
 func TestGuildService_OperateMember(t *testing.T) {
@@ -385,14 +388,18 @@ func TestGuildService_PostScout(t *testing.T) {
 	strings := ScoutInviteStrings{Title: "Invite", Body: "Join 「%s」"}
 
 	tests := []struct {
-		name         string
-		membership   *GuildMember
-		guild        *Guild
-		hasApp       bool
-		hasAppErr    error
-		createAppErr error
-		getMemberErr error
-		wantErr      error
+		name          string
+		membership    *GuildMember
+		guild         *Guild
+		hasApp        bool
+		hasAppErr     error
+		createAppErr  error
+		getMemberErr  error
+		rejects       bool
+		limits        ScoutLimits
+		guildInvites  int
+		targetInvites int
+		wantErr       error
 	}{
 		{
 			name:       "successful scout",
@@ -412,6 +419,38 @@ func TestGuildService_PostScout(t *testing.T) {
 			guild:      &Guild{ID: 10, Name: "TestGuild"},
 			wantErr:    ErrCannotRecruit,
 		},
+		{
+			name:       "target rejects scouts",
+			membership: &GuildMember{GuildID: 10, CharID: 1, IsLeader: true, OrderIndex: 1},
+			guild:      &Guild{ID: 10, Name: "TestGuild"},
+			rejects:    true,
+			wantErr:    ErrScoutsRejected,
+		},
+		{
+			name:         "guild limit reached",
+			membership:   &GuildMember{GuildID: 10, CharID: 1, IsLeader: true, OrderIndex: 1},
+			guild:        &Guild{ID: 10, Name: "TestGuild"},
+			limits:       ScoutLimits{MaxPerGuild: 3},
+			guildInvites: 3,
+			wantErr:      ErrGuildScoutLimit,
+		},
+		{
+			name:          "target limit reached",
+			membership:    &GuildMember{GuildID: 10, CharID: 1, IsLeader: true, OrderIndex: 1},
+			guild:         &Guild{ID: 10, Name: "TestGuild"},
+			limits:        ScoutLimits{MaxPerGuild: 3, MaxPerTarget: 2},
+			guildInvites:  2,
+			targetInvites: 2,
+			wantErr:       ErrTargetScoutLimit,
+		},
+		{
+			name:          "under limits",
+			membership:    &GuildMember{GuildID: 10, CharID: 1, IsLeader: true, OrderIndex: 1},
+			guild:         &Guild{ID: 10, Name: "TestGuild"},
+			limits:        ScoutLimits{MaxPerGuild: 3, MaxPerTarget: 2},
+			guildInvites:  2,
+			targetInvites: 1,
+		},
 		{
 			name:         "nil membership",
 			getMemberErr: errors.New("not found"),
@@ -423,22 +462,25 @@ func TestGuildService_PostScout(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			guildMock := &mockGuildRepo{
-				membership:   tt.membership,
-				hasAppResult: tt.hasApp,
-				hasAppErr:    tt.hasAppErr,
-				createAppErr: tt.createAppErr,
-				getMemberErr: tt.getMemberErr,
+				membership:    tt.membership,
+				hasAppResult:  tt.hasApp,
+				hasAppErr:     tt.hasAppErr,
+				createAppErr:  tt.createAppErr,
+				getMemberErr:  tt.getMemberErr,
+				guildInvites:  tt.guildInvites,
+				targetInvites: tt.targetInvites,
 			}
 			guildMock.guild = tt.guild
 			svc := newTestGuildService(guildMock, &mockMailRepo{})
+			svc.charRepo.(*mockCharacterRepo).bools["restrict_guild_scout"] = tt.rejects
 
-			err := svc.PostScout(1, 42, strings)
+			err := svc.PostScout(1, 42, tt.limits, strings)
 
 			if tt.wantErr != nil {
 				if err == nil {
 					t.Fatal("Expected error, got nil")
 				}
-				if errors.Is(tt.wantErr, ErrAlreadyInvited) || errors.Is(tt.wantErr, ErrCannotRecruit) {
+				if tt.getMemberErr == nil {
 					if !errors.Is(err, tt.wantErr) {
 						t.Errorf("Expected %v, got %v", tt.wantErr, err)
 					}
@@ -568,3 +610,222 @@ func TestGuildService_AnswerScout(t *testing.T) {
 		})
 	}
 }
+
+func TestGuildService_ApplyToGuild(t *testing.T) {
+	table := mhfguild.DefaultTable(cfg.ZZ, nil)
+
+	guildMock := &mockGuildRepo{guild: &Guild{ID: 10, RankRP: table[3].RP}}
+	svc := newTestGuildService(guildMock, &mockMailRepo{})
+	if err := svc.ApplyToGuild(2, 10, table, cfg.ZZ); err != nil {
+		t.Fatalf("ApplyToGuild failed: %v", err)
+	}
+	if len(guildMock.appliedArgs) != 3 || guildMock.appliedArgs[2] != table.MemberLimit(3) {
+		t.Errorf("Apply args = %v, want the member limit of rank 3 (%d)", guildMock.appliedArgs, table.MemberLimit(3))
+	}
+
+	if err := svc.ApplyToGuild(2, 11, table, cfg.ZZ); !errors.Is(err, ErrGuildNotFound) {
+		t.Errorf("ApplyToGuild for an unknown guild = %v, want ErrGuildNotFound", err)
+	}
+
+	guildMock.applyErr = ErrAlreadyApplied
+	if err := svc.ApplyToGuild(2, 10, table, cfg.ZZ); !errors.Is(err, ErrAlreadyApplied) {
+		t.Errorf("ApplyToGuild = %v, want ErrAlreadyApplied", err)
+	}
+}
+
+// newAllianceTestRepo returns a repo holding guild 20 (led by char 2) and an
+// alliance whose parent guild 10 is led by char 1.
+func newAllianceTestRepo() *mockGuildRepo {
+	return &mockGuildRepo{
+		guild: &Guild{ID: 20, Name: "Applicant", GuildLeader: GuildLeader{LeaderCharID: 2}},
+		alliance: &GuildAlliance{
+			ID: 5, Name: "Allies", Recruiting: true, ParentGuildID: 10,
+			ParentGuild: Guild{ID: 10, GuildLeader: GuildLeader{LeaderCharID: 1}},
+		},
+	}
+}
+
+func TestGuildService_ApplyToAlliance(t *testing.T) {
+	tests := []struct {
+		name      string
+		actor     uint32
+		setup     func(*mockGuildRepo)
+		wantErrIs error
+	}{
+		{"leader applies", 2, nil, nil},
+		{"not guild leader", 3, nil, ErrUnauthorized},
+		{"unknown guild", 2, func(m *mockGuildRepo) { m.guild.ID = 21 }, ErrGuildNotFound},
+		{"already allied", 2, func(m *mockGuildRepo) { m.guild.AllianceID = 7 }, ErrAlreadyAllied},
+		{"unknown alliance", 2, func(m *mockGuildRepo) { m.alliance = nil }, ErrAllianceNotFound},
+		{"closed", 2, func(m *mockGuildRepo) { m.alliance.Recruiting = false }, ErrAllianceClosed},
+		{"full", 2, func(m *mockGuildRepo) {
+			m.alliance.SubGuild1ID, m.alliance.SubGuild2ID = 11, 12
+		}, ErrAllianceFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guildMock := newAllianceTestRepo()
+			if tt.setup != nil {
+				tt.setup(guildMock)
+			}
+			mailMock := &mockMailRepo{}
+			svc := newTestGuildService(guildMock, mailMock)
+
+			err := svc.ApplyToAlliance(tt.actor, 20, 5)
+			if !errors.Is(err, tt.wantErrIs) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErrIs)
+			}
+			if tt.wantErrIs != nil {
+				if len(guildMock.allianceApps) != 0 {
+					t.Error("application should not be queued")
+				}
+				return
+			}
+			if len(guildMock.allianceApps) != 1 || guildMock.allianceApps[0].AppliedBy != 2 {
+				t.Errorf("applications = %+v, want one by char 2", guildMock.allianceApps)
+			}
+			if len(mailMock.sentMails) != 1 || mailMock.sentMails[0].recipientID != 1 {
+				t.Errorf("mails = %+v, want one to the parent guild leader", mailMock.sentMails)
+			}
+		})
+	}
+}
+
+func TestGuildService_WithdrawAllianceApplication_UnknownGuild(t *testing.T) {
+	guildMock := newAllianceTestRepo()
+	guildMock.allianceApps = []*AllianceApplication{{AllianceID: 5, GuildID: 20, AppliedBy: 2}}
+	svc := newTestGuildService(guildMock, &mockMailRepo{})
+
+	if err := svc.WithdrawAllianceApplication(2, 21, 5); !errors.Is(err, ErrGuildNotFound) {
+		t.Fatalf("err = %v, want %v", err, ErrGuildNotFound)
+	}
+	if len(guildMock.allianceApps) != 1 {
+		t.Error("application should be kept")
+	}
+}
+
+func TestGuildService_AnswerAllianceApplication(t *testing.T) {
+	t.Run("accept fills a sub guild slot", func(t *testing.T) {
+		guildMock := newAllianceTestRepo()
+		guildMock.allianceApps = []*AllianceApplication{{AllianceID: 5, GuildID: 20, AppliedBy: 2}}
+		mailMock := &mockMailRepo{}
+		svc := newTestGuildService(guildMock, mailMock)
+
+		if err := svc.AnswerAllianceApplication(1, 5, 20, true); err != nil {
+			t.Fatal(err)
+		}
+		if guildMock.alliance.SubGuild1ID != 20 || len(guildMock.allianceApps) != 0 {
+			t.Errorf("sub1 = %d, applications = %d; want 20 and 0", guildMock.alliance.SubGuild1ID, len(guildMock.allianceApps))
+		}
+		if len(mailMock.sentMails) != 1 || mailMock.sentMails[0].recipientID != 2 || mailMock.sentMails[0].subject != "Accepted!" {
+			t.Errorf("mails = %+v", mailMock.sentMails)
+		}
+	})
+
+	t.Run("decline drops the application", func(t *testing.T) {
+		guildMock := newAllianceTestRepo()
+		guildMock.allianceApps = []*AllianceApplication{{AllianceID: 5, GuildID: 20, AppliedBy: 2}}
+		mailMock := &mockMailRepo{}
+		svc := newTestGuildService(guildMock, mailMock)
+
+		if err := svc.AnswerAllianceApplication(1, 5, 20, false); err != nil {
+			t.Fatal(err)
+		}
+		if guildMock.alliance.SubGuild1ID != 0 || len(guildMock.allianceApps) != 0 {
+			t.Error("declined guild should not join and its application should be gone")
+		}
+		if len(mailMock.sentMails) != 1 || mailMock.sentMails[0].subject != "Rejected" {
+			t.Errorf("mails = %+v", mailMock.sentMails)
+		}
+	})
+
+	t.Run("full alliance keeps the application", func(t *testing.T) {
+		guildMock := newAllianceTestRepo()
+		guildMock.alliance.SubGuild1ID, guildMock.alliance.SubGuild2ID = 11, 12
+		guildMock.allianceApps = []*AllianceApplication{{AllianceID: 5, GuildID: 20, AppliedBy: 2}}
+		svc := newTestGuildService(guildMock, &mockMailRepo{})
+
+		if err := svc.AnswerAllianceApplication(1, 5, 20, true); !errors.Is(err, ErrAllianceFull) {
+			t.Fatalf("err = %v, want ErrAllianceFull", err)
+		}
+		if len(guildMock.allianceApps) != 1 {
+			t.Error("application should be kept")
+		}
+	})
+
+	t.Run("only the parent leader may answer", func(t *testing.T) {
+		guildMock := newAllianceTestRepo()
+		guildMock.allianceApps = []*AllianceApplication{{AllianceID: 5, GuildID: 20, AppliedBy: 2}}
+		svc := newTestGuildService(guildMock, &mockMailRepo{})
+
+		if err := svc.AnswerAllianceApplication(2, 5, 20, true); !errors.Is(err, ErrUnauthorized) {
+			t.Fatalf("err = %v, want ErrUnauthorized", err)
+		}
+	})
+
+	t.Run("missing application", func(t *testing.T) {
+		svc := newTestGuildService(newAllianceTestRepo(), &mockMailRepo{})
+		if err := svc.AnswerAllianceApplication(1, 5, 20, true); !errors.Is(err, ErrApplicationMissing) {
+			t.Fatalf("err = %v, want ErrApplicationMissing", err)
+		}
+	})
+}
+
+func TestGuildService_SetAllianceRecruiting(t *testing.T) {
+	guildMock := newAllianceTestRepo()
+	svc := newTestGuildService(guildMock, &mockMailRepo{})
+
+	if err := svc.SetAllianceRecruiting(2, 5, false); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("err = %v, want ErrUnauthorized", err)
+	}
+	if err := svc.SetAllianceRecruiting(1, 5, false); err != nil {
+		t.Fatal(err)
+	}
+	if guildMock.alliance.Recruiting {
+		t.Error("alliance should no longer be recruiting")
+	}
+}
+
+func TestGuildService_ForceRank(t *testing.T) {
+	strings := RankChangeStrings{
+		Fallback:  MailText{Subject: "Guild Rank", Body: "%s is now rank %d."},
+		Localized: map[string]MailText{"jp": {Subject: "猟団ランク", Body: "%sのランクが%dになりました。"}},
+	}
+	table := mhfguild.DefaultTable(cfg.ZZ, nil)
+
+	guildMock := &mockGuildRepo{}
+	mailMock := &mockMailRepo{}
+	svc := newTestGuildService(guildMock, mailMock)
+	guild := &Guild{ID: 10, Name: "Hunters"}
+	rank := uint16(5)
+	if err := svc.ForceRank(guild, &rank, 3, table, cfg.ZZ, strings); err != nil {
+		t.Fatalf("ForceRank failed: %v", err)
+	}
+	if guildMock.forcedRank == nil || *guildMock.forcedRank != 5 || guild.ForcedRank != &rank {
+		t.Errorf("forced rank = %v, want 5", guildMock.forcedRank)
+	}
+	if len(mailMock.guildMails) != 1 {
+		t.Fatalf("guild mails = %+v, want one", mailMock.guildMails)
+	}
+	mail := mailMock.guildMails[0]
+	if mail.guildID != 10 || mail.fallback.Body != "Hunters is now rank 5." || mail.localized["jp"].Body != "Huntersのランクが5になりました。" {
+		t.Errorf("mail = %+v, want the rank change in each language", mail)
+	}
+	if len(guildMock.events) != 1 || guildMock.events[0].Action != GuildEventRank || guildMock.events[0].Detail != "0 -> 5" {
+		t.Errorf("events = %+v, want one rank change 0 -> 5", guildMock.events)
+	}
+
+	guildMock = &mockGuildRepo{}
+	mailMock = &mockMailRepo{}
+	svc = newTestGuildService(guildMock, mailMock)
+	rank = 0
+	if err := svc.ForceRank(&Guild{ID: 10}, &rank, 3, table, cfg.ZZ, strings); err != nil {
+		t.Fatalf("ForceRank failed: %v", err)
+	}
+	if !guildMock.forcedRankSet {
+		t.Error("forced rank should still be stored")
+	}
+	if len(mailMock.guildMails) != 0 || len(guildMock.events) != 0 {
+		t.Error("an unchanged rank should not notify members")
+	}
+}
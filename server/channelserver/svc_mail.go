@@ -35,6 +35,12 @@ func (svc *MailService) SendSystem(recipientID uint32, subject, body string) err
 	return svc.mailRepo.SendMail(0, recipientID, subject, body, 0, 0, false, true)
 }
 
+// SendSystemToGuild sends a system notification mail to every member of the
+// guild in their own language with one database round trip.
+func (svc *MailService) SendSystemToGuild(guildID uint32, fallback MailText, localized map[string]MailText) error {
+	return svc.mailRepo.SendSystemToGuild(guildID, fallback, localized)
+}
+
 // SendGuildInvite sends a guild invitation mail (flagged as guild invite).
 func (svc *MailService) SendGuildInvite(senderID, recipientID uint32, subject, body string) error {
 	return svc.mailRepo.SendMail(senderID, recipientID, subject, body, 0, 0, true, false)
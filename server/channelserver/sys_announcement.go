@@ -0,0 +1,135 @@
+package channelserver
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"erupe-ce/common/announcement"
+	"erupe-ce/common/cron"
+
+	"go.uber.org/zap"
+)
+
+// Announcement kinds.
+const (
+	AnnouncementOnce      = announcement.Once
+	AnnouncementRecurring = announcement.Recurring
+	AnnouncementCountdown = announcement.Countdown
+)
+
+const (
+	// announcementTick is how often each channel checks for due announcements.
+	announcementTick = 15 * time.Second
+	// announcementGrace is how late an occurrence may still be sent, so that
+	// announcements missed while the server was down are not sent on startup.
+	announcementGrace = 2 * time.Minute
+)
+
+// Validate checks that the announcement's fields are consistent with its kind.
+func (a *Announcement) Validate() error {
+	var sendAt *time.Time
+	if a.SendAt.Valid {
+		sendAt = &a.SendAt.Time
+	}
+	return announcement.Validate(a.Kind, a.Message, sendAt, a.Schedule, a.Countdown)
+}
+
+// occurrence returns the latest unsent occurrence of the announcement that is
+// due at now, along with the event time it refers to.
+func (a *Announcement) occurrence(now time.Time) (at, event time.Time, ok bool) {
+	notBefore := now.Add(-announcementGrace)
+	if a.LastSentAt.Valid && !a.LastSentAt.Time.Before(notBefore) {
+		notBefore = a.LastSentAt.Time.Add(time.Nanosecond)
+	}
+	due := func(t time.Time) bool {
+		return !t.After(now) && !t.Before(notBefore)
+	}
+
+	switch a.Kind {
+	case AnnouncementOnce:
+		if due(a.SendAt.Time) {
+			return a.SendAt.Time, a.SendAt.Time, true
+		}
+	case AnnouncementRecurring:
+		schedule, err := cron.Parse(a.Schedule)
+		if err != nil {
+			return
+		}
+		if t, found := schedule.Prev(now, notBefore); found {
+			return t, t, true
+		}
+	case AnnouncementCountdown:
+		for _, m := range a.Countdown {
+			t := a.SendAt.Time.Add(-time.Duration(m) * time.Minute)
+			if due(t) && (!ok || t.After(at)) {
+				at, event, ok = t, a.SendAt.Time, true
+			}
+		}
+	}
+	return
+}
+
+// renderAnnouncement fills the placeholders in an announcement's MHFML
+// message: {minutes} is the time left until the event, {time} and {date} the
+// event's local time and date.
+func renderAnnouncement(message string, at, event time.Time) string {
+	event = event.Local()
+	return strings.NewReplacer(
+		"{minutes}", strconv.Itoa(int(event.Sub(at).Round(time.Minute).Minutes())),
+		"{time}", event.Format("15:04"),
+		"{date}", event.Format("2006-01-02"),
+	).Replace(message)
+}
+
+// runAnnouncements worldcasts scheduled announcements until shutdown.
+func (s *Server) runAnnouncements() {
+	if s.announcementRepo == nil {
+		return
+	}
+	ticker := time.NewTicker(announcementTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+		s.sendDueAnnouncements(time.Now())
+	}
+}
+
+// sendDueAnnouncements worldcasts every announcement due at now that no other
+// channel server has already claimed.
+func (s *Server) sendDueAnnouncements(now time.Time) {
+	announcements, err := s.announcementRepo.ListEnabled()
+	if err != nil {
+		s.logger.Error("Failed to list announcements", zap.Error(err))
+		return
+	}
+	for _, a := range announcements {
+		at, event, ok := a.occurrence(now)
+		if !ok {
+			continue
+		}
+		claimed, err := s.announcementRepo.ClaimSend(a.ID, at)
+		if err != nil {
+			s.logger.Error("Failed to claim announcement", zap.Error(err), zap.Uint32("id", a.ID))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		s.logger.Info("Sending announcement", zap.Uint32("id", a.ID), zap.String("kind", a.Kind))
+		s.WorldcastChatMessage(renderAnnouncement(a.Message, at, event))
+	}
+}
+
+// parseAnnouncementTime parses a local "2006-01-02T15:04" time or an RFC 3339
+// timestamp.
+func parseAnnouncementTime(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02T15:04", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,129 @@
+package channelserver
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func validTime(t time.Time) sql.NullTime { return sql.NullTime{Time: t, Valid: true} }
+
+func TestAnnouncementValidate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		a       Announcement
+		wantErr bool
+	}{
+		{"once", Announcement{Kind: AnnouncementOnce, Message: "hi", SendAt: validTime(now)}, false},
+		{"once without time", Announcement{Kind: AnnouncementOnce, Message: "hi"}, true},
+		{"empty message", Announcement{Kind: AnnouncementOnce, Message: " ", SendAt: validTime(now)}, true},
+		{"recurring", Announcement{Kind: AnnouncementRecurring, Message: "hi", Schedule: "0 * * * *"}, false},
+		{"recurring bad cron", Announcement{Kind: AnnouncementRecurring, Message: "hi", Schedule: "hourly"}, true},
+		{"countdown", Announcement{Kind: AnnouncementCountdown, Message: "hi", SendAt: validTime(now), Countdown: pq.Int64Array{10, 5}}, false},
+		{"countdown without offsets", Announcement{Kind: AnnouncementCountdown, Message: "hi", SendAt: validTime(now)}, true},
+		{"countdown negative offset", Announcement{Kind: AnnouncementCountdown, Message: "hi", SendAt: validTime(now), Countdown: pq.Int64Array{-1}}, true},
+		{"unknown kind", Announcement{Kind: "weekly", Message: "hi"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.a.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnnouncementOccurrence(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 30, 20, 0, time.Local)
+
+	t.Run("once due", func(t *testing.T) {
+		a := Announcement{Kind: AnnouncementOnce, SendAt: validTime(now.Add(-time.Minute))}
+		if at, _, ok := a.occurrence(now); !ok || !at.Equal(now.Add(-time.Minute)) {
+			t.Errorf("occurrence() = %v, %v", at, ok)
+		}
+	})
+	t.Run("once in future", func(t *testing.T) {
+		a := Announcement{Kind: AnnouncementOnce, SendAt: validTime(now.Add(time.Minute))}
+		if _, _, ok := a.occurrence(now); ok {
+			t.Error("future announcement should not be due")
+		}
+	})
+	t.Run("once missed", func(t *testing.T) {
+		a := Announcement{Kind: AnnouncementOnce, SendAt: validTime(now.Add(-time.Hour))}
+		if _, _, ok := a.occurrence(now); ok {
+			t.Error("announcement past the grace period should not be sent")
+		}
+	})
+	t.Run("once already sent", func(t *testing.T) {
+		sendAt := now.Add(-time.Minute)
+		a := Announcement{Kind: AnnouncementOnce, SendAt: validTime(sendAt), LastSentAt: validTime(sendAt)}
+		if _, _, ok := a.occurrence(now); ok {
+			t.Error("sent announcement should not be due again")
+		}
+	})
+	t.Run("recurring", func(t *testing.T) {
+		a := Announcement{Kind: AnnouncementRecurring, Schedule: "30 12 * * *"}
+		want := time.Date(2026, time.October, 18, 12, 30, 0, 0, time.Local)
+		at, event, ok := a.occurrence(now)
+		if !ok || !at.Equal(want) || !event.Equal(want) {
+			t.Errorf("occurrence() = %v, %v, %v, want %v", at, event, ok, want)
+		}
+		a.LastSentAt = validTime(want)
+		if _, _, ok := a.occurrence(now); ok {
+			t.Error("recurring occurrence should only be sent once")
+		}
+	})
+	t.Run("countdown picks latest offset", func(t *testing.T) {
+		event := now.Add(9 * time.Minute)
+		a := Announcement{Kind: AnnouncementCountdown, SendAt: validTime(event), Countdown: pq.Int64Array{30, 10, 9, 5}}
+		at, gotEvent, ok := a.occurrence(now)
+		if !ok || !at.Equal(event.Add(-9*time.Minute)) || !gotEvent.Equal(event) {
+			t.Errorf("occurrence() = %v, %v, %v", at, gotEvent, ok)
+		}
+		a.LastSentAt = validTime(at)
+		if _, _, ok := a.occurrence(now); ok {
+			t.Error("countdown step should only be sent once")
+		}
+	})
+}
+
+func TestRenderAnnouncement(t *testing.T) {
+	event := time.Date(2026, time.October, 18, 20, 0, 0, 0, time.Local)
+	got := renderAnnouncement("<C_4>Maintenance in {minutes} min at {time} on {date}", event.Add(-15*time.Minute), event)
+	want := "<C_4>Maintenance in 15 min at 20:00 on 2026-10-18"
+	if got != want {
+		t.Errorf("renderAnnouncement() = %q, want %q", got, want)
+	}
+}
+
+func TestSendDueAnnouncements(t *testing.T) {
+	server := createMockServer()
+	repo := &mockAnnouncementRepo{}
+	server.announcementRepo = repo
+	session := createMockSession(1, server)
+	server.sessions[&mockConn{}] = session
+
+	now := time.Now()
+	repo.announcements = []Announcement{
+		{ID: 1, Kind: AnnouncementOnce, Message: "due", SendAt: validTime(now.Add(-time.Second)), Enabled: true},
+		{ID: 2, Kind: AnnouncementOnce, Message: "later", SendAt: validTime(now.Add(time.Hour)), Enabled: true},
+		{ID: 3, Kind: AnnouncementOnce, Message: "disabled", SendAt: validTime(now.Add(-time.Second))},
+	}
+
+	server.sendDueAnnouncements(now)
+	if n := len(session.sendPackets); n != 1 {
+		t.Errorf("packets = %d, want 1", n)
+	}
+	if _, ok := repo.claimed[1]; !ok {
+		t.Error("announcement 1 should be claimed")
+	}
+
+	// Another channel running the same tick loses the claim.
+	server.sendDueAnnouncements(now)
+	if n := len(session.sendPackets); n != 1 {
+		t.Errorf("packets after second tick = %d, want 1", n)
+	}
+}
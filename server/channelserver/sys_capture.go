@@ -96,6 +96,16 @@ func startCapture(server *Server, conn network.Conn, remoteAddr net.Addr, server
 	return rc, rc, cleanup
 }
 
+// startTap wraps a network.Conn with a TapConn if live streaming is enabled.
+// Returns the (possibly wrapped) conn and the TapConn (nil if disabled).
+func startTap(server *Server, conn network.Conn) (network.Conn, *pcap.TapConn) {
+	if server.packetHub == nil {
+		return conn, nil
+	}
+	tc := pcap.NewTapConn(conn, server.packetHub)
+	return tc, tc
+}
+
 // sanitizeAddr replaces characters that are problematic in filenames.
 func sanitizeAddr(addr string) string {
 	out := make([]byte, 0, len(addr))
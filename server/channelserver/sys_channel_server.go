@@ -12,6 +12,8 @@ import (
 	"erupe-ce/network"
 	"erupe-ce/network/binpacket"
 	"erupe-ce/network/mhfpacket"
+	"erupe-ce/network/pcap"
+	"erupe-ce/network/transport"
 	"erupe-ce/server/discordbot"
 
 	"github.com/jmoiron/sqlx"
@@ -24,6 +26,7 @@ type Config struct {
 	Logger      *zap.Logger
 	DB          *sqlx.DB
 	DiscordBot  *discordbot.DiscordBot
+	PacketHub   *pcap.Hub // Live packet streaming to the API; nil if disabled
 	ErupeConfig *cfg.Config
 	Name        string
 	Enable      bool
@@ -71,6 +74,9 @@ type Server struct {
 	miscRepo           MiscRepo
 	scenarioRepo       ScenarioRepo
 	mercenaryRepo      MercenaryRepo
+	discordRepo        DiscordRepo
+	announcementRepo   AnnouncementRepo
+	chatRepo           ChatRepo
 	mailService        *MailService
 	guildService       *GuildService
 	achievementService *AchievementService
@@ -87,8 +93,15 @@ type Server struct {
 
 	stages StageMap
 
-	// Used to map different languages
-	i18n i18n
+	// Used to map different languages. i18n is the server default; locales
+	// holds every loaded locale by code for per-session rendering.
+	i18n    i18n
+	locales map[string]*i18n
+
+	chatFilter *chatFilter
+	rateLimits *rateLimitPolicy
+
+	ioStats sessionIOStats // Send queue counters across all sessions
 
 	userBinary *UserBinaryStore
 	minidata   *MinidataStore
@@ -101,6 +114,8 @@ type Server struct {
 	// Discord chat integration
 	discordBot *discordbot.DiscordBot
 
+	packetHub *pcap.Hub // Live packet streaming; nil if disabled
+
 	name string
 
 	raviente *Raviente
@@ -108,6 +123,8 @@ type Server struct {
 	questCache *QuestCache
 
 	handlerTable map[network.PacketID]handlerFunc
+	handlers     handlerChain // Middleware around handlerTable
+	handlerStats [network.MSG_SYS_reserve1AF + 1]handlerStat
 }
 
 // NewServer creates a new Server type.
@@ -126,6 +143,7 @@ func NewServer(config *Config) *Server {
 		semaphore:      make(map[string]*Semaphore),
 		semaphoreIndex: 7,
 		discordBot:     config.DiscordBot,
+		packetHub:      config.PacketHub,
 		name:           config.Name,
 		raviente: &Raviente{
 			id:       1,
@@ -158,6 +176,9 @@ func NewServer(config *Config) *Server {
 	s.miscRepo = NewMiscRepository(config.DB)
 	s.scenarioRepo = NewScenarioRepository(config.DB)
 	s.mercenaryRepo = NewMercenaryRepository(config.DB)
+	s.discordRepo = NewDiscordRepository(config.DB)
+	s.announcementRepo = NewAnnouncementRepository(config.DB)
+	s.chatRepo = NewChatRepository(config.DB)
 
 	s.mailService = NewMailService(s.mailRepo, s.guildRepo, s.logger)
 	s.guildService = NewGuildService(s.guildRepo, s.mailService, s.charRepo, s.logger)
@@ -187,7 +208,20 @@ func NewServer(config *Config) *Server {
 	// MezFes
 	s.stages.Store("sl1Ns462p0a0u0", NewStage("sl1Ns462p0a0u0"))
 
-	s.i18n = getLangStrings(s)
+	s.locales, s.i18n = loadConfiguredLocales(config.ErupeConfig, s.logger)
+
+	var err error
+	s.chatFilter, err = newChatFilter(config.ErupeConfig.Chat.Filter)
+	if err != nil {
+		s.logger.Error("Failed to compile chat filter, chat will not be filtered", zap.Error(err))
+	}
+
+	s.rateLimits, err = newRateLimitPolicy(config.ErupeConfig.RateLimit)
+	if err != nil {
+		s.logger.Error("Invalid rate limit configuration, packets will not be rate limited", zap.Error(err))
+	}
+
+	s.Use(s.defaultMiddleware()...)
 
 	return s
 }
@@ -198,18 +232,29 @@ func (s *Server) Start() error {
 	if err != nil {
 		return err
 	}
-	s.listener = l
+	tl, err := transport.Listen(l, s.erupeConfig.Channel.Transport)
+	if err != nil {
+		_ = l.Close()
+		return err
+	}
+	s.listener = tl
 
 	initCommands(s.erupeConfig.Commands, s.logger)
 
 	go s.acceptClients()
 	go s.manageSessions()
 	go s.invalidateSessions()
+	go s.runAnnouncements()
+	go s.runGuildAuditPrune()
+	go s.runGuildScoutExpiry()
+	go s.runGuildHuntExpiry()
 
 	// Start the discord bot for chat integration.
 	if s.erupeConfig.Discord.Enabled && s.discordBot != nil {
-		s.discordBot.Session.AddHandler(s.onDiscordMessage)
-		s.discordBot.Session.AddHandler(s.onInteraction)
+		// Relayed messages and slash commands act on every channel through
+		// the registry, so only the first channel server to start on this
+		// bot handles them.
+		s.discordBot.AddHandlersOnce(s.onDiscordMessage, s.onInteraction)
 	}
 
 	return nil
@@ -280,7 +325,11 @@ func (s *Server) manageSessions() {
 	}
 }
 
+// getObjectId returns the lowest object ID not held by a connected session.
+// It takes the server lock, so callers must not already hold it.
 func (s *Server) getObjectId() uint16 {
+	s.Lock()
+	defer s.Unlock()
 	ids := make(map[uint16]struct{})
 	for _, sess := range s.sessions {
 		ids[sess.objectID] = struct{}{}
@@ -297,6 +346,7 @@ func (s *Server) getObjectId() uint16 {
 func (s *Server) invalidateSessions() {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
+	var ioStats SessionIOStats
 	for {
 		select {
 		case <-s.done:
@@ -304,6 +354,8 @@ func (s *Server) invalidateSessions() {
 		case <-ticker.C:
 		}
 
+		ioStats = s.logBackPressure(ioStats)
+
 		s.Lock()
 		var timedOut []*Session
 		for _, sess := range s.sessions {
@@ -342,13 +394,44 @@ func (s *Server) BroadcastMHF(pkt mhfpacket.MHFPacket, ignoredSession *Session)
 	}
 }
 
+// BroadcastLocalizedMHF broadcasts a packet built in each session's preferred
+// language. build is called at most once per language.
+func (s *Server) BroadcastLocalizedMHF(build func(lang *i18n) mhfpacket.MHFPacket, ignoredSession *Session) {
+	s.Lock()
+	defer s.Unlock()
+	pkts := make(map[*i18n]mhfpacket.MHFPacket)
+	for _, session := range s.sessions {
+		if session == ignoredSession {
+			continue
+		}
+
+		lang := session.lang()
+		pkt, ok := pkts[lang]
+		if !ok {
+			pkt = build(lang)
+			pkts[lang] = pkt
+		}
+
+		bf := byteframe.NewByteFrame()
+		bf.WriteUint16(uint16(pkt.Opcode()))
+		_ = pkt.Build(bf, session.clientContext)
+		session.QueueSendNonBlocking(bf.Data())
+	}
+}
+
 // WorldcastMHF broadcasts a packet to all sessions across all channel servers.
 func (s *Server) WorldcastMHF(pkt mhfpacket.MHFPacket, ignoredSession *Session, ignoredChannel *Server) {
 	s.Registry.Worldcast(pkt, ignoredSession, ignoredChannel)
 }
 
-// BroadcastChatMessage broadcasts a simple chat message to all the sessions.
-func (s *Server) BroadcastChatMessage(message string) {
+// WorldcastLocalizedMHF broadcasts a packet built in each recipient's
+// language to all sessions across all channel servers.
+func (s *Server) WorldcastLocalizedMHF(build func(lang *i18n) mhfpacket.MHFPacket, ignoredSession *Session, ignoredChannel *Server) {
+	s.Registry.WorldcastLocalized(build, ignoredSession, ignoredChannel)
+}
+
+// serverChatPacket builds a server-originated chat message packet.
+func (s *Server) serverChatPacket(message string) *mhfpacket.MsgSysCastedBinary {
 	bf := byteframe.NewByteFrame()
 	bf.SetLE()
 	msgBinChat := &binpacket.MsgBinChat{
@@ -360,10 +443,21 @@ func (s *Server) BroadcastChatMessage(message string) {
 	}
 	_ = msgBinChat.Build(bf)
 
-	s.BroadcastMHF(&mhfpacket.MsgSysCastedBinary{
+	return &mhfpacket.MsgSysCastedBinary{
 		MessageType:    BinaryMessageTypeChat,
 		RawDataPayload: bf.Data(),
-	}, nil)
+	}
+}
+
+// BroadcastChatMessage broadcasts a simple chat message to all the sessions.
+func (s *Server) BroadcastChatMessage(message string) {
+	s.BroadcastMHF(s.serverChatPacket(message), nil)
+}
+
+// WorldcastChatMessage broadcasts a simple chat message to all sessions
+// across all channel servers.
+func (s *Server) WorldcastChatMessage(message string) {
+	s.WorldcastMHF(s.serverChatPacket(message), nil, nil)
 }
 
 // DiscordChannelSend sends a chat message to the configured Discord channel.
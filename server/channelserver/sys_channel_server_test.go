@@ -297,6 +297,32 @@ func TestBroadcastMHFAllSessions(t *testing.T) {
 	}
 }
 
+func TestBroadcastLocalizedMHF(t *testing.T) {
+	server := createMockServer()
+	langs := []string{"", "jp", "jp"}
+	sessions := make([]*Session, len(langs))
+	for i, code := range langs {
+		sessions[i] = createMockSession(uint32(i+1), server)
+		sessions[i].setLanguage(code)
+		server.sessions[&mockConn{}] = sessions[i]
+	}
+
+	var built []string
+	server.BroadcastLocalizedMHF(func(lang *i18n) mhfpacket.MHFPacket {
+		built = append(built, lang.language)
+		return &mhfpacket.MsgSysNop{}
+	}, nil)
+
+	if len(built) != 2 {
+		t.Errorf("build called %d times, want once per language: %v", len(built), built)
+	}
+	for i, session := range sessions {
+		if n := len(session.sendPackets); n != 1 {
+			t.Errorf("session %d received %d packets, want 1", i, n)
+		}
+	}
+}
+
 // TestFindSessionByCharID tests finding sessions by character ID
 func TestFindSessionByCharID(t *testing.T) {
 	server := createTestServer()
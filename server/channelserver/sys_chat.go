@@ -0,0 +1,146 @@
+package channelserver
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"erupe-ce/common/byteframe"
+	cfg "erupe-ce/config"
+	"erupe-ce/network/binpacket"
+
+	"go.uber.org/zap"
+)
+
+// Chat filter actions, as configured and as recorded in chat_logs.
+const (
+	chatFilterMask = "mask"
+	chatFilterDrop = "drop"
+
+	chatLogMasked  = "masked"
+	chatLogDropped = "dropped"
+)
+
+// chatFilter masks or drops chat messages matching configured words or
+// regular expressions.
+type chatFilter struct {
+	drop     bool
+	patterns []*regexp.Regexp
+}
+
+// newChatFilter compiles the configured filter. It returns nil if the filter
+// is disabled or has nothing to match.
+func newChatFilter(opts cfg.ChatFilterOptions) (*chatFilter, error) {
+	if !opts.Enabled {
+		return nil, nil
+	}
+	f := &chatFilter{}
+	switch strings.ToLower(opts.Action) {
+	case chatFilterMask, "":
+	case chatFilterDrop:
+		f.drop = true
+	default:
+		return nil, fmt.Errorf("unknown chat filter action %q", opts.Action)
+	}
+	for _, word := range opts.Words {
+		if word == "" {
+			continue
+		}
+		f.patterns = append(f.patterns, regexp.MustCompile(`(?i)`+regexp.QuoteMeta(word)))
+	}
+	for _, pattern := range opts.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("chat filter pattern %q: %w", pattern, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	if len(f.patterns) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// apply returns the filtered message and the action taken: "" if nothing
+// matched, chatLogMasked or chatLogDropped.
+func (f *chatFilter) apply(message string) (string, string) {
+	if f == nil {
+		return message, ""
+	}
+	filtered := message
+	for _, re := range f.patterns {
+		filtered = re.ReplaceAllStringFunc(filtered, func(match string) string {
+			return strings.Repeat("*", len([]rune(match)))
+		})
+	}
+	if filtered == message {
+		return message, ""
+	}
+	if f.drop {
+		return "", chatLogDropped
+	}
+	return filtered, chatLogMasked
+}
+
+// chatTypeName names a chat type for the chat log.
+func chatTypeName(t binpacket.ChatType) string {
+	switch t {
+	case binpacket.ChatTypeWorld:
+		return "world"
+	case binpacket.ChatTypeStage:
+		return "stage"
+	case binpacket.ChatTypeGuild:
+		return "guild"
+	case binpacket.ChatTypeAlliance:
+		return "alliance"
+	case binpacket.ChatTypeParty:
+		return "party"
+	case binpacket.ChatTypeWhisper:
+		return "whisper"
+	default:
+		return fmt.Sprintf("unknown(%d)", t)
+	}
+}
+
+// moderateChat filters and logs a player chat message before it is
+// broadcast. It returns the payload to forward, rebuilt if the filter masked
+// the message, and false if the message must not be sent.
+func (s *Session) moderateChat(chat *binpacket.MsgBinChat, payload []byte, targets []uint32) ([]byte, bool) {
+	original := chat.Message
+	filtered, action := s.server.chatFilter.apply(original)
+
+	if s.server.erupeConfig.Chat.Log && s.server.chatRepo != nil {
+		var stageID string
+		s.Lock()
+		if s.stage != nil {
+			stageID = s.stage.id
+		}
+		s.Unlock()
+		err := s.server.chatRepo.Log(ChatLogEntry{
+			CharID:       s.charID,
+			SenderName:   chat.SenderName,
+			ChatType:     chatTypeName(chat.Type),
+			ChannelID:    s.server.ID,
+			StageID:      stageID,
+			Targets:      targets,
+			Message:      original,
+			FilterAction: action,
+		})
+		if err != nil {
+			s.logger.Error("Failed to log chat message", zap.Error(err))
+		}
+	}
+
+	switch action {
+	case chatLogDropped:
+		s.logger.Info("Chat message dropped by filter", zap.Uint32("charID", s.charID))
+		return nil, false
+	case chatLogMasked:
+		chat.Message = filtered
+		bf := byteframe.NewByteFrame()
+		bf.SetLE()
+		_ = chat.Build(bf)
+		return bf.Data(), true
+	}
+	return payload, true
+}
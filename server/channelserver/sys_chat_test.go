@@ -0,0 +1,223 @@
+package channelserver
+
+import (
+	"testing"
+
+	"erupe-ce/common/byteframe"
+	cfg "erupe-ce/config"
+	"erupe-ce/network/binpacket"
+	"erupe-ce/network/mhfpacket"
+)
+
+func TestNewChatFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    cfg.ChatFilterOptions
+		wantNil bool
+		wantErr bool
+	}{
+		{"disabled", cfg.ChatFilterOptions{Words: []string{"bad"}}, true, false},
+		{"nothing to match", cfg.ChatFilterOptions{Enabled: true, Words: []string{""}}, true, false},
+		{"words", cfg.ChatFilterOptions{Enabled: true, Words: []string{"bad"}}, false, false},
+		{"drop", cfg.ChatFilterOptions{Enabled: true, Action: "DROP", Patterns: []string{`\d{4}`}}, false, false},
+		{"unknown action", cfg.ChatFilterOptions{Enabled: true, Action: "ban", Words: []string{"bad"}}, true, true},
+		{"bad pattern", cfg.ChatFilterOptions{Enabled: true, Patterns: []string{"("}}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newChatFilter(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (f == nil) != tt.wantNil {
+				t.Errorf("filter = %v, wantNil %v", f, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestChatFilterApply(t *testing.T) {
+	mask, _ := newChatFilter(cfg.ChatFilterOptions{Enabled: true, Words: []string{"bad word", "ばか"}, Patterns: []string{`(?i)discord\.gg/\w+`}})
+	drop, _ := newChatFilter(cfg.ChatFilterOptions{Enabled: true, Action: "drop", Words: []string{"spam"}})
+
+	tests := []struct {
+		name       string
+		f          *chatFilter
+		in         string
+		want       string
+		wantAction string
+	}{
+		{"nil filter", nil, "bad word", "bad word", ""},
+		{"no match", mask, "hello", "hello", ""},
+		{"case-insensitive word", mask, "a BAD WORD here", "a ******** here", chatLogMasked},
+		{"multibyte word", mask, "このばか", "この**", chatLogMasked},
+		{"pattern", mask, "join discord.gg/abc", "join **************", chatLogMasked},
+		{"drop", drop, "buy SPAM now", "", chatLogDropped},
+		{"drop no match", drop, "hello", "hello", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, action := tt.f.apply(tt.in)
+			if got != tt.want || action != tt.wantAction {
+				t.Errorf("apply(%q) = %q, %q, want %q, %q", tt.in, got, action, tt.want, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestChatTypeName(t *testing.T) {
+	if got := chatTypeName(binpacket.ChatTypeWhisper); got != "whisper" {
+		t.Errorf("chatTypeName(whisper) = %q", got)
+	}
+	if got := chatTypeName(binpacket.ChatType(42)); got != "unknown(42)" {
+		t.Errorf("chatTypeName(42) = %q", got)
+	}
+}
+
+// createChatSessions returns a sender and a listener sharing a stage.
+func createChatSessions(t *testing.T, opts cfg.ChatOptions) (*Session, *Session, *mockChatRepo) {
+	t.Helper()
+	server := createMockServer()
+	server.erupeConfig.CommandPrefix = "!"
+	server.erupeConfig.Chat = opts
+	repo := &mockChatRepo{}
+	server.chatRepo = repo
+	var err error
+	if server.chatFilter, err = newChatFilter(opts.Filter); err != nil {
+		t.Fatal(err)
+	}
+
+	stage := NewStage("sl1Ns200p0a0u0")
+	sender := createMockSession(1, server)
+	listener := createMockSession(2, server)
+	for _, s := range []*Session{sender, listener} {
+		s.stage = stage
+		stage.clients[s] = s.charID
+	}
+	return sender, listener, repo
+}
+
+func stageChatPacket(message string) *mhfpacket.MsgSysCastBinary {
+	bf := byteframe.NewByteFrame()
+	bf.SetLE()
+	_ = (&binpacket.MsgBinChat{Type: binpacket.ChatTypeStage, Message: message, SenderName: "Sender"}).Build(bf)
+	return &mhfpacket.MsgSysCastBinary{
+		BroadcastType:  BroadcastTypeStage,
+		MessageType:    BinaryMessageTypeChat,
+		RawDataPayload: bf.Data(),
+	}
+}
+
+// receivedChat parses the chat message out of a queued MsgSysCastedBinary.
+func receivedChat(t *testing.T, s *Session) string {
+	t.Helper()
+	select {
+	case p := <-s.sendPackets:
+		bf := byteframe.NewByteFrameFromBytes(p.data)
+		_ = bf.ReadUint16() // Opcode
+		casted := &mhfpacket.MsgSysCastedBinary{}
+		if err := casted.Parse(bf, s.clientContext); err != nil {
+			t.Fatalf("parse casted binary: %v", err)
+		}
+		chat := &binpacket.MsgBinChat{}
+		inner := byteframe.NewByteFrameFromBytes(casted.RawDataPayload)
+		inner.SetLE()
+		_ = chat.Parse(inner)
+		return chat.Message
+	default:
+		t.Fatal("no chat message received")
+		return ""
+	}
+}
+
+func TestCastBinaryChat_LogsMessages(t *testing.T) {
+	sender, listener, repo := createChatSessions(t, cfg.ChatOptions{Log: true})
+
+	handleMsgSysCastBinary(sender, stageChatPacket("hello"))
+
+	if got := receivedChat(t, listener); got != "hello" {
+		t.Errorf("listener received %q, want hello", got)
+	}
+	if len(repo.entries) != 1 {
+		t.Fatalf("logged %d entries, want 1", len(repo.entries))
+	}
+	e := repo.entries[0]
+	if e.CharID != 1 || e.ChatType != "stage" || e.StageID != "sl1Ns200p0a0u0" || e.Message != "hello" || e.FilterAction != "" {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func TestCastBinaryChat_LogDisabled(t *testing.T) {
+	sender, listener, repo := createChatSessions(t, cfg.ChatOptions{})
+
+	handleMsgSysCastBinary(sender, stageChatPacket("hello"))
+
+	if len(listener.sendPackets) != 1 {
+		t.Error("message should still be broadcast")
+	}
+	if len(repo.entries) != 0 {
+		t.Errorf("logged %d entries with logging disabled", len(repo.entries))
+	}
+}
+
+func TestCastBinaryChat_Masked(t *testing.T) {
+	sender, listener, repo := createChatSessions(t, cfg.ChatOptions{
+		Log:    true,
+		Filter: cfg.ChatFilterOptions{Enabled: true, Action: "mask", Words: []string{"bad"}},
+	})
+
+	handleMsgSysCastBinary(sender, stageChatPacket("so bad"))
+
+	if got := receivedChat(t, listener); got != "so ***" {
+		t.Errorf("listener received %q, want masked message", got)
+	}
+	if len(repo.entries) != 1 || repo.entries[0].Message != "so bad" || repo.entries[0].FilterAction != chatLogMasked {
+		t.Errorf("entries = %+v, want original message marked masked", repo.entries)
+	}
+}
+
+func TestCastBinaryChat_Dropped(t *testing.T) {
+	sender, listener, repo := createChatSessions(t, cfg.ChatOptions{
+		Log:    true,
+		Filter: cfg.ChatFilterOptions{Enabled: true, Action: "drop", Words: []string{"bad"}},
+	})
+
+	handleMsgSysCastBinary(sender, stageChatPacket("so bad"))
+
+	if len(listener.sendPackets) != 0 {
+		t.Error("dropped message was broadcast")
+	}
+	if len(repo.entries) != 1 || repo.entries[0].FilterAction != chatLogDropped {
+		t.Errorf("entries = %+v, want message logged as dropped", repo.entries)
+	}
+}
+
+func TestCastBinaryChat_TargetedMasked(t *testing.T) {
+	sender, listener, repo := createChatSessions(t, cfg.ChatOptions{
+		Log:    true,
+		Filter: cfg.ChatFilterOptions{Enabled: true, Words: []string{"bad"}},
+	})
+	sender.server.sessions[&mockConn{}] = listener
+
+	chat := byteframe.NewByteFrame()
+	chat.SetLE()
+	_ = (&binpacket.MsgBinChat{Type: binpacket.ChatTypeWhisper, Message: "bad news", SenderName: "Sender"}).Build(chat)
+	bf := byteframe.NewByteFrame()
+	_ = (&binpacket.MsgBinTargeted{TargetCount: 1, TargetCharIDs: []uint32{2}, RawDataPayload: chat.Data()}).Build(bf)
+
+	handleMsgSysCastBinary(sender, &mhfpacket.MsgSysCastBinary{
+		BroadcastType:  BroadcastTypeTargeted,
+		MessageType:    BinaryMessageTypeChat,
+		RawDataPayload: bf.Data(),
+	})
+
+	if got := receivedChat(t, listener); got != "*** news" {
+		t.Errorf("whisper received %q, want masked message", got)
+	}
+	if len(repo.entries) != 1 {
+		t.Fatalf("logged %d entries, want 1", len(repo.entries))
+	}
+	if e := repo.entries[0]; e.ChatType != "whisper" || len(e.Targets) != 1 || e.Targets[0] != 2 {
+		t.Errorf("entry = %+v", e)
+	}
+}
@@ -0,0 +1,54 @@
+package channelserver
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// guildAuditPruneTick is how often each channel prunes expired guild audit
+// log entries.
+const guildAuditPruneTick = time.Hour
+
+// runGuildAuditPrune deletes guild audit log entries older than
+// Guild.AuditLogDays until shutdown.
+func (s *Server) runGuildAuditPrune() {
+	if s.guildRepo == nil || s.erupeConfig.Guild.AuditLogDays <= 0 {
+		return
+	}
+	ticker := time.NewTicker(guildAuditPruneTick)
+	defer ticker.Stop()
+	for {
+		s.pruneGuildAuditLog(time.Now())
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneGuildAuditLog deletes the entries that expired as of now.
+func (s *Server) pruneGuildAuditLog(now time.Time) {
+	before := now.AddDate(0, 0, -s.erupeConfig.Guild.AuditLogDays)
+	n, err := s.guildRepo.PruneEvents(before)
+	if err != nil {
+		s.logger.Error("Failed to prune guild audit log", zap.Error(err))
+		return
+	}
+	if n > 0 {
+		s.logger.Info("Pruned guild audit log", zap.Int64("entries", n))
+	}
+}
+
+// formatItemChanges summarises guild item box changes for the audit log,
+// e.g. "7:+5 1630:-2".
+func formatItemChanges(changes []GuildItemChange) string {
+	parts := make([]string, len(changes))
+	for i, c := range changes {
+		parts[i] = fmt.Sprintf("%d:%+d", c.ItemID, c.Quantity)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,92 @@
+package channelserver
+
+import (
+	"testing"
+
+	"erupe-ce/common/mhfitem"
+	"erupe-ce/network/mhfpacket"
+)
+
+func TestFormatItemChanges(t *testing.T) {
+	before := []mhfitem.MHFItemStack{
+		{WarehouseID: 1, Item: mhfitem.MHFItem{ItemID: 1630}, Quantity: 5},
+		{WarehouseID: 2, Item: mhfitem.MHFItem{ItemID: 7}, Quantity: 2},
+		{WarehouseID: 3, Item: mhfitem.MHFItem{ItemID: 9}, Quantity: 1},
+	}
+	after := []mhfitem.MHFItemStack{
+		{WarehouseID: 1, Item: mhfitem.MHFItem{ItemID: 1630}, Quantity: 3},
+		{WarehouseID: 2, Item: mhfitem.MHFItem{ItemID: 7}, Quantity: 2},
+		{WarehouseID: 4, Item: mhfitem.MHFItem{ItemID: 8}, Quantity: 10},
+	}
+	if got, want := formatItemChanges(diffGuildItems(before, after)), "8:+10 9:-1 1630:-2"; got != want {
+		t.Errorf("formatItemChanges = %q, want %q", got, want)
+	}
+	if got := diffGuildItems(before, before); len(got) != 0 {
+		t.Errorf("unchanged box = %+v, want no changes", got)
+	}
+}
+
+func TestUpdateGuildItem_LogsChanges(t *testing.T) {
+	server := createMockServer()
+	guildMock := &mockGuildRepo{membership: &GuildMember{GuildID: 10, CharID: 3, OrderIndex: 5}}
+	guildMock.itemBox = mhfitem.SerializeWarehouseItems([]mhfitem.MHFItemStack{
+		{WarehouseID: 1, Item: mhfitem.MHFItem{ItemID: 1630}, Quantity: 5},
+	})
+	server.guildRepo = guildMock
+	ensureGuildService(server)
+	session := createMockSession(3, server)
+
+	handleMsgMhfUpdateGuildItem(session, &mhfpacket.MsgMhfUpdateGuildItem{
+		AckHandle: 1,
+		GuildID:   10,
+		UpdatedItems: []mhfitem.MHFItemStack{
+			{WarehouseID: 1, Item: mhfitem.MHFItem{ItemID: 1630}, Quantity: 1},
+		},
+	})
+	_ = readAck(t, session)
+
+	if len(guildMock.events) != 1 {
+		t.Fatalf("logged %d events, want 1", len(guildMock.events))
+	}
+	e := guildMock.events[0]
+	if e.GuildID != 10 || e.ActorID != 3 || e.Action != GuildEventItemBox || e.Detail != "1630:-4" {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestOperateMember_LogsKick(t *testing.T) {
+	guildMock := &mockGuildRepo{
+		guild:      &Guild{ID: 10, Name: "TestGuild", GuildLeader: GuildLeader{LeaderCharID: 1}},
+		membership: &GuildMember{GuildID: 10, CharID: 1, IsLeader: true, OrderIndex: 1},
+	}
+	svc := newTestGuildService(guildMock, &mockMailRepo{})
+
+	if _, err := svc.OperateMember(1, 42, GuildMemberActionKick); err != nil {
+		t.Fatal(err)
+	}
+	if len(guildMock.events) != 1 {
+		t.Fatalf("logged %d events, want 1", len(guildMock.events))
+	}
+	if e := guildMock.events[0]; e.Action != GuildEventKick || e.ActorID != 1 || e.TargetCharID != 42 {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestSetGuildManageRight_LogsRecruiter(t *testing.T) {
+	server := createMockServer()
+	guildMock := &mockGuildRepo{membership: &GuildMember{GuildID: 10, CharID: 42, OrderIndex: 5}}
+	server.guildRepo = guildMock
+	ensureGuildService(server)
+	session := createMockSession(1, server)
+
+	handleMsgMhfSetGuildManageRight(session, &mhfpacket.MsgMhfSetGuildManageRight{AckHandle: 1, CharID: 42, Allowed: true})
+	_ = readAck(t, session)
+
+	if len(guildMock.events) != 1 {
+		t.Fatalf("logged %d events, want 1", len(guildMock.events))
+	}
+	e := guildMock.events[0]
+	if e.GuildID != 10 || e.ActorID != 1 || e.TargetCharID != 42 || e.Action != GuildEventRecruiter || e.Detail != "granted" {
+		t.Errorf("event = %+v", e)
+	}
+}
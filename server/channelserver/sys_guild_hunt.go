@@ -0,0 +1,51 @@
+package channelserver
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// guildHuntExpiryTick is how often each channel ends expired clan treasure
+// hunts.
+const guildHuntExpiryTick = 10 * time.Minute
+
+// runGuildHuntExpiry ends clan treasure hunts older than
+// GameplayOptions.TreasureHuntExpiry until shutdown.
+func (s *Server) runGuildHuntExpiry() {
+	if s.guildRepo == nil || s.erupeConfig.GameplayOptions.TreasureHuntExpiry == 0 {
+		return
+	}
+	ticker := time.NewTicker(guildHuntExpiryTick)
+	defer ticker.Stop()
+	for {
+		s.expireGuildHunts(TimeAdjusted())
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireGuildHunts ends the hunts that expired as of now, handing their
+// reporters' souvenirs out as if the hunt had been collected, and deletes
+// hunts once their Partnya cooldown has also run out.
+func (s *Server) expireGuildHunts(now time.Time) {
+	opts := s.erupeConfig.GameplayOptions
+	expiry := time.Duration(opts.TreasureHuntExpiry) * time.Second
+	ended, err := s.guildRepo.EndExpiredHunts(now.Add(-expiry))
+	if err != nil {
+		s.logger.Error("Failed to end expired treasure hunts", zap.Error(err))
+		return
+	}
+	keep := max(expiry, time.Duration(opts.TreasureHuntPartnyaCooldown)*time.Second)
+	purged, err := s.guildRepo.PurgeHunts(now.Add(-keep))
+	if err != nil {
+		s.logger.Error("Failed to purge treasure hunts", zap.Error(err))
+		return
+	}
+	if ended > 0 || purged > 0 {
+		s.logger.Info("Expired treasure hunts", zap.Int("ended", ended), zap.Int("purged", purged))
+	}
+}
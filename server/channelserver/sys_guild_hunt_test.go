@@ -0,0 +1,30 @@
+package channelserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpireGuildHunts(t *testing.T) {
+	server := createMockServer()
+	server.erupeConfig.GameplayOptions.TreasureHuntExpiry = 3600
+	server.erupeConfig.GameplayOptions.TreasureHuntPartnyaCooldown = 7200
+	guildMock := &mockGuildRepo{}
+	server.guildRepo = guildMock
+
+	now := time.Now()
+	server.expireGuildHunts(now)
+	if want := now.Add(-time.Hour); !guildMock.endedBefore.Equal(want) {
+		t.Errorf("ended hunts before %v, want %v", guildMock.endedBefore, want)
+	}
+	if want := now.Add(-2 * time.Hour); !guildMock.purgedBefore.Equal(want) {
+		t.Errorf("purged hunts before %v, want %v", guildMock.purgedBefore, want)
+	}
+
+	// Hunts are never deleted before they expire, whatever the cooldown.
+	server.erupeConfig.GameplayOptions.TreasureHuntPartnyaCooldown = 60
+	server.expireGuildHunts(now)
+	if want := now.Add(-time.Hour); !guildMock.purgedBefore.Equal(want) {
+		t.Errorf("purged hunts before %v, want %v", guildMock.purgedBefore, want)
+	}
+}
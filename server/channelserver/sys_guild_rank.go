@@ -0,0 +1,34 @@
+package channelserver
+
+import (
+	"erupe-ce/common/mhfguild"
+)
+
+// guildRankTable returns the rank table configured for this server.
+func (s *Server) guildRankTable() mhfguild.Table {
+	return mhfguild.ConfiguredTable(s.erupeConfig)
+}
+
+// guildRank returns the rank the guild is shown at, honouring any forced rank.
+func (s *Server) guildRank(g *Guild) uint16 {
+	return g.Rank(s.guildRankTable(), s.erupeConfig.RealClientMode)
+}
+
+// rankChangeStrings returns the guild rank change mail in every locale, with
+// fallback for members whose language has no locale.
+func rankChangeStrings(locales map[string]*i18n, fallback *i18n) RankChangeStrings {
+	text := func(lang *i18n) MailText {
+		return MailText{Subject: lang.guild.rank.title, Body: lang.guild.rank.body}
+	}
+	strings := RankChangeStrings{Fallback: text(fallback), Localized: make(map[string]MailText, len(locales))}
+	for code, lang := range locales {
+		strings.Localized[code] = text(lang)
+	}
+	return strings
+}
+
+// notifyGuildRankChange mails the guild's members and records an audit log
+// entry when its rank moved from oldRank to newRank.
+func (s *Server) notifyGuildRankChange(guild *Guild, oldRank, newRank uint16, actorID uint32) {
+	s.guildService.NotifyRankChange(guild, oldRank, newRank, actorID, rankChangeStrings(s.locales, &s.i18n))
+}
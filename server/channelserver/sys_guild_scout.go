@@ -0,0 +1,52 @@
+package channelserver
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// guildScoutExpiryTick is how often each channel withdraws expired guild
+// scout invitations.
+const guildScoutExpiryTick = 10 * time.Minute
+
+// runGuildScoutExpiry withdraws guild scout invitations left unanswered for
+// Guild.Scouts.ExpiryHours until shutdown.
+func (s *Server) runGuildScoutExpiry() {
+	if s.guildRepo == nil || s.erupeConfig.Guild.Scouts.ExpiryHours <= 0 {
+		return
+	}
+	ticker := time.NewTicker(guildScoutExpiryTick)
+	defer ticker.Stop()
+	for {
+		s.expireGuildScouts(time.Now())
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireGuildScouts withdraws the invitations that expired as of now and
+// mails each sender, in their own language, that the invitation lapsed.
+func (s *Server) expireGuildScouts(now time.Time) {
+	before := now.Add(-time.Duration(s.erupeConfig.Guild.Scouts.ExpiryHours) * time.Hour)
+	scouts, err := s.guildRepo.ExpireInvitations(before)
+	if err != nil {
+		s.logger.Error("Failed to expire guild scouts", zap.Error(err))
+		return
+	}
+	for _, sc := range scouts {
+		expired := s.langForChar(sc.ActorID).guild.invite.expired
+		if err := s.mailService.SendSystem(sc.ActorID, expired.title,
+			fmt.Sprintf(expired.body, sc.CharName, sc.GuildName)); err != nil {
+			s.logger.Warn("Failed to send guild scout expiry mail",
+				zap.Uint32("charID", sc.ActorID), zap.Error(err))
+		}
+	}
+	if len(scouts) > 0 {
+		s.logger.Info("Expired guild scouts", zap.Int("invitations", len(scouts)))
+	}
+}
@@ -0,0 +1,42 @@
+package channelserver
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpireGuildScouts(t *testing.T) {
+	server := createMockServer()
+	server.erupeConfig.Guild.Scouts.ExpiryHours = 72
+	guildMock := &mockGuildRepo{expiredScouts: []*ExpiredScout{
+		{GuildID: 10, GuildName: "Hunters", CharID: 42, CharName: "Target", ActorID: 1},
+	}}
+	mailMock := &mockMailRepo{}
+	server.guildRepo = guildMock
+	server.mailRepo = mailMock
+	server.charRepo = newMockCharacterRepo()
+	ensureMailService(server)
+
+	now := time.Now()
+	server.expireGuildScouts(now)
+	if want := now.Add(-72 * time.Hour); !guildMock.expireBefore.Equal(want) {
+		t.Errorf("expired before %v, want %v", guildMock.expireBefore, want)
+	}
+	if len(mailMock.sentMails) != 1 {
+		t.Fatalf("sent %d mails, want 1", len(mailMock.sentMails))
+	}
+	m := mailMock.sentMails[0]
+	if m.recipientID != 1 || !m.isSystemMessage {
+		t.Errorf("mail = %+v, want a system mail to the sender", m)
+	}
+	if !strings.Contains(m.body, "Target") || !strings.Contains(m.body, "Hunters") {
+		t.Errorf("body = %q, want the target and guild names", m.body)
+	}
+
+	// Another channel sweeping the same invitations finds nothing left.
+	server.expireGuildScouts(now)
+	if len(mailMock.sentMails) != 1 {
+		t.Errorf("sent %d mails after second sweep, want 1", len(mailMock.sentMails))
+	}
+}
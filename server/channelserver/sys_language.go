@@ -1,6 +1,33 @@
 package channelserver
 
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	cfg "erupe-ce/config"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+//go:embed locales/*.json
+var builtinLocaleFiles embed.FS
+
+// defaultLocale is the locale every other locale falls back to for strings it
+// does not define.
+const defaultLocale = "en"
+
+// i18n holds every server-originated string in one language. Locales are
+// loaded from JSON or TOML files whose nested keys mirror the struct fields,
+// e.g. {"commands": {"timer": {"enabled": "..."}}}.
 type i18n struct {
+	code     string // Locale code, i.e. the file name without extension
 	language string
 	cafe     struct {
 		reset string
@@ -52,6 +79,20 @@ type i18n struct {
 			enabled  string
 			disabled string
 		}
+		lang struct {
+			current string
+			success string
+			error   string
+		}
+		announce struct {
+			error    string
+			list     string
+			empty    string
+			created  string
+			deleted  string
+			notFound string
+			invalid  string
+		}
 		ravi struct {
 			noCommand string
 			start     struct {
@@ -98,144 +139,266 @@ type i18n struct {
 				title string
 				body  string
 			}
+			expired struct {
+				title string
+				body  string
+			}
+		}
+		rank struct {
+			title string
+			body  string
+		}
+	}
+}
+
+// fields maps each locale file key to the string it populates.
+func (i *i18n) fields() map[string]*string {
+	return map[string]*string{
+		"language":                    &i.language,
+		"cafe.reset":                  &i.cafe.reset,
+		"timer":                       &i.timer,
+		"commands.noOp":               &i.commands.noOp,
+		"commands.disabled":           &i.commands.disabled,
+		"commands.reload":             &i.commands.reload,
+		"commands.playtime":           &i.commands.playtime,
+		"commands.kqf.get":            &i.commands.kqf.get,
+		"commands.kqf.set.error":      &i.commands.kqf.set.error,
+		"commands.kqf.set.success":    &i.commands.kqf.set.success,
+		"commands.kqf.version":        &i.commands.kqf.version,
+		"commands.rights.error":       &i.commands.rights.error,
+		"commands.rights.success":     &i.commands.rights.success,
+		"commands.course.error":       &i.commands.course.error,
+		"commands.course.disabled":    &i.commands.course.disabled,
+		"commands.course.enabled":     &i.commands.course.enabled,
+		"commands.course.locked":      &i.commands.course.locked,
+		"commands.teleport.error":     &i.commands.teleport.error,
+		"commands.teleport.success":   &i.commands.teleport.success,
+		"commands.psn.error":          &i.commands.psn.error,
+		"commands.psn.success":        &i.commands.psn.success,
+		"commands.psn.exists":         &i.commands.psn.exists,
+		"commands.discord.success":    &i.commands.discord.success,
+		"commands.ban.success":        &i.commands.ban.success,
+		"commands.ban.noUser":         &i.commands.ban.noUser,
+		"commands.ban.invalid":        &i.commands.ban.invalid,
+		"commands.ban.error":          &i.commands.ban.error,
+		"commands.ban.length":         &i.commands.ban.length,
+		"commands.timer.enabled":      &i.commands.timer.enabled,
+		"commands.timer.disabled":     &i.commands.timer.disabled,
+		"commands.lang.current":       &i.commands.lang.current,
+		"commands.lang.success":       &i.commands.lang.success,
+		"commands.lang.error":         &i.commands.lang.error,
+		"commands.announce.error":     &i.commands.announce.error,
+		"commands.announce.list":      &i.commands.announce.list,
+		"commands.announce.empty":     &i.commands.announce.empty,
+		"commands.announce.created":   &i.commands.announce.created,
+		"commands.announce.deleted":   &i.commands.announce.deleted,
+		"commands.announce.notFound":  &i.commands.announce.notFound,
+		"commands.announce.invalid":   &i.commands.announce.invalid,
+		"commands.ravi.noCommand":     &i.commands.ravi.noCommand,
+		"commands.ravi.start.success": &i.commands.ravi.start.success,
+		"commands.ravi.start.error":   &i.commands.ravi.start.error,
+		"commands.ravi.multiplier":    &i.commands.ravi.multiplier,
+		"commands.ravi.res.success":   &i.commands.ravi.res.success,
+		"commands.ravi.res.error":     &i.commands.ravi.res.error,
+		"commands.ravi.sed.success":   &i.commands.ravi.sed.success,
+		"commands.ravi.request":       &i.commands.ravi.request,
+		"commands.ravi.error":         &i.commands.ravi.error,
+		"commands.ravi.noPlayers":     &i.commands.ravi.noPlayers,
+		"commands.ravi.version":       &i.commands.ravi.version,
+		"raviente.berserk":            &i.raviente.berserk,
+		"raviente.extreme":            &i.raviente.extreme,
+		"raviente.extremeLimited":     &i.raviente.extremeLimited,
+		"raviente.berserkSmall":       &i.raviente.berserkSmall,
+		"guild.invite.title":          &i.guild.invite.title,
+		"guild.invite.body":           &i.guild.invite.body,
+		"guild.invite.success.title":  &i.guild.invite.success.title,
+		"guild.invite.success.body":   &i.guild.invite.success.body,
+		"guild.invite.accepted.title": &i.guild.invite.accepted.title,
+		"guild.invite.accepted.body":  &i.guild.invite.accepted.body,
+		"guild.invite.rejected.title": &i.guild.invite.rejected.title,
+		"guild.invite.rejected.body":  &i.guild.invite.rejected.body,
+		"guild.invite.declined.title": &i.guild.invite.declined.title,
+		"guild.invite.declined.body":  &i.guild.invite.declined.body,
+		"guild.invite.expired.title":  &i.guild.invite.expired.title,
+		"guild.invite.expired.body":   &i.guild.invite.expired.body,
+		"guild.rank.title":            &i.guild.rank.title,
+		"guild.rank.body":             &i.guild.rank.body,
+	}
+}
+
+// apply overwrites the strings named in values. Keys are matched
+// case-insensitively since Viper lowercases them while reading.
+func (i *i18n) apply(values map[string]string) {
+	fields := make(map[string]*string)
+	for key, field := range i.fields() {
+		fields[strings.ToLower(key)] = field
+	}
+	for key, value := range values {
+		if field, ok := fields[strings.ToLower(key)]; ok {
+			*field = value
+		}
+	}
+}
+
+// readLocale parses a JSON or TOML locale into a flat map of dotted keys.
+func readLocale(v *viper.Viper) map[string]string {
+	values := make(map[string]string)
+	for _, key := range v.AllKeys() {
+		values[key] = v.GetString(key)
+	}
+	return values
+}
+
+// loadLocales reads the built-in locales and then every .json or .toml file
+// in dir, keyed by file name without extension. Files in dir override the
+// built-in strings key by key, and any key a locale omits falls back to
+// English. A missing dir is not an error.
+func loadLocales(dir string) (map[string]*i18n, error) {
+	raw := make(map[string]map[string]string)
+	merge := func(code string, values map[string]string) {
+		if raw[code] == nil {
+			raw[code] = make(map[string]string)
+		}
+		for k, v := range values {
+			raw[code][k] = v
+		}
+	}
+
+	entries, err := builtinLocaleFiles.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		data, err := builtinLocaleFiles.ReadFile("locales/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		v := viper.New()
+		v.SetConfigType("json")
+		if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("built-in locale %s: %w", entry.Name(), err)
+		}
+		merge(strings.TrimSuffix(entry.Name(), ".json"), readLocale(v))
+	}
+
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if entry.IsDir() || (ext != ".json" && ext != ".toml") {
+				continue
+			}
+			v := viper.New()
+			v.SetConfigFile(filepath.Join(dir, entry.Name()))
+			if err := v.ReadInConfig(); err != nil {
+				return nil, fmt.Errorf("locale %s: %w", entry.Name(), err)
+			}
+			merge(strings.TrimSuffix(entry.Name(), ext), readLocale(v))
+		}
+	}
+
+	var base i18n
+	base.apply(raw[defaultLocale])
+	locales := make(map[string]*i18n, len(raw))
+	for code, values := range raw {
+		locale := base
+		locale.code = code
+		locale.apply(values)
+		locales[code] = &locale
+	}
+	return locales, nil
+}
+
+var (
+	builtinLocalesOnce sync.Once
+	builtinLocales     map[string]*i18n
+)
+
+// getBuiltinLocales returns the locales embedded in the binary.
+func getBuiltinLocales() map[string]*i18n {
+	builtinLocalesOnce.Do(func() {
+		var err error
+		builtinLocales, err = loadLocales("")
+		if err != nil {
+			panic(err)
 		}
+	})
+	return builtinLocales
+}
+
+// localeCodes returns the sorted codes of the server's locales.
+func (s *Server) localeCodes() []string {
+	codes := make([]string, 0, len(s.locales))
+	for code := range s.locales {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// langFor returns the strings for the given locale code, or the server's
+// default language if the code is empty or unknown.
+func (s *Server) langFor(code string) *i18n {
+	if locale, ok := s.locales[code]; ok {
+		return locale
 	}
+	return &s.i18n
+}
+
+// langForChar returns the preferred language of a character who may be
+// offline.
+func (s *Server) langForChar(charID uint32) *i18n {
+	if s.Registry != nil {
+		if session := s.FindSessionByCharID(charID); session != nil {
+			return session.lang()
+		}
+	}
+	code, _ := s.charRepo.ReadString(charID, "language")
+	return s.langFor(code)
+}
+
+// languageCode returns the session's preferred locale code. It is safe to
+// call from any goroutine.
+func (s *Session) languageCode() string {
+	code, _ := s.language.Load().(string)
+	return code
+}
+
+// setLanguage sets the session's preferred locale code.
+func (s *Session) setLanguage(code string) {
+	s.language.Store(code)
+}
+
+// lang returns the strings for the session's preferred language.
+func (s *Session) lang() *i18n {
+	return s.server.langFor(s.languageCode())
 }
 
 func getLangStrings(s *Server) i18n {
-	var i i18n
-	switch s.erupeConfig.Language {
-	case "jp":
-		i.language = "日本語"
-		i.cafe.reset = "%d/%dにリセット"
-		i.timer = "タイマー：%02d'%02d\"%02d.%03d (%df)"
-
-		i.commands.noOp = "You don't have permission to use this command"
-		i.commands.disabled = "%sのコマンドは無効です"
-		i.commands.reload = "リロードします"
-		i.commands.kqf.get = "現在のキークエストフラグ：%x"
-		i.commands.kqf.set.error = "キークエコマンドエラー　例：%s set xxxxxxxxxxxxxxxx"
-		i.commands.kqf.set.success = "キークエストのフラグが更新されました。ワールド／ランドを移動してください"
-		i.commands.kqf.version = "This command is disabled prior to MHFG10"
-		i.commands.rights.error = "コース更新コマンドエラー　例：%s x"
-		i.commands.rights.success = "コース情報を更新しました：%d"
-		i.commands.course.error = "コース確認コマンドエラー　例：%s <name>"
-		i.commands.course.disabled = "%sコースは無効です"
-		i.commands.course.enabled = "%sコースは有効です"
-		i.commands.course.locked = "%sコースはロックされています"
-		i.commands.teleport.error = "テレポートコマンドエラー　構文：%s x y"
-		i.commands.teleport.success = "%d %dにテレポート"
-		i.commands.psn.error = "PSN連携コマンドエラー　例：%s <psn id>"
-		i.commands.psn.success = "PSN「%s」が連携されています"
-		i.commands.psn.exists = "PSNは既存のユーザに接続されています"
-
-		i.commands.discord.success = "あなたのDiscordトークン：%s"
-
-		i.commands.ban.noUser = "Could not find user"
-		i.commands.ban.success = "Successfully banned %s"
-		i.commands.ban.invalid = "Invalid Character ID"
-		i.commands.ban.error = "Error in command. Format: %s <id> [length]"
-		i.commands.ban.length = " until %s"
-
-		i.commands.ravi.noCommand = "ラヴィコマンドが指定されていません"
-		i.commands.ravi.start.success = "大討伐を開始します"
-		i.commands.ravi.start.error = "大討伐は既に開催されています"
-		i.commands.ravi.multiplier = "ラヴィダメージ倍率：ｘ%.2f"
-		i.commands.ravi.res.success = "復活支援を実行します"
-		i.commands.ravi.res.error = "復活支援は実行されませんでした"
-		i.commands.ravi.sed.success = "鎮静支援を実行します"
-		i.commands.ravi.request = "鎮静支援を要請します"
-		i.commands.ravi.error = "ラヴィコマンドが認識されません"
-		i.commands.ravi.noPlayers = "誰も大討伐に参加していません"
-		i.commands.ravi.version = "This command is disabled outside of MHFZZ"
-
-		i.raviente.berserk = "<大討伐：猛狂期>が開催されました！"
-		i.raviente.extreme = "<大討伐：猛狂期【極】>が開催されました！"
-		i.raviente.extremeLimited = "<大討伐：猛狂期【極】(制限付)>が開催されました！"
-		i.raviente.berserkSmall = "<大討伐：猛狂期(小数)>が開催されました！"
-
-		i.guild.invite.title = "猟団勧誘のご案内"
-		i.guild.invite.body = "猟団「%s」からの勧誘通知です。\n「勧誘に返答」より、返答を行ってください。"
-
-		i.guild.invite.success.title = "成功"
-		i.guild.invite.success.body = "あなたは「%s」に参加できました。"
-
-		i.guild.invite.accepted.title = "承諾されました"
-		i.guild.invite.accepted.body = "招待した狩人が「%s」への招待を承諾しました。"
-
-		i.guild.invite.rejected.title = "却下しました"
-		i.guild.invite.rejected.body = "あなたは「%s」への参加を却下しました。"
-
-		i.guild.invite.declined.title = "辞退しました"
-		i.guild.invite.declined.body = "招待した狩人が「%s」への招待を辞退しました。"
-	default:
-		i.language = "English"
-		i.cafe.reset = "Resets on %d/%d"
-		i.timer = "Time: %02d:%02d:%02d.%03d (%df)"
-
-		i.commands.noOp = "You don't have permission to use this command"
-		i.commands.disabled = "%s command is disabled"
-		i.commands.reload = "Reloading players..."
-		i.commands.playtime = "Playtime: %d hours %d minutes %d seconds"
-
-		i.commands.kqf.get = "KQF: %x"
-		i.commands.kqf.set.error = "Error in command. Format: %s set xxxxxxxxxxxxxxxx"
-		i.commands.kqf.set.success = "KQF set, please switch Land/World"
-		i.commands.kqf.version = "This command is disabled prior to MHFG10"
-		i.commands.rights.error = "Error in command. Format: %s x"
-		i.commands.rights.success = "Set rights integer: %d"
-		i.commands.course.error = "Error in command. Format: %s <name>"
-		i.commands.course.disabled = "%s Course disabled"
-		i.commands.course.enabled = "%s Course enabled"
-		i.commands.course.locked = "%s Course is locked"
-		i.commands.teleport.error = "Error in command. Format: %s x y"
-		i.commands.teleport.success = "Teleporting to %d %d"
-		i.commands.psn.error = "Error in command. Format: %s <psn id>"
-		i.commands.psn.success = "Connected PSN ID: %s"
-		i.commands.psn.exists = "PSN ID is connected to another account!"
-
-		i.commands.discord.success = "Your Discord token: %s"
-
-		i.commands.ban.noUser = "Could not find user"
-		i.commands.ban.success = "Successfully banned %s"
-		i.commands.ban.invalid = "Invalid Character ID"
-		i.commands.ban.error = "Error in command. Format: %s <id> [length]"
-		i.commands.ban.length = " until %s"
-
-		i.commands.timer.enabled = "Quest timer enabled"
-		i.commands.timer.disabled = "Quest timer disabled"
-
-		i.commands.ravi.noCommand = "No Raviente command specified!"
-		i.commands.ravi.start.success = "The Great Slaying will begin in a moment"
-		i.commands.ravi.start.error = "The Great Slaying has already begun!"
-		i.commands.ravi.multiplier = "Raviente multiplier is currently %.2fx"
-		i.commands.ravi.res.success = "Sending resurrection support!"
-		i.commands.ravi.res.error = "Resurrection support has not been requested!"
-		i.commands.ravi.sed.success = "Sending sedation support if requested!"
-		i.commands.ravi.request = "Requesting sedation support!"
-		i.commands.ravi.error = "Raviente command not recognised!"
-		i.commands.ravi.noPlayers = "No one has joined the Great Slaying!"
-		i.commands.ravi.version = "This command is disabled outside of MHFZZ"
-
-		i.raviente.berserk = "<Great Slaying: Berserk> is being held!"
-		i.raviente.extreme = "<Great Slaying: Extreme> is being held!"
-		i.raviente.extremeLimited = "<Great Slaying: Extreme (Limited)> is being held!"
-		i.raviente.berserkSmall = "<Great Slaying: Berserk (Small)> is being held!"
-
-		i.guild.invite.title = "Invitation!"
-		i.guild.invite.body = "You have been invited to join\n「%s」\nDo you want to accept?"
-
-		i.guild.invite.success.title = "Success!"
-		i.guild.invite.success.body = "You have successfully joined\n「%s」."
-
-		i.guild.invite.accepted.title = "Accepted"
-		i.guild.invite.accepted.body = "The recipient accepted your invitation to join\n「%s」."
-
-		i.guild.invite.rejected.title = "Rejected"
-		i.guild.invite.rejected.body = "You rejected the invitation to join\n「%s」."
-
-		i.guild.invite.declined.title = "Declined"
-		i.guild.invite.declined.body = "The recipient declined your invitation to join\n「%s」."
-	}
-	return i
+	if s.locales == nil {
+		s.locales = getBuiltinLocales()
+	}
+	return defaultLang(s.locales, s.erupeConfig.Language)
+}
+
+// defaultLang returns the locale for code, or English if there is none.
+func defaultLang(locales map[string]*i18n, code string) i18n {
+	if locale, ok := locales[code]; ok {
+		return *locale
+	}
+	return *locales[defaultLocale]
+}
+
+// loadConfiguredLocales loads the locales in LocalesPath, falling back to the
+// built-in ones on error, and returns them with the configured default.
+func loadConfiguredLocales(config *cfg.Config, logger *zap.Logger) (map[string]*i18n, i18n) {
+	locales, err := loadLocales(config.LocalesPath)
+	if err != nil {
+		logger.Error("Failed to load locales, using built-in strings", zap.Error(err))
+		locales = getBuiltinLocales()
+	}
+	return locales, defaultLang(locales, config.Language)
 }
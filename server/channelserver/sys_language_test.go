@@ -1,6 +1,9 @@
 package channelserver
 
 import (
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	cfg "erupe-ce/config"
@@ -92,3 +95,146 @@ func TestGetLangStrings_EmptyLanguage(t *testing.T) {
 		t.Errorf("Empty language should default to English, got %q", lang.language)
 	}
 }
+
+func TestLoadLocales_Builtin(t *testing.T) {
+	locales, err := loadLocales("")
+	if err != nil {
+		t.Fatalf("loadLocales() error = %v", err)
+	}
+	for _, code := range []string{"en", "jp"} {
+		locale, ok := locales[code]
+		if !ok {
+			t.Fatalf("built-in locale %q missing", code)
+		}
+		if locale.code != code {
+			t.Errorf("code = %q, want %q", locale.code, code)
+		}
+		for key, field := range locale.fields() {
+			if *field == "" {
+				t.Errorf("%s: %s should not be empty", code, key)
+			}
+		}
+	}
+	// Keys missing from jp.json fall back to English.
+	if locales["jp"].commands.timer.enabled != locales["en"].commands.timer.enabled {
+		t.Error("jp should fall back to English for commands.timer.enabled")
+	}
+}
+
+func TestLoadLocales_Directory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"en.json":    `{"commands": {"reload": "Reloading!"}}`,
+		"fr.toml":    "language = \"Français\"\n[cafe]\nreset = \"Réinitialisation le %d/%d\"\n",
+		"notes.txt":  "ignored",
+		"unknown.js": "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	locales, err := loadLocales(dir)
+	if err != nil {
+		t.Fatalf("loadLocales() error = %v", err)
+	}
+	if len(locales) != 3 {
+		t.Errorf("len(locales) = %d, want 3", len(locales))
+	}
+	if got := locales["en"].commands.reload; got != "Reloading!" {
+		t.Errorf("en commands.reload = %q, want override", got)
+	}
+	if got := locales["en"].language; got != "English" {
+		t.Errorf("en language = %q, want built-in value kept", got)
+	}
+	fr := locales["fr"]
+	if fr == nil {
+		t.Fatal("fr locale missing")
+	}
+	if fr.language != "Français" || fr.cafe.reset != "Réinitialisation le %d/%d" {
+		t.Errorf("fr strings not loaded: %q, %q", fr.language, fr.cafe.reset)
+	}
+	if fr.commands.reload != "Reloading!" {
+		t.Errorf("fr should fall back to the overridden English, got %q", fr.commands.reload)
+	}
+	if locales["jp"].commands.reload == "Reloading!" {
+		t.Error("jp should keep its own commands.reload")
+	}
+}
+
+func TestLoadLocales_MissingDirectory(t *testing.T) {
+	locales, err := loadLocales(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("loadLocales() error = %v", err)
+	}
+	if _, ok := locales["en"]; !ok {
+		t.Error("built-in locales should still load")
+	}
+}
+
+func TestLoadLocales_InvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadLocales(dir); err == nil {
+		t.Error("loadLocales() should fail on malformed files")
+	}
+}
+
+func TestSessionLang(t *testing.T) {
+	server := createMockServer()
+	session := createMockSession(1, server)
+
+	if got := session.lang(); got != &server.i18n {
+		t.Error("empty preference should use the server default")
+	}
+	session.setLanguage("jp")
+	if got := session.lang().language; got != "日本語" {
+		t.Errorf("lang().language = %q, want 日本語", got)
+	}
+	session.setLanguage("xx")
+	if got := session.lang(); got != &server.i18n {
+		t.Error("unknown preference should use the server default")
+	}
+}
+
+func TestSessionLang_ConcurrentAccess(t *testing.T) {
+	server := createMockServer()
+	session := createMockSession(1, server)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			session.setLanguage([]string{"en", "jp"}[i%2])
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = session.lang()
+		}
+	}()
+	wg.Wait()
+}
+
+func TestLangForChar(t *testing.T) {
+	server := createMockServer()
+	charRepo := newMockCharacterRepo()
+	server.charRepo = charRepo
+
+	charRepo.strings["language"] = "jp"
+	if got := server.langForChar(99).language; got != "日本語" {
+		t.Errorf("offline character language = %q, want 日本語", got)
+	}
+
+	session := createMockSession(1, server)
+	session.setLanguage("en")
+	server.sessions[session.rawConn] = session
+	if got := server.langForChar(1).language; got != "English" {
+		t.Errorf("online character language = %q, want English", got)
+	}
+}
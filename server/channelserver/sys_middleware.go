@@ -0,0 +1,280 @@
+package channelserver
+
+import (
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"erupe-ce/network"
+	"erupe-ce/network/mhfpacket"
+
+	"go.uber.org/zap"
+)
+
+// HandlerFunc handles a parsed packet for a session.
+type HandlerFunc = handlerFunc
+
+// Middleware wraps the handler for an opcode. It may run code before and
+// after calling next, or not call it at all to reject the packet.
+type Middleware func(opcode network.PacketID, next HandlerFunc) HandlerFunc
+
+// handlerChain composes middleware around the handler table. Dispatch reads
+// an immutable compiled table, so handlers can be wrapped or replaced while
+// sessions are running.
+type handlerChain struct {
+	mu        sync.Mutex
+	global    []Middleware
+	perOpcode map[network.PacketID][]Middleware
+	overrides map[network.PacketID]HandlerFunc
+	compiled  atomic.Pointer[map[network.PacketID]handlerFunc]
+}
+
+// Use wraps every handler in the given middleware. Middleware added earlier
+// runs first.
+func (s *Server) Use(mw ...Middleware) {
+	s.handlers.mu.Lock()
+	defer s.handlers.mu.Unlock()
+	s.handlers.global = append(s.handlers.global, mw...)
+	s.compileHandlers()
+}
+
+// WrapHandler wraps the handler for a single opcode. Opcode middleware runs
+// inside the middleware added with Use.
+func (s *Server) WrapHandler(opcode network.PacketID, mw Middleware) {
+	s.handlers.mu.Lock()
+	defer s.handlers.mu.Unlock()
+	if s.handlers.perOpcode == nil {
+		s.handlers.perOpcode = make(map[network.PacketID][]Middleware)
+	}
+	s.handlers.perOpcode[opcode] = append(s.handlers.perOpcode[opcode], mw)
+	s.compileHandlers()
+}
+
+// SetHandler replaces the handler for an opcode. Middleware still applies.
+func (s *Server) SetHandler(opcode network.PacketID, h HandlerFunc) {
+	s.handlers.mu.Lock()
+	defer s.handlers.mu.Unlock()
+	if s.handlers.overrides == nil {
+		s.handlers.overrides = make(map[network.PacketID]HandlerFunc)
+	}
+	s.handlers.overrides[opcode] = h
+	s.compileHandlers()
+}
+
+// compileHandlers rebuilds the dispatch table. Callers hold handlers.mu.
+func (s *Server) compileHandlers() {
+	table := make(map[network.PacketID]handlerFunc, len(s.handlerTable)+len(s.handlers.overrides))
+	for opcode, h := range s.handlerTable {
+		table[opcode] = h
+	}
+	for opcode, h := range s.handlers.overrides {
+		table[opcode] = h
+	}
+	for opcode, h := range table {
+		mws := s.handlers.perOpcode[opcode]
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i](opcode, h)
+		}
+		for i := len(s.handlers.global) - 1; i >= 0; i-- {
+			h = s.handlers.global[i](opcode, h)
+		}
+		table[opcode] = h
+	}
+	s.handlers.compiled.Store(&table)
+}
+
+// handler returns the handler to dispatch opcode to, with middleware applied.
+func (s *Server) handler(opcode network.PacketID) (handlerFunc, bool) {
+	table := s.handlers.compiled.Load()
+	if table == nil {
+		h, ok := s.handlerTable[opcode]
+		return h, ok
+	}
+	h, ok := (*table)[opcode]
+	return h, ok
+}
+
+// defaultMiddleware returns the middleware installed on every channel server.
+func (s *Server) defaultMiddleware() []Middleware {
+	mws := []Middleware{s.recoverMiddleware, loginMiddleware, rateLimitMiddleware}
+	if len(s.erupeConfig.DebugOptions.TraceOpcodes) > 0 {
+		mws = append(mws, s.traceMiddleware(s.erupeConfig.DebugOptions.TraceOpcodes))
+	}
+	return append(mws, s.timingMiddleware)
+}
+
+// HandlerStat summarises the calls to one opcode's handler.
+type HandlerStat struct {
+	Calls  uint64
+	Total  time.Duration
+	Max    time.Duration
+	Panics uint64
+}
+
+// handlerStat holds the live counters behind HandlerStat.
+type handlerStat struct {
+	calls  atomic.Uint64
+	total  atomic.Int64
+	max    atomic.Int64
+	panics atomic.Uint64
+}
+
+// HandlerStats returns timing and panic counts for every opcode handled so far.
+func (s *Server) HandlerStats() map[network.PacketID]HandlerStat {
+	stats := make(map[network.PacketID]HandlerStat)
+	for i := range s.handlerStats {
+		st := &s.handlerStats[i]
+		if st.calls.Load() == 0 && st.panics.Load() == 0 {
+			continue
+		}
+		stats[network.PacketID(i)] = HandlerStat{
+			Calls:  st.calls.Load(),
+			Total:  time.Duration(st.total.Load()),
+			Max:    time.Duration(st.max.Load()),
+			Panics: st.panics.Load(),
+		}
+	}
+	return stats
+}
+
+// statFor returns the counters for opcode, or nil if it is out of range.
+func (s *Server) statFor(opcode network.PacketID) *handlerStat {
+	if int(opcode) >= len(s.handlerStats) {
+		return nil
+	}
+	return &s.handlerStats[opcode]
+}
+
+// recoverMiddleware keeps a panicking handler from killing the session's
+// receive loop, logging the opcode and stack.
+func (s *Server) recoverMiddleware(opcode network.PacketID, next HandlerFunc) HandlerFunc {
+	return func(sess *Session, p mhfpacket.MHFPacket) {
+		defer func() {
+			if r := recover(); r != nil {
+				if st := s.statFor(opcode); st != nil {
+					st.panics.Add(1)
+				}
+				sess.logger.Error("Handler panicked",
+					zap.Stringer("opcode", opcode),
+					zap.Uint32("charID", sess.charID),
+					zap.Any("panic", r),
+					zap.ByteString("stack", debug.Stack()),
+				)
+			}
+		}()
+		next(sess, p)
+	}
+}
+
+// timingMiddleware records handler durations and logs handlers slower than
+// DebugOptions.SlowHandlerThreshold.
+func (s *Server) timingMiddleware(opcode network.PacketID, next HandlerFunc) HandlerFunc {
+	st := s.statFor(opcode)
+	if st == nil {
+		return next
+	}
+	return func(sess *Session, p mhfpacket.MHFPacket) {
+		start := time.Now()
+		next(sess, p)
+		elapsed := time.Since(start)
+		st.calls.Add(1)
+		st.total.Add(int64(elapsed))
+		for {
+			cur := st.max.Load()
+			if int64(elapsed) <= cur || st.max.CompareAndSwap(cur, int64(elapsed)) {
+				break
+			}
+		}
+		threshold := time.Duration(s.erupeConfig.DebugOptions.SlowHandlerThreshold) * time.Millisecond
+		if threshold > 0 && elapsed >= threshold {
+			sess.logger.Warn("Slow packet handler",
+				zap.Stringer("opcode", opcode),
+				zap.Uint32("charID", sess.charID),
+				zap.Duration("elapsed", elapsed),
+			)
+		}
+	}
+}
+
+// loginMiddleware rejects MSG_MHF_* packets from sessions that have not
+// logged in a character with MsgSysLogin, failing their ACK if they expect
+// one.
+func loginMiddleware(opcode network.PacketID, next HandlerFunc) HandlerFunc {
+	if !strings.HasPrefix(opcode.String(), "MSG_MHF_") {
+		return next
+	}
+	return func(s *Session, p mhfpacket.MHFPacket) {
+		if s.charID == 0 {
+			s.logger.Warn("Rejected packet before login", zap.Stringer("opcode", opcode))
+			rejectPacket(s, p)
+			return
+		}
+		next(s, p)
+	}
+}
+
+// rejectPacket answers a packet that middleware refuses to handle with a
+// fail ACK, so a client waiting on its AckHandle does not hang. Packets
+// without an AckHandle are dropped silently.
+func rejectPacket(s *Session, p mhfpacket.MHFPacket) {
+	if ackHandle, ok := packetAckHandle(p); ok {
+		doAckSimpleFail(s, ackHandle, make([]byte, 4))
+	}
+}
+
+// packetAckHandle returns the AckHandle field of a parsed packet. Packet
+// structs declare the field individually, so it is looked up by name.
+func packetAckHandle(p mhfpacket.MHFPacket) (uint32, bool) {
+	v := reflect.ValueOf(p)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return 0, false
+	}
+	f := v.FieldByName("AckHandle")
+	if !f.IsValid() || f.Kind() != reflect.Uint32 {
+		return 0, false
+	}
+	return uint32(f.Uint()), true
+}
+
+// traceMiddleware logs the parsed contents of the named opcodes.
+func (s *Server) traceMiddleware(names []string) Middleware {
+	traced := make(map[network.PacketID]bool, len(names))
+	for _, name := range names {
+		if opcode, ok := network.PacketIDFromString(name); ok {
+			traced[opcode] = true
+		} else {
+			s.logger.Warn("Unknown opcode in TraceOpcodes", zap.String("opcode", name))
+		}
+	}
+	return func(opcode network.PacketID, next HandlerFunc) HandlerFunc {
+		if !traced[opcode] {
+			return next
+		}
+		return func(sess *Session, p mhfpacket.MHFPacket) {
+			sess.logger.Info("Packet trace",
+				zap.Stringer("opcode", opcode),
+				zap.Uint32("charID", sess.charID),
+				zap.String("name", sess.Name),
+				zap.Any("packet", p),
+			)
+			next(sess, p)
+		}
+	}
+}
+
+// Observe returns middleware that calls fn with every packet before it is
+// handled, for capture and inspection hooks.
+func Observe(fn func(s *Session, p mhfpacket.MHFPacket)) Middleware {
+	return func(_ network.PacketID, next HandlerFunc) HandlerFunc {
+		return func(s *Session, p mhfpacket.MHFPacket) {
+			fn(s, p)
+			next(s, p)
+		}
+	}
+}
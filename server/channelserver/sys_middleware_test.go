@@ -0,0 +1,247 @@
+package channelserver
+
+import (
+	"testing"
+	"time"
+
+	cfg "erupe-ce/config"
+	"erupe-ce/network"
+	"erupe-ce/network/mhfpacket"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newMiddlewareTestServer returns a server whose handler table records the
+// opcodes it handles.
+func newMiddlewareTestServer(handled *[]network.PacketID) *Server {
+	server := createMockServer()
+	record := func(opcode network.PacketID) handlerFunc {
+		return func(*Session, mhfpacket.MHFPacket) { *handled = append(*handled, opcode) }
+	}
+	server.handlerTable = map[network.PacketID]handlerFunc{
+		network.MSG_SYS_PING:     record(network.MSG_SYS_PING),
+		network.MSG_MHF_LOADDATA: record(network.MSG_MHF_LOADDATA),
+	}
+	return server
+}
+
+func TestHandler_NoMiddleware(t *testing.T) {
+	var handled []network.PacketID
+	server := newMiddlewareTestServer(&handled)
+
+	h, ok := server.handler(network.MSG_SYS_PING)
+	if !ok {
+		t.Fatal("handler not found")
+	}
+	h(createMockSession(1, server), nil)
+	if len(handled) != 1 {
+		t.Errorf("handled = %v, want one call", handled)
+	}
+	if _, ok := server.handler(network.MSG_SYS_TIME); ok {
+		t.Error("found handler for opcode missing from the table")
+	}
+}
+
+func TestUse_Order(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(_ network.PacketID, next HandlerFunc) HandlerFunc {
+			return func(s *Session, p mhfpacket.MHFPacket) {
+				calls = append(calls, name+" before")
+				next(s, p)
+				calls = append(calls, name+" after")
+			}
+		}
+	}
+	var handled []network.PacketID
+	server := newMiddlewareTestServer(&handled)
+	server.Use(trace("outer"), trace("middle"))
+	server.WrapHandler(network.MSG_SYS_PING, trace("opcode"))
+
+	h, _ := server.handler(network.MSG_SYS_PING)
+	h(createMockSession(1, server), nil)
+
+	want := []string{"outer before", "middle before", "opcode before", "opcode after", "middle after", "outer after"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("calls[%d] = %q, want %q", i, calls[i], want[i])
+		}
+	}
+
+	calls = nil
+	h, _ = server.handler(network.MSG_MHF_LOADDATA)
+	h(createMockSession(1, server), nil)
+	if len(calls) != 4 {
+		t.Errorf("opcode middleware applied to another opcode: %v", calls)
+	}
+}
+
+func TestSetHandler(t *testing.T) {
+	var handled []network.PacketID
+	server := newMiddlewareTestServer(&handled)
+	wrapped := 0
+	server.Use(func(_ network.PacketID, next HandlerFunc) HandlerFunc {
+		return func(s *Session, p mhfpacket.MHFPacket) {
+			wrapped++
+			next(s, p)
+		}
+	})
+
+	replaced := 0
+	server.SetHandler(network.MSG_SYS_PING, func(*Session, mhfpacket.MHFPacket) { replaced++ })
+	server.SetHandler(network.MSG_SYS_TIME, func(*Session, mhfpacket.MHFPacket) { replaced++ })
+
+	for _, opcode := range []network.PacketID{network.MSG_SYS_PING, network.MSG_SYS_TIME} {
+		h, ok := server.handler(opcode)
+		if !ok {
+			t.Fatalf("no handler for %s", opcode)
+		}
+		h(createMockSession(1, server), nil)
+	}
+	if replaced != 2 || len(handled) != 0 {
+		t.Errorf("replaced = %d, original = %d; want 2, 0", replaced, len(handled))
+	}
+	if wrapped != 2 {
+		t.Errorf("middleware ran %d times, want 2", wrapped)
+	}
+	if _, ok := server.handlerTable[network.MSG_SYS_TIME]; ok {
+		t.Error("SetHandler modified the base handler table")
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	server := createMockServer()
+	server.handlerTable = map[network.PacketID]handlerFunc{
+		network.MSG_SYS_PING: func(*Session, mhfpacket.MHFPacket) { panic("boom") },
+	}
+	server.Use(server.recoverMiddleware)
+
+	h, _ := server.handler(network.MSG_SYS_PING)
+	h(createMockSession(1, server), nil) // Must not panic
+
+	if got := server.HandlerStats()[network.MSG_SYS_PING].Panics; got != 1 {
+		t.Errorf("Panics = %d, want 1", got)
+	}
+}
+
+func TestTimingMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	server := createMockServer()
+	server.erupeConfig.DebugOptions.SlowHandlerThreshold = 5
+	server.handlerTable = map[network.PacketID]handlerFunc{
+		network.MSG_SYS_PING: func(*Session, mhfpacket.MHFPacket) {},
+		network.MSG_SYS_TIME: func(*Session, mhfpacket.MHFPacket) { time.Sleep(10 * time.Millisecond) },
+	}
+	server.Use(server.timingMiddleware)
+	s := createMockSession(1, server)
+	s.logger = zap.New(core)
+
+	fast, _ := server.handler(network.MSG_SYS_PING)
+	fast(s, nil)
+	fast(s, nil)
+	slow, _ := server.handler(network.MSG_SYS_TIME)
+	slow(s, nil)
+
+	stats := server.HandlerStats()
+	if stats[network.MSG_SYS_PING].Calls != 2 {
+		t.Errorf("ping calls = %d, want 2", stats[network.MSG_SYS_PING].Calls)
+	}
+	if stats[network.MSG_SYS_TIME].Max < 10*time.Millisecond {
+		t.Errorf("time max = %v, want at least 10ms", stats[network.MSG_SYS_TIME].Max)
+	}
+	if logs.FilterMessage("Slow packet handler").Len() != 1 {
+		t.Errorf("slow handler logs = %d, want 1", logs.FilterMessage("Slow packet handler").Len())
+	}
+}
+
+func TestLoginMiddleware(t *testing.T) {
+	var handled []network.PacketID
+	server := newMiddlewareTestServer(&handled)
+	server.Use(loginMiddleware)
+	s := createMockSession(0, server)
+
+	for _, opcode := range []network.PacketID{network.MSG_SYS_PING, network.MSG_MHF_LOADDATA} {
+		h, _ := server.handler(opcode)
+		h(s, nil)
+	}
+	if len(handled) != 1 || handled[0] != network.MSG_SYS_PING {
+		t.Errorf("handled before login = %v, want only MSG_SYS_PING", handled)
+	}
+
+	s.charID = 1
+	h, _ := server.handler(network.MSG_MHF_LOADDATA)
+	h(s, nil)
+	if len(handled) != 2 {
+		t.Errorf("MSG_MHF_LOADDATA not handled after login")
+	}
+}
+
+func TestLoginMiddleware_FailsAck(t *testing.T) {
+	var handled []network.PacketID
+	server := newMiddlewareTestServer(&handled)
+	server.Use(loginMiddleware)
+	s := createMockSession(0, server)
+
+	h, _ := server.handler(network.MSG_MHF_LOADDATA)
+	h(s, &mhfpacket.MsgMhfLoaddata{AckHandle: 42})
+	if len(handled) != 0 {
+		t.Fatalf("handled before login = %v, want none", handled)
+	}
+	ack := readAck(t, s)
+	if ack.AckHandle != 42 || ack.ErrorCode == 0 {
+		t.Errorf("ack = %+v, want a fail ACK for handle 42", ack)
+	}
+}
+
+func TestTraceMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	var handled []network.PacketID
+	server := newMiddlewareTestServer(&handled)
+	server.logger = zap.New(core)
+	server.Use(server.traceMiddleware([]string{"msg_sys_ping", "MSG_NOT_REAL"}))
+	s := createMockSession(1, server)
+	s.logger = server.logger
+
+	for _, opcode := range []network.PacketID{network.MSG_SYS_PING, network.MSG_MHF_LOADDATA} {
+		h, _ := server.handler(opcode)
+		h(s, &mhfpacket.MsgSysPing{AckHandle: 3})
+	}
+	if n := logs.FilterMessage("Packet trace").Len(); n != 1 {
+		t.Errorf("trace logs = %d, want 1", n)
+	}
+	if n := logs.FilterMessage("Unknown opcode in TraceOpcodes").Len(); n != 1 {
+		t.Errorf("unknown opcode warnings = %d, want 1", n)
+	}
+	if len(handled) != 2 {
+		t.Errorf("handled = %v, want both packets", handled)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	var handled []network.PacketID
+	server := newMiddlewareTestServer(&handled)
+	var seen []mhfpacket.MHFPacket
+	server.Use(Observe(func(_ *Session, p mhfpacket.MHFPacket) { seen = append(seen, p) }))
+
+	pkt := &mhfpacket.MsgSysPing{AckHandle: 9}
+	h, _ := server.handler(network.MSG_SYS_PING)
+	h(createMockSession(1, server), pkt)
+	if len(seen) != 1 || seen[0] != pkt || len(handled) != 1 {
+		t.Errorf("seen = %v, handled = %v", seen, handled)
+	}
+}
+
+func TestDefaultMiddleware(t *testing.T) {
+	server := createMockServer()
+	if n := len(server.defaultMiddleware()); n != 4 {
+		t.Errorf("default middleware = %d, want 4", n)
+	}
+	server.erupeConfig = &cfg.Config{DebugOptions: cfg.DebugOptions{TraceOpcodes: []string{"MSG_SYS_PING"}}}
+	if n := len(server.defaultMiddleware()); n != 5 {
+		t.Errorf("default middleware with tracing = %d, want 5", n)
+	}
+}
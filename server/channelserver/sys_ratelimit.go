@@ -0,0 +1,210 @@
+package channelserver
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	cfg "erupe-ce/config"
+	"erupe-ce/network"
+	"erupe-ce/network/mhfpacket"
+
+	"go.uber.org/zap"
+)
+
+// Rate limit penalty actions.
+const (
+	rateLimitWarn       = "warn"
+	rateLimitDrop       = "drop"
+	rateLimitDisconnect = "disconnect"
+	rateLimitBan        = "ban"
+)
+
+// defaultRateLimits are the built-in rules for opcodes that are either sent
+// at a high rate by legitimate clients or expensive to handle. Rules in
+// RateLimit.Opcodes override them.
+var defaultRateLimits = map[network.PacketID]cfg.RateLimitRule{
+	network.MSG_SYS_POSITION_OBJECT:  {Rate: 30, Burst: 60},
+	network.MSG_SYS_CAST_BINARY:      {Rate: 30, Burst: 60},
+	network.MSG_SYS_SET_STAGE_BINARY: {Rate: 10, Burst: 30},
+	network.MSG_MHF_ENUMERATE_HOUSE:  {Rate: 2, Burst: 10},
+	network.MSG_MHF_LIST_MAIL:        {Rate: 2, Burst: 10},
+	network.MSG_MHF_ENUMERATE_GUILD:  {Rate: 2, Burst: 10},
+	network.MSG_MHF_SEND_MAIL:        {Rate: 1, Burst: 5},
+}
+
+// rateLimitPolicy is the compiled form of cfg.RateLimitOptions.
+type rateLimitPolicy struct {
+	rules     map[network.PacketID]cfg.RateLimitRule
+	fallback  cfg.RateLimitRule
+	action    string
+	threshold int
+	window    time.Duration
+	banLength time.Duration
+}
+
+// newRateLimitPolicy compiles the configured limits. It returns nil if rate
+// limiting is disabled.
+func newRateLimitPolicy(opts cfg.RateLimitOptions) (*rateLimitPolicy, error) {
+	if !opts.Enabled {
+		return nil, nil
+	}
+	p := &rateLimitPolicy{
+		rules:     make(map[network.PacketID]cfg.RateLimitRule, len(defaultRateLimits)+len(opts.Opcodes)),
+		fallback:  opts.Default,
+		action:    strings.ToLower(opts.Penalty.Action),
+		threshold: opts.Penalty.Threshold,
+		window:    time.Duration(opts.Penalty.Window) * time.Second,
+		banLength: time.Duration(opts.Penalty.BanMinutes) * time.Minute,
+	}
+	switch p.action {
+	case "":
+		p.action = rateLimitDrop
+	case rateLimitWarn, rateLimitDrop, rateLimitDisconnect, rateLimitBan:
+	default:
+		return nil, fmt.Errorf("unknown rate limit penalty %q", opts.Penalty.Action)
+	}
+	for id, rule := range defaultRateLimits {
+		p.rules[id] = rule
+	}
+	for name, rule := range opts.Opcodes {
+		id, ok := network.PacketIDFromString(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown opcode %q in rate limits", name)
+		}
+		p.rules[id] = rule
+	}
+	return p, nil
+}
+
+// rule returns the limit for opcode; a zero Rate means unlimited.
+func (p *rateLimitPolicy) rule(opcode network.PacketID) cfg.RateLimitRule {
+	if rule, ok := p.rules[opcode]; ok {
+		return rule
+	}
+	return p.fallback
+}
+
+// tokenBucket tracks the packets a session may still send for one opcode.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket for the time elapsed since the last call and
+// consumes a token if one is available.
+func (b *tokenBucket) take(rule cfg.RateLimitRule, now time.Time) bool {
+	burst := float64(rule.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * rule.Rate
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sessionLimiter holds a session's token buckets and recent violations. It
+// is only used from the session's receive loop.
+type sessionLimiter struct {
+	buckets     map[network.PacketID]*tokenBucket
+	violations  int
+	windowStart time.Time
+}
+
+// rateLimitMiddleware skips packets over the session's rate limits, failing
+// their ACK if they expect one.
+func rateLimitMiddleware(opcode network.PacketID, next HandlerFunc) HandlerFunc {
+	return func(s *Session, p mhfpacket.MHFPacket) {
+		if s.rateLimited(opcode, time.Now()) {
+			rejectPacket(s, p)
+			return
+		}
+		next(s, p)
+	}
+}
+
+// rateLimited reports whether the packet must be skipped because the session
+// exceeded its limit for opcode, applying the configured penalty.
+func (s *Session) rateLimited(opcode network.PacketID, now time.Time) bool {
+	policy := s.server.rateLimits
+	if policy == nil {
+		return false
+	}
+	rule := policy.rule(opcode)
+	if rule.Rate <= 0 {
+		return false
+	}
+	l := &s.limiter
+	if l.buckets == nil {
+		l.buckets = make(map[network.PacketID]*tokenBucket)
+	}
+	bucket, ok := l.buckets[opcode]
+	if !ok {
+		bucket = &tokenBucket{}
+		l.buckets[opcode] = bucket
+	}
+	if bucket.take(rule, now) {
+		return false
+	}
+
+	if now.Sub(l.windowStart) > policy.window {
+		l.windowStart = now
+		l.violations = 0
+	}
+	l.violations++
+	if l.violations == 1 {
+		s.logger.Warn("Packet rate limit exceeded",
+			zap.Uint32("charID", s.charID),
+			zap.Uint32("userID", s.userID),
+			zap.String("name", s.Name),
+			zap.Stringer("opcode", opcode),
+			zap.Float64("rate", rule.Rate),
+			zap.Int("burst", rule.Burst),
+			zap.String("action", policy.action),
+		)
+	}
+
+	switch policy.action {
+	case rateLimitWarn:
+		return false
+	case rateLimitDisconnect, rateLimitBan:
+		if policy.threshold > 0 && l.violations >= policy.threshold {
+			s.punishRateLimit(policy, opcode, now)
+		}
+	}
+	return true
+}
+
+// punishRateLimit disconnects a session that kept exceeding its limits, and
+// bans its account for a while if the policy says so.
+func (s *Session) punishRateLimit(policy *rateLimitPolicy, opcode network.PacketID, now time.Time) {
+	fields := []zap.Field{
+		zap.Uint32("charID", s.charID),
+		zap.Uint32("userID", s.userID),
+		zap.String("name", s.Name),
+		zap.Stringer("opcode", opcode),
+		zap.Int("violations", s.limiter.violations),
+		zap.Duration("window", policy.window),
+		zap.String("action", policy.action),
+	}
+	if policy.action == rateLimitBan && s.userID != 0 {
+		expiry := now.Add(policy.banLength)
+		if err := s.server.userRepo.BanUser(s.userID, &expiry); err != nil {
+			s.logger.Error("Failed to ban rate limit offender", zap.Error(err))
+		}
+		fields = append(fields, zap.Time("banned_until", expiry))
+	}
+	s.logger.Warn("Disconnecting rate limit offender", fields...)
+	s.markClosed()
+}
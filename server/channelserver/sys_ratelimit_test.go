@@ -0,0 +1,224 @@
+package channelserver
+
+import (
+	"testing"
+	"time"
+
+	cfg "erupe-ce/config"
+	"erupe-ce/network"
+	"erupe-ce/network/mhfpacket"
+)
+
+func TestNewRateLimitPolicy(t *testing.T) {
+	p, err := newRateLimitPolicy(cfg.RateLimitOptions{})
+	if p != nil || err != nil {
+		t.Errorf("disabled policy = %v, %v; want nil, nil", p, err)
+	}
+
+	p, err = newRateLimitPolicy(cfg.RateLimitOptions{
+		Enabled: true,
+		Default: cfg.RateLimitRule{Rate: 50, Burst: 100},
+		Opcodes: map[string]cfg.RateLimitRule{
+			"msg_sys_position_object": {Rate: 5, Burst: 5}, // Viper lowercases map keys
+			"MSG_MHF_LOADDATA":        {Rate: 1, Burst: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("newRateLimitPolicy() error = %v", err)
+	}
+	if p.action != rateLimitDrop {
+		t.Errorf("action = %q, want %q", p.action, rateLimitDrop)
+	}
+	if got := p.rule(network.MSG_SYS_POSITION_OBJECT); got.Rate != 5 {
+		t.Errorf("overridden rule = %+v, want rate 5", got)
+	}
+	if got := p.rule(network.MSG_MHF_LOADDATA); got.Rate != 1 {
+		t.Errorf("configured rule = %+v, want rate 1", got)
+	}
+	if got := p.rule(network.MSG_MHF_LIST_MAIL); got != defaultRateLimits[network.MSG_MHF_LIST_MAIL] {
+		t.Errorf("built-in rule = %+v, want %+v", got, defaultRateLimits[network.MSG_MHF_LIST_MAIL])
+	}
+	if got := p.rule(network.MSG_SYS_PING); got.Rate != 50 {
+		t.Errorf("fallback rule = %+v, want rate 50", got)
+	}
+
+	if _, err := newRateLimitPolicy(cfg.RateLimitOptions{Enabled: true, Penalty: cfg.RateLimitPenalty{Action: "explode"}}); err == nil {
+		t.Error("expected error for unknown penalty")
+	}
+	if _, err := newRateLimitPolicy(cfg.RateLimitOptions{Enabled: true, Opcodes: map[string]cfg.RateLimitRule{"MSG_FAKE": {}}}); err == nil {
+		t.Error("expected error for unknown opcode")
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	rule := cfg.RateLimitRule{Rate: 10, Burst: 3}
+	now := time.Unix(1000, 0)
+	b := &tokenBucket{}
+
+	for i := 0; i < 3; i++ {
+		if !b.take(rule, now) {
+			t.Fatalf("take %d within burst refused", i)
+		}
+	}
+	if b.take(rule, now) {
+		t.Error("take beyond burst allowed")
+	}
+	if !b.take(rule, now.Add(100*time.Millisecond)) {
+		t.Error("take after refill refused")
+	}
+	if b.take(rule, now.Add(100*time.Millisecond)) {
+		t.Error("second take after single refill allowed")
+	}
+
+	// Long idle periods refill only up to the burst size.
+	later := now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		b.take(rule, later)
+	}
+	if b.take(rule, later) {
+		t.Error("bucket refilled beyond burst")
+	}
+}
+
+func newRateLimitedSession(t *testing.T, penalty cfg.RateLimitPenalty) *Session {
+	t.Helper()
+	server := createMockServer()
+	policy, err := newRateLimitPolicy(cfg.RateLimitOptions{
+		Enabled: true,
+		Opcodes: map[string]cfg.RateLimitRule{"MSG_SYS_PING": {Rate: 1, Burst: 2}},
+		Penalty: penalty,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.rateLimits = policy
+	s := createMockSession(1, server)
+	s.userID = 7
+	s.done = make(chan struct{})
+	s.ackStart = make(map[uint32]time.Time)
+	return s
+}
+
+func TestRateLimited_Drop(t *testing.T) {
+	s := newRateLimitedSession(t, cfg.RateLimitPenalty{Action: rateLimitDrop, Threshold: 1, Window: 10})
+	now := time.Unix(1000, 0)
+
+	if s.rateLimited(network.MSG_SYS_PING, now) || s.rateLimited(network.MSG_SYS_PING, now) {
+		t.Fatal("packets within burst were limited")
+	}
+	if !s.rateLimited(network.MSG_SYS_PING, now) {
+		t.Error("packet over the limit was not dropped")
+	}
+	if s.rateLimited(network.MSG_SYS_TIME, now) {
+		t.Error("opcode without a rule was limited")
+	}
+	if s.closed.Load() {
+		t.Error("drop penalty closed the session")
+	}
+}
+
+func TestRateLimited_Warn(t *testing.T) {
+	s := newRateLimitedSession(t, cfg.RateLimitPenalty{Action: rateLimitWarn})
+	now := time.Unix(1000, 0)
+	for i := 0; i < 5; i++ {
+		if s.rateLimited(network.MSG_SYS_PING, now) {
+			t.Fatalf("packet %d limited under the warn penalty", i)
+		}
+	}
+	if s.limiter.violations != 3 {
+		t.Errorf("violations = %d, want 3", s.limiter.violations)
+	}
+}
+
+func TestRateLimited_DisconnectAfterThreshold(t *testing.T) {
+	s := newRateLimitedSession(t, cfg.RateLimitPenalty{Action: rateLimitDisconnect, Threshold: 3, Window: 10})
+	now := time.Unix(1000, 0)
+	s.rateLimited(network.MSG_SYS_PING, now)
+	s.rateLimited(network.MSG_SYS_PING, now)
+
+	s.rateLimited(network.MSG_SYS_PING, now)
+	s.rateLimited(network.MSG_SYS_PING, now)
+	if s.closed.Load() {
+		t.Fatal("session closed before reaching the threshold")
+	}
+	s.rateLimited(network.MSG_SYS_PING, now)
+	if !s.closed.Load() {
+		t.Error("session not closed after reaching the threshold")
+	}
+}
+
+func TestRateLimited_ViolationWindowResets(t *testing.T) {
+	s := newRateLimitedSession(t, cfg.RateLimitPenalty{Action: rateLimitDisconnect, Threshold: 2, Window: 10})
+	now := time.Unix(1000, 0)
+	s.rateLimited(network.MSG_SYS_PING, now)
+	s.rateLimited(network.MSG_SYS_PING, now)
+	s.rateLimited(network.MSG_SYS_PING, now) // First violation
+
+	later := now.Add(time.Minute)
+	s.rateLimited(network.MSG_SYS_PING, later)
+	s.rateLimited(network.MSG_SYS_PING, later)
+	s.rateLimited(network.MSG_SYS_PING, later) // First violation of a new window
+	if s.closed.Load() {
+		t.Error("violations from an old window counted towards the threshold")
+	}
+}
+
+func TestRateLimited_Ban(t *testing.T) {
+	s := newRateLimitedSession(t, cfg.RateLimitPenalty{Action: rateLimitBan, Threshold: 1, Window: 10, BanMinutes: 30})
+	repo := &mockUserRepoCommands{}
+	s.server.userRepo = repo
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 3; i++ {
+		s.rateLimited(network.MSG_SYS_PING, now)
+	}
+	if repo.bannedUID != 7 {
+		t.Fatalf("banned user = %d, want 7", repo.bannedUID)
+	}
+	if repo.banExpiry == nil || !repo.banExpiry.Equal(now.Add(30*time.Minute)) {
+		t.Errorf("ban expiry = %v, want %v", repo.banExpiry, now.Add(30*time.Minute))
+	}
+	if !s.closed.Load() {
+		t.Error("banned session not closed")
+	}
+}
+
+func TestRateLimitMiddleware_FailsAck(t *testing.T) {
+	s := newRateLimitedSession(t, cfg.RateLimitPenalty{Action: rateLimitDrop})
+	handled := 0
+	h := rateLimitMiddleware(network.MSG_SYS_PING, func(*Session, mhfpacket.MHFPacket) { handled++ })
+
+	for i := uint32(1); i <= 3; i++ {
+		h(s, &mhfpacket.MsgSysPing{AckHandle: i})
+	}
+	if handled != 2 {
+		t.Fatalf("handler called %d times, want 2", handled)
+	}
+	ack := readAck(t, s)
+	if ack.AckHandle != 3 || ack.ErrorCode == 0 {
+		t.Errorf("ack = %+v, want a fail ACK for handle 3", ack)
+	}
+	select {
+	case <-s.sendPackets:
+		t.Error("unexpected extra packet queued")
+	default:
+	}
+}
+
+func TestHandlePacketGroup_RateLimitSkipsHandler(t *testing.T) {
+	s := newRateLimitedSession(t, cfg.RateLimitPenalty{Action: rateLimitDrop})
+	s.server.rateLimits.rules[network.MSG_SYS_TIME] = cfg.RateLimitRule{Rate: 1, Burst: 1}
+	handled := 0
+	s.server.handlerTable = map[network.PacketID]handlerFunc{
+		network.MSG_SYS_TIME: func(*Session, mhfpacket.MHFPacket) { handled++ },
+	}
+	s.server.Use(rateLimitMiddleware)
+
+	pkt := []byte{0x00, byte(network.MSG_SYS_TIME), 0x00, 0x00, 0x00, 0x00, 0x00} // opcode, bool, uint32
+	group := append(append([]byte{}, pkt...), pkt...)
+	s.handlePacketGroup(group)
+
+	if handled != 1 {
+		t.Errorf("handler called %d times, want 1", handled)
+	}
+}
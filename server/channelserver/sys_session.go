@@ -53,6 +53,7 @@ type Session struct {
 	token            string
 	kqf              []byte
 	kqfOverride      bool
+	language         atomic.Value // string: preferred locale code, unset or empty for the server default
 
 	playtime     uint32
 	playtimeTime time.Time
@@ -73,9 +74,13 @@ type Session struct {
 
 	Name           string
 	closed         atomic.Bool
+	done           chan struct{} // Closed when the session is closed, stopping sendLoop
+	closeOnce      sync.Once
+	limiter        sessionLimiter // Per-opcode rate limit state, used by recvLoop only
 	ackStart       map[uint32]time.Time
 	captureConn    *pcap.RecordingConn // non-nil when capture is active
 	captureCleanup func()              // Called on session close to flush/close capture file
+	tapConn        *pcap.TapConn       // non-nil when live streaming is enabled
 }
 
 // NewSession creates a new Session type.
@@ -83,6 +88,7 @@ func NewSession(server *Server, conn net.Conn) *Session {
 	var cryptConn network.Conn = network.NewCryptConn(conn, server.erupeConfig.RealClientMode, server.logger.Named(conn.RemoteAddr().String()))
 
 	cryptConn, captureConn, captureCleanup := startCapture(server, cryptConn, conn.RemoteAddr(), pcap.ServerTypeChannel)
+	cryptConn, tapConn := startTap(server, cryptConn)
 
 	s := &Session{
 		logger:         server.logger.Named(conn.RemoteAddr().String()),
@@ -90,6 +96,7 @@ func NewSession(server *Server, conn net.Conn) *Session {
 		rawConn:        conn,
 		cryptConn:      cryptConn,
 		sendPackets:    make(chan packet, 20),
+		done:           make(chan struct{}),
 		clientContext:  &clientctx.ClientContext{RealClientMode: server.erupeConfig.RealClientMode},
 		lastPacket:     time.Now(),
 		objectID:       server.getObjectId(),
@@ -99,6 +106,7 @@ func NewSession(server *Server, conn net.Conn) *Session {
 		semaphoreID:    make([]uint16, 2),
 		captureConn:    captureConn,
 		captureCleanup: captureCleanup,
+		tapConn:        tapConn,
 	}
 	return s
 }
@@ -117,17 +125,27 @@ func (s *Session) QueueSend(data []byte) {
 	if len(data) >= 2 {
 		s.logMessage(binary.BigEndian.Uint16(data[0:2]), data, "Server", s.Name)
 	}
-	s.sendPackets <- packet{data, true}
+	select {
+	case s.sendPackets <- packet{data, true}:
+	default:
+		// Queue full: wait for the send loop and record the stall.
+		start := time.Now()
+		s.sendPackets <- packet{data, true}
+		s.server.ioStats.recordBlocked(time.Since(start))
+	}
+	s.server.ioStats.recordQueued(len(s.sendPackets))
 }
 
 // QueueSendNonBlocking queues a packet (raw []byte) to be sent, dropping the packet entirely if the queue is full.
 func (s *Session) QueueSendNonBlocking(data []byte) {
 	select {
 	case s.sendPackets <- packet{data, true}:
+		s.server.ioStats.recordQueued(len(s.sendPackets))
 		if len(data) >= 2 {
 			s.logMessage(binary.BigEndian.Uint16(data[0:2]), data, "Server", s.Name)
 		}
 	default:
+		s.server.ioStats.recordDropped()
 		s.logger.Warn("Packet queue too full, dropping!")
 	}
 }
@@ -167,23 +185,73 @@ func (s *Session) QueueAck(ackHandle uint32, data []byte) {
 	s.QueueSend(bf.Data())
 }
 
+// sendBatchMaxBytes is the size at which a coalesced write stops taking
+// further packets from the queue.
+const sendBatchMaxBytes = 0x8000
+
+// markClosed flags the session as closed and wakes its send loop.
+func (s *Session) markClosed() {
+	s.closed.Store(true)
+	s.closeOnce.Do(func() {
+		if s.done != nil {
+			close(s.done)
+		}
+	})
+}
+
+// sendLoop writes queued packets as soon as they arrive, until the session is
+// closed. Each packet keeps its own terminator; with CoalescePackets enabled,
+// packets already waiting in the queue share a single encrypted write.
 func (s *Session) sendLoop() {
 	for {
+		var pkt packet
+		select {
+		case <-s.done:
+			return
+		case pkt = <-s.sendPackets:
+		}
 		if s.closed.Load() {
 			return
 		}
-		// Send each packet individually with its own terminator
-		for len(s.sendPackets) > 0 {
-			pkt := <-s.sendPackets
-			err := s.cryptConn.SendPacket(append(pkt.data, []byte{0x00, 0x10}...))
-			if err != nil {
-				s.logger.Warn("Failed to send packet", zap.Error(err))
-			}
+		if s.server.erupeConfig.CoalescePackets {
+			data, count := s.nextBatch(pkt)
+			s.writePacket(data, count)
+		} else {
+			s.writePacket(append(pkt.data, []byte{0x00, 0x10}...), 1)
 		}
-		time.Sleep(time.Duration(s.server.erupeConfig.LoopDelay) * time.Millisecond)
 	}
 }
 
+// nextBatch appends the packets already waiting in the queue to first, each
+// with its own terminator, without blocking. It returns the batch and the
+// number of packets in it.
+func (s *Session) nextBatch(first packet) ([]byte, int) {
+	buf := make([]byte, 0, len(first.data)+2)
+	buf = append(buf, first.data...)
+	buf = append(buf, 0x00, 0x10)
+	count := 1
+	for len(buf) < sendBatchMaxBytes {
+		select {
+		case pkt := <-s.sendPackets:
+			buf = append(buf, pkt.data...)
+			buf = append(buf, 0x00, 0x10)
+			count++
+		default:
+			return buf, count
+		}
+	}
+	return buf, count
+}
+
+// writePacket encrypts and sends data holding count terminated packets.
+func (s *Session) writePacket(data []byte, count int) {
+	if err := s.cryptConn.SendPacket(data); err != nil {
+		s.logger.Warn("Failed to send packet", zap.Error(err))
+		return
+	}
+	s.server.ioStats.recordWrite(count)
+}
+
 func (s *Session) recvLoop() {
 	for {
 		if s.closed.Load() {
@@ -223,7 +291,6 @@ func (s *Session) recvLoop() {
 			return
 		}
 		s.handlePacketGroup(pkt)
-		time.Sleep(time.Duration(s.server.erupeConfig.LoopDelay) * time.Millisecond)
 	}
 }
 
@@ -247,7 +314,7 @@ func (s *Session) handlePacketGroup(pktGroup []byte) {
 	s.logMessage(opcodeUint16, pktGroup, s.Name, "Server")
 
 	if opcode == network.MSG_SYS_LOGOUT {
-		s.closed.Store(true)
+		s.markClosed()
 		return
 	}
 	// Get the packet parser and handler for this opcode.
@@ -274,12 +341,15 @@ func (s *Session) handlePacketGroup(pktGroup []byte) {
 		return
 	}
 	// Handle the packet.
-	handler, ok := s.server.handlerTable[opcode]
+	handler, ok := s.server.handler(opcode)
 	if !ok {
 		s.logger.Warn("No handler for opcode", zap.Stringer("opcode", opcode))
 		return
 	}
 	handler(s, mhfPkt)
+	if s.closed.Load() {
+		return
+	}
 	// If there is more data on the stream that the .Parse method didn't read, then read another packet off it.
 	remainingData := bf.DataFromCurrent()
 	if len(remainingData) >= 2 {
@@ -0,0 +1,86 @@
+package channelserver
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SessionIOStats is a snapshot of a channel server's send queue counters,
+// summed over all of its sessions since startup.
+type SessionIOStats struct {
+	Queued        uint64        // Packets accepted into a send queue
+	Dropped       uint64        // Non-blocking sends dropped because the queue was full
+	Blocked       uint64        // Blocking sends that had to wait for queue space
+	BlockedTime   time.Duration // Total time blocking sends spent waiting
+	Writes        uint64        // Encrypted writes to clients
+	Packets       uint64        // Packets written; exceeds Writes when coalescing
+	MaxQueueDepth int           // Highest send queue length observed
+}
+
+// sessionIOStats holds the live counters behind SessionIOStats.
+type sessionIOStats struct {
+	queued       atomic.Uint64
+	dropped      atomic.Uint64
+	blocked      atomic.Uint64
+	blockedNanos atomic.Int64
+	writes       atomic.Uint64
+	packets      atomic.Uint64
+	maxDepth     atomic.Int64
+}
+
+func (st *sessionIOStats) recordQueued(depth int) {
+	st.queued.Add(1)
+	for {
+		cur := st.maxDepth.Load()
+		if int64(depth) <= cur || st.maxDepth.CompareAndSwap(cur, int64(depth)) {
+			return
+		}
+	}
+}
+
+func (st *sessionIOStats) recordDropped() {
+	st.dropped.Add(1)
+}
+
+func (st *sessionIOStats) recordBlocked(d time.Duration) {
+	st.blocked.Add(1)
+	st.blockedNanos.Add(int64(d))
+}
+
+func (st *sessionIOStats) recordWrite(packets int) {
+	st.writes.Add(1)
+	st.packets.Add(uint64(packets))
+}
+
+// SessionIOStats returns the server's send queue counters.
+func (s *Server) SessionIOStats() SessionIOStats {
+	st := &s.ioStats
+	return SessionIOStats{
+		Queued:        st.queued.Load(),
+		Dropped:       st.dropped.Load(),
+		Blocked:       st.blocked.Load(),
+		BlockedTime:   time.Duration(st.blockedNanos.Load()),
+		Writes:        st.writes.Load(),
+		Packets:       st.packets.Load(),
+		MaxQueueDepth: int(st.maxDepth.Load()),
+	}
+}
+
+// logBackPressure warns when sends were dropped or stalled since prev, and
+// returns the current counters for the next call.
+func (s *Server) logBackPressure(prev SessionIOStats) SessionIOStats {
+	cur := s.SessionIOStats()
+	dropped := cur.Dropped - prev.Dropped
+	blocked := cur.Blocked - prev.Blocked
+	if dropped > 0 || blocked > 0 {
+		s.logger.Warn("Session send queues under back-pressure",
+			zap.Uint64("dropped", dropped),
+			zap.Uint64("blocked", blocked),
+			zap.Duration("blocked_time", cur.BlockedTime-prev.BlockedTime),
+			zap.Int("max_queue_depth", cur.MaxQueueDepth),
+		)
+	}
+	return cur
+}
@@ -0,0 +1,74 @@
+package channelserver
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSessionIOStats_QueueCounters(t *testing.T) {
+	s := createTestSession(&MockCryptConn{})
+	s.sendPackets = make(chan packet, 2)
+
+	s.QueueSendNonBlocking([]byte{0x00, 0x01})
+	s.QueueSendNonBlocking([]byte{0x00, 0x02})
+	s.QueueSendNonBlocking([]byte{0x00, 0x03}) // Dropped
+
+	stats := s.server.SessionIOStats()
+	if stats.Queued != 2 {
+		t.Errorf("Queued = %d, want 2", stats.Queued)
+	}
+	if stats.Dropped != 1 {
+		t.Errorf("Dropped = %d, want 1", stats.Dropped)
+	}
+	if stats.MaxQueueDepth != 2 {
+		t.Errorf("MaxQueueDepth = %d, want 2", stats.MaxQueueDepth)
+	}
+}
+
+func TestSessionIOStats_BlockedSend(t *testing.T) {
+	s := createTestSession(&MockCryptConn{})
+	s.sendPackets = make(chan packet, 1)
+	s.QueueSend([]byte{0x00, 0x01})
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		<-s.sendPackets
+	}()
+	s.QueueSend([]byte{0x00, 0x02}) // Waits for the reader above
+
+	stats := s.server.SessionIOStats()
+	if stats.Blocked != 1 {
+		t.Errorf("Blocked = %d, want 1", stats.Blocked)
+	}
+	if stats.BlockedTime < 10*time.Millisecond {
+		t.Errorf("BlockedTime = %v, want at least 10ms", stats.BlockedTime)
+	}
+}
+
+func TestLogBackPressure(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	server := &Server{logger: zap.New(core)}
+
+	prev := server.logBackPressure(SessionIOStats{})
+	if logs.Len() != 0 {
+		t.Fatalf("logged %d entries with no pressure, want 0", logs.Len())
+	}
+
+	server.ioStats.recordDropped()
+	server.ioStats.recordDropped()
+	prev = server.logBackPressure(prev)
+	if logs.Len() != 1 {
+		t.Fatalf("logged %d entries after drops, want 1", logs.Len())
+	}
+	if got := logs.All()[0].ContextMap()["dropped"]; got != uint64(2) {
+		t.Errorf("dropped = %v, want 2", got)
+	}
+
+	server.logBackPressure(prev)
+	if logs.Len() != 1 {
+		t.Errorf("logged again without new pressure")
+	}
+}
@@ -357,3 +357,100 @@ func TestPacketQueueAckFormat(t *testing.T) {
 		t.Error("ACK packet missing proper terminator")
 	}
 }
+
+// TestSendLoopExitsOnClose verifies that closing the session wakes an idle
+// send loop instead of leaving it blocked on the queue.
+func TestSendLoopExitsOnClose(t *testing.T) {
+	mock := &MockCryptConn{sentPackets: make([][]byte, 0)}
+	s := createTestSession(mock)
+	s.done = make(chan struct{})
+
+	exited := make(chan struct{})
+	go func() {
+		s.sendLoop()
+		close(exited)
+	}()
+
+	s.markClosed()
+	s.markClosed() // Must be safe to call twice
+
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("sendLoop did not exit after markClosed")
+	}
+}
+
+// TestSendLoopSendsWithoutDelay verifies that a queued packet is written
+// immediately rather than on the next polling interval.
+func TestSendLoopSendsWithoutDelay(t *testing.T) {
+	mock := &MockCryptConn{sentPackets: make([][]byte, 0)}
+	s := createTestSession(mock)
+	s.done = make(chan struct{})
+	s.server.erupeConfig.LoopDelay = 1000
+	defer s.markClosed()
+
+	go s.sendLoop()
+	s.QueueSend([]byte{0x00, 0x01, 0xAA})
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for mock.PacketCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("packet not sent within 500ms")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestSendLoopCoalescesQueuedPackets verifies that packets waiting in the
+// queue share one write when coalescing is enabled, each keeping its own
+// terminator.
+func TestSendLoopCoalescesQueuedPackets(t *testing.T) {
+	mock := &MockCryptConn{sentPackets: make([][]byte, 0)}
+	s := createTestSession(mock)
+	s.done = make(chan struct{})
+	s.server.erupeConfig.CoalescePackets = true
+	defer s.markClosed()
+
+	s.QueueSend([]byte{0x00, 0x01, 0xAA})
+	s.QueueSend([]byte{0x00, 0x02, 0xBB})
+	s.QueueSend([]byte{0x00, 0x03, 0xCC})
+	go s.sendLoop()
+
+	deadline := time.Now().Add(time.Second)
+	for mock.PacketCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no packets sent")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	sent := mock.GetSentPackets()
+	if len(sent) != 1 {
+		t.Fatalf("got %d writes, want 1", len(sent))
+	}
+	want := []byte{0x00, 0x01, 0xAA, 0x00, 0x10, 0x00, 0x02, 0xBB, 0x00, 0x10, 0x00, 0x03, 0xCC, 0x00, 0x10}
+	if !bytes.Equal(sent[0], want) {
+		t.Errorf("batch = % X, want % X", sent[0], want)
+	}
+	stats := s.server.SessionIOStats()
+	if stats.Writes != 1 || stats.Packets != 3 {
+		t.Errorf("stats = %+v, want 1 write of 3 packets", stats)
+	}
+}
+
+// TestNextBatchStopsAtMaxBytes verifies that a batch stops taking packets
+// once it reaches sendBatchMaxBytes.
+func TestNextBatchStopsAtMaxBytes(t *testing.T) {
+	s := createTestSession(&MockCryptConn{})
+	big := make([]byte, sendBatchMaxBytes)
+	s.sendPackets <- packet{data: []byte{0x00, 0x02}}
+
+	data, count := s.nextBatch(packet{data: big})
+	if count != 1 || len(data) != len(big)+2 {
+		t.Errorf("got %d packets in %d bytes, want 1 in %d", count, len(data), len(big)+2)
+	}
+	if len(s.sendPackets) != 1 {
+		t.Errorf("queue length = %d, want 1", len(s.sendPackets))
+	}
+}
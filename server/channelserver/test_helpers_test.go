@@ -64,6 +64,10 @@ func ensureMailService(s *Server) {
 // ensureGuildService wires the GuildService from the server's current repos.
 // Call this after setting guildRepo, mailRepo, and charRepo on the mock server.
 func ensureGuildService(s *Server) {
+	if s.charRepo == nil {
+		// Scout handlers look up the recipient's language preference.
+		s.charRepo = newMockCharacterRepo()
+	}
 	ensureMailService(s)
 	s.guildService = NewGuildService(s.guildRepo, s.mailService, s.charRepo, s.logger)
 }
@@ -3,13 +3,16 @@ package discordbot
 import (
 	cfg "erupe-ce/config"
 	"regexp"
+	"sync"
 
 	"github.com/bwmarrin/discordgo"
 	"go.uber.org/zap"
 )
 
 // Commands defines the slash commands registered with Discord, including
-// account linking and password management.
+// account linking, password management, server status lookups and
+// moderation. Moderation commands are only honoured for Discord users whose
+// linked Erupe account has operator rights.
 var Commands = []*discordgo.ApplicationCommand{
 	{
 		Name:        "link",
@@ -35,6 +38,92 @@ var Commands = []*discordgo.ApplicationCommand{
 			},
 		},
 	},
+	{
+		Name:        "status",
+		Description: "Show the number of players on each channel",
+	},
+	{
+		Name:        "who",
+		Description: "List the characters currently online",
+	},
+	{
+		Name:        "character",
+		Description: "Look up a character by name",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "name",
+				Description: "The character name",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "ban",
+		Description: "Ban the account owning a character",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "id",
+				Description: "The 6 character ID of the character",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "length",
+				Description: "Ban length, e.g. 30m, 12h, 7d (permanent if omitted)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "kick",
+		Description: "Disconnect the account owning a character",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "id",
+				Description: "The 6 character ID of the character",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "mute",
+		Description: "Stop relaying a Discord user's messages in-game",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "The Discord user to mute",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "unmute",
+		Description: "Resume relaying a Discord user's messages in-game",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "The Discord user to unmute",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "announce",
+		Description: "Broadcast a message to every channel",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "message",
+				Description: "The message to broadcast",
+				Required:    true,
+			},
+		},
+	},
 }
 
 // DiscordBot manages a Discord session and provides methods for relaying
@@ -45,6 +134,8 @@ type DiscordBot struct {
 	logger       *zap.Logger
 	MainGuild    *discordgo.Guild
 	RelayChannel *discordgo.Channel
+
+	handlersOnce sync.Once
 }
 
 // Options holds the configuration and logger required to create a DiscordBot.
@@ -66,6 +157,9 @@ func NewDiscordBot(options Options) (discordBot *DiscordBot, err error) {
 	var relayChannel *discordgo.Channel
 
 	if options.Config.Discord.RelayChannel.Enabled {
+		// Reading relayed messages requires the privileged message content
+		// intent, which must also be enabled in the Discord developer portal.
+		session.Identify.Intents |= discordgo.IntentsGuildMessages | discordgo.IntentMessageContent
 		relayChannel, err = session.Channel(options.Config.Discord.RelayChannel.RelayChannelID)
 	}
 
@@ -91,6 +185,20 @@ func (bot *DiscordBot) Start() (err error) {
 	return
 }
 
+// AddHandlersOnce registers event handlers on the session the first time it
+// is called and ignores later calls, so that channel servers sharing the bot
+// handle each event only once. It reports whether the handlers were added.
+func (bot *DiscordBot) AddHandlersOnce(handlers ...interface{}) bool {
+	added := false
+	bot.handlersOnce.Do(func() {
+		for _, h := range handlers {
+			bot.Session.AddHandler(h)
+		}
+		added = true
+	})
+	return added
+}
+
 // NormalizeDiscordMessage replaces all mentions to real name from the message.
 func (bot *DiscordBot) NormalizeDiscordMessage(message string) string {
 	userRegex := regexp.MustCompile(`<@!?(\d{17,19})>`)
@@ -3,6 +3,8 @@ package discordbot
 import (
 	"regexp"
 	"testing"
+
+	"github.com/bwmarrin/discordgo"
 )
 
 func TestReplaceTextAll(t *testing.T) {
@@ -254,8 +256,16 @@ func TestCommands_Structure(t *testing.T) {
 	}
 
 	expectedCommands := map[string]bool{
-		"link":     false,
-		"password": false,
+		"link":      false,
+		"password":  false,
+		"status":    false,
+		"who":       false,
+		"character": false,
+		"ban":       false,
+		"mute":      false,
+		"unmute":    false,
+		"kick":      false,
+		"announce":  false,
 	}
 
 	for _, cmd := range Commands {
@@ -413,3 +423,18 @@ func BenchmarkReplaceTextAll_NoMatches(b *testing.B) {
 		_ = ReplaceTextAll(text, userRegex, handler)
 	}
 }
+
+func TestAddHandlersOnce(t *testing.T) {
+	bot := &DiscordBot{Session: &discordgo.Session{}}
+	handler := func(*discordgo.Session, *discordgo.MessageCreate) {}
+
+	if !bot.AddHandlersOnce(handler) {
+		t.Error("first call should add the handlers")
+	}
+	if bot.AddHandlersOnce(handler) {
+		t.Error("second call should not add the handlers again")
+	}
+	if other := (&DiscordBot{Session: &discordgo.Session{}}); !other.AddHandlersOnce(handler) {
+		t.Error("a separate bot should get its own handlers")
+	}
+}
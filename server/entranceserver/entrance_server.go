@@ -10,6 +10,7 @@ import (
 
 	cfg "erupe-ce/config"
 	"erupe-ce/network"
+	"erupe-ce/network/transport"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
@@ -52,8 +53,13 @@ func (s *Server) Start() error {
 	if err != nil {
 		return err
 	}
+	tl, err := transport.Listen(l, s.erupeConfig.Entrance.Transport)
+	if err != nil {
+		_ = l.Close()
+		return err
+	}
 
-	s.listener = l
+	s.listener = tl
 
 	go s.acceptClients()
 
@@ -122,7 +128,8 @@ func (s *Server) handleEntranceServerConnection(conn net.Conn) {
 		s.logger.Debug("Inbound packet", zap.Int("bytes", len(pkt)), zap.String("data", hex.Dump(pkt)))
 	}
 
-	local := strings.Split(conn.RemoteAddr().String(), ":")[0] == "127.0.0.1"
+	// Tunneled clients reach the channels through their local companion proxy.
+	local := strings.Split(conn.RemoteAddr().String(), ":")[0] == "127.0.0.1" || transport.Secured(conn)
 
 	data := makeSv2Resp(s.erupeConfig, s, local)
 	if len(pkt) > 5 {
@@ -9,6 +9,7 @@ import (
 
 	"erupe-ce/common/byteframe"
 	"erupe-ce/common/gametime"
+	"erupe-ce/common/mhfguild"
 	"go.uber.org/zap"
 )
 
@@ -98,6 +99,9 @@ func encodeServerInfo(config *cfg.Config, s *Server, local bool) []byte {
 			maxClanMembers = lastRow[1]
 		}
 	}
+	if ranks := config.Guild.Ranks; len(ranks) > 0 {
+		maxClanMembers = min(ranks[len(ranks)-1].Members, mhfguild.MaxMembers)
+	}
 	bf.WriteUint32(uint32(maxClanMembers))
 
 	return bf.Data()
@@ -0,0 +1,6 @@
+-- Discord users muted from the chat relay into the game.
+CREATE TABLE IF NOT EXISTS public.discord_relay_mutes (
+    discord_id text PRIMARY KEY,
+    muted_by text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
@@ -0,0 +1,2 @@
+-- Per-character language preference. NULL uses the server default language.
+ALTER TABLE public.characters ADD COLUMN IF NOT EXISTS language text;
@@ -0,0 +1,16 @@
+-- Scheduled server announcements, worldcast by the channel servers.
+--   once:      sent at send_at
+--   recurring: sent on every minute matching the cron expression in schedule
+--   countdown: sent the given number of minutes before send_at
+CREATE TABLE IF NOT EXISTS public.announcements (
+    id serial PRIMARY KEY,
+    kind text NOT NULL CHECK (kind IN ('once', 'recurring', 'countdown')),
+    message text NOT NULL,
+    send_at timestamp with time zone,
+    schedule text,
+    countdown integer[],
+    enabled boolean DEFAULT true NOT NULL,
+    last_sent_at timestamp with time zone,
+    created_by text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
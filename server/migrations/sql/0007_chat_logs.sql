@@ -0,0 +1,18 @@
+-- Chat messages of every broadcast type, recorded when Chat.Log is enabled.
+-- message is the text as sent; filter_action records what the chat filter
+-- did with it ('', 'masked' or 'dropped').
+CREATE TABLE IF NOT EXISTS public.chat_logs (
+    id bigserial PRIMARY KEY,
+    char_id integer NOT NULL,
+    sender_name text NOT NULL,
+    chat_type text NOT NULL,
+    channel_id integer NOT NULL,
+    stage_id text DEFAULT '' NOT NULL,
+    targets integer[],
+    message text NOT NULL,
+    filter_action text DEFAULT '' NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS chat_logs_created_at_idx ON public.chat_logs (created_at);
+CREATE INDEX IF NOT EXISTS chat_logs_char_id_idx ON public.chat_logs (char_id, created_at);
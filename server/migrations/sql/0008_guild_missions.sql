@@ -0,0 +1,31 @@
+-- Guild missions. rotation numbers the period of Guild.Missions.RotationDays
+-- the mission was offered in, so targets, counts and completions from earlier
+-- rotations are simply ignored.
+
+-- The mission each guild is currently working on.
+CREATE TABLE IF NOT EXISTS public.guild_mission_targets (
+    guild_id integer PRIMARY KEY,
+    mission_id integer NOT NULL,
+    rotation integer NOT NULL,
+    set_by integer NOT NULL,
+    set_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+-- Each member's contribution to a mission.
+CREATE TABLE IF NOT EXISTS public.guild_mission_counts (
+    guild_id integer NOT NULL,
+    mission_id integer NOT NULL,
+    rotation integer NOT NULL,
+    character_id integer NOT NULL,
+    count integer DEFAULT 0 NOT NULL,
+    PRIMARY KEY (guild_id, mission_id, rotation, character_id)
+);
+
+-- Missions a guild has completed, so each pays out once per rotation.
+CREATE TABLE IF NOT EXISTS public.guild_mission_completions (
+    guild_id integer NOT NULL,
+    mission_id integer NOT NULL,
+    rotation integer NOT NULL,
+    completed_at timestamp with time zone DEFAULT now() NOT NULL,
+    PRIMARY KEY (guild_id, mission_id, rotation)
+);
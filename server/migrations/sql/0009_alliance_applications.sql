@@ -0,0 +1,14 @@
+-- Alliance applications. recruiting is the parent guild leader's toggle for
+-- accepting new applications; guilds wait in guild_alliance_applications
+-- until the parent guild leader accepts or declines them.
+ALTER TABLE public.guild_alliances ADD COLUMN IF NOT EXISTS recruiting boolean DEFAULT true NOT NULL;
+
+CREATE TABLE IF NOT EXISTS public.guild_alliance_applications (
+    alliance_id integer NOT NULL,
+    guild_id integer NOT NULL,
+    applied_by integer NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    PRIMARY KEY (alliance_id, guild_id)
+);
+
+CREATE INDEX IF NOT EXISTS guild_alliance_applications_guild_id_idx ON public.guild_alliance_applications (guild_id);
@@ -0,0 +1,13 @@
+-- Guild audit log: who changed what in a guild. Entries outlive the guild
+-- and are pruned after Guild.AuditLogDays.
+CREATE TABLE IF NOT EXISTS public.guild_audit_log (
+    id serial PRIMARY KEY,
+    guild_id integer NOT NULL,
+    actor_id integer NOT NULL,
+    target_id integer DEFAULT 0 NOT NULL,
+    action varchar(32) NOT NULL,
+    detail text DEFAULT ''::text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS guild_audit_log_guild_id_created_at_idx ON public.guild_audit_log (guild_id, created_at);
@@ -0,0 +1,13 @@
+-- Guild item box history. quantity is positive for deposits and negative for
+-- withdrawals; the day's withdrawals count against Guild.ItemWithdrawalLimits.
+CREATE TABLE IF NOT EXISTS public.guild_item_transactions (
+    id serial PRIMARY KEY,
+    guild_id integer NOT NULL,
+    character_id integer NOT NULL,
+    item_id integer NOT NULL,
+    quantity integer NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS guild_item_transactions_guild_id_character_id_created_at_idx
+    ON public.guild_item_transactions (guild_id, character_id, created_at);
@@ -0,0 +1,20 @@
+-- Guild weekly bonus. week_start is the Monday (JST) the week began, so rows
+-- from earlier weeks are simply ignored.
+
+-- Quests cleared and large monsters hunted by each member in a week.
+CREATE TABLE IF NOT EXISTS public.guild_weekly_activity (
+    guild_id integer NOT NULL,
+    week_start timestamp with time zone NOT NULL,
+    character_id integer NOT NULL,
+    quests integer DEFAULT 0 NOT NULL,
+    kills integer DEFAULT 0 NOT NULL,
+    PRIMARY KEY (guild_id, week_start, character_id)
+);
+
+-- Exceptional users registered towards a guild's weekly bonus.
+CREATE TABLE IF NOT EXISTS public.guild_weekly_bonus (
+    guild_id integer NOT NULL,
+    week_start timestamp with time zone NOT NULL,
+    exceptional_users integer DEFAULT 0 NOT NULL,
+    PRIMARY KEY (guild_id, week_start)
+);
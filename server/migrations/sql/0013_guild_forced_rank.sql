@@ -0,0 +1,2 @@
+-- Guild rank override set by operators. NULL derives the rank from rank_rp.
+ALTER TABLE public.guilds ADD COLUMN IF NOT EXISTS forced_rank smallint;
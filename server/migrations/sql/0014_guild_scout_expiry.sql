@@ -0,0 +1,4 @@
+-- Guild scout invitations expire after Guild.Scouts.ExpiryHours; index the
+-- sweep over outstanding invitations.
+CREATE INDEX IF NOT EXISTS guild_applications_invited_created_at
+    ON public.guild_applications (created_at) WHERE application_type = 'invited';
@@ -0,0 +1,14 @@
+-- Clan treasure hunts now outlive restarts and logouts and are ended by a
+-- sweeper once they expire.
+
+-- Souvenirs earned by members who reported on an ended hunt, kept until the
+-- member collects them.
+CREATE TABLE IF NOT EXISTS public.guild_hunt_souvenirs (
+    character_id integer NOT NULL,
+    destination integer NOT NULL,
+    quantity integer DEFAULT 0 NOT NULL,
+    PRIMARY KEY (character_id, destination)
+);
+
+CREATE INDEX IF NOT EXISTS guild_characters_treasure_hunt
+    ON public.guild_characters (treasure_hunt) WHERE treasure_hunt IS NOT NULL;
@@ -0,0 +1,12 @@
+-- Guild audit log entries name their target character and target guild in
+-- separate columns instead of one target_id whose meaning depended on the
+-- action. Alliance entries targeted guilds; every other action targeted a
+-- character.
+ALTER TABLE public.guild_audit_log
+    ADD COLUMN IF NOT EXISTS target_char_id integer DEFAULT 0 NOT NULL,
+    ADD COLUMN IF NOT EXISTS target_guild_id integer DEFAULT 0 NOT NULL;
+
+UPDATE public.guild_audit_log SET target_guild_id = target_id WHERE action LIKE 'alliance%';
+UPDATE public.guild_audit_log SET target_char_id = target_id WHERE action NOT LIKE 'alliance%';
+
+ALTER TABLE public.guild_audit_log DROP COLUMN IF EXISTS target_id;
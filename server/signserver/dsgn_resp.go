@@ -6,6 +6,7 @@ import (
 	ps "erupe-ce/common/pascalstring"
 	"erupe-ce/common/stringsupport"
 	cfg "erupe-ce/config"
+	"erupe-ce/network/transport"
 	"fmt"
 	"strings"
 	"time"
@@ -58,7 +59,9 @@ func (s *Session) makeSignResponse(uid uint32) []byte {
 		ps.Uint8(bf, s.server.erupeConfig.PatchServerManifest, false)
 		ps.Uint8(bf, s.server.erupeConfig.PatchServerFile, false)
 	}
-	if strings.Split(s.rawConn.RemoteAddr().String(), ":")[0] == "127.0.0.1" {
+	// Tunneled clients reach the entrance server through their local
+	// companion proxy.
+	if strings.Split(s.rawConn.RemoteAddr().String(), ":")[0] == "127.0.0.1" || transport.Secured(s.rawConn) {
 		ps.Uint8(bf, fmt.Sprintf("127.0.0.1:%d", s.server.erupeConfig.Entrance.Port), false)
 	} else {
 		ps.Uint8(bf, fmt.Sprintf("%s:%d", s.server.erupeConfig.Host, s.server.erupeConfig.Entrance.Port), false)
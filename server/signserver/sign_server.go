@@ -8,6 +8,7 @@ import (
 
 	cfg "erupe-ce/config"
 	"erupe-ce/network"
+	"erupe-ce/network/transport"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
@@ -51,7 +52,12 @@ func (s *Server) Start() error {
 	if err != nil {
 		return err
 	}
-	s.listener = l
+	tl, err := transport.Listen(l, s.erupeConfig.Sign.Transport)
+	if err != nil {
+		_ = l.Close()
+		return err
+	}
+	s.listener = tl
 
 	go s.acceptClients()
 